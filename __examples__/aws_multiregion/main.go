@@ -6,51 +6,35 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 
 	"github.com/borderzero/discovery"
 	"github.com/borderzero/discovery/discoverers"
 	"github.com/borderzero/discovery/engines"
+	"github.com/borderzero/discovery/utils"
 )
 
 func main() {
-	awsRegions := []string{
-		"af-south-1",
-		"ap-east-1",
-		"ap-northeast-1",
-		"ap-northeast-2",
-		"ap-south-1",
-		"ap-southeast-1",
-		"ap-southeast-2",
-		"ca-central-1",
-		"eu-central-1",
-		"eu-north-1",
-		"eu-south-1",
-		"eu-west-1",
-		"eu-west-2",
-		"eu-west-3",
-		"me-south-1",
-		"sa-east-1",
-		"us-east-1",
-		"us-east-2",
-		"us-west-1",
-		"us-west-2",
-	}
-
 	ctx := context.Background()
 	cfg, err := config.LoadDefaultConfig(ctx)
 	if err != nil {
-		log.Fatalf("unable to load SDK config, %w", err)
+		log.Fatalf("unable to load SDK config, %v", err)
 	}
 
-	ds := []discovery.Discoverer{}
-	for _, region := range awsRegions {
-		cfg.Region = region
-		ds = append(ds, discoverers.NewAwsEc2Discoverer(cfg))
-	}
+	// runs an ec2 discoverer in every enabled region of the account
+	multiRegionEc2Discoverer := discoverers.NewAwsMultiRegionDiscoverer(
+		cfg,
+		func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			return discoverers.NewAwsEc2Discoverer(
+				cfg,
+				discoverers.WithAwsEc2DiscovererStsClient(stsClient),
+			)
+		},
+	)
 
 	engine := engines.NewOneOffEngine(
-		engines.OneOffEngineOptionWithDiscoverers(ds...),
+		engines.OneOffEngineOptionWithDiscoverers(multiRegionEc2Discoverer),
 	)
 
 	results := make(chan *discovery.Result, 10)
@@ -60,7 +44,7 @@ func main() {
 	for result := range results {
 		byt, err := json.Marshal(result)
 		if err != nil {
-			fmt.Println(fmt.Sprintf("[ERROR] failed to json encode result: %w", err))
+			fmt.Println(fmt.Sprintf("[ERROR] failed to json encode result: %v", err))
 		}
 		fmt.Println(string(byt))
 	}
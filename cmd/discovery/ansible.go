@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/exporters"
+)
+
+// runAnsible runs all discoverers once and prints an Ansible dynamic inventory
+// (list) or the hostvars of a single host, as expected from an inventory script.
+// Since Ansible invokes inventory scripts without any other flags, the
+// configuration file is usually set with the DISCOVERY_CONFIG environment variable.
+func runAnsible(ctx context.Context, configPath string, list bool, host string) error {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	cfg.Mode = modeOnce // inventory scripts must exit
+
+	engine, err := buildEngine(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	results := make(chan *discovery.Result, 10)
+
+	go engine.Run(ctx, results)
+
+	collected := []*discovery.Result{}
+	for result := range results {
+		printProblems(result) // stderr, stdout must be valid json
+		collected = append(collected, result)
+	}
+
+	exporter := exporters.NewAnsibleExporter()
+	if list {
+		return exporter.WriteList(os.Stdout, collected)
+	}
+	return exporter.WriteHost(os.Stdout, collected, host)
+}
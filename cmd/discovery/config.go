@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/registry"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	modeOnce       = "once"
+	modeContinuous = "continuous"
+)
+
+// config is the top-level configuration file.
+type config struct {
+	Mode        string             `yaml:"mode"`
+	Discoverers []discovererConfig `yaml:"discoverers"`
+}
+
+// discovererConfig declares a single discoverer. The options are
+// specific to the kind, see the registry package for each kind's options.
+type discovererConfig struct {
+	Kind     string          `yaml:"kind"`
+	Interval time.Duration   `yaml:"interval"` // only used in continuous mode
+	Options  registry.Config `yaml:"options"`
+}
+
+// loadConfig reads a YAML (or JSON, which is a subset of YAML) configuration
+// file, rejecting unknown keys so that typos do not go unnoticed.
+func loadConfig(path string) (*config, error) {
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read configuration file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(byt))
+	decoder.KnownFields(true)
+
+	cfg := &config{Mode: modeOnce}
+	if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to decode configuration file: %v", err)
+	}
+	if cfg.Mode != modeOnce && cfg.Mode != modeContinuous {
+		return nil, fmt.Errorf("invalid mode \"%s\" (must be \"%s\" or \"%s\")", cfg.Mode, modeOnce, modeContinuous)
+	}
+	if len(cfg.Discoverers) == 0 {
+		return nil, fmt.Errorf("no discoverers declared in configuration file")
+	}
+	return cfg, nil
+}
+
+// buildDiscoverer returns the discoverer declared by a discovererConfig.
+func buildDiscoverer(ctx context.Context, dc discovererConfig) (discovery.Discoverer, error) {
+	return registry.Build(ctx, dc.Kind, dc.Options)
+}
@@ -0,0 +1,156 @@
+// Command discovery runs the discoverers declared in a YAML or JSON
+// configuration file, either once or continuously, and prints results.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/engines"
+	"github.com/borderzero/discovery/formats"
+)
+
+const (
+	defaultConfigPath = "discovery.yaml"
+
+	// configPathEnvVar is the environment variable overriding the default configuration
+	// file path, e.g. for when invoked by Ansible as an inventory script (without flags).
+	configPathEnvVar = "DISCOVERY_CONFIG"
+
+	// outputJson prints full results (including metadata) as JSON lines,
+	// any other output is the name of a format in the formats package.
+	outputJson = "json"
+)
+
+func main() {
+	configPathDefault := defaultConfigPath
+	if path := os.Getenv(configPathEnvVar); path != "" {
+		configPathDefault = path
+	}
+
+	configPath := flag.String("config", configPathDefault, "path to the YAML or JSON configuration file (defaults to $"+configPathEnvVar+" if set)")
+	mode := flag.String("mode", "", "run mode, either \"once\" or \"continuous\" (overrides the configuration file)")
+	output := flag.String("output", outputJson, "output format, one of: json, table, csv, yaml, ndjson")
+	list := flag.Bool("list", false, "print an Ansible dynamic inventory and exit (Ansible inventory script mode)")
+	host := flag.String("host", "", "print the Ansible hostvars of a host and exit (Ansible inventory script mode)")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var err error
+	if *list || *host != "" {
+		err = runAnsible(ctx, *configPath, *list, *host)
+	} else {
+		err = run(ctx, *configPath, *mode, *output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, configPath, mode, output string) error {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	if mode != "" {
+		cfg.Mode = mode
+	}
+
+	var formatter formats.Formatter
+	if output != outputJson {
+		if formatter, err = formats.NewFormatter(output); err != nil {
+			return err
+		}
+	}
+
+	engine, err := buildEngine(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	results := make(chan *discovery.Result, 10)
+
+	go engine.Run(ctx, results)
+
+	if formatter == nil {
+		encoder := json.NewEncoder(os.Stdout)
+		for result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return fmt.Errorf("failed to json encode result: %v", err)
+			}
+		}
+		return nil
+	}
+
+	// formats only render resources, so errors and warnings go to stderr.
+	// In once mode all results are rendered together at the end so that
+	// the output is sorted as a whole, in continuous mode each result is
+	// rendered as it arrives.
+	collected := []*discovery.Result{}
+	for result := range results {
+		printProblems(result)
+		if cfg.Mode == modeOnce {
+			collected = append(collected, result)
+			continue
+		}
+		if err := formatter.Format(os.Stdout, []*discovery.Result{result}); err != nil {
+			return err
+		}
+	}
+	if cfg.Mode == modeOnce {
+		return formatter.Format(os.Stdout, collected)
+	}
+	return nil
+}
+
+// printProblems prints the errors and warnings of a result to stderr.
+func printProblems(result *discovery.Result) {
+	result.Lock()
+	defer result.Unlock()
+
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "error: %s: %s\n", result.Metadata.DiscovererId, e)
+	}
+	for _, w := range result.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", result.Metadata.DiscovererId, w)
+	}
+}
+
+func buildEngine(ctx context.Context, cfg *config) (discovery.Engine, error) {
+	switch cfg.Mode {
+	case modeOnce:
+		ds := []discovery.Discoverer{}
+		for i, dc := range cfg.Discoverers {
+			d, err := buildDiscoverer(ctx, dc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid discoverer at index %d: %v", i, err)
+			}
+			ds = append(ds, d)
+		}
+		return engines.NewOneOffEngine(engines.OneOffEngineOptionWithDiscoverers(ds...)), nil
+	case modeContinuous:
+		opts := []engines.ContinuousEngineOption{}
+		for i, dc := range cfg.Discoverers {
+			d, err := buildDiscoverer(ctx, dc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid discoverer at index %d: %v", i, err)
+			}
+			dopts := []engines.DiscovererOption{}
+			if dc.Interval > 0 {
+				dopts = append(dopts, engines.WithInitialInterval(dc.Interval))
+			}
+			opts = append(opts, engines.WithDiscoverer(d, dopts...))
+		}
+		return engines.NewContinuousEngine(opts...), nil
+	}
+	return nil, fmt.Errorf("invalid mode \"%s\" (must be \"%s\" or \"%s\")", cfg.Mode, modeOnce, modeContinuous)
+}
@@ -0,0 +1,89 @@
+package discovery
+
+import (
+	"reflect"
+	"sort"
+)
+
+const (
+	// DeltaTypeAdded is the delta type for resources not present in the previous result.
+	DeltaTypeAdded = "added"
+
+	// DeltaTypeUpdated is the delta type for resources whose details changed.
+	DeltaTypeUpdated = "updated"
+
+	// DeltaTypeRemoved is the delta type for resources not present in the current result.
+	DeltaTypeRemoved = "removed"
+)
+
+// ResourceDelta represents a change to a resource between two results of the same discoverer.
+type ResourceDelta struct {
+	DeltaType    string `json:"delta_type"`
+	Key          string `json:"key"`
+	DiscovererId string `json:"discoverer_id"`
+
+	// Resource is the current version of the resource,
+	// or the last known version for removed resources.
+	Resource Resource `json:"resource"`
+}
+
+// Diff returns the changes to resources between two results of the same
+// discoverer, sorted by resource key. The previous result may be nil.
+//
+// Resources are only reported as updated when they differ in more than their
+// volatile fields (see Resource.WithoutVolatileFields), so that e.g. a flapping
+// reachability check does not produce an update on every run. Updates carry
+// the current version of the resource, volatile fields included.
+//
+// If the current result has errors, resources missing from it are not
+// reported as removed, since the discoverer may have failed to list them.
+func Diff(previous, current *Result) []ResourceDelta {
+	before := map[string]Resource{}
+	if previous != nil {
+		previous.Lock()
+		for _, resource := range previous.Resources {
+			before[resource.Key()] = resource
+		}
+		previous.Unlock()
+	}
+
+	current.Lock()
+	defer current.Unlock()
+
+	discovererId := current.Metadata.DiscovererId
+
+	deltas := []ResourceDelta{}
+	after := map[string]struct{}{}
+	for _, resource := range current.Resources {
+		key := resource.Key()
+		after[key] = struct{}{}
+
+		old, existed := before[key]
+		if !existed {
+			deltas = append(deltas, newResourceDelta(DeltaTypeAdded, key, discovererId, resource))
+			continue
+		}
+		if !reflect.DeepEqual(old.WithoutVolatileFields(), resource.WithoutVolatileFields()) {
+			deltas = append(deltas, newResourceDelta(DeltaTypeUpdated, key, discovererId, resource))
+		}
+	}
+	if len(current.Errors) == 0 {
+		for key, resource := range before {
+			if _, exists := after[key]; !exists {
+				deltas = append(deltas, newResourceDelta(DeltaTypeRemoved, key, discovererId, resource))
+			}
+		}
+	}
+
+	sort.SliceStable(deltas, func(i, j int) bool { return deltas[i].Key < deltas[j].Key })
+	return deltas
+}
+
+func newResourceDelta(deltaType, key, discovererId string, resource Resource) ResourceDelta {
+	return ResourceDelta{
+		DeltaType:    deltaType,
+		Key:          key,
+		DiscovererId: discovererId,
+		Resource:     resource,
+	}
+}
@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	newResult := func(errored bool, resources ...Resource) *Result {
+		result := NewResult("d")
+		result.AddResources(resources...)
+		if errored {
+			result.AddError("failed to list some resources")
+		}
+		result.Done()
+		return result
+	}
+	ec2 := func(instanceType string, reachable bool) Resource {
+		return Resource{
+			ResourceType: ResourceTypeAwsEc2Instance,
+			AwsEc2InstanceDetails: &AwsEc2InstanceDetails{
+				InstanceId:                "i-1",
+				InstanceType:              instanceType,
+				PrivateIpAddressReachable: &reachable,
+			},
+		}
+	}
+	ssm := func(pingStatus string, lastPing time.Time) Resource {
+		return Resource{
+			ResourceType: ResourceTypeAwsSsmTarget,
+			AwsSsmTargetDetails: &AwsSsmTargetDetails{
+				InstanceId:       "mi-1",
+				PingStatus:       pingStatus,
+				LastPingDateTime: &lastPing,
+			},
+		}
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		previous       *Result
+		current        *Result
+		expectedDeltas []string // delta type
+	}{
+		{
+			name:           "added without a previous result",
+			current:        newResult(false, ec2("t3.micro", true)),
+			expectedDeltas: []string{DeltaTypeAdded},
+		},
+		{
+			name:           "updated details",
+			previous:       newResult(false, ec2("t3.micro", true)),
+			current:        newResult(false, ec2("t3.large", true)),
+			expectedDeltas: []string{DeltaTypeUpdated},
+		},
+		{
+			name:           "unchanged reachability flip",
+			previous:       newResult(false, ec2("t3.micro", true)),
+			current:        newResult(false, ec2("t3.micro", false)),
+			expectedDeltas: []string{},
+		},
+		{
+			name:           "unchanged ssm ping",
+			previous:       newResult(false, ssm("Online", now.Add(-time.Minute))),
+			current:        newResult(false, ssm("Online", now)),
+			expectedDeltas: []string{},
+		},
+		{
+			name:           "updated ssm ping status",
+			previous:       newResult(false, ssm("Online", now.Add(-time.Minute))),
+			current:        newResult(false, ssm("ConnectionLost", now)),
+			expectedDeltas: []string{DeltaTypeUpdated},
+		},
+		{
+			name:           "removed",
+			previous:       newResult(false, ec2("t3.micro", true)),
+			current:        newResult(false),
+			expectedDeltas: []string{DeltaTypeRemoved},
+		},
+		{
+			name:           "not removed from errored results",
+			previous:       newResult(false, ec2("t3.micro", true)),
+			current:        newResult(true),
+			expectedDeltas: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deltas := Diff(test.previous, test.current)
+			if len(deltas) != len(test.expectedDeltas) {
+				t.Fatalf("expected deltas %v, got %v", test.expectedDeltas, deltas)
+			}
+			for i, delta := range deltas {
+				if delta.DeltaType != test.expectedDeltas[i] {
+					t.Fatalf("expected deltas %v, got %v", test.expectedDeltas, deltas)
+				}
+			}
+		})
+	}
+}
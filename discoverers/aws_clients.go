@@ -0,0 +1,105 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// AwsEc2Client represents the subset of the AWS EC2 API used by discoverers.
+type AwsEc2Client interface {
+	ec2.DescribeInstancesAPIClient
+	ec2.DescribeInstanceConnectEndpointsAPIClient
+}
+
+// AwsEc2NetworkClient represents the subset of the AWS EC2 API used for static reachability analysis.
+type AwsEc2NetworkClient interface {
+	ec2.DescribeSecurityGroupRulesAPIClient
+	ec2.DescribeNetworkAclsAPIClient
+	ec2.DescribeRouteTablesAPIClient
+	ec2.DescribeNetworkInterfacesAPIClient
+}
+
+// AwsEc2RegionsClient represents the subset of the AWS EC2 API used to enumerate regions.
+type AwsEc2RegionsClient interface {
+	DescribeRegions(context.Context, *ec2.DescribeRegionsInput, ...func(*ec2.Options)) (*ec2.DescribeRegionsOutput, error)
+}
+
+// AwsSsmClient represents the subset of the AWS SSM API used by discoverers.
+type AwsSsmClient interface {
+	ssm.DescribeInstanceInformationAPIClient
+}
+
+// AwsEcsClient represents the subset of the AWS ECS API used by discoverers.
+type AwsEcsClient interface {
+	ecs.ListClustersAPIClient
+	ecs.ListServicesAPIClient
+	DescribeServices(context.Context, *ecs.DescribeServicesInput, ...func(*ecs.Options)) (*ecs.DescribeServicesOutput, error)
+}
+
+// AwsEksClient represents the subset of the AWS EKS API used by discoverers.
+type AwsEksClient interface {
+	eks.ListClustersAPIClient
+	eks.DescribeClusterAPIClient
+}
+
+// AwsRdsClient represents the subset of the AWS RDS API used by discoverers.
+type AwsRdsClient interface {
+	rds.DescribeDBInstancesAPIClient
+	rds.DescribeDBClustersAPIClient
+	rds.DescribeDBClusterEndpointsAPIClient
+	rds.DescribeDBProxiesAPIClient
+	rds.DescribeDBProxyEndpointsAPIClient
+	rds.DescribeDBProxyTargetGroupsAPIClient
+	rds.DescribeDBProxyTargetsAPIClient
+	ListTagsForResource(context.Context, *rds.ListTagsForResourceInput, ...func(*rds.Options)) (*rds.ListTagsForResourceOutput, error)
+}
+
+// AwsOrganizationsClient represents the subset of the AWS Organizations API used by discoverers.
+type AwsOrganizationsClient interface {
+	organizations.ListAccountsAPIClient
+}
+
+// ensure the AWS SDK clients implement the client interfaces at compile-time.
+var (
+	_ AwsEc2Client           = (*ec2.Client)(nil)
+	_ AwsEc2NetworkClient    = (*ec2.Client)(nil)
+	_ AwsEc2RegionsClient    = (*ec2.Client)(nil)
+	_ AwsSsmClient           = (*ssm.Client)(nil)
+	_ AwsEcsClient           = (*ecs.Client)(nil)
+	_ AwsEksClient           = (*eks.Client)(nil)
+	_ AwsRdsClient           = (*rds.Client)(nil)
+	_ AwsOrganizationsClient = (*organizations.Client)(nil)
+)
+
+// awsPaginator represents the paginators of AWS APIs, with T the output of
+// an api call and O the options of the api client.
+type awsPaginator[T any, O any] interface {
+	HasMorePages() bool
+	NextPage(context.Context, ...func(*O)) (T, error)
+}
+
+// collectAwsPages returns all the pages of an AWS API paginator, with the timeout applied to each page.
+func collectAwsPages[T any, O any](ctx context.Context, paginator awsPaginator[T, O], timeout time.Duration) ([]T, error) {
+	pages := []T{}
+	for paginator.HasMorePages() {
+		page, err := func() (T, error) {
+			pageCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			return paginator.NextPage(pageCtx)
+		}()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get next page: %v", err)
+		}
+		pages = append(pages, page)
+	}
+	return pages, nil
+}
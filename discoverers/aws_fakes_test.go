@@ -0,0 +1,541 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	ekstypes "github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+)
+
+const fakeAwsAccountId = "123456789012"
+
+// fakePages represents the pages of a fake paginated AWS api call. Pages are
+// linked by tokens holding the index of the next page, and requesting the
+// page at an index in errs fails with that error.
+type fakePages[T any] struct {
+	sync.Mutex
+
+	pages     []T
+	errs      map[int]error
+	requested []int // indexes of the pages requested, in order
+}
+
+// page returns the page for a token (nil for the first page)
+// along with the token of the next page (nil for the last page).
+func (fp *fakePages[T]) page(token *string) (T, *string, error) {
+	fp.Lock()
+	defer fp.Unlock()
+
+	var zero T
+
+	index := 0
+	if token != nil {
+		parsed, err := strconv.Atoi(aws.ToString(token))
+		if err != nil {
+			return zero, nil, fmt.Errorf("invalid token %q", aws.ToString(token))
+		}
+		index = parsed
+	}
+	fp.requested = append(fp.requested, index)
+
+	if err, ok := fp.errs[index]; ok {
+		return zero, nil, err
+	}
+	if index >= len(fp.pages) {
+		return zero, nil, nil // no pages, as apis respond to empty lists
+	}
+	var next *string
+	if index+1 < len(fp.pages) {
+		next = aws.String(strconv.Itoa(index + 1))
+	}
+	return fp.pages[index], next, nil
+}
+
+// setErr makes requesting the first page fail with an error (if not nil).
+func (fp *fakePages[T]) setErr(err error) {
+	fp.Lock()
+	defer fp.Unlock()
+
+	if err != nil {
+		fp.errs = map[int]error{0: err}
+	}
+}
+
+// fakeStsClient is a fake utils.AwsStsClient, for a caller
+// in the given partition (or in the "aws" partition), which
+// fails to assume the roles in roleErrs.
+type fakeStsClient struct {
+	err       error
+	partition string
+	roleErrs  map[string]error // by role arn
+
+	getCallerIdentityCalls atomic.Int64
+
+	mu      sync.Mutex // protects assumed
+	assumed []sts.AssumeRoleInput
+}
+
+func (f *fakeStsClient) GetCallerIdentity(
+	context.Context,
+	*sts.GetCallerIdentityInput,
+	...func(*sts.Options),
+) (*sts.GetCallerIdentityOutput, error) {
+	f.getCallerIdentityCalls.Add(1)
+	if f.err != nil {
+		return nil, f.err
+	}
+	partition := f.partition
+	if partition == "" {
+		partition = "aws"
+	}
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(fakeAwsAccountId),
+		Arn:     aws.String(fmt.Sprintf("arn:%s:iam::%s:user/fake", partition, fakeAwsAccountId)),
+	}, nil
+}
+
+func (f *fakeStsClient) AssumeRole(
+	_ context.Context,
+	input *sts.AssumeRoleInput,
+	_ ...func(*sts.Options),
+) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	f.assumed = append(f.assumed, *input)
+	f.mu.Unlock()
+
+	if f.err != nil {
+		return nil, f.err
+	}
+	if err, ok := f.roleErrs[aws.ToString(input.RoleArn)]; ok {
+		return nil, err
+	}
+	return &sts.AssumeRoleOutput{Credentials: &ststypes.Credentials{
+		AccessKeyId:     aws.String("AKIAFAKE"),
+		SecretAccessKey: aws.String("fake"),
+		SessionToken:    aws.String("fake"),
+		Expiration:      aws.Time(time.Now().Add(time.Hour)),
+	}}, nil
+}
+
+// fakeEc2Client is a fake AwsEc2Client.
+type fakeEc2Client struct {
+	instances fakePages[*ec2.DescribeInstancesOutput]
+	endpoints fakePages[*ec2.DescribeInstanceConnectEndpointsOutput]
+}
+
+func (f *fakeEc2Client) DescribeInstances(
+	_ context.Context,
+	input *ec2.DescribeInstancesInput,
+	_ ...func(*ec2.Options),
+) (*ec2.DescribeInstancesOutput, error) {
+	page, next, err := f.instances.page(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &ec2.DescribeInstancesOutput{NextToken: next}
+	if page != nil {
+		output.Reservations = page.Reservations
+	}
+	return output, nil
+}
+
+func (f *fakeEc2Client) DescribeInstanceConnectEndpoints(
+	_ context.Context,
+	input *ec2.DescribeInstanceConnectEndpointsInput,
+	_ ...func(*ec2.Options),
+) (*ec2.DescribeInstanceConnectEndpointsOutput, error) {
+	page, next, err := f.endpoints.page(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &ec2.DescribeInstanceConnectEndpointsOutput{NextToken: next}
+	if page != nil {
+		output.InstanceConnectEndpoints = page.InstanceConnectEndpoints
+	}
+	return output, nil
+}
+
+// assumedRoles returns the sorted arns of the roles assumed so far.
+func (f *fakeStsClient) assumedRoles() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roleArns := []string{}
+	for _, input := range f.assumed {
+		roleArns = append(roleArns, aws.ToString(input.RoleArn))
+	}
+	slices.Sort(roleArns)
+	return roleArns
+}
+
+// fakeEc2RegionsClient is a fake AwsEc2RegionsClient.
+type fakeEc2RegionsClient struct {
+	regions []ec2types.Region
+	err     error
+}
+
+func (f *fakeEc2RegionsClient) DescribeRegions(
+	context.Context,
+	*ec2.DescribeRegionsInput,
+	...func(*ec2.Options),
+) (*ec2.DescribeRegionsOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ec2.DescribeRegionsOutput{Regions: f.regions}, nil
+}
+
+// fakeRdsClient is a fake AwsRdsClient.
+type fakeRdsClient struct {
+	instances         fakePages[*rds.DescribeDBInstancesOutput]
+	clusters          fakePages[*rds.DescribeDBClustersOutput]
+	clusterEndpoints  fakePages[*rds.DescribeDBClusterEndpointsOutput]
+	proxies           fakePages[*rds.DescribeDBProxiesOutput]
+	proxyEndpoints    fakePages[*rds.DescribeDBProxyEndpointsOutput]
+	proxyTargetGroups fakePages[*rds.DescribeDBProxyTargetGroupsOutput]
+	proxyTargets      fakePages[*rds.DescribeDBProxyTargetsOutput]
+	tags              map[string][]rdstypes.Tag // by resource arn
+	tagsErr           error
+}
+
+func (f *fakeRdsClient) DescribeDBInstances(
+	_ context.Context,
+	input *rds.DescribeDBInstancesInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBInstancesOutput, error) {
+	page, next, err := f.instances.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBInstancesOutput{Marker: next}
+	if page != nil {
+		output.DBInstances = page.DBInstances
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) DescribeDBClusters(
+	_ context.Context,
+	input *rds.DescribeDBClustersInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBClustersOutput, error) {
+	page, next, err := f.clusters.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBClustersOutput{Marker: next}
+	if page != nil {
+		output.DBClusters = page.DBClusters
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) DescribeDBClusterEndpoints(
+	_ context.Context,
+	input *rds.DescribeDBClusterEndpointsInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBClusterEndpointsOutput, error) {
+	page, next, err := f.clusterEndpoints.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBClusterEndpointsOutput{Marker: next}
+	if page != nil {
+		output.DBClusterEndpoints = page.DBClusterEndpoints
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) DescribeDBProxies(
+	_ context.Context,
+	input *rds.DescribeDBProxiesInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBProxiesOutput, error) {
+	page, next, err := f.proxies.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBProxiesOutput{Marker: next}
+	if page != nil {
+		output.DBProxies = page.DBProxies
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) DescribeDBProxyEndpoints(
+	_ context.Context,
+	input *rds.DescribeDBProxyEndpointsInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBProxyEndpointsOutput, error) {
+	page, next, err := f.proxyEndpoints.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBProxyEndpointsOutput{Marker: next}
+	if page != nil {
+		output.DBProxyEndpoints = page.DBProxyEndpoints
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) DescribeDBProxyTargetGroups(
+	_ context.Context,
+	input *rds.DescribeDBProxyTargetGroupsInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBProxyTargetGroupsOutput, error) {
+	page, next, err := f.proxyTargetGroups.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBProxyTargetGroupsOutput{Marker: next}
+	if page != nil {
+		output.TargetGroups = page.TargetGroups
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) DescribeDBProxyTargets(
+	_ context.Context,
+	input *rds.DescribeDBProxyTargetsInput,
+	_ ...func(*rds.Options),
+) (*rds.DescribeDBProxyTargetsOutput, error) {
+	page, next, err := f.proxyTargets.page(input.Marker)
+	if err != nil {
+		return nil, err
+	}
+	output := &rds.DescribeDBProxyTargetsOutput{Marker: next}
+	if page != nil {
+		output.Targets = page.Targets
+	}
+	return output, nil
+}
+
+func (f *fakeRdsClient) ListTagsForResource(
+	_ context.Context,
+	input *rds.ListTagsForResourceInput,
+	_ ...func(*rds.Options),
+) (*rds.ListTagsForResourceOutput, error) {
+	if f.tagsErr != nil {
+		return nil, f.tagsErr
+	}
+	return &rds.ListTagsForResourceOutput{TagList: f.tags[aws.ToString(input.ResourceName)]}, nil
+}
+
+// fakeSsmClient is a fake AwsSsmClient.
+type fakeSsmClient struct {
+	instanceInformation fakePages[*ssm.DescribeInstanceInformationOutput]
+}
+
+func (f *fakeSsmClient) DescribeInstanceInformation(
+	_ context.Context,
+	input *ssm.DescribeInstanceInformationInput,
+	_ ...func(*ssm.Options),
+) (*ssm.DescribeInstanceInformationOutput, error) {
+	page, next, err := f.instanceInformation.page(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &ssm.DescribeInstanceInformationOutput{NextToken: next}
+	if page != nil {
+		output.InstanceInformationList = page.InstanceInformationList
+	}
+	return output, nil
+}
+
+// fakeEcsClient is a fake AwsEcsClient serving
+// a single page of services for every cluster.
+type fakeEcsClient struct {
+	clusters fakePages[*ecs.ListClustersOutput]
+	services map[string][]ecstypes.Service // by cluster arn
+}
+
+func (f *fakeEcsClient) ListClusters(
+	_ context.Context,
+	input *ecs.ListClustersInput,
+	_ ...func(*ecs.Options),
+) (*ecs.ListClustersOutput, error) {
+	page, next, err := f.clusters.page(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &ecs.ListClustersOutput{NextToken: next}
+	if page != nil {
+		output.ClusterArns = page.ClusterArns
+	}
+	return output, nil
+}
+
+func (f *fakeEcsClient) ListServices(
+	_ context.Context,
+	input *ecs.ListServicesInput,
+	_ ...func(*ecs.Options),
+) (*ecs.ListServicesOutput, error) {
+	output := &ecs.ListServicesOutput{}
+	for _, service := range f.services[aws.ToString(input.Cluster)] {
+		output.ServiceArns = append(output.ServiceArns, aws.ToString(service.ServiceArn))
+	}
+	return output, nil
+}
+
+func (f *fakeEcsClient) DescribeServices(
+	_ context.Context,
+	input *ecs.DescribeServicesInput,
+	_ ...func(*ecs.Options),
+) (*ecs.DescribeServicesOutput, error) {
+	output := &ecs.DescribeServicesOutput{}
+	for _, service := range f.services[aws.ToString(input.Cluster)] {
+		for _, serviceArn := range input.Services {
+			if aws.ToString(service.ServiceArn) == serviceArn {
+				output.Services = append(output.Services, service)
+			}
+		}
+	}
+	return output, nil
+}
+
+// fakeEksClient is a fake AwsEksClient.
+type fakeEksClient struct {
+	clusterNames fakePages[*eks.ListClustersOutput]
+	clusters     map[string]*ekstypes.Cluster // by name
+}
+
+func (f *fakeEksClient) ListClusters(
+	_ context.Context,
+	input *eks.ListClustersInput,
+	_ ...func(*eks.Options),
+) (*eks.ListClustersOutput, error) {
+	page, next, err := f.clusterNames.page(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &eks.ListClustersOutput{NextToken: next}
+	if page != nil {
+		output.Clusters = page.Clusters
+	}
+	return output, nil
+}
+
+func (f *fakeEksClient) DescribeCluster(
+	_ context.Context,
+	input *eks.DescribeClusterInput,
+	_ ...func(*eks.Options),
+) (*eks.DescribeClusterOutput, error) {
+	cluster, ok := f.clusters[aws.ToString(input.Name)]
+	if !ok {
+		return nil, fmt.Errorf("cluster %q not found", aws.ToString(input.Name))
+	}
+	return &eks.DescribeClusterOutput{Cluster: cluster}, nil
+}
+
+// fakeOrganizationsClient is a fake AwsOrganizationsClient.
+type fakeOrganizationsClient struct {
+	accounts fakePages[*organizations.ListAccountsOutput]
+}
+
+func (f *fakeOrganizationsClient) ListAccounts(
+	_ context.Context,
+	input *organizations.ListAccountsInput,
+	_ ...func(*organizations.Options),
+) (*organizations.ListAccountsOutput, error) {
+	page, next, err := f.accounts.page(input.NextToken)
+	if err != nil {
+		return nil, err
+	}
+	output := &organizations.ListAccountsOutput{NextToken: next}
+	if page != nil {
+		output.Accounts = page.Accounts
+	}
+	return output, nil
+}
+
+// fakeAwsDiscoverers is a factory of fake per-region (or per-account) discoverers,
+// which discover a single ec2 instance with the id of their region, in the account
+// their sts client answers with, along with the errors and warnings configured for
+// their region.
+type fakeAwsDiscoverers struct {
+	sync.Mutex
+
+	discovererId string
+	errs         map[string][]string // by region
+	warnings     map[string][]string // by region
+	built        []string            // regions of the discoverers built, in order
+}
+
+func (f *fakeAwsDiscoverers) factory(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+	f.Lock()
+	defer f.Unlock()
+
+	f.built = append(f.built, cfg.Region)
+	return &fakeAwsDiscoverer{parent: f, region: cfg.Region, stsClient: stsClient}
+}
+
+// builtRegions returns the regions of the discoverers built so far, sorted.
+func (f *fakeAwsDiscoverers) builtRegions() []string {
+	f.Lock()
+	defer f.Unlock()
+
+	regions := slices.Clone(f.built)
+	slices.Sort(regions)
+	return regions
+}
+
+type fakeAwsDiscoverer struct {
+	parent    *fakeAwsDiscoverers
+	region    string
+	stsClient utils.AwsStsClient
+}
+
+func (d *fakeAwsDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := discovery.NewResult(d.parent.discovererId)
+	defer result.Done()
+
+	awsAccountId, _, err := utils.AwsAccountIdAndPartitionFromStsClient(ctx, d.stsClient, time.Second)
+	if err != nil {
+		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
+		return result
+	}
+	result.AddResources(discovery.Resource{
+		ResourceType: discovery.ResourceTypeAwsEc2Instance,
+		AwsEc2InstanceDetails: &discovery.AwsEc2InstanceDetails{
+			AwsBaseDetails: discovery.AwsBaseDetails{AwsAccountId: awsAccountId, AwsRegion: d.region},
+			InstanceId:     d.region,
+		},
+	})
+	for _, err := range d.parent.errs[d.region] {
+		result.AddError(err)
+	}
+	for _, warning := range d.parent.warnings[d.region] {
+		result.AddWarning(warning)
+	}
+	return result
+}
+
+// ensure the fakes implement the client interfaces at compile-time.
+var (
+	_ AwsEc2Client           = (*fakeEc2Client)(nil)
+	_ AwsEc2RegionsClient    = (*fakeEc2RegionsClient)(nil)
+	_ AwsSsmClient           = (*fakeSsmClient)(nil)
+	_ AwsEcsClient           = (*fakeEcsClient)(nil)
+	_ AwsEksClient           = (*fakeEksClient)(nil)
+	_ AwsRdsClient           = (*fakeRdsClient)(nil)
+	_ AwsOrganizationsClient = (*fakeOrganizationsClient)(nil)
+)
@@ -0,0 +1,542 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/borderzero/discovery"
+)
+
+// AwsReachabilitySource represents the source of traffic for the static reachability
+// analysis of AWS resources, i.e. an IPv4 CIDR block (or address), a security group
+// or a network interface (e.g. that of the EC2 instance the discoverer runs on).
+// Exactly one of the fields must be set.
+//
+// For security group sources only security group rules are analysed, since the
+// addresses and subnets of the group's members (for network ACLs and routes) are
+// not known.
+type AwsReachabilitySource struct {
+	Cidr               string
+	SecurityGroupId    string
+	NetworkInterfaceId string
+}
+
+// awsReachabilityTarget represents the network placement of an AWS resource
+// to analyse the static reachability of. The ip address may be unknown (e.g.
+// for RDS instances), in which case the VPC's CIDR block is used instead.
+type awsReachabilityTarget struct {
+	vpcId            string
+	subnetIds        []string
+	securityGroupIds []string
+	ipAddress        string
+	hasPublicIp      bool
+}
+
+// awsResolvedReachabilitySource represents an AwsReachabilitySource resolved
+// against the EC2 API. The prefix is invalid for security group sources.
+type awsResolvedReachabilitySource struct {
+	description      string
+	prefix           netip.Prefix
+	securityGroupIds []string
+	vpcId            string
+	subnetId         string
+}
+
+// awsNetworkSnapshot represents the security group rules, network ACLs and route
+// tables of a region, fetched once per discovery run for the static reachability
+// analysis of all resources.
+type awsNetworkSnapshot struct {
+	source       awsResolvedReachabilitySource
+	rulesByGroup map[string][]types.SecurityGroupRule
+	networkAcls  []types.NetworkAcl
+	routeTables  []types.RouteTable
+}
+
+// newAwsNetworkSnapshot fetches the network configuration of the client's region
+// and resolves the given source, with the timeout applied to each api call page.
+func newAwsNetworkSnapshot(
+	ctx context.Context,
+	client AwsEc2NetworkClient,
+	source AwsReachabilitySource,
+	timeout time.Duration,
+) (*awsNetworkSnapshot, error) {
+	resolvedSource, err := resolveAwsReachabilitySource(ctx, client, source, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve reachability source: %v", err)
+	}
+
+	rulesPages, err := collectAwsPages(ctx, ec2.NewDescribeSecurityGroupRulesPaginator(
+		client,
+		&ec2.DescribeSecurityGroupRulesInput{},
+	), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe security group rules: %v", err)
+	}
+	rulesByGroup := map[string][]types.SecurityGroupRule{}
+	for _, page := range rulesPages {
+		for _, rule := range page.SecurityGroupRules {
+			groupId := aws.ToString(rule.GroupId)
+			rulesByGroup[groupId] = append(rulesByGroup[groupId], rule)
+		}
+	}
+
+	networkAclsPages, err := collectAwsPages(ctx, ec2.NewDescribeNetworkAclsPaginator(
+		client,
+		&ec2.DescribeNetworkAclsInput{},
+	), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe network acls: %v", err)
+	}
+	networkAcls := []types.NetworkAcl{}
+	for _, page := range networkAclsPages {
+		networkAcls = append(networkAcls, page.NetworkAcls...)
+	}
+
+	routeTablesPages, err := collectAwsPages(ctx, ec2.NewDescribeRouteTablesPaginator(
+		client,
+		&ec2.DescribeRouteTablesInput{},
+	), timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe route tables: %v", err)
+	}
+	routeTables := []types.RouteTable{}
+	for _, page := range routeTablesPages {
+		routeTables = append(routeTables, page.RouteTables...)
+	}
+
+	return &awsNetworkSnapshot{
+		source:       resolvedSource,
+		rulesByGroup: rulesByGroup,
+		networkAcls:  networkAcls,
+		routeTables:  routeTables,
+	}, nil
+}
+
+func resolveAwsReachabilitySource(
+	ctx context.Context,
+	client AwsEc2NetworkClient,
+	source AwsReachabilitySource,
+	timeout time.Duration,
+) (awsResolvedReachabilitySource, error) {
+	switch {
+	case source.Cidr != "":
+		prefix, err := parseIpv4Prefix(source.Cidr)
+		if err != nil {
+			return awsResolvedReachabilitySource{}, err
+		}
+		return awsResolvedReachabilitySource{description: prefix.String(), prefix: prefix}, nil
+	case source.SecurityGroupId != "":
+		return awsResolvedReachabilitySource{
+			description:      source.SecurityGroupId,
+			securityGroupIds: []string{source.SecurityGroupId},
+		}, nil
+	case source.NetworkInterfaceId != "":
+		describeNetworkInterfacesCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		describeNetworkInterfacesOutput, err := client.DescribeNetworkInterfaces(
+			describeNetworkInterfacesCtx,
+			&ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: []string{source.NetworkInterfaceId}},
+		)
+		if err != nil {
+			return awsResolvedReachabilitySource{}, fmt.Errorf("failed to describe network interface: %v", err)
+		}
+		if len(describeNetworkInterfacesOutput.NetworkInterfaces) == 0 {
+			return awsResolvedReachabilitySource{}, fmt.Errorf("network interface %s not found", source.NetworkInterfaceId)
+		}
+		eni := describeNetworkInterfacesOutput.NetworkInterfaces[0]
+		prefix, err := parseIpv4Prefix(aws.ToString(eni.PrivateIpAddress))
+		if err != nil {
+			return awsResolvedReachabilitySource{}, err
+		}
+		securityGroupIds := []string{}
+		for _, group := range eni.Groups {
+			securityGroupIds = append(securityGroupIds, aws.ToString(group.GroupId))
+		}
+		return awsResolvedReachabilitySource{
+			description:      fmt.Sprintf("%s (%s)", source.NetworkInterfaceId, prefix.Addr()),
+			prefix:           prefix,
+			securityGroupIds: securityGroupIds,
+			vpcId:            aws.ToString(eni.VpcId),
+			subnetId:         aws.ToString(eni.SubnetId),
+		}, nil
+	}
+	return awsResolvedReachabilitySource{}, fmt.Errorf("no cidr, security group id or network interface id set")
+}
+
+// analyze returns whether the source of the snapshot can reach the given tcp port of
+// a target, according to the security group rules, network ACLs and route tables of
+// the snapshot. For targets in multiple subnets (e.g. RDS instances in a DB subnet
+// group), the target is reachable if it is reachable in any subnet.
+//
+// Note that the network ACL rules for return traffic (network ACLs are stateless)
+// are not analysed.
+func (s *awsNetworkSnapshot) analyze(target awsReachabilityTarget, port int32) discovery.AwsStaticReachability {
+	reachability := discovery.AwsStaticReachability{
+		Source: s.source.description,
+		Port:   port,
+	}
+
+	targetPrefix, ok := s.targetPrefix(target)
+	if !ok {
+		reachability.Reason = "the address of the target is unknown"
+		return reachability
+	}
+
+	ingressRule, ok := s.securityGroupRule(target.securityGroupIds, false, port, s.source.prefix, s.source.securityGroupIds)
+	if !ok {
+		reachability.Reason = "no security group rule of the target allows inbound traffic from the source"
+		return reachability
+	}
+	reachability.SecurityGroupRule = ingressRule
+
+	if len(s.source.securityGroupIds) > 0 {
+		egressRule, ok := s.securityGroupRule(s.source.securityGroupIds, true, port, targetPrefix, target.securityGroupIds)
+		if !ok {
+			reachability.Reason = "no security group rule of the source allows outbound traffic to the target"
+			return reachability
+		}
+		reachability.SourceSecurityGroupRule = egressRule
+	}
+
+	// network acls and routes can only be analysed for sources with known addresses
+	if !s.source.prefix.IsValid() {
+		reachability.Reachable = true
+		return reachability
+	}
+
+	for _, subnetId := range target.subnetIds {
+		subnetReachability := reachability
+		if s.analyzeSubnet(&subnetReachability, target, subnetId, targetPrefix, port) {
+			return subnetReachability
+		}
+		reachability.Reason = subnetReachability.Reason
+	}
+	if len(target.subnetIds) == 0 {
+		reachability.Reason = "the subnet of the target is unknown"
+	}
+	return reachability
+}
+
+// analyzeSubnet analyses the network ACLs and routes between the source and a target in
+// a given subnet, and records the allowing rules (or the reason if not allowed) in reachability.
+func (s *awsNetworkSnapshot) analyzeSubnet(
+	reachability *discovery.AwsStaticReachability,
+	target awsReachabilityTarget,
+	subnetId string,
+	targetPrefix netip.Prefix,
+	port int32,
+) bool {
+	// traffic within a subnet is not subject to network acls
+	if s.source.subnetId != subnetId {
+		networkAclRule, allowed := s.networkAclRule(target.vpcId, subnetId, false, port, s.source.prefix)
+		if !allowed {
+			reachability.Reason = fmt.Sprintf("the network acl of subnet %s denies inbound traffic: %s", subnetId, networkAclRule)
+			return false
+		}
+		reachability.NetworkAclRule = networkAclRule
+
+		if s.source.subnetId != "" {
+			sourceNetworkAclRule, allowed := s.networkAclRule(s.source.vpcId, s.source.subnetId, true, port, targetPrefix)
+			if !allowed {
+				reachability.Reason = fmt.Sprintf("the network acl of subnet %s denies outbound traffic: %s", s.source.subnetId, sourceNetworkAclRule)
+				return false
+			}
+			reachability.SourceNetworkAclRule = sourceNetworkAclRule
+		}
+	}
+
+	route, reason := s.route(target.vpcId, subnetId, s.source.prefix, target.hasPublicIp)
+	if reason != "" {
+		reachability.Reason = fmt.Sprintf("the route table of subnet %s has %s", subnetId, reason)
+		return false
+	}
+	reachability.Route = route
+
+	if s.source.subnetId != "" {
+		sourceRoute, reason := s.route(s.source.vpcId, s.source.subnetId, targetPrefix, true)
+		if reason != "" {
+			reachability.Reason = fmt.Sprintf("the route table of subnet %s has %s", s.source.subnetId, reason)
+			return false
+		}
+		reachability.SourceRoute = sourceRoute
+	}
+
+	reachability.Reachable = true
+	reachability.Reason = ""
+	return true
+}
+
+// targetPrefix returns the address of a target as a prefix, or the CIDR
+// block of the target's VPC (its local route) if the address is unknown.
+func (s *awsNetworkSnapshot) targetPrefix(target awsReachabilityTarget) (netip.Prefix, bool) {
+	if prefix, err := parseIpv4Prefix(target.ipAddress); err == nil {
+		return prefix, true
+	}
+	for _, subnetId := range target.subnetIds {
+		routeTable, ok := s.routeTable(target.vpcId, subnetId)
+		if !ok {
+			continue
+		}
+		for _, route := range routeTable.Routes {
+			if aws.ToString(route.GatewayId) == "local" {
+				if prefix, err := netip.ParsePrefix(aws.ToString(route.DestinationCidrBlock)); err == nil {
+					return prefix, true
+				}
+			}
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+// securityGroupRule returns the first ingress (or egress) rule of the given security groups which
+// allows tcp traffic on a port from (or to) a peer, given by its prefix and/or its security groups.
+func (s *awsNetworkSnapshot) securityGroupRule(
+	groupIds []string,
+	egress bool,
+	port int32,
+	peerPrefix netip.Prefix,
+	peerGroupIds []string,
+) (string, bool) {
+	for _, groupId := range groupIds {
+		for _, rule := range s.rulesByGroup[groupId] {
+			if aws.ToBool(rule.IsEgress) != egress {
+				continue
+			}
+			if !protocolAndPortMatch(aws.ToString(rule.IpProtocol), aws.ToInt32(rule.FromPort), aws.ToInt32(rule.ToPort), port) {
+				continue
+			}
+			peer := ""
+			if cidr := aws.ToString(rule.CidrIpv4); cidr != "" && prefixContains(cidr, peerPrefix) {
+				peer = cidr
+			}
+			if rule.ReferencedGroupInfo != nil && slices.Contains(peerGroupIds, aws.ToString(rule.ReferencedGroupInfo.GroupId)) {
+				peer = aws.ToString(rule.ReferencedGroupInfo.GroupId)
+			}
+			if peer == "" {
+				continue
+			}
+			direction := "from"
+			if egress {
+				direction = "to"
+			}
+			return fmt.Sprintf(
+				"%s (%s): %s %s %s",
+				aws.ToString(rule.SecurityGroupRuleId),
+				groupId,
+				describeProtocolAndPorts(aws.ToString(rule.IpProtocol), aws.ToInt32(rule.FromPort), aws.ToInt32(rule.ToPort)),
+				direction,
+				peer,
+			), true
+		}
+	}
+	return "", false
+}
+
+// networkAclRule returns the network ACL rule of a subnet which decides whether tcp traffic on a
+// port from (or to) a peer is allowed i.e. the lowest numbered rule matching the traffic.
+func (s *awsNetworkSnapshot) networkAclRule(
+	vpcId string,
+	subnetId string,
+	egress bool,
+	port int32,
+	peerPrefix netip.Prefix,
+) (string, bool) {
+	networkAcl, ok := s.networkAcl(vpcId, subnetId)
+	if !ok {
+		return "no network acl found", false
+	}
+	entries := slices.Clone(networkAcl.Entries)
+	sort.Slice(entries, func(i, j int) bool {
+		return aws.ToInt32(entries[i].RuleNumber) < aws.ToInt32(entries[j].RuleNumber)
+	})
+	for _, entry := range entries {
+		if aws.ToBool(entry.Egress) != egress {
+			continue
+		}
+		fromPort, toPort := int32(-1), int32(-1)
+		if entry.PortRange != nil {
+			fromPort, toPort = aws.ToInt32(entry.PortRange.From), aws.ToInt32(entry.PortRange.To)
+		}
+		if !protocolAndPortMatch(aws.ToString(entry.Protocol), fromPort, toPort, port) {
+			continue
+		}
+		cidr := aws.ToString(entry.CidrBlock)
+		if cidr == "" || !prefixContains(cidr, peerPrefix) {
+			continue
+		}
+		direction := "from"
+		if egress {
+			direction = "to"
+		}
+		return fmt.Sprintf(
+			"%s #%d: %s %s %s %s",
+			aws.ToString(networkAcl.NetworkAclId),
+			aws.ToInt32(entry.RuleNumber),
+			entry.RuleAction,
+			describeProtocolAndPorts(aws.ToString(entry.Protocol), fromPort, toPort),
+			direction,
+			cidr,
+		), entry.RuleAction == types.RuleActionAllow
+	}
+	return fmt.Sprintf("%s: no matching rule", aws.ToString(networkAcl.NetworkAclId)), false
+}
+
+// route returns the route of a subnet to a peer i.e. the most specific route
+// of the subnet's route table matching the peer, or the reason for no usable route.
+func (s *awsNetworkSnapshot) route(
+	vpcId string,
+	subnetId string,
+	peerPrefix netip.Prefix,
+	hasPublicIp bool,
+) (string, string) {
+	routeTable, ok := s.routeTable(vpcId, subnetId)
+	if !ok {
+		return "", "no route table"
+	}
+	var best *types.Route
+	bestBits := -1
+	for i, route := range routeTable.Routes {
+		destination, err := netip.ParsePrefix(aws.ToString(route.DestinationCidrBlock))
+		if err != nil || !prefixContains(destination.String(), peerPrefix) {
+			continue
+		}
+		if destination.Bits() > bestBits {
+			best, bestBits = &routeTable.Routes[i], destination.Bits()
+		}
+	}
+	if best == nil {
+		return "", fmt.Sprintf("no route to %s", peerPrefix)
+	}
+	gateway := routeGateway(*best)
+	description := fmt.Sprintf(
+		"%s: %s via %s",
+		aws.ToString(routeTable.RouteTableId),
+		aws.ToString(best.DestinationCidrBlock),
+		gateway,
+	)
+	switch {
+	case best.State == types.RouteStateBlackhole:
+		return "", fmt.Sprintf("a blackhole route (%s)", description)
+	case strings.HasPrefix(gateway, "nat-"):
+		return "", fmt.Sprintf("a route via a nat gateway (%s), which does not allow inbound connections", description)
+	case strings.HasPrefix(gateway, "igw-") && !hasPublicIp:
+		return "", fmt.Sprintf("a route via an internet gateway (%s) but the target has no public ip address", description)
+	}
+	return description, ""
+}
+
+func (s *awsNetworkSnapshot) networkAcl(vpcId, subnetId string) (types.NetworkAcl, bool) {
+	for _, networkAcl := range s.networkAcls {
+		for _, association := range networkAcl.Associations {
+			if aws.ToString(association.SubnetId) == subnetId {
+				return networkAcl, true
+			}
+		}
+	}
+	// subnets with no explicit association use the default network acl of the vpc
+	for _, networkAcl := range s.networkAcls {
+		if aws.ToBool(networkAcl.IsDefault) && aws.ToString(networkAcl.VpcId) == vpcId {
+			return networkAcl, true
+		}
+	}
+	return types.NetworkAcl{}, false
+}
+
+func (s *awsNetworkSnapshot) routeTable(vpcId, subnetId string) (types.RouteTable, bool) {
+	for _, routeTable := range s.routeTables {
+		for _, association := range routeTable.Associations {
+			if aws.ToString(association.SubnetId) == subnetId {
+				return routeTable, true
+			}
+		}
+	}
+	// subnets with no explicit association use the main route table of the vpc
+	for _, routeTable := range s.routeTables {
+		if aws.ToString(routeTable.VpcId) != vpcId {
+			continue
+		}
+		for _, association := range routeTable.Associations {
+			if aws.ToBool(association.Main) {
+				return routeTable, true
+			}
+		}
+	}
+	return types.RouteTable{}, false
+}
+
+func routeGateway(route types.Route) string {
+	for _, gateway := range []*string{
+		route.GatewayId,
+		route.NatGatewayId,
+		route.TransitGatewayId,
+		route.VpcPeeringConnectionId,
+		route.NetworkInterfaceId,
+		route.InstanceId,
+		route.LocalGatewayId,
+		route.CarrierGatewayId,
+		route.EgressOnlyInternetGatewayId,
+		route.CoreNetworkArn,
+	} {
+		if aws.ToString(gateway) != "" {
+			return aws.ToString(gateway)
+		}
+	}
+	return "unknown"
+}
+
+// protocolAndPortMatch returns whether a security group or network acl rule with the given
+// protocol and port range matches tcp traffic on a port. Port ranges of -1 match all ports.
+func protocolAndPortMatch(protocol string, fromPort, toPort, port int32) bool {
+	switch protocol {
+	case "-1", "all":
+		return true
+	case "tcp", "6":
+		return fromPort == -1 || (fromPort <= port && port <= toPort)
+	}
+	return false
+}
+
+func describeProtocolAndPorts(protocol string, fromPort, toPort int32) string {
+	switch {
+	case protocol == "-1" || protocol == "all":
+		return "all traffic"
+	case fromPort == -1 || (fromPort == 0 && toPort == 65535):
+		return "tcp all ports"
+	case fromPort == toPort:
+		return fmt.Sprintf("tcp %d", fromPort)
+	}
+	return fmt.Sprintf("tcp %d-%d", fromPort, toPort)
+}
+
+// prefixContains returns whether the given cidr contains the whole of a prefix.
+func prefixContains(cidr string, prefix netip.Prefix) bool {
+	if !prefix.IsValid() {
+		return false
+	}
+	outer, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return false
+	}
+	return outer.Bits() <= prefix.Bits() && outer.Contains(prefix.Addr())
+}
+
+// parseIpv4Prefix parses an IPv4 CIDR block or an IPv4 address (as a /32 prefix).
+func parseIpv4Prefix(s string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(s); err == nil && addr.Is4() {
+		return netip.PrefixFrom(addr, 32), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil || !prefix.Addr().Is4() {
+		return netip.Prefix{}, fmt.Errorf("invalid IPv4 address or CIDR block %q", s)
+	}
+	return prefix.Masked(), nil
+}
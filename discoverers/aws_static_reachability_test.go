@@ -0,0 +1,463 @@
+package discoverers
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/borderzero/discovery"
+)
+
+func newReachabilityTestSgRule(ruleId, groupId string, egress bool, protocol string, port int32, cidr, referencedGroupId string) types.SecurityGroupRule {
+	rule := types.SecurityGroupRule{
+		SecurityGroupRuleId: aws.String(ruleId),
+		GroupId:             aws.String(groupId),
+		IsEgress:            aws.Bool(egress),
+		IpProtocol:          aws.String(protocol),
+		FromPort:            aws.Int32(port),
+		ToPort:              aws.Int32(port),
+	}
+	if cidr != "" {
+		rule.CidrIpv4 = aws.String(cidr)
+	}
+	if referencedGroupId != "" {
+		rule.ReferencedGroupInfo = &types.ReferencedSecurityGroup{GroupId: aws.String(referencedGroupId)}
+	}
+	return rule
+}
+
+func newReachabilityTestAclEntry(ruleNumber int32, egress bool, action types.RuleAction, protocol string, port int32, cidr string) types.NetworkAclEntry {
+	entry := types.NetworkAclEntry{
+		RuleNumber: aws.Int32(ruleNumber),
+		Egress:     aws.Bool(egress),
+		RuleAction: action,
+		Protocol:   aws.String(protocol),
+		CidrBlock:  aws.String(cidr),
+	}
+	if port != -1 {
+		entry.PortRange = &types.PortRange{From: aws.Int32(port), To: aws.Int32(port)}
+	}
+	return entry
+}
+
+func newReachabilityTestRoute(destination string, route types.Route) types.Route {
+	route.DestinationCidrBlock = aws.String(destination)
+	if route.State == "" {
+		route.State = types.RouteStateActive
+	}
+	return route
+}
+
+// newReachabilityTestSnapshot returns the snapshot of a vpc (10.0.0.0/16) with:
+//   - subnet-app: with its own network acl (denying ssh from 10.0.9.0/24 before allowing
+//     all traffic) and route table (with a default route via an internet gateway)
+//   - subnet-private and subnet-src: with the default network acl (allowing all traffic)
+//     and the main route table (with a default route via a nat gateway)
+//   - subnet-bh: with a route table with a blackhole route to 192.168.0.0/16
+func newReachabilityTestSnapshot(source awsResolvedReachabilitySource) *awsNetworkSnapshot {
+	local := newReachabilityTestRoute("10.0.0.0/16", types.Route{GatewayId: aws.String("local")})
+	return &awsNetworkSnapshot{
+		source: source,
+		rulesByGroup: map[string][]types.SecurityGroupRule{
+			"sg-app": {
+				newReachabilityTestSgRule("sgr-ssh", "sg-app", false, "tcp", 22, "10.0.0.0/16", ""),
+				newReachabilityTestSgRule("sgr-https", "sg-app", false, "tcp", 443, "", "sg-bastion"),
+				newReachabilityTestSgRule("sgr-http", "sg-app", false, "6", 80, "0.0.0.0/0", ""),
+			},
+			"sg-bastion": {
+				newReachabilityTestSgRule("sgr-out", "sg-bastion", true, "-1", -1, "0.0.0.0/0", ""),
+			},
+		},
+		networkAcls: []types.NetworkAcl{
+			{
+				NetworkAclId: aws.String("acl-default"),
+				VpcId:        aws.String("vpc-1"),
+				IsDefault:    aws.Bool(true),
+				Entries: []types.NetworkAclEntry{
+					newReachabilityTestAclEntry(100, false, types.RuleActionAllow, "-1", -1, "0.0.0.0/0"),
+					newReachabilityTestAclEntry(100, true, types.RuleActionAllow, "-1", -1, "0.0.0.0/0"),
+				},
+			},
+			{
+				NetworkAclId: aws.String("acl-app"),
+				VpcId:        aws.String("vpc-1"),
+				Associations: []types.NetworkAclAssociation{{SubnetId: aws.String("subnet-app")}},
+				Entries: []types.NetworkAclEntry{ // not in rule number order
+					newReachabilityTestAclEntry(200, false, types.RuleActionAllow, "-1", -1, "0.0.0.0/0"),
+					newReachabilityTestAclEntry(100, false, types.RuleActionDeny, "6", 22, "10.0.9.0/24"),
+					newReachabilityTestAclEntry(100, true, types.RuleActionAllow, "-1", -1, "0.0.0.0/0"),
+				},
+			},
+		},
+		routeTables: []types.RouteTable{
+			{
+				RouteTableId: aws.String("rtb-app"),
+				VpcId:        aws.String("vpc-1"),
+				Associations: []types.RouteTableAssociation{{SubnetId: aws.String("subnet-app")}},
+				Routes: []types.Route{
+					local,
+					newReachabilityTestRoute("0.0.0.0/0", types.Route{GatewayId: aws.String("igw-1")}),
+				},
+			},
+			{
+				RouteTableId: aws.String("rtb-main"),
+				VpcId:        aws.String("vpc-1"),
+				Associations: []types.RouteTableAssociation{{Main: aws.Bool(true)}},
+				Routes: []types.Route{
+					newReachabilityTestRoute("0.0.0.0/0", types.Route{NatGatewayId: aws.String("nat-1")}),
+					local,
+				},
+			},
+			{
+				RouteTableId: aws.String("rtb-bh"),
+				VpcId:        aws.String("vpc-1"),
+				Associations: []types.RouteTableAssociation{{SubnetId: aws.String("subnet-bh")}},
+				Routes: []types.Route{
+					local,
+					newReachabilityTestRoute("192.168.0.0/16", types.Route{
+						VpcPeeringConnectionId: aws.String("pcx-1"),
+						State:                  types.RouteStateBlackhole,
+					}),
+				},
+			},
+		},
+	}
+}
+
+func newReachabilityTestCidrSource(cidr string) awsResolvedReachabilitySource {
+	return awsResolvedReachabilitySource{description: cidr, prefix: netip.MustParsePrefix(cidr)}
+}
+
+func TestAwsNetworkSnapshotAnalyze(t *testing.T) {
+	app := awsReachabilityTarget{
+		vpcId:            "vpc-1",
+		subnetIds:        []string{"subnet-app"},
+		securityGroupIds: []string{"sg-app"},
+		ipAddress:        "10.0.1.10",
+	}
+	withSubnets := func(target awsReachabilityTarget, subnetIds ...string) awsReachabilityTarget {
+		target.subnetIds = subnetIds
+		return target
+	}
+	withPublicIp := app
+	withPublicIp.hasPublicIp = true
+	withoutAddress := withSubnets(app, "subnet-app", "subnet-private") // e.g. rds instances
+	withoutAddress.ipAddress = ""
+
+	tests := []struct {
+		name     string
+		source   awsResolvedReachabilitySource
+		target   awsReachabilityTarget
+		port     int32
+		expected discovery.AwsStaticReachability
+	}{
+		{
+			name:   "reachable within the vpc",
+			source: newReachabilityTestCidrSource("10.0.5.0/24"),
+			target: app,
+			port:   22,
+			expected: discovery.AwsStaticReachability{
+				Reachable:         true,
+				SecurityGroupRule: "sgr-ssh (sg-app): tcp 22 from 10.0.0.0/16",
+				NetworkAclRule:    "acl-app #200: allow all traffic from 0.0.0.0/0",
+				Route:             "rtb-app: 10.0.0.0/16 via local",
+			},
+		},
+		{
+			name:   "network acl deny rule before allow rule",
+			source: newReachabilityTestCidrSource("10.0.9.0/24"),
+			target: app,
+			port:   22,
+			expected: discovery.AwsStaticReachability{
+				SecurityGroupRule: "sgr-ssh (sg-app): tcp 22 from 10.0.0.0/16",
+				Reason:            "the network acl of subnet subnet-app denies inbound traffic: acl-app #100: deny tcp 22 from 10.0.9.0/24",
+			},
+		},
+		{
+			name:   "no security group rule",
+			source: newReachabilityTestCidrSource("10.0.5.0/24"),
+			target: app,
+			port:   3306,
+			expected: discovery.AwsStaticReachability{
+				Reason: "no security group rule of the target allows inbound traffic from the source",
+			},
+		},
+		{
+			name:   "internet gateway route with a public ip",
+			source: newReachabilityTestCidrSource("203.0.113.0/24"),
+			target: withPublicIp,
+			port:   80,
+			expected: discovery.AwsStaticReachability{
+				Reachable:         true,
+				SecurityGroupRule: "sgr-http (sg-app): tcp 80 from 0.0.0.0/0",
+				NetworkAclRule:    "acl-app #200: allow all traffic from 0.0.0.0/0",
+				Route:             "rtb-app: 0.0.0.0/0 via igw-1",
+			},
+		},
+		{
+			name:   "internet gateway route without a public ip",
+			source: newReachabilityTestCidrSource("203.0.113.0/24"),
+			target: app,
+			port:   80,
+			expected: discovery.AwsStaticReachability{
+				// only the reason is kept for subnets in which the target is not reachable
+				SecurityGroupRule: "sgr-http (sg-app): tcp 80 from 0.0.0.0/0",
+				Reason:            "the route table of subnet subnet-app has a route via an internet gateway (rtb-app: 0.0.0.0/0 via igw-1) but the target has no public ip address",
+			},
+		},
+		{
+			name:   "nat gateway route of the main route table",
+			source: newReachabilityTestCidrSource("203.0.113.0/24"),
+			target: withSubnets(withPublicIp, "subnet-private"),
+			port:   80,
+			expected: discovery.AwsStaticReachability{
+				SecurityGroupRule: "sgr-http (sg-app): tcp 80 from 0.0.0.0/0",
+				Reason:            "the route table of subnet subnet-private has a route via a nat gateway (rtb-main: 0.0.0.0/0 via nat-1), which does not allow inbound connections",
+			},
+		},
+		{
+			name:   "blackhole route",
+			source: newReachabilityTestCidrSource("192.168.1.0/24"),
+			target: withSubnets(withPublicIp, "subnet-bh"),
+			port:   80,
+			expected: discovery.AwsStaticReachability{
+				SecurityGroupRule: "sgr-http (sg-app): tcp 80 from 0.0.0.0/0",
+				Reason:            "the route table of subnet subnet-bh has a blackhole route (rtb-bh: 192.168.0.0/16 via pcx-1)",
+			},
+		},
+		{
+			name:   "reachable in any subnet of a target without an address",
+			source: newReachabilityTestCidrSource("10.0.9.0/24"),
+			target: withoutAddress,
+			port:   22,
+			expected: discovery.AwsStaticReachability{
+				Reachable:         true,
+				SecurityGroupRule: "sgr-ssh (sg-app): tcp 22 from 10.0.0.0/16",
+				NetworkAclRule:    "acl-default #100: allow all traffic from 0.0.0.0/0",
+				Route:             "rtb-main: 10.0.0.0/16 via local",
+			},
+		},
+		{
+			name:   "unknown address",
+			source: newReachabilityTestCidrSource("10.0.5.0/24"),
+			target: withSubnets(awsReachabilityTarget{vpcId: "vpc-2", securityGroupIds: []string{"sg-app"}}),
+			port:   22,
+			expected: discovery.AwsStaticReachability{
+				Reason: "the address of the target is unknown",
+			},
+		},
+		{
+			name:   "security group source",
+			source: awsResolvedReachabilitySource{description: "sg-bastion", securityGroupIds: []string{"sg-bastion"}},
+			target: app,
+			port:   443,
+			expected: discovery.AwsStaticReachability{
+				Reachable:               true,
+				SecurityGroupRule:       "sgr-https (sg-app): tcp 443 from sg-bastion",
+				SourceSecurityGroupRule: "sgr-out (sg-bastion): all traffic to 0.0.0.0/0",
+			},
+		},
+		{
+			name: "network interface source",
+			source: awsResolvedReachabilitySource{
+				description:      "eni-1 (10.0.3.4)",
+				prefix:           netip.MustParsePrefix("10.0.3.4/32"),
+				securityGroupIds: []string{"sg-bastion"},
+				vpcId:            "vpc-1",
+				subnetId:         "subnet-src",
+			},
+			target: app,
+			port:   443,
+			expected: discovery.AwsStaticReachability{
+				Reachable:               true,
+				SecurityGroupRule:       "sgr-https (sg-app): tcp 443 from sg-bastion",
+				SourceSecurityGroupRule: "sgr-out (sg-bastion): all traffic to 0.0.0.0/0",
+				NetworkAclRule:          "acl-app #200: allow all traffic from 0.0.0.0/0",
+				SourceNetworkAclRule:    "acl-default #100: allow all traffic to 0.0.0.0/0",
+				Route:                   "rtb-app: 10.0.0.0/16 via local",
+				SourceRoute:             "rtb-main: 10.0.0.0/16 via local",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.expected.Source = test.source.description
+			test.expected.Port = test.port
+
+			reachability := newReachabilityTestSnapshot(test.source).analyze(test.target, test.port)
+
+			if !reflect.DeepEqual(reachability, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, reachability)
+			}
+		})
+	}
+}
+
+func TestAwsNetworkSnapshotNetworkAclRule(t *testing.T) {
+	snapshot := newReachabilityTestSnapshot(awsResolvedReachabilitySource{})
+
+	tests := []struct {
+		name            string
+		vpcId           string
+		subnetId        string
+		egress          bool
+		port            int32
+		peer            string
+		expectedRule    string
+		expectedAllowed bool
+	}{
+		{
+			name:         "lowest numbered matching rule wins",
+			vpcId:        "vpc-1",
+			subnetId:     "subnet-app",
+			port:         22,
+			peer:         "10.0.9.10/32",
+			expectedRule: "acl-app #100: deny tcp 22 from 10.0.9.0/24",
+		},
+		{
+			name:            "later rule when earlier rules do not match the port",
+			vpcId:           "vpc-1",
+			subnetId:        "subnet-app",
+			port:            443,
+			peer:            "10.0.9.10/32",
+			expectedRule:    "acl-app #200: allow all traffic from 0.0.0.0/0",
+			expectedAllowed: true,
+		},
+		{
+			name:            "egress rules for outbound traffic",
+			vpcId:           "vpc-1",
+			subnetId:        "subnet-app",
+			egress:          true,
+			port:            22,
+			peer:            "10.0.9.10/32",
+			expectedRule:    "acl-app #100: allow all traffic to 0.0.0.0/0",
+			expectedAllowed: true,
+		},
+		{
+			name:            "default network acl of the vpc",
+			vpcId:           "vpc-1",
+			subnetId:        "subnet-private",
+			port:            22,
+			peer:            "10.0.9.10/32",
+			expectedRule:    "acl-default #100: allow all traffic from 0.0.0.0/0",
+			expectedAllowed: true,
+		},
+		{
+			name:         "no network acl",
+			vpcId:        "vpc-2",
+			subnetId:     "subnet-other",
+			port:         22,
+			peer:         "10.0.9.10/32",
+			expectedRule: "no network acl found",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule, allowed := snapshot.networkAclRule(test.vpcId, test.subnetId, test.egress, test.port, netip.MustParsePrefix(test.peer))
+			if rule != test.expectedRule || allowed != test.expectedAllowed {
+				t.Fatalf("expected %q (allowed: %t), got %q (allowed: %t)", test.expectedRule, test.expectedAllowed, rule, allowed)
+			}
+		})
+	}
+}
+
+func TestAwsNetworkSnapshotRoute(t *testing.T) {
+	snapshot := newReachabilityTestSnapshot(awsResolvedReachabilitySource{})
+
+	tests := []struct {
+		name           string
+		vpcId          string
+		subnetId       string
+		peer           string
+		hasPublicIp    bool
+		expectedRoute  string
+		expectedReason string
+	}{
+		{
+			name:          "most specific route",
+			vpcId:         "vpc-1",
+			subnetId:      "subnet-app",
+			peer:          "10.0.3.0/24",
+			expectedRoute: "rtb-app: 10.0.0.0/16 via local",
+		},
+		{
+			name:          "internet gateway route with a public ip",
+			vpcId:         "vpc-1",
+			subnetId:      "subnet-app",
+			peer:          "203.0.113.7/32",
+			hasPublicIp:   true,
+			expectedRoute: "rtb-app: 0.0.0.0/0 via igw-1",
+		},
+		{
+			name:           "internet gateway route without a public ip",
+			vpcId:          "vpc-1",
+			subnetId:       "subnet-app",
+			peer:           "203.0.113.7/32",
+			expectedReason: "a route via an internet gateway (rtb-app: 0.0.0.0/0 via igw-1) but the target has no public ip address",
+		},
+		{
+			name:           "nat gateway route of the main route table",
+			vpcId:          "vpc-1",
+			subnetId:       "subnet-private",
+			peer:           "203.0.113.7/32",
+			hasPublicIp:    true,
+			expectedReason: "a route via a nat gateway (rtb-main: 0.0.0.0/0 via nat-1), which does not allow inbound connections",
+		},
+		{
+			name:           "blackhole route",
+			vpcId:          "vpc-1",
+			subnetId:       "subnet-bh",
+			peer:           "192.168.1.0/24",
+			expectedReason: "a blackhole route (rtb-bh: 192.168.0.0/16 via pcx-1)",
+		},
+		{
+			name:           "no matching route",
+			vpcId:          "vpc-1",
+			subnetId:       "subnet-bh",
+			peer:           "203.0.113.7/32",
+			expectedReason: "no route to 203.0.113.7/32",
+		},
+		{
+			name:           "no route table",
+			vpcId:          "vpc-2",
+			subnetId:       "subnet-other",
+			peer:           "10.0.3.0/24",
+			expectedReason: "no route table",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			route, reason := snapshot.route(test.vpcId, test.subnetId, netip.MustParsePrefix(test.peer), test.hasPublicIp)
+			if route != test.expectedRoute || reason != test.expectedReason {
+				t.Fatalf("expected route %q (reason: %q), got %q (reason: %q)", test.expectedRoute, test.expectedReason, route, reason)
+			}
+		})
+	}
+}
+
+func TestPrefixContains(t *testing.T) {
+	tests := []struct {
+		name     string
+		cidr     string
+		prefix   netip.Prefix
+		expected bool
+	}{
+		{name: "address in block", cidr: "10.0.0.0/16", prefix: netip.MustParsePrefix("10.0.1.10/32"), expected: true},
+		{name: "smaller block in block", cidr: "10.0.0.0/16", prefix: netip.MustParsePrefix("10.0.1.0/24"), expected: true},
+		{name: "same block", cidr: "10.0.1.0/24", prefix: netip.MustParsePrefix("10.0.1.0/24"), expected: true},
+		{name: "everything", cidr: "0.0.0.0/0", prefix: netip.MustParsePrefix("203.0.113.0/24"), expected: true},
+		{name: "larger block", cidr: "10.0.1.0/24", prefix: netip.MustParsePrefix("10.0.0.0/16"), expected: false},
+		{name: "other block", cidr: "10.0.0.0/16", prefix: netip.MustParsePrefix("10.1.0.0/24"), expected: false},
+		{name: "invalid cidr", cidr: "10.0.0.0/33", prefix: netip.MustParsePrefix("10.0.1.10/32"), expected: false},
+		{name: "invalid prefix", cidr: "0.0.0.0/0", prefix: netip.Prefix{}, expected: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if contains := prefixContains(test.cidr, test.prefix); contains != test.expected {
+				t.Fatalf("expected %t, got %t", test.expected, contains)
+			}
+		})
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"slices"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
 	"github.com/aws/aws-sdk-go-v2/service/ssm"
 	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/borderzero/border0-go/lib/types/maps"
 	"github.com/borderzero/border0-go/lib/types/pointer"
 	"github.com/borderzero/border0-go/lib/types/set"
@@ -31,6 +34,16 @@ const (
 	defaultAwsEc2ReachabilityCheckCacheCleanPeriod  = time.Minute * 30
 	defaultAwsEc2ReachabilityCheckCacheTtl          = time.Second * 5 // barely any caching
 	defaultAwsEc2ReachabilityRequired               = false
+	defaultAwsEc2EiceCheckEnabled                   = false
+
+	defaultAwsEc2DiscovererDescribeInstanceConnectEndpointsTimeout = time.Second * 10
+
+	// ports analysed by the static reachability analysis when no ports are given.
+	defaultAwsEc2StaticReachabilityLinuxPort   = 22   // default ssh port
+	defaultAwsEc2StaticReachabilityWindowsPort = 3389 // default rdp port
+
+	// tag set by AWS on instances launched by an auto scaling group.
+	ec2AutoScalingGroupNameTag = "aws:autoscaling:groupName"
 )
 
 var (
@@ -55,7 +68,11 @@ var (
 
 // AwsEc2Discoverer represents a discoverer for AWS EC2 resources.
 type AwsEc2Discoverer struct {
-	cfg aws.Config
+	cfg              aws.Config
+	ec2Client        AwsEc2Client
+	ec2NetworkClient AwsEc2NetworkClient
+	ssmClient        AwsSsmClient
+	stsClient        utils.AwsStsClient
 
 	discovererId             string
 	ssmStatusCheckEnabled    bool
@@ -71,6 +88,12 @@ type AwsEc2Discoverer struct {
 	networkReachabilityCheckCache         *cache.Cache[string, bool]
 	networkReachabilityCheckCacheItemOpts []cache.ItemOption
 	reachabilityRequired                  bool
+
+	eiceCheckEnabled                        bool
+	describeInstanceConnectEndpointsTimeout time.Duration
+
+	staticReachabilitySource *AwsReachabilitySource
+	staticReachabilityPorts  []int32
 }
 
 // ensure AwsEc2Discoverer implements discovery.Discoverer at compile-time.
@@ -84,6 +107,27 @@ func WithAwsEc2DiscovererDiscovererId(discovererId string) AwsEc2DiscovererOptio
 	return func(ec2d *AwsEc2Discoverer) { ec2d.discovererId = discovererId }
 }
 
+// WithAwsEc2DiscovererEc2Client is the AwsEc2DiscovererOption to set a non default aws ec2 client.
+func WithAwsEc2DiscovererEc2Client(client AwsEc2Client) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) { ec2d.ec2Client = client }
+}
+
+// WithAwsEc2DiscovererEc2NetworkClient is the AwsEc2DiscovererOption to set a
+// non default aws ec2 client for the static reachability analysis.
+func WithAwsEc2DiscovererEc2NetworkClient(client AwsEc2NetworkClient) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) { ec2d.ec2NetworkClient = client }
+}
+
+// WithAwsEc2DiscovererSsmClient is the AwsEc2DiscovererOption to set a non default aws ssm client.
+func WithAwsEc2DiscovererSsmClient(client AwsSsmClient) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) { ec2d.ssmClient = client }
+}
+
+// WithAwsEc2DiscovererStsClient is the AwsEc2DiscovererOption to set a non default aws sts client.
+func WithAwsEc2DiscovererStsClient(client utils.AwsStsClient) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) { ec2d.stsClient = client }
+}
+
 // WithAwsEc2DiscovererSsmStatusCheck is the AwsEc2DiscovererOption
 // to enable/disable checking instances' status with SSM.
 // If required is true, enabled is automatically set to true.
@@ -110,6 +154,28 @@ func WithAwsEc2DiscovererReachabilityRequired(required bool) AwsEc2DiscovererOpt
 	}
 }
 
+// WithAwsEc2DiscovererEiceCheck is the AwsEc2DiscovererOption to enable/disable
+// checking whether instances are reachable via an EC2 Instance Connect Endpoint
+// in their VPC (disabled by default, as it requires the ec2:DescribeInstanceConnectEndpoints
+// permission). Instances are considered reachable when a create-complete endpoint
+// exists in their VPC, whether or not their security groups allow its traffic.
+// Failing to describe endpoints results in a warning, not an error.
+func WithAwsEc2DiscovererEiceCheck(enabled bool) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) { ec2d.eiceCheckEnabled = enabled }
+}
+
+// WithAwsEc2DiscovererStaticReachabilityAnalysis is the AwsEc2DiscovererOption to enable the
+// static (offline) analysis of whether the given source can reach the given ports of instances,
+// based on security group rules, network ACLs and route tables. When no ports are given, the
+// ssh port of Linux instances and the rdp port of Windows instances are analysed. Failing to
+// fetch the network configuration results in a warning, not an error.
+func WithAwsEc2DiscovererStaticReachabilityAnalysis(source AwsReachabilitySource, ports ...int32) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) {
+		ec2d.staticReachabilitySource = &source
+		ec2d.staticReachabilityPorts = ports
+	}
+}
+
 // WithAwsEc2DiscovererNetworkReachabilityCheckCache is the AwsEc2DiscovererOption
 // to set the network reachability check cache and new item options.
 func WithAwsEc2DiscovererNetworkReachabilityCheckCache(cache *cache.Cache[string, bool], itemOpts ...cache.ItemOption) AwsEc2DiscovererOption {
@@ -126,11 +192,17 @@ func WithAwsEc2DiscovererGetAccountIdTimeout(timeout time.Duration) AwsEc2Discov
 }
 
 // WithAwsEc2DiscovererDescribeInstancesTimeout is the AwsEc2DiscovererOption
-// to set a non default timeout for the describe instnaces api call.
+// to set a non default timeout for each page of the describe instances api call.
 func WithAwsEc2DiscovererDescribeInstancesTimeout(timeout time.Duration) AwsEc2DiscovererOption {
 	return func(ec2d *AwsEc2Discoverer) { ec2d.describeInstancesTimeout = timeout }
 }
 
+// WithAwsEc2DiscovererDescribeInstanceConnectEndpointsTimeout is the AwsEc2DiscovererOption to set
+// a non default timeout for each page of the describe instance connect endpoints api call.
+func WithAwsEc2DiscovererDescribeInstanceConnectEndpointsTimeout(timeout time.Duration) AwsEc2DiscovererOption {
+	return func(ec2d *AwsEc2Discoverer) { ec2d.describeInstanceConnectEndpointsTimeout = timeout }
+}
+
 // WithAwsEc2DiscovererIncludedInstanceStates is the AwsEc2DiscovererOption
 // to set a non default list of states for instances to include in results.
 func WithAwsEc2DiscovererIncludedInstanceStates(states ...types.InstanceStateName) AwsEc2DiscovererOption {
@@ -152,7 +224,11 @@ func WithAwsEc2DiscovererExclusionInstanceTags(tags map[string][]string) AwsEc2D
 // NewEngine returns a new AwsEc2Discoverer, initialized with the given options.
 func NewAwsEc2Discoverer(cfg aws.Config, opts ...AwsEc2DiscovererOption) *AwsEc2Discoverer {
 	ec2d := &AwsEc2Discoverer{
-		cfg: cfg,
+		cfg:              cfg,
+		ec2Client:        ec2.NewFromConfig(cfg),
+		ec2NetworkClient: ec2.NewFromConfig(cfg),
+		ssmClient:        ssm.NewFromConfig(cfg),
+		stsClient:        sts.NewFromConfig(cfg),
 
 		discovererId:             defaultAwsEc2DiscovererDiscovererId,
 		ssmStatusCheckEnabled:    defaultAwsEc2SsmStatusCheckEnabled,
@@ -172,6 +248,12 @@ func NewAwsEc2Discoverer(cfg aws.Config, opts ...AwsEc2DiscovererOption) *AwsEc2
 			cache.WithExpiration(defaultAwsEc2ReachabilityCheckCacheTtl),
 		},
 		reachabilityRequired: defaultAwsEc2ReachabilityRequired,
+
+		eiceCheckEnabled:                        defaultAwsEc2EiceCheckEnabled,
+		describeInstanceConnectEndpointsTimeout: defaultAwsEc2DiscovererDescribeInstanceConnectEndpointsTimeout,
+
+		staticReachabilitySource: nil,
+		staticReachabilityPorts:  nil,
 	}
 	for _, opt := range opts {
 		opt(ec2d)
@@ -184,7 +266,7 @@ func (ec2d *AwsEc2Discoverer) Discover(ctx context.Context) *discovery.Result {
 	result := discovery.NewResult(ec2d.discovererId)
 	defer result.Done()
 
-	awsAccountId, err := utils.AwsAccountIdFromConfig(ctx, ec2d.cfg, ec2d.getAccountIdTimeout)
+	awsAccountId, awsPartition, err := utils.AwsAccountIdAndPartitionFromStsClient(ctx, ec2d.stsClient, ec2d.getAccountIdTimeout)
 	if err != nil {
 		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
 		return result
@@ -205,24 +287,48 @@ func (ec2d *AwsEc2Discoverer) Discover(ctx context.Context) *discovery.Result {
 		}
 	}
 
-	// describe ec2 instances
-	describeInstancesCtx, cancel := context.WithTimeout(ctx, ec2d.describeInstancesTimeout)
-	defer cancel()
+	var eicesByVpc map[string][]types.Ec2InstanceConnectEndpoint
+	if ec2d.eiceCheckEnabled {
+		endpoints, err := describeInstanceConnectEndpoints(ctx, ec2d.ec2Client, ec2d.describeInstanceConnectEndpointsTimeout)
+		if err != nil {
+			result.AddWarningf("failed to describe ec2 instance connect endpoints: %v", err)
+		} else {
+			eicesByVpc = map[string][]types.Ec2InstanceConnectEndpoint{}
+			for _, endpoint := range endpoints {
+				if endpoint.State == types.Ec2InstanceConnectEndpointStateCreateComplete {
+					vpcId := aws.ToString(endpoint.VpcId)
+					eicesByVpc[vpcId] = append(eicesByVpc[vpcId], endpoint)
+				}
+			}
+		}
+	}
 
-	// TODO: use paginator
-	ec2Client := ec2.NewFromConfig(ec2d.cfg)
-	describeInstancesOutput, err := ec2Client.DescribeInstances(describeInstancesCtx, &ec2.DescribeInstancesInput{})
+	// describe ec2 instances
+	reservations, err := ec2d.describeInstances(ctx)
 	if err != nil {
 		result.AddErrorf("failed to describe ec2 instances: %v", err)
 		return result
 	}
 
+	var networkSnapshot *awsNetworkSnapshot
+	if ec2d.staticReachabilitySource != nil {
+		networkSnapshot, err = newAwsNetworkSnapshot(
+			ctx,
+			ec2d.ec2NetworkClient,
+			*ec2d.staticReachabilitySource,
+			ec2d.describeInstancesTimeout,
+		)
+		if err != nil {
+			result.AddWarningf("failed to fetch network configuration for static reachability analysis: %v", err)
+		}
+	}
+
 	// wait group for reachability checks
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
 	// filter and build resources
-	for _, reservation := range describeInstancesOutput.Reservations {
+	for _, reservation := range reservations {
 		for _, instance := range reservation.Instances {
 			// ignore instances with no state
 			if instance.State == nil {
@@ -255,7 +361,8 @@ func (ec2d *AwsEc2Discoverer) Discover(ctx context.Context) *discovery.Result {
 				AwsRegion:    ec2d.cfg.Region,
 				AwsAccountId: awsAccountId,
 				AwsArn: fmt.Sprintf(
-					"arn:aws:ec2:%s:%s:instance/%s",
+					"arn:%s:ec2:%s:%s:instance/%s",
+					awsPartition,
 					ec2d.cfg.Region,
 					awsAccountId,
 					instanceId,
@@ -293,6 +400,26 @@ func (ec2d *AwsEc2Discoverer) Discover(ctx context.Context) *discovery.Result {
 				InstanceState:    string(pointer.ValueOrZero(instance.State).Name),
 
 				InstanceSsmStatus: ssmInstanceStatus,
+
+				Platform:              ec2InstancePlatform(instance),
+				PlatformDetails:       aws.ToString(instance.PlatformDetails),
+				Architecture:          string(instance.Architecture),
+				LaunchTime:            instance.LaunchTime,
+				KeyName:               aws.ToString(instance.KeyName),
+				IamInstanceProfileArn: aws.ToString(pointer.ValueOrZero(instance.IamInstanceProfile).Arn),
+				SecurityGroups:        ec2SecurityGroups(instance.SecurityGroups),
+				NetworkInterfaces:     ec2NetworkInterfaces(instance.NetworkInterfaces),
+				Ipv6Addresses:         ec2Ipv6Addresses(instance),
+				ImdsV2Required:        pointer.ValueOrZero(instance.MetadataOptions).HttpTokens == types.HttpTokensStateRequired,
+				AutoScalingGroupName:  tags[ec2AutoScalingGroupNameTag],
+			}
+			if eicesByVpc != nil {
+				eiceId := ec2InstanceConnectEndpointId(eicesByVpc[ec2InstanceDetails.VpcId], ec2InstanceDetails.SubnetId)
+				ec2InstanceDetails.InstanceConnectEndpointId = eiceId
+				ec2InstanceDetails.InstanceConnectEndpointReachable = pointer.To(eiceId != "")
+			}
+			if networkSnapshot != nil {
+				ec2InstanceDetails.StaticReachability = ec2d.staticReachability(networkSnapshot, ec2InstanceDetails)
 			}
 
 			wg.Add(1)
@@ -303,6 +430,136 @@ func (ec2d *AwsEc2Discoverer) Discover(ctx context.Context) *discovery.Result {
 	return result
 }
 
+// describeInstances returns the reservations of all the instances in the region,
+// with the describe instances timeout applied to each page rather than to all pages.
+func (ec2d *AwsEc2Discoverer) describeInstances(ctx context.Context) ([]types.Reservation, error) {
+	pages, err := collectAwsPages(ctx, ec2.NewDescribeInstancesPaginator(
+		ec2d.ec2Client,
+		&ec2.DescribeInstancesInput{},
+	), ec2d.describeInstancesTimeout)
+	if err != nil {
+		return nil, err
+	}
+	reservations := []types.Reservation{}
+	for _, page := range pages {
+		reservations = append(reservations, page.Reservations...)
+	}
+	return reservations, nil
+}
+
+// ec2InstanceConnectEndpointId returns the id of the EC2 Instance Connect Endpoint
+// to reach an instance through, given the endpoints in the instance's VPC. An endpoint
+// reaches any subnet of its VPC, but an endpoint in the instance's subnet is preferred.
+func ec2InstanceConnectEndpointId(endpoints []types.Ec2InstanceConnectEndpoint, subnetId string) string {
+	for _, endpoint := range endpoints {
+		if aws.ToString(endpoint.SubnetId) == subnetId {
+			return aws.ToString(endpoint.InstanceConnectEndpointId)
+		}
+	}
+	if len(endpoints) > 0 {
+		return aws.ToString(endpoints[0].InstanceConnectEndpointId)
+	}
+	return ""
+}
+
+// staticReachability analyses the static reachability of the ports of an instance.
+func (ec2d *AwsEc2Discoverer) staticReachability(
+	networkSnapshot *awsNetworkSnapshot,
+	ec2Details *discovery.AwsEc2InstanceDetails,
+) []discovery.AwsStaticReachability {
+	ports := ec2d.staticReachabilityPorts
+	if len(ports) == 0 {
+		ports = []int32{defaultAwsEc2StaticReachabilityLinuxPort}
+		if ec2Details.Platform == discovery.Ec2InstancePlatformWindows {
+			ports = []int32{defaultAwsEc2StaticReachabilityWindowsPort}
+		}
+	}
+	target := awsReachabilityTarget{
+		vpcId:            ec2Details.VpcId,
+		subnetIds:        []string{ec2Details.SubnetId},
+		securityGroupIds: slice.Transform(ec2Details.SecurityGroups, func(g discovery.AwsSecurityGroup) string { return g.GroupId }),
+		ipAddress:        ec2Details.PrivateIpAddress,
+		hasPublicIp:      ec2Details.PublicIpAddress != "",
+	}
+	staticReachability := []discovery.AwsStaticReachability{}
+	for _, port := range ports {
+		staticReachability = append(staticReachability, networkSnapshot.analyze(target, port))
+	}
+	return staticReachability
+}
+
+// ec2InstancePlatform returns the platform (os) of an instance. Only Windows
+// instances have a platform in the EC2 API, all others are Linux/UNIX.
+func ec2InstancePlatform(instance types.Instance) string {
+	if instance.Platform == types.PlatformValuesWindows {
+		return discovery.Ec2InstancePlatformWindows
+	}
+	return discovery.Ec2InstancePlatformLinux
+}
+
+func ec2SecurityGroups(groups []types.GroupIdentifier) []discovery.AwsSecurityGroup {
+	securityGroups := []discovery.AwsSecurityGroup{}
+	for _, group := range groups {
+		securityGroups = append(securityGroups, discovery.AwsSecurityGroup{
+			GroupId:   aws.ToString(group.GroupId),
+			GroupName: aws.ToString(group.GroupName),
+		})
+	}
+	return securityGroups
+}
+
+func ec2NetworkInterfaces(enis []types.InstanceNetworkInterface) []discovery.AwsEc2NetworkInterface {
+	networkInterfaces := []discovery.AwsEc2NetworkInterface{}
+	for _, eni := range enis {
+		networkInterface := discovery.AwsEc2NetworkInterface{
+			NetworkInterfaceId: aws.ToString(eni.NetworkInterfaceId),
+			DeviceIndex:        aws.ToInt32(pointer.ValueOrZero(eni.Attachment).DeviceIndex),
+			SubnetId:           aws.ToString(eni.SubnetId),
+			PrivateIpAddresses: []string{},
+			PublicIpAddress:    aws.ToString(pointer.ValueOrZero(eni.Association).PublicIp),
+		}
+		// the primary private ip address goes first
+		for _, primary := range []bool{true, false} {
+			for _, privateIp := range eni.PrivateIpAddresses {
+				if aws.ToBool(privateIp.Primary) == primary {
+					networkInterface.PrivateIpAddresses = append(
+						networkInterface.PrivateIpAddresses,
+						aws.ToString(privateIp.PrivateIpAddress),
+					)
+				}
+			}
+		}
+		for _, ipv6 := range eni.Ipv6Addresses {
+			networkInterface.Ipv6Addresses = append(networkInterface.Ipv6Addresses, aws.ToString(ipv6.Ipv6Address))
+		}
+		for _, group := range eni.Groups {
+			networkInterface.SecurityGroupIds = append(networkInterface.SecurityGroupIds, aws.ToString(group.GroupId))
+		}
+		networkInterfaces = append(networkInterfaces, networkInterface)
+	}
+	sort.Slice(networkInterfaces, func(i, j int) bool {
+		return networkInterfaces[i].DeviceIndex < networkInterfaces[j].DeviceIndex
+	})
+	return networkInterfaces
+}
+
+// ec2Ipv6Addresses returns the ipv6 addresses of an instance across all its
+// network interfaces, with the primary ipv6 address (if any) first.
+func ec2Ipv6Addresses(instance types.Instance) []string {
+	addresses := []string{}
+	if primary := aws.ToString(instance.Ipv6Address); primary != "" {
+		addresses = append(addresses, primary)
+	}
+	for _, eni := range instance.NetworkInterfaces {
+		for _, ipv6 := range eni.Ipv6Addresses {
+			if address := aws.ToString(ipv6.Ipv6Address); !slices.Contains(addresses, address) {
+				addresses = append(addresses, address)
+			}
+		}
+	}
+	return addresses
+}
+
 func (ec2d *AwsEc2Discoverer) reachabilityCheckAndAdd(
 	ctx context.Context,
 	wg *sync.WaitGroup,
@@ -380,6 +637,11 @@ func (ec2d *AwsEc2Discoverer) shouldIncludeInstance(
 		return true
 	}
 
+	// include if reachable via an EC2 Instance Connect Endpoint
+	if pointer.ValueOrZero(ec2Details.InstanceConnectEndpointReachable) {
+		return true
+	}
+
 	// include if reachable via any private or public dnsname or ip
 	if pointer.ValueOrZero(ec2Details.PublicDnsNameReachable) ||
 		pointer.ValueOrZero(ec2Details.PrivateDnsNameReachable) ||
@@ -427,47 +689,33 @@ func (ec2d *AwsEc2Discoverer) reachabilityCheck(
 	return false
 }
 
+// collectSsmInstanceStatuses adds the SSM status of all the instances known to SSM to
+// a map of instance id to whether the instance is online, with the describe instances
+// timeout applied to each page.
 func (ec2d *AwsEc2Discoverer) collectSsmInstanceStatuses(
 	ctx context.Context,
 	statuses map[string]bool,
 ) error {
-	paginator := ssm.NewDescribeInstanceInformationPaginator(
-		ssm.NewFromConfig(ec2d.cfg),
+	pages, err := collectAwsPages(ctx, ssm.NewDescribeInstanceInformationPaginator(
+		ec2d.ssmClient,
 		&ssm.DescribeInstanceInformationInput{},
-	)
-	for paginator.HasMorePages() {
-		err := processSsmInstanceInformationPage(
-			ctx,
-			statuses,
-			paginator,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to process SSM instance information page: %v", err)
-		}
-	}
-	return nil
-}
-
-func processSsmInstanceInformationPage(
-	ctx context.Context,
-	statuses map[string]bool,
-	paginator *ssm.DescribeInstanceInformationPaginator,
-) error {
-	describeInstanceInformationOutput, err := paginator.NextPage(ctx)
+	), ec2d.describeInstancesTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to get next page: %v", err)
+		return fmt.Errorf("failed to describe SSM instance information: %v", err)
 	}
-	for _, instanceInfo := range describeInstanceInformationOutput.InstanceInformationList {
-		// note: presense in the response implies that the SSM api knows
-		// about this instance (so it is associated with SSM). We add it
-		// to the map with an offline status (false).
-		statuses[aws.ToString(instanceInfo.InstanceId)] = false
-
-		onlinePingStatus := instanceInfo.PingStatus == ssmtypes.PingStatusOnline
-		timeSinceLastPing := time.Since(aws.ToTime(instanceInfo.LastPingDateTime))
-
-		if onlinePingStatus && timeSinceLastPing <= 10*time.Minute {
-			statuses[aws.ToString(instanceInfo.InstanceId)] = true // mark online
+	for _, page := range pages {
+		for _, instanceInfo := range page.InstanceInformationList {
+			// note: presense in the response implies that the SSM api knows
+			// about this instance (so it is associated with SSM). We add it
+			// to the map with an offline status (false).
+			statuses[aws.ToString(instanceInfo.InstanceId)] = false
+
+			onlinePingStatus := instanceInfo.PingStatus == ssmtypes.PingStatusOnline
+			timeSinceLastPing := time.Since(aws.ToTime(instanceInfo.LastPingDateTime))
+
+			if onlinePingStatus && timeSinceLastPing <= 10*time.Minute {
+				statuses[aws.ToString(instanceInfo.InstanceId)] = true // mark online
+			}
 		}
 	}
 	return nil
@@ -0,0 +1,491 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/borderzero/border0-go/lib/types/pointer"
+	"github.com/borderzero/discovery"
+)
+
+func newEc2TestInstance(instanceId string, state types.InstanceStateName, tags ...string) types.Instance {
+	return types.Instance{
+		InstanceId: aws.String(instanceId),
+		State:      &types.InstanceState{Name: state},
+		Tags:       newTestTags(newEc2TestTag, tags...),
+	}
+}
+
+func newEc2TestPage(instances ...types.Instance) *ec2.DescribeInstancesOutput {
+	return &ec2.DescribeInstancesOutput{
+		Reservations: []types.Reservation{{Instances: instances}},
+	}
+}
+
+// newEc2TestDiscoverer returns an AwsEc2Discoverer for an ec2 client with all
+// optional checks disabled (the eice check is by default), to be re-enabled by
+// the given options.
+func newEc2TestDiscoverer(ec2Client AwsEc2Client, opts ...AwsEc2DiscovererOption) *AwsEc2Discoverer {
+	return NewAwsEc2Discoverer(
+		aws.Config{Region: "us-east-1"},
+		append(
+			[]AwsEc2DiscovererOption{
+				WithAwsEc2DiscovererEc2Client(ec2Client),
+				WithAwsEc2DiscovererStsClient(&fakeStsClient{}),
+				WithAwsEc2DiscovererSsmStatusCheck(false, false),
+				WithAwsEc2DiscovererNetworkReachabilityCheck(false),
+			},
+			opts...,
+		)...,
+	)
+}
+
+func TestAwsEc2DiscovererDiscoverPagination(t *testing.T) {
+	pages := []*ec2.DescribeInstancesOutput{
+		newEc2TestPage(
+			newEc2TestInstance("i-1", types.InstanceStateNameRunning),
+			newEc2TestInstance("i-2", types.InstanceStateNameRunning),
+		),
+		newEc2TestPage(newEc2TestInstance("i-3", types.InstanceStateNameRunning)),
+		newEc2TestPage(newEc2TestInstance("i-4", types.InstanceStateNameRunning)),
+	}
+
+	tests := []struct {
+		name              string
+		errs              map[int]error
+		expectedIds       []string
+		expectedRequested []int
+		expectError       bool
+	}{
+		{
+			name:              "collects instances of all pages",
+			expectedIds:       []string{"i-1", "i-2", "i-3", "i-4"},
+			expectedRequested: []int{0, 1, 2},
+		},
+		{
+			name:              "fails when a later page fails",
+			errs:              map[int]error{1: errors.New("throttled")},
+			expectedIds:       []string{},
+			expectedRequested: []int{0, 1},
+			expectError:       true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ec2Client := &fakeEc2Client{}
+			ec2Client.instances.pages = pages
+			ec2Client.instances.errs = test.errs
+
+			result := newEc2TestDiscoverer(ec2Client).Discover(context.Background())
+
+			if ids := ec2InstanceIds(result); !slices.Equal(ids, test.expectedIds) {
+				t.Fatalf("expected instances %v, got %v", test.expectedIds, ids)
+			}
+			if !slices.Equal(ec2Client.instances.requested, test.expectedRequested) {
+				t.Fatalf("expected pages %v to be requested, got %v", test.expectedRequested, ec2Client.instances.requested)
+			}
+			if test.expectError != (len(result.Errors) > 0) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, result.Errors)
+			}
+			if test.expectError && !strings.Contains(result.Errors[0], "failed to describe ec2 instances") {
+				t.Fatalf("unexpected error: %s", result.Errors[0])
+			}
+		})
+	}
+}
+
+func TestAwsEc2DiscovererDiscoverFilters(t *testing.T) {
+	instances := newEc2TestPage(
+		newEc2TestInstance("i-web", types.InstanceStateNameRunning, "team", "web"),
+		newEc2TestInstance("i-db", types.InstanceStateNameRunning, "team", "db"),
+		newEc2TestInstance("i-scratch", types.InstanceStateNameRunning, "team", "web", "scratch", "true"),
+		newEc2TestInstance("i-stopped", types.InstanceStateNameStopped, "team", "web"),
+		newEc2TestInstance("i-terminated", types.InstanceStateNameTerminated),
+	)
+
+	tests := []struct {
+		name        string
+		opts        []AwsEc2DiscovererOption
+		expectedIds []string
+	}{
+		{
+			name:        "default states",
+			expectedIds: []string{"i-db", "i-scratch", "i-stopped", "i-terminated", "i-web"},
+		},
+		{
+			name:        "included states",
+			opts:        []AwsEc2DiscovererOption{WithAwsEc2DiscovererIncludedInstanceStates(types.InstanceStateNameRunning)},
+			expectedIds: []string{"i-db", "i-scratch", "i-web"},
+		},
+		{
+			name:        "inclusion tags",
+			opts:        []AwsEc2DiscovererOption{WithAwsEc2DiscovererInclusionInstanceTags(map[string][]string{"team": {"web"}})},
+			expectedIds: []string{"i-scratch", "i-stopped", "i-web"},
+		},
+		{
+			name:        "exclusion tags",
+			opts:        []AwsEc2DiscovererOption{WithAwsEc2DiscovererExclusionInstanceTags(map[string][]string{"scratch": {}})},
+			expectedIds: []string{"i-db", "i-stopped", "i-terminated", "i-web"},
+		},
+		{
+			name: "inclusion and exclusion tags and included states",
+			opts: []AwsEc2DiscovererOption{
+				WithAwsEc2DiscovererIncludedInstanceStates(types.InstanceStateNameRunning),
+				WithAwsEc2DiscovererInclusionInstanceTags(map[string][]string{"team": {"web"}}),
+				WithAwsEc2DiscovererExclusionInstanceTags(map[string][]string{"scratch": {"true"}}),
+			},
+			expectedIds: []string{"i-web"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ec2Client := &fakeEc2Client{}
+			ec2Client.instances.pages = []*ec2.DescribeInstancesOutput{instances}
+
+			result := newEc2TestDiscoverer(ec2Client, test.opts...).Discover(context.Background())
+
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if ids := ec2InstanceIds(result); !slices.Equal(ids, test.expectedIds) {
+				t.Fatalf("expected instances %v, got %v", test.expectedIds, ids)
+			}
+		})
+	}
+}
+
+func TestAwsEc2DiscovererDiscoverSsmStatus(t *testing.T) {
+	now := time.Now()
+	instances := newEc2TestPage(
+		newEc2TestInstance("i-online", types.InstanceStateNameRunning),
+		newEc2TestInstance("i-stale", types.InstanceStateNameRunning),
+		newEc2TestInstance("i-lost", types.InstanceStateNameRunning),
+		newEc2TestInstance("i-none", types.InstanceStateNameRunning),
+	)
+	// spread over pages to cover the pagination of the ssm api as well
+	information := []*ssm.DescribeInstanceInformationOutput{
+		{InstanceInformationList: []ssmtypes.InstanceInformation{
+			{InstanceId: aws.String("i-online"), PingStatus: ssmtypes.PingStatusOnline, LastPingDateTime: aws.Time(now)},
+			{InstanceId: aws.String("i-stale"), PingStatus: ssmtypes.PingStatusOnline, LastPingDateTime: aws.Time(now.Add(-time.Hour))},
+		}},
+		{InstanceInformationList: []ssmtypes.InstanceInformation{
+			{InstanceId: aws.String("i-lost"), PingStatus: ssmtypes.PingStatusConnectionLost, LastPingDateTime: aws.Time(now)},
+		}},
+	}
+
+	tests := []struct {
+		name             string
+		enabled          bool
+		required         bool
+		ssmErr           error
+		expectedStatuses map[string]string // by instance id
+		expectError      bool
+		expectWarning    bool
+	}{
+		{
+			name:    "statuses of associated and unassociated instances",
+			enabled: true,
+			expectedStatuses: map[string]string{
+				"i-online": discovery.Ec2InstanceSsmStatusOnline,
+				"i-stale":  discovery.Ec2InstanceSsmStatusOffline,
+				"i-lost":   discovery.Ec2InstanceSsmStatusOffline,
+				"i-none":   discovery.Ec2InstanceSsmStatusNotAssociated,
+			},
+		},
+		{
+			name: "not checked when disabled",
+			expectedStatuses: map[string]string{
+				"i-online": discovery.Ec2InstanceSsmStatusNotChecked,
+				"i-stale":  discovery.Ec2InstanceSsmStatusNotChecked,
+				"i-lost":   discovery.Ec2InstanceSsmStatusNotChecked,
+				"i-none":   discovery.Ec2InstanceSsmStatusNotChecked,
+			},
+		},
+		{
+			name:    "not checked when the optional check fails",
+			enabled: true,
+			ssmErr:  errors.New("access denied"),
+			expectedStatuses: map[string]string{
+				"i-online": discovery.Ec2InstanceSsmStatusNotChecked,
+				"i-stale":  discovery.Ec2InstanceSsmStatusNotChecked,
+				"i-lost":   discovery.Ec2InstanceSsmStatusNotChecked,
+				"i-none":   discovery.Ec2InstanceSsmStatusNotChecked,
+			},
+			expectWarning: true,
+		},
+		{
+			name:             "no instances when the required check fails",
+			enabled:          true,
+			required:         true,
+			ssmErr:           errors.New("access denied"),
+			expectedStatuses: map[string]string{},
+			expectError:      true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ec2Client := &fakeEc2Client{}
+			ec2Client.instances.pages = []*ec2.DescribeInstancesOutput{instances}
+			ssmClient := &fakeSsmClient{}
+			ssmClient.instanceInformation.pages = information
+			if test.ssmErr != nil {
+				ssmClient.instanceInformation.errs = map[int]error{1: test.ssmErr}
+			}
+
+			result := newEc2TestDiscoverer(
+				ec2Client,
+				WithAwsEc2DiscovererSsmClient(ssmClient),
+				WithAwsEc2DiscovererSsmStatusCheck(test.enabled, test.required),
+			).Discover(context.Background())
+
+			if test.expectError != (len(result.Errors) > 0) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, result.Errors)
+			}
+			if test.expectWarning != (len(result.Warnings) > 0) {
+				t.Fatalf("expected warning: %t, got: %v", test.expectWarning, result.Warnings)
+			}
+			statuses := map[string]string{}
+			for _, resource := range result.Resources {
+				statuses[resource.AwsEc2InstanceDetails.InstanceId] = resource.AwsEc2InstanceDetails.InstanceSsmStatus
+			}
+			if len(statuses) != len(test.expectedStatuses) {
+				t.Fatalf("expected statuses %v, got %v", test.expectedStatuses, statuses)
+			}
+			for instanceId, expected := range test.expectedStatuses {
+				if statuses[instanceId] != expected {
+					t.Fatalf("expected statuses %v, got %v", test.expectedStatuses, statuses)
+				}
+			}
+		})
+	}
+}
+
+func TestAwsEc2DiscovererShouldIncludeInstance(t *testing.T) {
+	tests := []struct {
+		name                 string
+		reachabilityRequired bool
+		details              discovery.AwsEc2InstanceDetails
+		expected             bool
+	}{
+		{
+			name:     "unreachable instance when reachability is not required",
+			details:  discovery.AwsEc2InstanceDetails{InstanceSsmStatus: discovery.Ec2InstanceSsmStatusOffline},
+			expected: true,
+		},
+		{
+			name:                 "online via ssm",
+			reachabilityRequired: true,
+			details:              discovery.AwsEc2InstanceDetails{InstanceSsmStatus: discovery.Ec2InstanceSsmStatusOnline},
+			expected:             true,
+		},
+		{
+			name:                 "offline via ssm",
+			reachabilityRequired: true,
+			details:              discovery.AwsEc2InstanceDetails{InstanceSsmStatus: discovery.Ec2InstanceSsmStatusOffline},
+			expected:             false,
+		},
+		{
+			name:                 "reachable via an instance connect endpoint",
+			reachabilityRequired: true,
+			details:              discovery.AwsEc2InstanceDetails{InstanceConnectEndpointReachable: pointer.To(true)},
+			expected:             true,
+		},
+		{
+			name:                 "no instance connect endpoint",
+			reachabilityRequired: true,
+			details:              discovery.AwsEc2InstanceDetails{InstanceConnectEndpointReachable: pointer.To(false)},
+			expected:             false,
+		},
+		{
+			name:                 "reachable via the private ip address",
+			reachabilityRequired: true,
+			details: discovery.AwsEc2InstanceDetails{
+				PrivateDnsNameReachable:   pointer.To(false),
+				PrivateIpAddressReachable: pointer.To(true),
+			},
+			expected: true,
+		},
+		{
+			name:                 "reachable via the public dns name",
+			reachabilityRequired: true,
+			details:              discovery.AwsEc2InstanceDetails{PublicDnsNameReachable: pointer.To(true)},
+			expected:             true,
+		},
+		{
+			name:                 "unreachable via all addresses",
+			reachabilityRequired: true,
+			details: discovery.AwsEc2InstanceDetails{
+				PrivateDnsNameReachable:   pointer.To(false),
+				PrivateIpAddressReachable: pointer.To(false),
+				PublicDnsNameReachable:    pointer.To(false),
+				PublicIpAddressReachable:  pointer.To(false),
+			},
+			expected: false,
+		},
+		{
+			name:                 "nothing checked",
+			reachabilityRequired: true,
+			details:              discovery.AwsEc2InstanceDetails{InstanceSsmStatus: discovery.Ec2InstanceSsmStatusNotChecked},
+			expected:             false,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ec2d := newEc2TestDiscoverer(&fakeEc2Client{}, WithAwsEc2DiscovererReachabilityRequired(test.reachabilityRequired))
+			if included := ec2d.shouldIncludeInstance(&test.details); included != test.expected {
+				t.Fatalf("expected included: %t, got: %t", test.expected, included)
+			}
+		})
+	}
+}
+
+func TestAwsEc2DiscovererDiscoverReachabilityRequired(t *testing.T) {
+	ec2Client := &fakeEc2Client{}
+	ec2Client.instances.pages = []*ec2.DescribeInstancesOutput{newEc2TestPage(
+		newEc2TestInstance("i-online", types.InstanceStateNameRunning),
+		newEc2TestInstance("i-offline", types.InstanceStateNameRunning),
+	)}
+	ssmClient := &fakeSsmClient{}
+	ssmClient.instanceInformation.pages = []*ssm.DescribeInstanceInformationOutput{
+		{InstanceInformationList: []ssmtypes.InstanceInformation{
+			{InstanceId: aws.String("i-online"), PingStatus: ssmtypes.PingStatusOnline, LastPingDateTime: aws.Time(time.Now())},
+			{InstanceId: aws.String("i-offline"), PingStatus: ssmtypes.PingStatusInactive, LastPingDateTime: aws.Time(time.Now())},
+		}},
+	}
+
+	result := newEc2TestDiscoverer(
+		ec2Client,
+		WithAwsEc2DiscovererSsmClient(ssmClient),
+		WithAwsEc2DiscovererSsmStatusCheck(true, false),
+		WithAwsEc2DiscovererReachabilityRequired(true),
+	).Discover(context.Background())
+
+	if ids := ec2InstanceIds(result); !slices.Equal(ids, []string{"i-online"}) {
+		t.Fatalf("expected only the reachable instance, got %v", ids)
+	}
+}
+
+func TestAwsEc2DiscovererDiscoverInstanceConnectEndpoints(t *testing.T) {
+	inVpc := func(instanceId, vpcId, subnetId string) types.Instance {
+		instance := newEc2TestInstance(instanceId, types.InstanceStateNameRunning)
+		instance.VpcId = aws.String(vpcId)
+		instance.SubnetId = aws.String(subnetId)
+		return instance
+	}
+	instances := newEc2TestPage(
+		inVpc("i-same-subnet", "vpc-1", "subnet-2"),
+		inVpc("i-other-subnet", "vpc-1", "subnet-9"),
+		inVpc("i-pending-endpoint", "vpc-2", "subnet-3"),
+		inVpc("i-no-endpoint", "vpc-3", "subnet-4"),
+	)
+	endpoints := []*ec2.DescribeInstanceConnectEndpointsOutput{
+		{InstanceConnectEndpoints: []types.Ec2InstanceConnectEndpoint{
+			newEiceTestEndpoint("eice-1", "vpc-1", "subnet-1", types.Ec2InstanceConnectEndpointStateCreateComplete),
+			newEiceTestEndpoint("eice-2", "vpc-1", "subnet-2", types.Ec2InstanceConnectEndpointStateCreateComplete),
+			newEiceTestEndpoint("eice-3", "vpc-2", "subnet-3", types.Ec2InstanceConnectEndpointStateCreateInProgress),
+		}},
+	}
+
+	tests := []struct {
+		name                 string
+		opts                 []AwsEc2DiscovererOption
+		endpointsErr         error
+		expectedEndpointIds  map[string]string // by instance id, "-" when not checked
+		expectEndpointsCalls bool
+		expectWarning        bool
+	}{
+		{
+			name: "not checked by default",
+			expectedEndpointIds: map[string]string{
+				"i-same-subnet":      "-",
+				"i-other-subnet":     "-",
+				"i-pending-endpoint": "-",
+				"i-no-endpoint":      "-",
+			},
+		},
+		{
+			name: "endpoints in the vpc, preferring the instance's subnet",
+			opts: []AwsEc2DiscovererOption{WithAwsEc2DiscovererEiceCheck(true)},
+			expectedEndpointIds: map[string]string{
+				"i-same-subnet":      "eice-2",
+				"i-other-subnet":     "eice-1",
+				"i-pending-endpoint": "",
+				"i-no-endpoint":      "",
+			},
+			expectEndpointsCalls: true,
+		},
+		{
+			name: "reachability bypass when reachability is required",
+			opts: []AwsEc2DiscovererOption{
+				WithAwsEc2DiscovererEiceCheck(true),
+				WithAwsEc2DiscovererReachabilityRequired(true),
+			},
+			expectedEndpointIds: map[string]string{
+				"i-same-subnet":  "eice-2",
+				"i-other-subnet": "eice-1",
+			},
+			expectEndpointsCalls: true,
+		},
+		{
+			name: "no instances reachable when the optional check fails",
+			opts: []AwsEc2DiscovererOption{
+				WithAwsEc2DiscovererEiceCheck(true),
+				WithAwsEc2DiscovererReachabilityRequired(true),
+			},
+			endpointsErr:         errors.New("access denied"),
+			expectedEndpointIds:  map[string]string{},
+			expectEndpointsCalls: true,
+			expectWarning:        true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ec2Client := &fakeEc2Client{}
+			ec2Client.instances.pages = []*ec2.DescribeInstancesOutput{instances}
+			ec2Client.endpoints.pages = endpoints
+			ec2Client.endpoints.setErr(test.endpointsErr)
+
+			// reachability required re-enables the network reachability check,
+			// which finds none of the (address-less) instances reachable
+			result := newEc2TestDiscoverer(ec2Client, test.opts...).Discover(context.Background())
+
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if test.expectWarning != (len(result.Warnings) > 0) {
+				t.Fatalf("expected warning: %t, got: %v", test.expectWarning, result.Warnings)
+			}
+			if called := len(ec2Client.endpoints.requested) > 0; called != test.expectEndpointsCalls {
+				t.Fatalf("expected endpoints to be described: %t, got: %t", test.expectEndpointsCalls, called)
+			}
+			endpointIds := map[string]string{}
+			for _, resource := range result.Resources {
+				details := resource.AwsEc2InstanceDetails
+				endpointIds[details.InstanceId] = "-"
+				if details.InstanceConnectEndpointReachable != nil {
+					endpointIds[details.InstanceId] = details.InstanceConnectEndpointId
+					if *details.InstanceConnectEndpointReachable != (details.InstanceConnectEndpointId != "") {
+						t.Fatalf("unexpected reachability for %s", details.InstanceId)
+					}
+				}
+			}
+			if len(endpointIds) != len(test.expectedEndpointIds) {
+				t.Fatalf("expected endpoints %v, got %v", test.expectedEndpointIds, endpointIds)
+			}
+			for instanceId, expected := range test.expectedEndpointIds {
+				if endpointIds[instanceId] != expected {
+					t.Fatalf("expected endpoints %v, got %v", test.expectedEndpointIds, endpointIds)
+				}
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/borderzero/border0-go/lib/types/maps"
 	"github.com/borderzero/border0-go/lib/types/slice"
 	"github.com/borderzero/discovery"
@@ -22,7 +23,9 @@ const (
 
 // AwsEcsDiscoverer represents a discoverer for AWS ECS resources.
 type AwsEcsDiscoverer struct {
-	cfg aws.Config
+	cfg       aws.Config
+	ecsClient AwsEcsClient
+	stsClient utils.AwsStsClient
 
 	discovererId         string
 	getAccountIdTimeout  time.Duration
@@ -41,6 +44,16 @@ func WithAwsEcsDiscovererDiscovererId(discovererId string) AwsEcsDiscovererOptio
 	return func(ecsd *AwsEcsDiscoverer) { ecsd.discovererId = discovererId }
 }
 
+// WithAwsEcsDiscovererEcsClient is the AwsEcsDiscovererOption to set a non default aws ecs client.
+func WithAwsEcsDiscovererEcsClient(client AwsEcsClient) AwsEcsDiscovererOption {
+	return func(ecsd *AwsEcsDiscoverer) { ecsd.ecsClient = client }
+}
+
+// WithAwsEcsDiscovererStsClient is the AwsEcsDiscovererOption to set a non default aws sts client.
+func WithAwsEcsDiscovererStsClient(client utils.AwsStsClient) AwsEcsDiscovererOption {
+	return func(ecsd *AwsEcsDiscoverer) { ecsd.stsClient = client }
+}
+
 // WithAwsEcsDiscovererGetAccountIdTimeout is the AwsEcsDiscovererOption
 // to set a non default timeout for getting the aws account id.
 func WithAwsEcsDiscovererGetAccountIdTimeout(timeout time.Duration) AwsEcsDiscovererOption {
@@ -62,7 +75,9 @@ func WithAwsEcsDiscovererExclusionServiceTags(tags map[string][]string) AwsEcsDi
 // NewAwsEcsDiscoverer returns a new AwsEcsDiscoverer.
 func NewAwsEcsDiscoverer(cfg aws.Config, opts ...AwsEcsDiscovererOption) *AwsEcsDiscoverer {
 	ecsd := &AwsEcsDiscoverer{
-		cfg: cfg,
+		cfg:       cfg,
+		ecsClient: ecs.NewFromConfig(cfg),
+		stsClient: sts.NewFromConfig(cfg),
 
 		discovererId:         defaultAwsEcsDiscovererDiscovererId,
 		getAccountIdTimeout:  defaultAwsEcsDiscovererGetAccountIdTimeout,
@@ -80,18 +95,17 @@ func (ecsd *AwsEcsDiscoverer) Discover(ctx context.Context) *discovery.Result {
 	result := discovery.NewResult(ecsd.discovererId)
 	defer result.Done()
 
-	awsAccountId, err := utils.AwsAccountIdFromConfig(ctx, ecsd.cfg, ecsd.getAccountIdTimeout)
+	awsAccountId, err := utils.AwsAccountIdFromStsClient(ctx, ecsd.stsClient, ecsd.getAccountIdTimeout)
 	if err != nil {
 		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
 		return result
 	}
 
-	ecsClient := ecs.NewFromConfig(ecsd.cfg)
-	paginator := ecs.NewListClustersPaginator(ecsClient, &ecs.ListClustersInput{})
+	paginator := ecs.NewListClustersPaginator(ecsd.ecsClient, &ecs.ListClustersInput{})
 	for paginator.HasMorePages() {
 		ok := ecsd.processEcsListClustersPage(
 			ctx,
-			ecsClient,
+			ecsd.ecsClient,
 			paginator,
 			result,
 			awsAccountId,
@@ -106,7 +120,7 @@ func (ecsd *AwsEcsDiscoverer) Discover(ctx context.Context) *discovery.Result {
 
 func (ecsd *AwsEcsDiscoverer) processEcsListClustersPage(
 	ctx context.Context,
-	ecsClient *ecs.Client,
+	ecsClient AwsEcsClient,
 	listClustersPaginator *ecs.ListClustersPaginator,
 	result *discovery.Result,
 	awsAccountId string,
@@ -151,7 +165,7 @@ func (ecsd *AwsEcsDiscoverer) processEcsListClustersPage(
 
 func (ecsd *AwsEcsDiscoverer) processEcsListServicesPage(
 	ctx context.Context,
-	ecsClient *ecs.Client,
+	ecsClient AwsEcsClient,
 	clusterArn string,
 	listServicesPaginator *ecs.ListServicesPaginator,
 	result *discovery.Result,
@@ -0,0 +1,113 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+func newEcsTestService(clusterArn, serviceName string, tags ...string) types.Service {
+	return types.Service{
+		ClusterArn:  aws.String(clusterArn),
+		ServiceArn:  aws.String(clusterArn + "/" + serviceName),
+		ServiceName: aws.String(serviceName),
+		Tags:        newTestTags(newEcsTestTag, tags...),
+	}
+}
+
+func TestAwsEcsDiscovererDiscover(t *testing.T) {
+	prod := "arn:aws:ecs:us-east-1:123456789012:cluster/prod"
+	dev := "arn:aws:ecs:us-east-1:123456789012:cluster/dev"
+	services := map[string][]types.Service{
+		prod: {
+			newEcsTestService(prod, "web", "team", "web"),
+			newEcsTestService(prod, "api", "team", "api"),
+		},
+		dev: {
+			newEcsTestService(dev, "web", "team", "web", "scratch", "true"),
+		},
+	}
+
+	tests := []struct {
+		name          string
+		opts          []AwsEcsDiscovererOption
+		clustersErrs  map[int]error
+		expectedNames []string // cluster/service
+		expectError   bool
+	}{
+		{
+			name:          "services of all clusters",
+			expectedNames: []string{"dev/web", "prod/api", "prod/web"},
+		},
+		{
+			name:          "inclusion tags",
+			opts:          []AwsEcsDiscovererOption{WithAwsEcsDiscovererInclusionServiceTags(map[string][]string{"team": {"web"}})},
+			expectedNames: []string{"dev/web", "prod/web"},
+		},
+		{
+			name:          "exclusion tags",
+			opts:          []AwsEcsDiscovererOption{WithAwsEcsDiscovererExclusionServiceTags(map[string][]string{"scratch": {}})},
+			expectedNames: []string{"prod/api", "prod/web"},
+		},
+		{
+			name:          "services of earlier pages when a later page of clusters fails",
+			clustersErrs:  map[int]error{1: errors.New("throttled")},
+			expectedNames: []string{"prod/api", "prod/web"},
+			expectError:   true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ecsClient := &fakeEcsClient{services: services}
+			ecsClient.clusters.pages = []*ecs.ListClustersOutput{
+				{ClusterArns: []string{prod}},
+				{ClusterArns: []string{dev}},
+			}
+			ecsClient.clusters.errs = test.clustersErrs
+
+			result := NewAwsEcsDiscoverer(
+				aws.Config{Region: "us-east-1"},
+				append(
+					[]AwsEcsDiscovererOption{
+						WithAwsEcsDiscovererEcsClient(ecsClient),
+						WithAwsEcsDiscovererStsClient(&fakeStsClient{}),
+					},
+					test.opts...,
+				)...,
+			).Discover(context.Background())
+
+			if test.expectError != (len(result.Errors) > 0) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, result.Errors)
+			}
+			names := []string{}
+			for _, resource := range result.Resources {
+				details := resource.AwsEcsServiceDetails
+				if details.AwsAccountId != fakeAwsAccountId {
+					t.Fatalf("expected account id %s, got %s", fakeAwsAccountId, details.AwsAccountId)
+				}
+				names = append(names, details.ClusterName+"/"+details.ServiceName)
+			}
+			slices.Sort(names)
+			if !slices.Equal(names, test.expectedNames) {
+				t.Fatalf("expected services %v, got %v", test.expectedNames, names)
+			}
+		})
+	}
+}
+
+func TestAwsEcsDiscovererDiscoverAccountIdError(t *testing.T) {
+	result := NewAwsEcsDiscoverer(
+		aws.Config{Region: "us-east-1"},
+		WithAwsEcsDiscovererEcsClient(&fakeEcsClient{}),
+		WithAwsEcsDiscovererStsClient(&fakeStsClient{err: errors.New("expired token")}),
+	).Discover(context.Background())
+
+	if len(result.Errors) != 1 || len(result.Resources) != 0 {
+		t.Fatalf("expected a single error and no resources, got %v and %d resources", result.Errors, len(result.Resources))
+	}
+}
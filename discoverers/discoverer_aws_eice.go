@@ -0,0 +1,211 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/borderzero/border0-go/lib/types/maps"
+	"github.com/borderzero/border0-go/lib/types/set"
+	"github.com/borderzero/border0-go/lib/types/slice"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	defaultAwsEiceDiscovererDiscovererId                            = "aws_eice_discoverer"
+	defaultAwsEiceDiscovererGetAccountIdTimeout                     = time.Second * 10
+	defaultAwsEiceDiscovererDescribeInstanceConnectEndpointsTimeout = time.Second * 10
+)
+
+var (
+	defaultAwsEiceDiscovererIncludedEndpointStates = set.New(
+		types.Ec2InstanceConnectEndpointStateCreateInProgress,
+		types.Ec2InstanceConnectEndpointStateCreateComplete,
+		types.Ec2InstanceConnectEndpointStateCreateFailed,
+		types.Ec2InstanceConnectEndpointStateDeleteInProgress,
+		types.Ec2InstanceConnectEndpointStateDeleteFailed,
+	)
+)
+
+// AwsEiceDiscoverer represents a discoverer for AWS EC2 Instance Connect Endpoints.
+type AwsEiceDiscoverer struct {
+	cfg       aws.Config
+	ec2Client AwsEc2Client
+	stsClient utils.AwsStsClient
+
+	discovererId                            string
+	getAccountIdTimeout                     time.Duration
+	describeInstanceConnectEndpointsTimeout time.Duration
+	includedEndpointStates                  set.Set[types.Ec2InstanceConnectEndpointState]
+	inclusionEndpointTags                   map[string][]string
+	exclusionEndpointTags                   map[string][]string
+}
+
+// ensure AwsEiceDiscoverer implements discovery.Discoverer at compile-time.
+var _ discovery.Discoverer = (*AwsEiceDiscoverer)(nil)
+
+// AwsEiceDiscovererOption represents a configuration option for an AwsEiceDiscoverer.
+type AwsEiceDiscovererOption func(*AwsEiceDiscoverer)
+
+// WithAwsEiceDiscovererDiscovererId is the AwsEiceDiscovererOption to set a non default discoverer id.
+func WithAwsEiceDiscovererDiscovererId(discovererId string) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.discovererId = discovererId }
+}
+
+// WithAwsEiceDiscovererEc2Client is the AwsEiceDiscovererOption to set a non default aws ec2 client.
+func WithAwsEiceDiscovererEc2Client(client AwsEc2Client) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.ec2Client = client }
+}
+
+// WithAwsEiceDiscovererStsClient is the AwsEiceDiscovererOption to set a non default aws sts client.
+func WithAwsEiceDiscovererStsClient(client utils.AwsStsClient) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.stsClient = client }
+}
+
+// WithAwsEiceDiscovererGetAccountIdTimeout is the AwsEiceDiscovererOption
+// to set a non default timeout for getting the aws account id.
+func WithAwsEiceDiscovererGetAccountIdTimeout(timeout time.Duration) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.getAccountIdTimeout = timeout }
+}
+
+// WithAwsEiceDiscovererDescribeInstanceConnectEndpointsTimeout is the AwsEiceDiscovererOption to
+// set a non default timeout for each page of the describe instance connect endpoints api call.
+func WithAwsEiceDiscovererDescribeInstanceConnectEndpointsTimeout(timeout time.Duration) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.describeInstanceConnectEndpointsTimeout = timeout }
+}
+
+// WithAwsEiceDiscovererIncludedEndpointStates is the AwsEiceDiscovererOption
+// to set a non default list of states for endpoints to include in results.
+func WithAwsEiceDiscovererIncludedEndpointStates(states ...types.Ec2InstanceConnectEndpointState) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.includedEndpointStates = set.New(states...) }
+}
+
+// WithAwsEiceDiscovererInclusionEndpointTags is the AwsEiceDiscovererOption
+// to set the inclusion tags filter for endpoints to include in results.
+func WithAwsEiceDiscovererInclusionEndpointTags(tags map[string][]string) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.inclusionEndpointTags = tags }
+}
+
+// WithAwsEiceDiscovererExclusionEndpointTags is the AwsEiceDiscovererOption
+// to set the exclusion tags filter for endpoints to exclude in results.
+func WithAwsEiceDiscovererExclusionEndpointTags(tags map[string][]string) AwsEiceDiscovererOption {
+	return func(eiced *AwsEiceDiscoverer) { eiced.exclusionEndpointTags = tags }
+}
+
+// NewAwsEiceDiscoverer returns a new AwsEiceDiscoverer, initialized with the given options.
+func NewAwsEiceDiscoverer(cfg aws.Config, opts ...AwsEiceDiscovererOption) *AwsEiceDiscoverer {
+	eiced := &AwsEiceDiscoverer{
+		cfg:       cfg,
+		ec2Client: ec2.NewFromConfig(cfg),
+		stsClient: sts.NewFromConfig(cfg),
+
+		discovererId:                            defaultAwsEiceDiscovererDiscovererId,
+		getAccountIdTimeout:                     defaultAwsEiceDiscovererGetAccountIdTimeout,
+		describeInstanceConnectEndpointsTimeout: defaultAwsEiceDiscovererDescribeInstanceConnectEndpointsTimeout,
+		includedEndpointStates:                  defaultAwsEiceDiscovererIncludedEndpointStates,
+		inclusionEndpointTags:                   nil,
+		exclusionEndpointTags:                   nil,
+	}
+	for _, opt := range opts {
+		opt(eiced)
+	}
+	return eiced
+}
+
+// Discover runs the AwsEiceDiscoverer.
+func (eiced *AwsEiceDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := discovery.NewResult(eiced.discovererId)
+	defer result.Done()
+
+	awsAccountId, awsPartition, err := utils.AwsAccountIdAndPartitionFromStsClient(ctx, eiced.stsClient, eiced.getAccountIdTimeout)
+	if err != nil {
+		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
+		return result
+	}
+
+	endpoints, err := describeInstanceConnectEndpoints(ctx, eiced.ec2Client, eiced.describeInstanceConnectEndpointsTimeout)
+	if err != nil {
+		result.AddErrorf("failed to describe ec2 instance connect endpoints: %v", err)
+		return result
+	}
+
+	for _, endpoint := range endpoints {
+		// ignore endpoints with un-included states
+		if !eiced.includedEndpointStates.Has(endpoint.State) {
+			continue
+		}
+		tags := slice.Map(
+			endpoint.Tags,
+			func(tag types.Tag) (string, string) {
+				return aws.ToString(tag.Key), aws.ToString(tag.Value)
+			},
+		)
+		// ignore endpoints that don't satisfy tag conditions
+		if !maps.MatchesFilters(tags, eiced.inclusionEndpointTags, eiced.exclusionEndpointTags) {
+			continue
+		}
+
+		// build resource
+		endpointId := aws.ToString(endpoint.InstanceConnectEndpointId)
+		awsArn := aws.ToString(endpoint.InstanceConnectEndpointArn)
+		if awsArn == "" {
+			awsArn = fmt.Sprintf("arn:%s:ec2:%s:%s:instance-connect-endpoint/%s", awsPartition, eiced.cfg.Region, awsAccountId, endpointId)
+		}
+		eiceDetails := &discovery.AwsEc2InstanceConnectEndpointDetails{
+			AwsBaseDetails: discovery.AwsBaseDetails{
+				AwsRegion:    eiced.cfg.Region,
+				AwsAccountId: awsAccountId,
+				AwsArn:       awsArn,
+			},
+			Tags:                      tags,
+			InstanceConnectEndpointId: endpointId,
+			VpcId:                     aws.ToString(endpoint.VpcId),
+			SubnetId:                  aws.ToString(endpoint.SubnetId),
+			AvailabilityZone:          aws.ToString(endpoint.AvailabilityZone),
+			State:                     string(endpoint.State),
+			StateMessage:              aws.ToString(endpoint.StateMessage),
+			DnsName:                   aws.ToString(endpoint.DnsName),
+			FipsDnsName:               aws.ToString(endpoint.FipsDnsName),
+			PreserveClientIp:          aws.ToBool(endpoint.PreserveClientIp),
+			SecurityGroupIds:          endpoint.SecurityGroupIds,
+			CreatedAt:                 endpoint.CreatedAt,
+		}
+
+		result.AddResources(discovery.Resource{
+			ResourceType:                         discovery.ResourceTypeAwsEc2InstanceConnectEndpoint,
+			AwsEc2InstanceConnectEndpointDetails: eiceDetails,
+		})
+	}
+
+	return result
+}
+
+// describeInstanceConnectEndpoints returns all the EC2 Instance Connect Endpoints in
+// the client's region, sorted by endpoint id, with the timeout applied to each page.
+func describeInstanceConnectEndpoints(
+	ctx context.Context,
+	client ec2.DescribeInstanceConnectEndpointsAPIClient,
+	timeout time.Duration,
+) ([]types.Ec2InstanceConnectEndpoint, error) {
+	pages, err := collectAwsPages(ctx, ec2.NewDescribeInstanceConnectEndpointsPaginator(
+		client,
+		&ec2.DescribeInstanceConnectEndpointsInput{},
+	), timeout)
+	if err != nil {
+		return nil, err
+	}
+	endpoints := []types.Ec2InstanceConnectEndpoint{}
+	for _, page := range pages {
+		endpoints = append(endpoints, page.InstanceConnectEndpoints...)
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return aws.ToString(endpoints[i].InstanceConnectEndpointId) < aws.ToString(endpoints[j].InstanceConnectEndpointId)
+	})
+	return endpoints, nil
+}
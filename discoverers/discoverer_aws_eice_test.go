@@ -0,0 +1,123 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+)
+
+func newEiceTestEndpoint(endpointId, vpcId, subnetId string, state types.Ec2InstanceConnectEndpointState) types.Ec2InstanceConnectEndpoint {
+	return types.Ec2InstanceConnectEndpoint{
+		InstanceConnectEndpointId: aws.String(endpointId),
+		VpcId:                     aws.String(vpcId),
+		SubnetId:                  aws.String(subnetId),
+		State:                     state,
+	}
+}
+
+func TestAwsEiceDiscovererDiscover(t *testing.T) {
+	tagged := newEiceTestEndpoint("eice-3", "vpc-2", "subnet-3", types.Ec2InstanceConnectEndpointStateCreateComplete)
+	tagged.Tags = newTestTags(newEc2TestTag, "team", "web")
+	withArn := newEiceTestEndpoint("eice-1", "vpc-1", "subnet-1", types.Ec2InstanceConnectEndpointStateCreateComplete)
+	withArn.InstanceConnectEndpointArn = aws.String("arn:aws:ec2:us-east-1:123456789012:instance-connect-endpoint/eice-1")
+
+	// endpoints are spread over pages (and out of order) to cover pagination and sorting
+	pages := []*ec2.DescribeInstanceConnectEndpointsOutput{
+		{InstanceConnectEndpoints: []types.Ec2InstanceConnectEndpoint{
+			tagged,
+			newEiceTestEndpoint("eice-4", "vpc-2", "subnet-4", types.Ec2InstanceConnectEndpointStateDeleteComplete),
+		}},
+		{InstanceConnectEndpoints: []types.Ec2InstanceConnectEndpoint{
+			withArn,
+			newEiceTestEndpoint("eice-2", "vpc-1", "subnet-2", types.Ec2InstanceConnectEndpointStateCreateInProgress),
+		}},
+	}
+
+	tests := []struct {
+		name        string
+		opts        []AwsEiceDiscovererOption
+		partition   string
+		errs        map[int]error
+		expectedIds []string
+		expectError bool
+	}{
+		{
+			name:        "default states",
+			expectedIds: []string{"eice-1", "eice-2", "eice-3"},
+		},
+		{
+			name: "included states",
+			opts: []AwsEiceDiscovererOption{
+				WithAwsEiceDiscovererIncludedEndpointStates(types.Ec2InstanceConnectEndpointStateCreateComplete),
+			},
+			expectedIds: []string{"eice-1", "eice-3"},
+		},
+		{
+			name:        "inclusion tags",
+			opts:        []AwsEiceDiscovererOption{WithAwsEiceDiscovererInclusionEndpointTags(map[string][]string{"team": {"web"}})},
+			expectedIds: []string{"eice-3"},
+		},
+		{
+			name:        "arns in the partition of the caller",
+			opts:        []AwsEiceDiscovererOption{WithAwsEiceDiscovererInclusionEndpointTags(map[string][]string{"team": {"web"}})},
+			partition:   "aws-us-gov",
+			expectedIds: []string{"eice-3"},
+		},
+		{
+			name:        "exclusion tags",
+			opts:        []AwsEiceDiscovererOption{WithAwsEiceDiscovererExclusionEndpointTags(map[string][]string{"team": {}})},
+			expectedIds: []string{"eice-1", "eice-2"},
+		},
+		{
+			name:        "error when a later page fails",
+			errs:        map[int]error{1: errors.New("throttled")},
+			expectedIds: []string{},
+			expectError: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ec2Client := &fakeEc2Client{}
+			ec2Client.endpoints.pages = pages
+			ec2Client.endpoints.errs = test.errs
+
+			result := NewAwsEiceDiscoverer(
+				aws.Config{Region: "us-east-1"},
+				append(
+					[]AwsEiceDiscovererOption{
+						WithAwsEiceDiscovererEc2Client(ec2Client),
+						WithAwsEiceDiscovererStsClient(&fakeStsClient{partition: test.partition}),
+					},
+					test.opts...,
+				)...,
+			).Discover(context.Background())
+
+			if test.expectError != (len(result.Errors) > 0) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, result.Errors)
+			}
+			ids := []string{}
+			for _, resource := range result.Resources {
+				ids = append(ids, resource.AwsEc2InstanceConnectEndpointDetails.InstanceConnectEndpointId)
+			}
+			if !slices.Equal(ids, test.expectedIds) {
+				t.Fatalf("expected endpoints %v (in order), got %v", test.expectedIds, ids)
+			}
+			partition := test.partition
+			if partition == "" {
+				partition = "aws"
+			}
+			for _, resource := range result.Resources {
+				details := resource.AwsEc2InstanceConnectEndpointDetails
+				expectedArn := "arn:" + partition + ":ec2:us-east-1:123456789012:instance-connect-endpoint/" + details.InstanceConnectEndpointId
+				if details.AwsArn != expectedArn {
+					t.Fatalf("expected arn %s, got %s", expectedArn, details.AwsArn)
+				}
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/eks"
 	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/borderzero/border0-go/lib/types/maps"
 	"github.com/borderzero/border0-go/lib/types/pointer"
 	"github.com/borderzero/discovery"
@@ -26,7 +27,9 @@ const (
 
 // AwsEksDiscoverer represents a discoverer for AWS EKS resources.
 type AwsEksDiscoverer struct {
-	cfg aws.Config
+	cfg       aws.Config
+	eksClient AwsEksClient
+	stsClient utils.AwsStsClient
 
 	discovererId        string
 	getAccountIdTimeout time.Duration
@@ -49,6 +52,16 @@ func WithAwsEksDiscovererDiscovererId(discovererId string) AwsEksDiscovererOptio
 	return func(eksd *AwsEksDiscoverer) { eksd.discovererId = discovererId }
 }
 
+// WithAwsEksDiscovererEksClient is the AwsEksDiscovererOption to set a non default aws eks client.
+func WithAwsEksDiscovererEksClient(client AwsEksClient) AwsEksDiscovererOption {
+	return func(eksd *AwsEksDiscoverer) { eksd.eksClient = client }
+}
+
+// WithAwsEksDiscovererStsClient is the AwsEksDiscovererOption to set a non default aws sts client.
+func WithAwsEksDiscovererStsClient(client utils.AwsStsClient) AwsEksDiscovererOption {
+	return func(eksd *AwsEksDiscoverer) { eksd.stsClient = client }
+}
+
 // WithAwsEksDiscovererGetAccountIdTimeout is the AwsEksDiscovererOption
 // to set a non default timeout for getting the aws account id.
 func WithAwsEksDiscovererGetAccountIdTimeout(timeout time.Duration) AwsEksDiscovererOption {
@@ -86,7 +99,9 @@ func WithAwsEksDiscovererReachabilityRequired(required bool) AwsEksDiscovererOpt
 // NewAwsEksDiscoverer returns a new AwsEksDiscoverer.
 func NewAwsEksDiscoverer(cfg aws.Config, opts ...AwsEksDiscovererOption) *AwsEksDiscoverer {
 	eksd := &AwsEksDiscoverer{
-		cfg: cfg,
+		cfg:       cfg,
+		eksClient: eks.NewFromConfig(cfg),
+		stsClient: sts.NewFromConfig(cfg),
 
 		discovererId:         defaultAwsEksDiscovererDiscovererId,
 		getAccountIdTimeout:  defaultAwsEksDiscovererGetAccountIdTimeout,
@@ -107,7 +122,7 @@ func (eksd *AwsEksDiscoverer) Discover(ctx context.Context) *discovery.Result {
 	result := discovery.NewResult(eksd.discovererId)
 	defer result.Done()
 
-	awsAccountId, err := utils.AwsAccountIdFromConfig(ctx, eksd.cfg, eksd.getAccountIdTimeout)
+	awsAccountId, err := utils.AwsAccountIdFromStsClient(ctx, eksd.stsClient, eksd.getAccountIdTimeout)
 	if err != nil {
 		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
 		return result
@@ -117,13 +132,12 @@ func (eksd *AwsEksDiscoverer) Discover(ctx context.Context) *discovery.Result {
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
-	eksClient := eks.NewFromConfig(eksd.cfg)
-	paginator := eks.NewListClustersPaginator(eksClient, &eks.ListClustersInput{})
+	paginator := eks.NewListClustersPaginator(eksd.eksClient, &eks.ListClustersInput{})
 	for paginator.HasMorePages() {
 		keepGoing := eksd.processEksListClustersPage(
 			ctx,
 			&wg,
-			eksClient,
+			eksd.eksClient,
 			paginator,
 			result,
 			awsAccountId,
@@ -139,7 +153,7 @@ func (eksd *AwsEksDiscoverer) Discover(ctx context.Context) *discovery.Result {
 func (eksd *AwsEksDiscoverer) processEksListClustersPage(
 	ctx context.Context,
 	wg *sync.WaitGroup,
-	eksClient *eks.Client,
+	eksClient AwsEksClient,
 	listClustersPaginator *eks.ListClustersPaginator,
 	result *discovery.Result,
 	awsAccountId string,
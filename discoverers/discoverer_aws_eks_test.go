@@ -0,0 +1,131 @@
+package discoverers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eks"
+	"github.com/aws/aws-sdk-go-v2/service/eks/types"
+	"github.com/borderzero/border0-go/lib/types/pointer"
+)
+
+func TestAwsEksDiscovererDiscover(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden) // any response means reachable
+	}))
+	defer server.Close()
+
+	// a port on which nothing listens, for an unreachable endpoint
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %v", err)
+	}
+	closedEndpoint := "http://" + listener.Addr().String()
+	listener.Close()
+
+	clusters := map[string]*types.Cluster{
+		"prod":    {Name: aws.String("prod"), Endpoint: aws.String(server.URL), Tags: map[string]string{"env": "prod"}},
+		"staging": {Name: aws.String("staging"), Endpoint: aws.String(closedEndpoint), Tags: map[string]string{"env": "staging"}},
+		"scratch": {Name: aws.String("scratch"), Endpoint: aws.String(server.URL), Tags: map[string]string{"env": "dev", "scratch": "true"}},
+	}
+
+	tests := []struct {
+		name              string
+		opts              []AwsEksDiscovererOption
+		expectedReachable map[string]*bool // by cluster name, nil when not checked
+	}{
+		{
+			name: "clusters of all pages",
+			opts: []AwsEksDiscovererOption{WithAwsEksDiscovererNetworkReachabilityCheck(false)},
+			expectedReachable: map[string]*bool{
+				"prod":    nil,
+				"staging": nil,
+				"scratch": nil,
+			},
+		},
+		{
+			name: "inclusion tags",
+			opts: []AwsEksDiscovererOption{
+				WithAwsEksDiscovererNetworkReachabilityCheck(false),
+				WithAwsEksDiscovererInclusionServiceTags(map[string][]string{"env": {"prod", "staging"}}),
+			},
+			expectedReachable: map[string]*bool{
+				"prod":    nil,
+				"staging": nil,
+			},
+		},
+		{
+			name: "exclusion tags",
+			opts: []AwsEksDiscovererOption{
+				WithAwsEksDiscovererNetworkReachabilityCheck(false),
+				WithAwsEksDiscovererExclusionServiceTags(map[string][]string{"scratch": {}}),
+			},
+			expectedReachable: map[string]*bool{
+				"prod":    nil,
+				"staging": nil,
+			},
+		},
+		{
+			name: "endpoint reachability",
+			expectedReachable: map[string]*bool{
+				"prod":    pointer.To(true),
+				"staging": pointer.To(false),
+				"scratch": pointer.To(true),
+			},
+		},
+		{
+			name: "only reachable clusters when reachability is required",
+			opts: []AwsEksDiscovererOption{WithAwsEksDiscovererReachabilityRequired(true)},
+			expectedReachable: map[string]*bool{
+				"prod":    pointer.To(true),
+				"scratch": pointer.To(true),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			eksClient := &fakeEksClient{clusters: clusters}
+			eksClient.clusterNames.pages = []*eks.ListClustersOutput{
+				{Clusters: []string{"prod", "staging"}},
+				{Clusters: []string{"scratch"}},
+			}
+
+			result := NewAwsEksDiscoverer(
+				aws.Config{Region: "us-east-1"},
+				append(
+					[]AwsEksDiscovererOption{
+						WithAwsEksDiscovererEksClient(eksClient),
+						WithAwsEksDiscovererStsClient(&fakeStsClient{}),
+					},
+					test.opts...,
+				)...,
+			).Discover(context.Background())
+
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			names := []string{}
+			for _, resource := range result.Resources {
+				details := resource.AwsEksClusterDetails
+				names = append(names, details.ClusterName)
+				expected, ok := test.expectedReachable[details.ClusterName]
+				if !ok {
+					t.Fatalf("unexpected cluster %s", details.ClusterName)
+				}
+				if (expected == nil) != (details.EndpointReachable == nil) ||
+					(expected != nil && *expected != *details.EndpointReachable) {
+					t.Fatalf("unexpected reachability for %s: %v", details.ClusterName, details.EndpointReachable)
+				}
+			}
+			if len(names) != len(test.expectedReachable) {
+				slices.Sort(names)
+				t.Fatalf("expected %d clusters, got %v", len(test.expectedReachable), names)
+			}
+		})
+	}
+}
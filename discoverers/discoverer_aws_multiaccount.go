@@ -0,0 +1,319 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	defaultAwsMultiAccountDiscovererDiscovererId        = "aws_multiaccount_discoverer"
+	defaultAwsMultiAccountDiscovererRoleSessionName     = "discovery"
+	defaultAwsMultiAccountDiscovererListAccountsTimeout = time.Second * 10
+	defaultAwsMultiAccountDiscovererMaxConcurrency      = 4
+)
+
+// AwsAccountDiscovererFactory represents a function which builds the discoverer for an
+// account, given an aws config with the credentials of the role assumed in the account
+// and an sts client which answers with the id of the account (without calling the AWS
+// STS API). For the account id to be used, the sts client must be passed on to the
+// discoverer e.g. with WithAwsEc2DiscovererStsClient.
+type AwsAccountDiscovererFactory func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer
+
+// AwsMultiAccountDiscoverer represents a discoverer which runs an AWS discoverer in
+// each of a list of AWS accounts (or in each active account of an AWS organization),
+// with the credentials of a role assumed in each account, and merges their results
+// into a single result. Errors and warnings of the per-account discoverers are
+// prefixed with their account id.
+//
+// Credentials are cached (and refreshed before they expire) per account across runs.
+type AwsMultiAccountDiscoverer struct {
+	cfg                 aws.Config
+	factory             AwsAccountDiscovererFactory
+	stsClient           stscreds.AssumeRoleAPIClient
+	organizationsClient AwsOrganizationsClient
+
+	discovererId         string
+	roleArns             []string
+	organizationRoleName string
+	externalId           string
+	roleSessionName      string
+	listAccountsTimeout  time.Duration
+	maxConcurrency       int64
+
+	mu          sync.Mutex // protects discoverers and configs
+	discoverers map[string]discovery.Discoverer
+	configs     map[string]aws.Config
+}
+
+// ensure AwsMultiAccountDiscoverer implements discovery.Discoverer at compile-time.
+var _ discovery.Discoverer = (*AwsMultiAccountDiscoverer)(nil)
+
+// AwsMultiAccountDiscovererOption represents a configuration option for an AwsMultiAccountDiscoverer.
+type AwsMultiAccountDiscovererOption func(*AwsMultiAccountDiscoverer)
+
+// WithAwsMultiAccountDiscovererDiscovererId is the AwsMultiAccountDiscovererOption to set a non
+// default discoverer id. By default, the discoverer id of the per-account discoverers is used.
+func WithAwsMultiAccountDiscovererDiscovererId(discovererId string) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.discovererId = discovererId }
+}
+
+// WithAwsMultiAccountDiscovererRoleArns is the AwsMultiAccountDiscovererOption
+// to set the ARNs of the roles to assume, one per account.
+func WithAwsMultiAccountDiscovererRoleArns(roleArns ...string) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.roleArns = roleArns }
+}
+
+// WithAwsMultiAccountDiscovererOrganizationRoleName is the AwsMultiAccountDiscovererOption
+// to discover resources in all active accounts of the organization of the base aws config,
+// by assuming the role with the given name (e.g. "OrganizationAccountAccessRole") in each
+// account. Ignored when role ARNs are set.
+func WithAwsMultiAccountDiscovererOrganizationRoleName(roleName string) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.organizationRoleName = roleName }
+}
+
+// WithAwsMultiAccountDiscovererExternalId is the AwsMultiAccountDiscovererOption
+// to set the external id to assume roles with.
+func WithAwsMultiAccountDiscovererExternalId(externalId string) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.externalId = externalId }
+}
+
+// WithAwsMultiAccountDiscovererRoleSessionName is the AwsMultiAccountDiscovererOption
+// to set a non default session name to assume roles with.
+func WithAwsMultiAccountDiscovererRoleSessionName(roleSessionName string) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.roleSessionName = roleSessionName }
+}
+
+// WithAwsMultiAccountDiscovererListAccountsTimeout is the AwsMultiAccountDiscovererOption
+// to set a non default timeout for each page of the list accounts api call.
+func WithAwsMultiAccountDiscovererListAccountsTimeout(timeout time.Duration) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.listAccountsTimeout = timeout }
+}
+
+// WithAwsMultiAccountDiscovererMaxConcurrency is the AwsMultiAccountDiscovererOption
+// to set a non default maximum number of accounts to discover resources in at once.
+// Values lower than 1 are ignored in favour of the default.
+func WithAwsMultiAccountDiscovererMaxConcurrency(concurrency int64) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) {
+		if concurrency < 1 {
+			concurrency = defaultAwsMultiAccountDiscovererMaxConcurrency // a weight of 0 never acquires
+		}
+		mad.maxConcurrency = concurrency
+	}
+}
+
+// WithAwsMultiAccountDiscovererStsClient is the AwsMultiAccountDiscovererOption
+// to set a non default aws sts client for assuming roles.
+func WithAwsMultiAccountDiscovererStsClient(client stscreds.AssumeRoleAPIClient) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.stsClient = client }
+}
+
+// WithAwsMultiAccountDiscovererOrganizationsClient is the AwsMultiAccountDiscovererOption
+// to set a non default aws organizations client for listing accounts.
+func WithAwsMultiAccountDiscovererOrganizationsClient(client AwsOrganizationsClient) AwsMultiAccountDiscovererOption {
+	return func(mad *AwsMultiAccountDiscoverer) { mad.organizationsClient = client }
+}
+
+// NewAwsMultiAccountDiscoverer returns a new AwsMultiAccountDiscoverer, which builds the
+// discoverer for each account with the given factory, initialized with the given options.
+func NewAwsMultiAccountDiscoverer(
+	cfg aws.Config,
+	factory AwsAccountDiscovererFactory,
+	opts ...AwsMultiAccountDiscovererOption,
+) *AwsMultiAccountDiscoverer {
+	mad := &AwsMultiAccountDiscoverer{
+		cfg:                 cfg,
+		factory:             factory,
+		stsClient:           sts.NewFromConfig(cfg),
+		organizationsClient: organizations.NewFromConfig(cfg),
+
+		discovererId:         "",
+		roleArns:             nil,
+		organizationRoleName: "",
+		externalId:           "",
+		roleSessionName:      defaultAwsMultiAccountDiscovererRoleSessionName,
+		listAccountsTimeout:  defaultAwsMultiAccountDiscovererListAccountsTimeout,
+		maxConcurrency:       defaultAwsMultiAccountDiscovererMaxConcurrency,
+
+		discoverers: map[string]discovery.Discoverer{},
+		configs:     map[string]aws.Config{},
+	}
+	for _, opt := range opts {
+		opt(mad)
+	}
+	return mad
+}
+
+// Discover runs the AwsMultiAccountDiscoverer.
+func (mad *AwsMultiAccountDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := discovery.NewResult(mad.discovererId)
+	defer result.Done()
+
+	roleArns, err := mad.getRoleArns(ctx)
+	if err != nil {
+		result.AddErrorf("failed to get the roles to assume: %v", err)
+		mad.setDiscovererId(result, nil)
+		return result
+	}
+
+	accountIds := make([]string, len(roleArns))
+	accountResults := make([]*discovery.Result, len(roleArns))
+
+	var wg sync.WaitGroup
+	sem := semaphore.NewWeighted(mad.maxConcurrency)
+	for i, roleArn := range roleArns {
+		parsed, err := arn.Parse(roleArn)
+		if err != nil {
+			result.AddErrorf("invalid role ARN \"%s\": %v", roleArn, err)
+			continue
+		}
+		accountIds[i] = parsed.AccountID
+
+		if err := sem.Acquire(ctx, 1); err != nil {
+			result.AddErrorf("failed to acquire semaphore: %v", err)
+			break
+		}
+		wg.Add(1)
+		go func(i int, roleArn string) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			accountResults[i] = mad.discoverAccount(ctx, accountIds[i], roleArn)
+		}(i, roleArn)
+	}
+	wg.Wait()
+
+	for i, accountResult := range accountResults {
+		if accountResult == nil {
+			continue
+		}
+		accountResult.Lock()
+		result.AddResources(accountResult.Resources...)
+		for _, err := range accountResult.Errors {
+			result.AddErrorf("%s: %s", accountIds[i], err)
+		}
+		for _, warning := range accountResult.Warnings {
+			result.AddWarningf("%s: %s", accountIds[i], warning)
+		}
+		accountResult.Unlock()
+	}
+	mad.setDiscovererId(result, accountResults)
+
+	return result
+}
+
+// discoverAccount runs the discoverer for an account, after making sure that the role can
+// be assumed so that failing to assume it is reported once rather than for every api call.
+func (mad *AwsMultiAccountDiscoverer) discoverAccount(ctx context.Context, accountId, roleArn string) *discovery.Result {
+	cfg, d := mad.discoverer(accountId, roleArn)
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		result := discovery.NewResult("")
+		result.AddErrorf("failed to assume role \"%s\": %v", roleArn, err)
+		result.Done()
+		return result
+	}
+	return d.Discover(ctx)
+}
+
+// discoverer returns the aws config and the discoverer for an account, building them
+// on first use so that credentials (and discoverers' state) are reused across runs.
+func (mad *AwsMultiAccountDiscoverer) discoverer(accountId, roleArn string) (aws.Config, discovery.Discoverer) {
+	mad.mu.Lock()
+	defer mad.mu.Unlock()
+
+	if d, ok := mad.discoverers[roleArn]; ok {
+		return mad.configs[roleArn], d
+	}
+	cfg := mad.cfg.Copy()
+	cfg.Credentials = aws.NewCredentialsCache(stscreds.NewAssumeRoleProvider(
+		mad.stsClient,
+		roleArn,
+		func(o *stscreds.AssumeRoleOptions) {
+			o.RoleSessionName = mad.roleSessionName
+			if mad.externalId != "" {
+				o.ExternalID = aws.String(mad.externalId)
+			}
+		},
+	))
+	d := mad.factory(cfg, &sharedAwsStsClient{awsAccountId: accountId})
+	mad.configs[roleArn] = cfg
+	mad.discoverers[roleArn] = d
+	return cfg, d
+}
+
+// getRoleArns returns the configured role ARNs, or the ARNs of the role with the
+// configured name in each active account of the organization.
+func (mad *AwsMultiAccountDiscoverer) getRoleArns(ctx context.Context) ([]string, error) {
+	if len(mad.roleArns) > 0 {
+		return mad.roleArns, nil
+	}
+	if mad.organizationRoleName == "" {
+		return nil, fmt.Errorf("neither role ARNs nor an organization role name are configured")
+	}
+
+	roleArns := []string{}
+	paginator := organizations.NewListAccountsPaginator(
+		mad.organizationsClient,
+		&organizations.ListAccountsInput{},
+	)
+	for paginator.HasMorePages() {
+		listAccountsOutput, err := mad.listAccountsPage(ctx, paginator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list organization accounts: %v", err)
+		}
+		for _, account := range listAccountsOutput.Accounts {
+			if account.Status != orgtypes.AccountStatusActive {
+				continue
+			}
+			roleArns = append(roleArns, fmt.Sprintf(
+				"arn:%s:iam::%s:role/%s",
+				utils.AwsPartitionFromArn(aws.ToString(account.Arn)),
+				aws.ToString(account.Id),
+				mad.organizationRoleName,
+			))
+		}
+	}
+	sort.Strings(roleArns)
+	return roleArns, nil
+}
+
+func (mad *AwsMultiAccountDiscoverer) listAccountsPage(
+	ctx context.Context,
+	paginator *organizations.ListAccountsPaginator,
+) (*organizations.ListAccountsOutput, error) {
+	listAccountsCtx, cancel := context.WithTimeout(ctx, mad.listAccountsTimeout)
+	defer cancel()
+
+	return paginator.NextPage(listAccountsCtx)
+}
+
+// setDiscovererId sets the discoverer id of a result to that of the
+// per-account results when no discoverer id was set with an option.
+func (mad *AwsMultiAccountDiscoverer) setDiscovererId(result *discovery.Result, accountResults []*discovery.Result) {
+	if mad.discovererId != "" {
+		return
+	}
+	discovererId := defaultAwsMultiAccountDiscovererDiscovererId
+	for _, accountResult := range accountResults {
+		if accountResult != nil && accountResult.Metadata.DiscovererId != "" {
+			discovererId = accountResult.Metadata.DiscovererId
+			break
+		}
+	}
+	result.Lock()
+	defer result.Unlock()
+
+	result.Metadata.DiscovererId = discovererId
+}
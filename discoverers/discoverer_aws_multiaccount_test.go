@@ -0,0 +1,233 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	orgtypes "github.com/aws/aws-sdk-go-v2/service/organizations/types"
+)
+
+func newMultiAccountTestAccount(arn, id string, status orgtypes.AccountStatus) orgtypes.Account {
+	return orgtypes.Account{Arn: aws.String(arn), Id: aws.String(id), Status: status}
+}
+
+func TestAwsMultiAccountDiscovererMaxConcurrency(t *testing.T) {
+	accountIds := []string{"111111111111", "222222222222", "333333333333"}
+	roleArns := []string{}
+	for _, accountId := range accountIds {
+		roleArns = append(roleArns, "arn:aws:iam::"+accountId+":role/discovery")
+	}
+
+	for _, concurrency := range []int64{-1, 0, 1, 2, 10} {
+		mad := NewAwsMultiAccountDiscoverer(
+			aws.Config{Region: "us-east-1"},
+			newEc2TestFactory(),
+			WithAwsMultiAccountDiscovererStsClient(&fakeStsClient{}),
+			WithAwsMultiAccountDiscovererRoleArns(roleArns...),
+			WithAwsMultiAccountDiscovererMaxConcurrency(concurrency),
+		)
+		if mad.maxConcurrency < 1 {
+			t.Fatalf("concurrency %d: expected a max concurrency of at least 1, got %d", concurrency, mad.maxConcurrency)
+		}
+
+		// a semaphore without room would block until the context is done
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		result := mad.Discover(ctx)
+		cancel()
+
+		if len(result.Errors) > 0 {
+			t.Fatalf("concurrency %d: unexpected errors: %v", concurrency, result.Errors)
+		}
+		if discovered := ec2AccountIds(result); !slices.Equal(discovered, accountIds) {
+			t.Fatalf("concurrency %d: expected instances in accounts %v, got %v", concurrency, accountIds, discovered)
+		}
+	}
+}
+
+func TestAwsMultiAccountDiscovererOrganizationAccounts(t *testing.T) {
+	tests := []struct {
+		name          string
+		pages         []*organizations.ListAccountsOutput
+		listErr       error
+		expectedRoles []string
+		expectedErr   string
+	}{
+		{
+			name: "active accounts of all pages",
+			pages: []*organizations.ListAccountsOutput{
+				{Accounts: []orgtypes.Account{
+					newMultiAccountTestAccount("arn:aws:organizations::111111111111:account/o-1/333333333333", "333333333333", orgtypes.AccountStatusActive),
+					newMultiAccountTestAccount("arn:aws:organizations::111111111111:account/o-1/444444444444", "444444444444", orgtypes.AccountStatusSuspended),
+				}},
+				{Accounts: []orgtypes.Account{
+					newMultiAccountTestAccount("arn:aws:organizations::111111111111:account/o-1/222222222222", "222222222222", orgtypes.AccountStatusActive),
+					newMultiAccountTestAccount("arn:aws:organizations::111111111111:account/o-1/555555555555", "555555555555", orgtypes.AccountStatusPendingClosure),
+				}},
+			},
+			expectedRoles: []string{
+				"arn:aws:iam::222222222222:role/OrganizationAccountAccessRole",
+				"arn:aws:iam::333333333333:role/OrganizationAccountAccessRole",
+			},
+		},
+		{
+			name: "partition of the account arns",
+			pages: []*organizations.ListAccountsOutput{
+				{Accounts: []orgtypes.Account{
+					newMultiAccountTestAccount("arn:aws-us-gov:organizations::111111111111:account/o-1/222222222222", "222222222222", orgtypes.AccountStatusActive),
+				}},
+			},
+			expectedRoles: []string{"arn:aws-us-gov:iam::222222222222:role/OrganizationAccountAccessRole"},
+		},
+		{
+			name:          "failing to list accounts",
+			listErr:       errors.New("not in an organization"),
+			expectedRoles: []string{},
+			expectedErr:   "failed to get the roles to assume: failed to list organization accounts: not in an organization",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			organizationsClient := &fakeOrganizationsClient{}
+			organizationsClient.accounts.pages = test.pages
+			organizationsClient.accounts.setErr(test.listErr)
+			stsClient := &fakeStsClient{}
+
+			mad := NewAwsMultiAccountDiscoverer(
+				aws.Config{Region: "us-east-1"},
+				(&fakeAwsDiscoverers{}).factory,
+				WithAwsMultiAccountDiscovererStsClient(stsClient),
+				WithAwsMultiAccountDiscovererOrganizationsClient(organizationsClient),
+				WithAwsMultiAccountDiscovererOrganizationRoleName("OrganizationAccountAccessRole"),
+			)
+			result := mad.Discover(context.Background())
+
+			if roles := stsClient.assumedRoles(); !slices.Equal(roles, test.expectedRoles) {
+				t.Fatalf("expected roles %v to be assumed, got %v", test.expectedRoles, roles)
+			}
+			if test.expectedErr == "" && len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if test.expectedErr != "" && !slices.Equal(result.Errors, []string{test.expectedErr}) {
+				t.Fatalf("expected error %q, got %v", test.expectedErr, result.Errors)
+			}
+		})
+	}
+}
+
+func TestAwsMultiAccountDiscovererDiscover(t *testing.T) {
+	discoverers := &fakeAwsDiscoverers{
+		discovererId: "ec2",
+		errs:         map[string][]string{"us-east-1": {"failed to describe instances"}},
+		warnings:     map[string][]string{"us-east-1": {"skipped an instance"}},
+	}
+	stsClient := &fakeStsClient{
+		roleErrs: map[string]error{"arn:aws:iam::333333333333:role/discovery": errors.New("access denied")},
+	}
+	mad := NewAwsMultiAccountDiscoverer(
+		aws.Config{Region: "us-east-1"},
+		discoverers.factory,
+		WithAwsMultiAccountDiscovererStsClient(stsClient),
+		WithAwsMultiAccountDiscovererRoleArns(
+			"arn:aws:iam::111111111111:role/discovery",
+			"not-an-arn",
+			"arn:aws:iam::222222222222:role/discovery",
+			"arn:aws:iam::333333333333:role/discovery",
+		),
+		WithAwsMultiAccountDiscovererExternalId("external"),
+		WithAwsMultiAccountDiscovererRoleSessionName("session"),
+	)
+
+	for run := 1; run <= 2; run++ {
+		result := mad.Discover(context.Background())
+
+		if ids := ec2AccountIds(result); !slices.Equal(ids, []string{"111111111111", "222222222222"}) {
+			t.Fatalf("run %d: expected resources in the accounts of the roles assumed, got %v", run, ids)
+		}
+
+		// errors of an account are prefixed with the account id, and failing to
+		// assume a role is reported once rather than by each api call
+		errs := slices.Clone(result.Errors)
+		slices.Sort(errs)
+		if len(errs) != 4 ||
+			errs[0] != "111111111111: failed to describe instances" ||
+			errs[1] != "222222222222: failed to describe instances" ||
+			!strings.HasPrefix(errs[2], "333333333333: failed to assume role \"arn:aws:iam::333333333333:role/discovery\"") ||
+			!strings.HasPrefix(errs[3], "invalid role ARN \"not-an-arn\"") {
+			t.Fatalf("run %d: unexpected errors %v", run, errs)
+		}
+		warnings := slices.Clone(result.Warnings)
+		slices.Sort(warnings)
+		if !slices.Equal(warnings, []string{"111111111111: skipped an instance", "222222222222: skipped an instance"}) {
+			t.Fatalf("run %d: expected warnings prefixed with their account id, got %v", run, warnings)
+		}
+		if result.Metadata.DiscovererId != "ec2" {
+			t.Fatalf("run %d: expected the discoverer id of the per-account discoverers, got %s", run, result.Metadata.DiscovererId)
+		}
+	}
+
+	// per-account discoverers are built once, including for the role which failed
+	if built := discoverers.builtRegions(); len(built) != 3 {
+		t.Fatalf("expected a single discoverer per valid role arn, got %d", len(built))
+	}
+	stsClient.mu.Lock()
+	defer stsClient.mu.Unlock()
+	for _, input := range stsClient.assumed {
+		if aws.ToString(input.ExternalId) != "external" || aws.ToString(input.RoleSessionName) != "session" {
+			t.Fatalf("expected roles to be assumed with the external id and session name, got %+v", input)
+		}
+	}
+}
+
+func TestAwsMultiAccountDiscovererDiscovererId(t *testing.T) {
+	tests := []struct {
+		name                 string
+		opts                 []AwsMultiAccountDiscovererOption
+		perAccountId         string
+		roleErr              error
+		expectedDiscovererId string
+	}{
+		{
+			name:                 "discoverer id of the per-account discoverers",
+			perAccountId:         "ec2",
+			expectedDiscovererId: "ec2",
+		},
+		{
+			name:                 "discoverer id set with an option",
+			opts:                 []AwsMultiAccountDiscovererOption{WithAwsMultiAccountDiscovererDiscovererId("all-accounts")},
+			perAccountId:         "ec2",
+			expectedDiscovererId: "all-accounts",
+		},
+		{
+			name:                 "default when no role could be assumed",
+			perAccountId:         "ec2",
+			roleErr:              errors.New("access denied"),
+			expectedDiscovererId: defaultAwsMultiAccountDiscovererDiscovererId,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mad := NewAwsMultiAccountDiscoverer(
+				aws.Config{Region: "us-east-1"},
+				(&fakeAwsDiscoverers{discovererId: test.perAccountId}).factory,
+				append(
+					[]AwsMultiAccountDiscovererOption{
+						WithAwsMultiAccountDiscovererStsClient(&fakeStsClient{err: test.roleErr}),
+						WithAwsMultiAccountDiscovererRoleArns("arn:aws:iam::111111111111:role/discovery"),
+					},
+					test.opts...,
+				)...,
+			)
+			result := mad.Discover(context.Background())
+
+			if result.Metadata.DiscovererId != test.expectedDiscovererId {
+				t.Fatalf("expected discoverer id %s, got %s", test.expectedDiscovererId, result.Metadata.DiscovererId)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+	"golang.org/x/sync/semaphore"
+)
+
+const (
+	defaultAwsMultiRegionDiscovererDiscovererId           = "aws_multiregion_discoverer"
+	defaultAwsMultiRegionDiscovererGetAccountIdTimeout    = time.Second * 10
+	defaultAwsMultiRegionDiscovererDescribeRegionsTimeout = time.Second * 10
+	defaultAwsMultiRegionDiscovererMaxConcurrency         = 4
+
+	// region to enumerate regions from when the aws config has no region.
+	defaultAwsMultiRegionDiscovererEnumerationRegion = "us-east-1"
+)
+
+// AwsRegionalDiscovererFactory represents a function which builds the discoverer for
+// a region, given an aws config for the region and an sts client which answers with
+// the aws account id looked up once (per run) for all regions. For the account id
+// lookup to be shared, the sts client must be passed on to the discoverer e.g. with
+// WithAwsEc2DiscovererStsClient.
+type AwsRegionalDiscovererFactory func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer
+
+// AwsMultiRegionDiscoverer represents a discoverer which runs an AWS discoverer
+// in each enabled region (or in a given list of regions) of an AWS account, with
+// bounded concurrency, and merges their results into a single result. Errors and
+// warnings of the per-region discoverers are prefixed with their region.
+type AwsMultiRegionDiscoverer struct {
+	cfg       aws.Config
+	factory   AwsRegionalDiscovererFactory
+	ec2Client AwsEc2RegionsClient
+	stsClient utils.AwsStsClient
+
+	discovererId           string
+	regions                []string
+	getAccountIdTimeout    time.Duration
+	describeRegionsTimeout time.Duration
+	maxConcurrency         int64
+
+	sharedStsClient *sharedAwsStsClient
+
+	mu          sync.Mutex // protects discoverers
+	discoverers map[string]discovery.Discoverer
+}
+
+// ensure AwsMultiRegionDiscoverer implements discovery.Discoverer at compile-time.
+var _ discovery.Discoverer = (*AwsMultiRegionDiscoverer)(nil)
+
+// AwsMultiRegionDiscovererOption represents a configuration option for an AwsMultiRegionDiscoverer.
+type AwsMultiRegionDiscovererOption func(*AwsMultiRegionDiscoverer)
+
+// WithAwsMultiRegionDiscovererDiscovererId is the AwsMultiRegionDiscovererOption to set a
+// non default discoverer id. By default, the discoverer id of the per-region discoverers is used.
+func WithAwsMultiRegionDiscovererDiscovererId(discovererId string) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) { mrd.discovererId = discovererId }
+}
+
+// WithAwsMultiRegionDiscovererRegions is the AwsMultiRegionDiscovererOption to set the
+// regions to discover resources in. By default, all enabled regions are enumerated.
+func WithAwsMultiRegionDiscovererRegions(regions ...string) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) { mrd.regions = regions }
+}
+
+// WithAwsMultiRegionDiscovererMaxConcurrency is the AwsMultiRegionDiscovererOption
+// to set a non default maximum number of regions to discover resources in at once.
+// Values lower than 1 are ignored in favour of the default.
+func WithAwsMultiRegionDiscovererMaxConcurrency(concurrency int64) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) {
+		if concurrency < 1 {
+			concurrency = defaultAwsMultiRegionDiscovererMaxConcurrency // a weight of 0 never acquires
+		}
+		mrd.maxConcurrency = concurrency
+	}
+}
+
+// WithAwsMultiRegionDiscovererGetAccountIdTimeout is the AwsMultiRegionDiscovererOption
+// to set a non default timeout for getting the aws account id.
+func WithAwsMultiRegionDiscovererGetAccountIdTimeout(timeout time.Duration) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) { mrd.getAccountIdTimeout = timeout }
+}
+
+// WithAwsMultiRegionDiscovererDescribeRegionsTimeout is the AwsMultiRegionDiscovererOption
+// to set a non default timeout for the describe regions api call.
+func WithAwsMultiRegionDiscovererDescribeRegionsTimeout(timeout time.Duration) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) { mrd.describeRegionsTimeout = timeout }
+}
+
+// WithAwsMultiRegionDiscovererEc2Client is the AwsMultiRegionDiscovererOption
+// to set a non default aws ec2 client for enumerating regions.
+func WithAwsMultiRegionDiscovererEc2Client(client AwsEc2RegionsClient) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) { mrd.ec2Client = client }
+}
+
+// WithAwsMultiRegionDiscovererStsClient is the AwsMultiRegionDiscovererOption to set a non default aws sts client.
+func WithAwsMultiRegionDiscovererStsClient(client utils.AwsStsClient) AwsMultiRegionDiscovererOption {
+	return func(mrd *AwsMultiRegionDiscoverer) { mrd.stsClient = client }
+}
+
+// NewAwsMultiRegionDiscoverer returns a new AwsMultiRegionDiscoverer, which builds the
+// discoverer for each region with the given factory, initialized with the given options.
+func NewAwsMultiRegionDiscoverer(
+	cfg aws.Config,
+	factory AwsRegionalDiscovererFactory,
+	opts ...AwsMultiRegionDiscovererOption,
+) *AwsMultiRegionDiscoverer {
+	enumerationCfg := cfg.Copy()
+	if enumerationCfg.Region == "" {
+		enumerationCfg.Region = defaultAwsMultiRegionDiscovererEnumerationRegion
+	}
+	mrd := &AwsMultiRegionDiscoverer{
+		cfg:       cfg,
+		factory:   factory,
+		ec2Client: ec2.NewFromConfig(enumerationCfg),
+		stsClient: sts.NewFromConfig(enumerationCfg),
+
+		discovererId:           "",
+		regions:                nil,
+		getAccountIdTimeout:    defaultAwsMultiRegionDiscovererGetAccountIdTimeout,
+		describeRegionsTimeout: defaultAwsMultiRegionDiscovererDescribeRegionsTimeout,
+		maxConcurrency:         defaultAwsMultiRegionDiscovererMaxConcurrency,
+
+		sharedStsClient: &sharedAwsStsClient{},
+		discoverers:     map[string]discovery.Discoverer{},
+	}
+	for _, opt := range opts {
+		opt(mrd)
+	}
+	return mrd
+}
+
+// Discover runs the AwsMultiRegionDiscoverer.
+func (mrd *AwsMultiRegionDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := discovery.NewResult(mrd.discovererId)
+	defer result.Done()
+
+	awsAccountId, awsPartition, err := utils.AwsAccountIdAndPartitionFromStsClient(ctx, mrd.stsClient, mrd.getAccountIdTimeout)
+	if err != nil {
+		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
+		mrd.setDiscovererId(result, nil)
+		return result
+	}
+	mrd.sharedStsClient.set(awsAccountId, awsPartition)
+
+	regions := mrd.regions
+	if len(regions) == 0 {
+		regions, err = mrd.enabledRegions(ctx)
+		if err != nil {
+			result.AddErrorf("failed to enumerate enabled AWS regions: %v", err)
+			mrd.setDiscovererId(result, nil)
+			return result
+		}
+	}
+
+	regionResults := make([]*discovery.Result, len(regions))
+
+	var wg sync.WaitGroup
+	sem := semaphore.NewWeighted(mrd.maxConcurrency)
+	for i, region := range regions {
+		if err := sem.Acquire(ctx, 1); err != nil {
+			result.AddErrorf("failed to acquire semaphore: %v", err)
+			break
+		}
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			regionResults[i] = mrd.discoverer(region).Discover(ctx)
+		}(i, region)
+	}
+	wg.Wait()
+
+	for i, regionResult := range regionResults {
+		if regionResult == nil {
+			continue
+		}
+		regionResult.Lock()
+		result.AddResources(regionResult.Resources...)
+		for _, err := range regionResult.Errors {
+			result.AddErrorf("%s: %s", regions[i], err)
+		}
+		for _, warning := range regionResult.Warnings {
+			result.AddWarningf("%s: %s", regions[i], warning)
+		}
+		regionResult.Unlock()
+	}
+	mrd.setDiscovererId(result, regionResults)
+
+	return result
+}
+
+// discoverer returns the discoverer for a region, building it on first use so that
+// per-region discoverers (and e.g. their reachability check caches) are reused across runs.
+func (mrd *AwsMultiRegionDiscoverer) discoverer(region string) discovery.Discoverer {
+	mrd.mu.Lock()
+	defer mrd.mu.Unlock()
+
+	if d, ok := mrd.discoverers[region]; ok {
+		return d
+	}
+	cfg := mrd.cfg.Copy()
+	cfg.Region = region
+	d := mrd.factory(cfg, mrd.sharedStsClient)
+	mrd.discoverers[region] = d
+	return d
+}
+
+// enabledRegions returns the regions which are enabled for the account, i.e.
+// the regions which do not require opting in and the regions opted in to.
+func (mrd *AwsMultiRegionDiscoverer) enabledRegions(ctx context.Context) ([]string, error) {
+	describeRegionsCtx, cancel := context.WithTimeout(ctx, mrd.describeRegionsTimeout)
+	defer cancel()
+
+	describeRegionsOutput, err := mrd.ec2Client.DescribeRegions(
+		describeRegionsCtx,
+		&ec2.DescribeRegionsInput{AllRegions: aws.Bool(true)},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe regions: %v", err)
+	}
+	regions := []string{}
+	for _, region := range describeRegionsOutput.Regions {
+		switch aws.ToString(region.OptInStatus) {
+		case "opt-in-not-required", "opted-in":
+			regions = append(regions, aws.ToString(region.RegionName))
+		}
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// setDiscovererId sets the discoverer id of a result to that of the
+// per-region results when no discoverer id was set with an option.
+func (mrd *AwsMultiRegionDiscoverer) setDiscovererId(result *discovery.Result, regionResults []*discovery.Result) {
+	if mrd.discovererId != "" {
+		return
+	}
+	discovererId := defaultAwsMultiRegionDiscovererDiscovererId
+	for _, regionResult := range regionResults {
+		if regionResult != nil && regionResult.Metadata.DiscovererId != "" {
+			discovererId = regionResult.Metadata.DiscovererId
+			break
+		}
+	}
+	result.Lock()
+	defer result.Unlock()
+
+	result.Metadata.DiscovererId = discovererId
+}
+
+// sharedAwsStsClient is an AwsStsClient which answers with an aws account id (and
+// partition) looked up once, rather than calling the AWS STS API for every region.
+type sharedAwsStsClient struct {
+	mu           sync.RWMutex
+	awsAccountId string
+	awsPartition string
+}
+
+// ensure sharedAwsStsClient implements utils.AwsStsClient at compile-time.
+var _ utils.AwsStsClient = (*sharedAwsStsClient)(nil)
+
+func (c *sharedAwsStsClient) set(awsAccountId, awsPartition string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.awsAccountId = awsAccountId
+	c.awsPartition = awsPartition
+}
+
+func (c *sharedAwsStsClient) GetCallerIdentity(
+	_ context.Context,
+	_ *sts.GetCallerIdentityInput,
+	_ ...func(*sts.Options),
+) (*sts.GetCallerIdentityOutput, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.awsAccountId == "" {
+		return nil, fmt.Errorf("the AWS account ID has not been looked up yet")
+	}
+	// the arn of the account's root user carries the partition
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(c.awsAccountId),
+		Arn:     aws.String(fmt.Sprintf("arn:%s:iam::%s:root", c.awsPartition, c.awsAccountId)),
+	}, nil
+}
@@ -0,0 +1,219 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+)
+
+// newEc2TestFactory returns a factory of AwsEc2Discoverers which discover a
+// single running instance with the id of the region of their aws config.
+func newEc2TestFactory() func(aws.Config, utils.AwsStsClient) discovery.Discoverer {
+	return func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+		ec2Client := &fakeEc2Client{}
+		ec2Client.instances.pages = []*ec2.DescribeInstancesOutput{
+			newEc2TestPage(newEc2TestInstance(cfg.Region, types.InstanceStateNameRunning)),
+		}
+		return newEc2TestDiscoverer(ec2Client, WithAwsEc2DiscovererStsClient(stsClient))
+	}
+}
+
+func TestAwsMultiRegionDiscovererMaxConcurrency(t *testing.T) {
+	regions := []string{"eu-west-1", "us-east-1", "us-west-2"}
+
+	for _, concurrency := range []int64{-1, 0, 1, 2, 10} {
+		mrd := NewAwsMultiRegionDiscoverer(
+			aws.Config{},
+			newEc2TestFactory(),
+			WithAwsMultiRegionDiscovererStsClient(&fakeStsClient{}),
+			WithAwsMultiRegionDiscovererRegions(regions...),
+			WithAwsMultiRegionDiscovererMaxConcurrency(concurrency),
+		)
+		if mrd.maxConcurrency < 1 {
+			t.Fatalf("concurrency %d: expected a max concurrency of at least 1, got %d", concurrency, mrd.maxConcurrency)
+		}
+
+		// a semaphore without room would block until the context is done
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+		result := mrd.Discover(ctx)
+		cancel()
+
+		if len(result.Errors) > 0 {
+			t.Fatalf("concurrency %d: unexpected errors: %v", concurrency, result.Errors)
+		}
+		if ids := ec2InstanceIds(result); !slices.Equal(ids, regions) {
+			t.Fatalf("concurrency %d: expected instances %v, got %v", concurrency, regions, ids)
+		}
+	}
+}
+
+func newMultiRegionTestRegion(name, optInStatus string) types.Region {
+	return types.Region{RegionName: aws.String(name), OptInStatus: aws.String(optInStatus)}
+}
+
+func TestAwsMultiRegionDiscovererEnabledRegions(t *testing.T) {
+	tests := []struct {
+		name            string
+		ec2Client       *fakeEc2RegionsClient
+		regions         []string
+		expectedRegions []string
+		expectedErr     string
+	}{
+		{
+			name: "regions which do not require opting in and opted in to",
+			ec2Client: &fakeEc2RegionsClient{regions: []types.Region{
+				newMultiRegionTestRegion("us-east-1", "opt-in-not-required"),
+				newMultiRegionTestRegion("af-south-1", "not-opted-in"),
+				newMultiRegionTestRegion("eu-west-1", "opt-in-not-required"),
+				newMultiRegionTestRegion("ap-east-1", "opted-in"),
+			}},
+			expectedRegions: []string{"ap-east-1", "eu-west-1", "us-east-1"},
+		},
+		{
+			name:            "configured regions are not enumerated",
+			ec2Client:       &fakeEc2RegionsClient{err: errors.New("access denied")},
+			regions:         []string{"eu-west-1"},
+			expectedRegions: []string{"eu-west-1"},
+		},
+		{
+			name:            "failing to enumerate regions",
+			ec2Client:       &fakeEc2RegionsClient{err: errors.New("access denied")},
+			expectedRegions: []string{},
+			expectedErr:     "failed to enumerate enabled AWS regions: failed to describe regions: access denied",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discoverers := &fakeAwsDiscoverers{}
+			mrd := NewAwsMultiRegionDiscoverer(
+				aws.Config{},
+				discoverers.factory,
+				WithAwsMultiRegionDiscovererStsClient(&fakeStsClient{}),
+				WithAwsMultiRegionDiscovererEc2Client(test.ec2Client),
+				WithAwsMultiRegionDiscovererRegions(test.regions...),
+			)
+			result := mrd.Discover(context.Background())
+
+			if ids := ec2InstanceIds(result); !slices.Equal(ids, test.expectedRegions) {
+				t.Fatalf("expected resources in regions %v, got %v", test.expectedRegions, ids)
+			}
+			if test.expectedErr == "" && len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if test.expectedErr != "" && !slices.Equal(result.Errors, []string{test.expectedErr}) {
+				t.Fatalf("expected error %q, got %v", test.expectedErr, result.Errors)
+			}
+		})
+	}
+}
+
+func TestAwsMultiRegionDiscovererDiscover(t *testing.T) {
+	discoverers := &fakeAwsDiscoverers{
+		discovererId: "ec2",
+		errs:         map[string][]string{"us-east-1": {"failed to describe instances"}},
+		warnings:     map[string][]string{"eu-west-1": {"skipped an instance"}},
+	}
+	stsClient := &fakeStsClient{}
+	mrd := NewAwsMultiRegionDiscoverer(
+		aws.Config{},
+		discoverers.factory,
+		WithAwsMultiRegionDiscovererStsClient(stsClient),
+		WithAwsMultiRegionDiscovererRegions("us-east-1", "eu-west-1"),
+	)
+
+	for run := 1; run <= 2; run++ {
+		result := mrd.Discover(context.Background())
+
+		if ids := ec2InstanceIds(result); !slices.Equal(ids, []string{"eu-west-1", "us-east-1"}) {
+			t.Fatalf("run %d: expected resources in both regions, got %v", run, ids)
+		}
+		if !slices.Equal(result.Errors, []string{"us-east-1: failed to describe instances"}) {
+			t.Fatalf("run %d: expected errors prefixed with their region, got %v", run, result.Errors)
+		}
+		if !slices.Equal(result.Warnings, []string{"eu-west-1: skipped an instance"}) {
+			t.Fatalf("run %d: expected warnings prefixed with their region, got %v", run, result.Warnings)
+		}
+		if result.Metadata.DiscovererId != "ec2" {
+			t.Fatalf("run %d: expected the discoverer id of the per-region discoverers, got %s", run, result.Metadata.DiscovererId)
+		}
+
+		// the account id is looked up once per run, and shared with all regions
+		if calls := stsClient.getCallerIdentityCalls.Load(); calls != int64(run) {
+			t.Fatalf("run %d: expected %d calls to the sts api, got %d", run, run, calls)
+		}
+		for _, resource := range result.Resources {
+			if resource.AwsEc2InstanceDetails.AwsAccountId != fakeAwsAccountId {
+				t.Fatalf("run %d: expected the shared account id, got %+v", run, resource.AwsEc2InstanceDetails)
+			}
+		}
+	}
+
+	// per-region discoverers are reused across runs
+	if built := discoverers.builtRegions(); !slices.Equal(built, []string{"eu-west-1", "us-east-1"}) {
+		t.Fatalf("expected a single discoverer per region, got discoverers for %v", built)
+	}
+}
+
+func TestAwsMultiRegionDiscovererDiscovererId(t *testing.T) {
+	tests := []struct {
+		name                 string
+		opts                 []AwsMultiRegionDiscovererOption
+		perRegionId          string
+		stsErr               error
+		expectedDiscovererId string
+	}{
+		{
+			name:                 "discoverer id of the per-region discoverers",
+			perRegionId:          "ec2",
+			expectedDiscovererId: "ec2",
+		},
+		{
+			name:                 "discoverer id set with an option",
+			opts:                 []AwsMultiRegionDiscovererOption{WithAwsMultiRegionDiscovererDiscovererId("all-regions")},
+			perRegionId:          "ec2",
+			expectedDiscovererId: "all-regions",
+		},
+		{
+			name:                 "default when the per-region discoverers have no discoverer id",
+			expectedDiscovererId: defaultAwsMultiRegionDiscovererDiscovererId,
+		},
+		{
+			name:                 "default when no region was discovered",
+			perRegionId:          "ec2",
+			stsErr:               errors.New("expired token"),
+			expectedDiscovererId: defaultAwsMultiRegionDiscovererDiscovererId,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mrd := NewAwsMultiRegionDiscoverer(
+				aws.Config{},
+				(&fakeAwsDiscoverers{discovererId: test.perRegionId}).factory,
+				append(
+					[]AwsMultiRegionDiscovererOption{
+						WithAwsMultiRegionDiscovererStsClient(&fakeStsClient{err: test.stsErr}),
+						WithAwsMultiRegionDiscovererRegions("us-east-1", "eu-west-1"),
+					},
+					test.opts...,
+				)...,
+			)
+			result := mrd.Discover(context.Background())
+
+			if result.Metadata.DiscovererId != test.expectedDiscovererId {
+				t.Fatalf("expected discoverer id %s, got %s", test.expectedDiscovererId, result.Metadata.DiscovererId)
+			}
+			if test.stsErr != nil && (len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "failed to get AWS account ID")) {
+				t.Fatalf("expected a single error for the account id lookup, got %v", result.Errors)
+			}
+		})
+	}
+}
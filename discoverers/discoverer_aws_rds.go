@@ -10,8 +10,10 @@ import (
 
 	cache "github.com/Code-Hex/go-generics-cache"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
 	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/borderzero/border0-go/lib/types/maps"
 	"github.com/borderzero/border0-go/lib/types/pointer"
 	"github.com/borderzero/border0-go/lib/types/set"
@@ -21,8 +23,11 @@ import (
 )
 
 const (
-	defaultAwsRdsDiscovererDiscovererId        = "aws_rds_discoverer"
-	defaultAwsRdsDiscovererGetAccountIdTimeout = time.Second * 10
+	defaultAwsRdsDiscovererDiscovererId             = "aws_rds_discoverer"
+	defaultAwsRdsDiscovererGetAccountIdTimeout      = time.Second * 10
+	defaultAwsRdsDiscovererDescribeInstancesTimeout = time.Second * 10
+	defaultAwsRdsDiscovererClustersEnabled          = true
+	defaultAwsRdsDiscovererProxiesEnabled           = true
 
 	defaultAwsRdsReachabilityCheckEnabled          = true
 	defaultAwsRdsReachabilityCheckCacheCleanPeriod = time.Minute * 30
@@ -34,20 +39,29 @@ var (
 	defaultAwsRdsDiscovererIncludedInstanceStatuses = set.New("creating", "backing-up", "starting", "available", "maintenance", "modifying")
 )
 
-// AwsRdsDiscoverer represents a discoverer for AWS RDS resources.
+// AwsRdsDiscoverer represents a discoverer for AWS RDS resources i.e.
+// DB instances, DB clusters (e.g. Aurora clusters) and RDS Proxies.
 type AwsRdsDiscoverer struct {
-	cfg aws.Config
+	cfg              aws.Config
+	rdsClient        AwsRdsClient
+	ec2NetworkClient AwsEc2NetworkClient
+	stsClient        utils.AwsStsClient
 
 	discovererId             string
 	getAccountIdTimeout      time.Duration
+	describeInstancesTimeout time.Duration
 	includedInstanceStatuses set.Set[string]
 	inclusionInstanceTags    map[string][]string
 	exclusionInstanceTags    map[string][]string
+	clustersEnabled          bool
+	proxiesEnabled           bool
 
 	networkReachabilityCheckEnabled       bool
 	networkReachabilityCheckCache         *cache.Cache[string, bool]
 	networkReachabilityCheckCacheItemOpts []cache.ItemOption
 	reachabilityRequired                  bool
+
+	staticReachabilitySource *AwsReachabilitySource
 }
 
 // ensure AwsRdsDiscoverer implements discovery.Discoverer at compile-time.
@@ -61,6 +75,22 @@ func WithAwsRdsDiscovererDiscovererId(discovererId string) AwsRdsDiscovererOptio
 	return func(rdsd *AwsRdsDiscoverer) { rdsd.discovererId = discovererId }
 }
 
+// WithAwsRdsDiscovererRdsClient is the AwsRdsDiscovererOption to set a non default aws rds client.
+func WithAwsRdsDiscovererRdsClient(client AwsRdsClient) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.rdsClient = client }
+}
+
+// WithAwsRdsDiscovererEc2NetworkClient is the AwsRdsDiscovererOption to set a
+// non default aws ec2 client for the static reachability analysis.
+func WithAwsRdsDiscovererEc2NetworkClient(client AwsEc2NetworkClient) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.ec2NetworkClient = client }
+}
+
+// WithAwsRdsDiscovererStsClient is the AwsRdsDiscovererOption to set a non default aws sts client.
+func WithAwsRdsDiscovererStsClient(client utils.AwsStsClient) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.stsClient = client }
+}
+
 // WithAwsRdsDiscovererReachabilityCheck is the AwsRdsDiscovererOption
 // to enable/disable checking instances' reachability via the network.
 func WithAwsRdsDiscovererNetworkReachabilityCheck(enabled bool) AwsRdsDiscovererOption {
@@ -77,6 +107,14 @@ func WithAwsRdsDiscovererReachabilityRequired(required bool) AwsRdsDiscovererOpt
 	}
 }
 
+// WithAwsRdsDiscovererStaticReachabilityAnalysis is the AwsRdsDiscovererOption to enable the
+// static (offline) analysis of whether the given source can reach the endpoint port of instances,
+// based on security group rules, network ACLs and route tables. Failing to fetch the network
+// configuration results in a warning, not an error.
+func WithAwsRdsDiscovererStaticReachabilityAnalysis(source AwsReachabilitySource) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.staticReachabilitySource = &source }
+}
+
 // WithAwsRdsDiscovererNetworkReachabilityCheckCache is the AwsRdsDiscovererOption
 // to set the network reachability check cache and new item options.
 func WithAwsRdsDiscovererNetworkReachabilityCheckCache(cache *cache.Cache[string, bool], itemOpts ...cache.ItemOption) AwsRdsDiscovererOption {
@@ -92,6 +130,28 @@ func WithAwsRdsDiscovererGetAccountIdTimeout(timeout time.Duration) AwsRdsDiscov
 	return func(rdsd *AwsRdsDiscoverer) { rdsd.getAccountIdTimeout = timeout }
 }
 
+// WithAwsRdsDiscovererDescribeInstancesTimeout is the AwsRdsDiscovererOption to set a non default
+// timeout for each page of the describe db instances (and db clusters and db proxies) api calls.
+func WithAwsRdsDiscovererDescribeInstancesTimeout(timeout time.Duration) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.describeInstancesTimeout = timeout }
+}
+
+// WithAwsRdsDiscovererClusters is the AwsRdsDiscovererOption to enable/disable
+// discovering DB clusters (e.g. Aurora clusters) along with DB instances. Clusters
+// are filtered with the instance statuses and tags filters. Failing to describe
+// clusters results in a warning, not an error.
+func WithAwsRdsDiscovererClusters(enabled bool) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.clustersEnabled = enabled }
+}
+
+// WithAwsRdsDiscovererProxies is the AwsRdsDiscovererOption to
+// enable/disable discovering RDS Proxies along with DB instances. Proxies
+// are filtered with the instance tags filters (but not the statuses filter).
+// Failing to describe proxies results in a warning, not an error.
+func WithAwsRdsDiscovererProxies(enabled bool) AwsRdsDiscovererOption {
+	return func(rdsd *AwsRdsDiscoverer) { rdsd.proxiesEnabled = enabled }
+}
+
 // WithAwsRdsDiscovererIncludedInstanceStatuses is the AwsRdsDiscovererOption
 // to set a non default list of statuses for instances to include in results.
 func WithAwsRdsDiscovererIncludedInstanceStatuses(statuses ...string) AwsRdsDiscovererOption {
@@ -114,13 +174,19 @@ func WithAwsRdsDiscovererExclusionInstanceTags(tags map[string][]string) AwsRdsD
 // NewAwsRdsDiscoverer returns a new AwsRdsDiscoverer, initialized with the given options.
 func NewAwsRdsDiscoverer(cfg aws.Config, opts ...AwsRdsDiscovererOption) *AwsRdsDiscoverer {
 	rdsd := &AwsRdsDiscoverer{
-		cfg: cfg,
+		cfg:              cfg,
+		rdsClient:        rds.NewFromConfig(cfg),
+		ec2NetworkClient: ec2.NewFromConfig(cfg),
+		stsClient:        sts.NewFromConfig(cfg),
 
 		discovererId:             defaultAwsRdsDiscovererDiscovererId,
 		getAccountIdTimeout:      defaultAwsRdsDiscovererGetAccountIdTimeout,
+		describeInstancesTimeout: defaultAwsRdsDiscovererDescribeInstancesTimeout,
 		includedInstanceStatuses: defaultAwsRdsDiscovererIncludedInstanceStatuses,
 		inclusionInstanceTags:    nil,
 		exclusionInstanceTags:    nil,
+		clustersEnabled:          defaultAwsRdsDiscovererClustersEnabled,
+		proxiesEnabled:           defaultAwsRdsDiscovererProxiesEnabled,
 
 		networkReachabilityCheckEnabled: defaultAwsRdsReachabilityCheckEnabled,
 		networkReachabilityCheckCache: cache.New[string, bool](
@@ -130,6 +196,8 @@ func NewAwsRdsDiscoverer(cfg aws.Config, opts ...AwsRdsDiscovererOption) *AwsRds
 			cache.WithExpiration(defaultAwsRdsReachabilityCheckCacheTtl),
 		},
 		reachabilityRequired: defaultAwsRdsReachabilityRequired,
+
+		staticReachabilitySource: nil,
 	}
 	for _, opt := range opts {
 		opt(rdsd)
@@ -142,28 +210,38 @@ func (rdsd *AwsRdsDiscoverer) Discover(ctx context.Context) *discovery.Result {
 	result := discovery.NewResult(rdsd.discovererId)
 	defer result.Done()
 
-	awsAccountId, err := utils.AwsAccountIdFromConfig(ctx, rdsd.cfg, rdsd.getAccountIdTimeout)
+	awsAccountId, err := utils.AwsAccountIdFromStsClient(ctx, rdsd.stsClient, rdsd.getAccountIdTimeout)
 	if err != nil {
 		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
 		return result
 	}
 
 	// describe rds instances
-	rdsClient := rds.NewFromConfig(rdsd.cfg)
-
-	// TODO: new context with timeout for describe instances
-	describeDBInstancesOutput, err := rdsClient.DescribeDBInstances(ctx, &rds.DescribeDBInstancesInput{})
+	instances, err := rdsd.describeDBInstances(ctx)
 	if err != nil {
 		result.AddErrorf("failed to describe rds instances: %v", err)
 		return result
 	}
 
+	var networkSnapshot *awsNetworkSnapshot
+	if rdsd.staticReachabilitySource != nil {
+		networkSnapshot, err = newAwsNetworkSnapshot(
+			ctx,
+			rdsd.ec2NetworkClient,
+			*rdsd.staticReachabilitySource,
+			rdsd.describeInstancesTimeout,
+		)
+		if err != nil {
+			result.AddWarningf("failed to fetch network configuration for static reachability analysis: %v", err)
+		}
+	}
+
 	// wait group for reachability checks
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
 	// filter and build resources
-	for _, instance := range describeDBInstancesOutput.DBInstances {
+	for _, instance := range instances {
 		// ignore instances with no status
 		if instance.DBInstanceStatus == nil {
 			continue // NOTE: this should emit a warning.
@@ -196,13 +274,27 @@ func (rdsd *AwsRdsDiscoverer) Discover(ctx context.Context) *discovery.Result {
 		for _, t := range instance.TagList {
 			tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
 		}
+		securityGroupIds := []string{}
+		for _, group := range instance.VpcSecurityGroups {
+			securityGroupIds = append(securityGroupIds, aws.ToString(group.VpcSecurityGroupId))
+		}
 		rdsInstanceDetails := &discovery.AwsRdsInstanceDetails{
-			AwsBaseDetails:       awsBaseDetails,
-			Tags:                 tags,
-			DbInstanceIdentifier: aws.ToString(instance.DBInstanceIdentifier),
-			DbInstanceStatus:     aws.ToString(instance.DBInstanceStatus),
-			Engine:               aws.ToString(instance.Engine),
-			EngineVersion:        aws.ToString(instance.EngineVersion),
+			AwsBaseDetails:                   awsBaseDetails,
+			Tags:                             tags,
+			DbInstanceIdentifier:             aws.ToString(instance.DBInstanceIdentifier),
+			DbInstanceStatus:                 aws.ToString(instance.DBInstanceStatus),
+			Engine:                           aws.ToString(instance.Engine),
+			EngineVersion:                    aws.ToString(instance.EngineVersion),
+			DbName:                           aws.ToString(instance.DBName),
+			MasterUsername:                   aws.ToString(instance.MasterUsername),
+			IamDatabaseAuthenticationEnabled: aws.ToBool(instance.IAMDatabaseAuthenticationEnabled),
+			CaCertificateIdentifier:          aws.ToString(instance.CACertificateIdentifier),
+			PubliclyAccessible:               aws.ToBool(instance.PubliclyAccessible),
+			MultiAZ:                          aws.ToBool(instance.MultiAZ),
+			SecurityGroupIds:                 securityGroupIds,
+		}
+		if instance.MasterUserSecret != nil {
+			rdsInstanceDetails.MasterUserSecretArn = aws.ToString(instance.MasterUserSecret.SecretArn)
 		}
 		if instance.DBSubnetGroup != nil {
 			rdsInstanceDetails.DBSubnetGroupName = aws.ToString(instance.DBSubnetGroup.DBSubnetGroupName)
@@ -218,14 +310,64 @@ func (rdsd *AwsRdsDiscoverer) Discover(ctx context.Context) *discovery.Result {
 			rdsInstanceDetails.EndpointAddress = ""
 			rdsInstanceDetails.EndpointPort = -1
 		}
+		if networkSnapshot != nil && rdsInstanceDetails.EndpointPort != -1 {
+			rdsInstanceDetails.StaticReachability = []discovery.AwsStaticReachability{
+				networkSnapshot.analyze(rdsReachabilityTarget(instance), rdsInstanceDetails.EndpointPort),
+			}
+		}
 
 		wg.Add(1)
 		go rdsd.reachabilityCheckAndAdd(ctx, &wg, result, rdsInstanceDetails)
 	}
 
+	if rdsd.clustersEnabled {
+		rdsd.discoverClusters(ctx, result, awsAccountId)
+	}
+	if rdsd.proxiesEnabled {
+		rdsd.discoverProxies(ctx, result, awsAccountId)
+	}
+
 	return result
 }
 
+// rdsReachabilityTarget returns the network placement of an instance for the static reachability
+// analysis. The addresses of RDS instances are not known (without resolving their endpoint), and
+// they may be placed in any subnet of their DB subnet group.
+func rdsReachabilityTarget(instance types.DBInstance) awsReachabilityTarget {
+	target := awsReachabilityTarget{
+		subnetIds:        []string{},
+		securityGroupIds: []string{},
+		hasPublicIp:      aws.ToBool(instance.PubliclyAccessible),
+	}
+	if instance.DBSubnetGroup != nil {
+		target.vpcId = aws.ToString(instance.DBSubnetGroup.VpcId)
+		for _, subnet := range instance.DBSubnetGroup.Subnets {
+			target.subnetIds = append(target.subnetIds, aws.ToString(subnet.SubnetIdentifier))
+		}
+	}
+	for _, group := range instance.VpcSecurityGroups {
+		target.securityGroupIds = append(target.securityGroupIds, aws.ToString(group.VpcSecurityGroupId))
+	}
+	return target
+}
+
+// describeDBInstances returns all the db instances in the region, with the
+// describe instances timeout applied to each page rather than to all pages.
+func (rdsd *AwsRdsDiscoverer) describeDBInstances(ctx context.Context) ([]types.DBInstance, error) {
+	pages, err := collectAwsPages(ctx, rds.NewDescribeDBInstancesPaginator(
+		rdsd.rdsClient,
+		&rds.DescribeDBInstancesInput{},
+	), rdsd.describeInstancesTimeout)
+	if err != nil {
+		return nil, err
+	}
+	instances := []types.DBInstance{}
+	for _, page := range pages {
+		instances = append(instances, page.DBInstances...)
+	}
+	return instances, nil
+}
+
 func (rdsd *AwsRdsDiscoverer) reachabilityCheckAndAdd(
 	ctx context.Context,
 	wg *sync.WaitGroup,
@@ -244,7 +386,7 @@ func (rdsd *AwsRdsDiscoverer) reachabilityCheckAndAdd(
 		)
 	}
 
-	if rdsd.reachabilityRequired && pointer.ValueOrZero(rdsDetails.NetworkReachable) {
+	if rdsd.reachabilityRequired && !pointer.ValueOrZero(rdsDetails.NetworkReachable) {
 		return
 	}
 
@@ -0,0 +1,330 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/borderzero/border0-go/lib/types/maps"
+	"github.com/borderzero/border0-go/lib/types/slice"
+	"github.com/borderzero/discovery"
+)
+
+// discoverClusters adds the DB clusters (e.g. Aurora clusters) of the region to the result,
+// with their writer and reader endpoints, custom endpoints and member instances. Clusters
+// are filtered like instances, by status and tags. Failures are warnings rather than errors,
+// as clusters are discovered on top of instances.
+func (rdsd *AwsRdsDiscoverer) discoverClusters(
+	ctx context.Context,
+	result *discovery.Result,
+	awsAccountId string,
+) {
+	clustersPages, err := collectAwsPages(ctx, rds.NewDescribeDBClustersPaginator(
+		rdsd.rdsClient,
+		&rds.DescribeDBClustersInput{},
+	), rdsd.describeInstancesTimeout)
+	if err != nil {
+		result.AddWarningf("failed to describe rds db clusters: %v", err)
+		return
+	}
+
+	// clusters are still added (without custom endpoints) when these fail
+	customEndpoints, err := rdsd.describeCustomClusterEndpoints(ctx)
+	if err != nil {
+		result.AddWarningf("failed to describe rds db cluster endpoints: %v", err)
+	}
+
+	for _, page := range clustersPages {
+		for _, cluster := range page.DBClusters {
+			// ignore clusters with un-included status
+			if !rdsd.includedInstanceStatuses.Has(aws.ToString(cluster.Status)) {
+				continue
+			}
+			// ignore clusters that don't satisfy tag conditions
+			if !maps.MatchesFilters(
+				slice.Map(
+					cluster.TagList,
+					func(tag types.Tag) (string, string) {
+						return aws.ToString(tag.Key), aws.ToString(tag.Value)
+					},
+				),
+				rdsd.inclusionInstanceTags,
+				rdsd.exclusionInstanceTags,
+			) {
+				continue
+			}
+
+			clusterIdentifier := aws.ToString(cluster.DBClusterIdentifier)
+
+			tags := map[string]string{}
+			for _, t := range cluster.TagList {
+				tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+			}
+			securityGroupIds := []string{}
+			for _, group := range cluster.VpcSecurityGroups {
+				securityGroupIds = append(securityGroupIds, aws.ToString(group.VpcSecurityGroupId))
+			}
+			members := []discovery.AwsRdsClusterMember{}
+			for _, member := range cluster.DBClusterMembers {
+				members = append(members, discovery.AwsRdsClusterMember{
+					DbInstanceIdentifier: aws.ToString(member.DBInstanceIdentifier),
+					IsClusterWriter:      aws.ToBool(member.IsClusterWriter),
+					PromotionTier:        aws.ToInt32(member.PromotionTier),
+				})
+			}
+
+			rdsClusterDetails := &discovery.AwsRdsClusterDetails{
+				AwsBaseDetails: discovery.AwsBaseDetails{
+					AwsRegion:    rdsd.cfg.Region,
+					AwsAccountId: awsAccountId,
+					AwsArn:       aws.ToString(cluster.DBClusterArn),
+				},
+				Tags:                             tags,
+				DbClusterIdentifier:              clusterIdentifier,
+				DbClusterStatus:                  aws.ToString(cluster.Status),
+				Engine:                           aws.ToString(cluster.Engine),
+				EngineVersion:                    aws.ToString(cluster.EngineVersion),
+				EngineMode:                       aws.ToString(cluster.EngineMode),
+				DatabaseName:                     aws.ToString(cluster.DatabaseName),
+				DBSubnetGroupName:                aws.ToString(cluster.DBSubnetGroup),
+				SecurityGroupIds:                 securityGroupIds,
+				WriterEndpointAddress:            aws.ToString(cluster.Endpoint),
+				ReaderEndpointAddress:            aws.ToString(cluster.ReaderEndpoint),
+				CustomEndpoints:                  customEndpoints[clusterIdentifier],
+				EndpointPort:                     aws.ToInt32(cluster.Port),
+				Members:                          members,
+				IamDatabaseAuthenticationEnabled: aws.ToBool(cluster.IAMDatabaseAuthenticationEnabled),
+			}
+			if cluster.ServerlessV2ScalingConfiguration != nil {
+				rdsClusterDetails.ServerlessV2ScalingConfiguration = &discovery.AwsRdsServerlessV2ScalingConfiguration{
+					MinCapacity: aws.ToFloat64(cluster.ServerlessV2ScalingConfiguration.MinCapacity),
+					MaxCapacity: aws.ToFloat64(cluster.ServerlessV2ScalingConfiguration.MaxCapacity),
+				}
+			}
+
+			result.AddResources(discovery.Resource{
+				ResourceType:         discovery.ResourceTypeAwsRdsCluster,
+				AwsRdsClusterDetails: rdsClusterDetails,
+			})
+		}
+	}
+}
+
+// describeCustomClusterEndpoints returns the custom endpoints of all DB clusters, by cluster identifier.
+func (rdsd *AwsRdsDiscoverer) describeCustomClusterEndpoints(ctx context.Context) (map[string][]discovery.AwsRdsClusterEndpoint, error) {
+	endpointsPages, err := collectAwsPages(ctx, rds.NewDescribeDBClusterEndpointsPaginator(
+		rdsd.rdsClient,
+		&rds.DescribeDBClusterEndpointsInput{
+			Filters: []types.Filter{{
+				Name:   aws.String("db-cluster-endpoint-type"),
+				Values: []string{"custom"},
+			}},
+		},
+	), rdsd.describeInstancesTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe custom endpoints: %v", err)
+	}
+	customEndpoints := map[string][]discovery.AwsRdsClusterEndpoint{}
+	for _, page := range endpointsPages {
+		for _, endpoint := range page.DBClusterEndpoints {
+			clusterIdentifier := aws.ToString(endpoint.DBClusterIdentifier)
+			customEndpoints[clusterIdentifier] = append(customEndpoints[clusterIdentifier], discovery.AwsRdsClusterEndpoint{
+				EndpointIdentifier: aws.ToString(endpoint.DBClusterEndpointIdentifier),
+				EndpointAddress:    aws.ToString(endpoint.Endpoint),
+				EndpointType:       aws.ToString(endpoint.CustomEndpointType),
+				Status:             aws.ToString(endpoint.Status),
+				StaticMembers:      endpoint.StaticMembers,
+				ExcludedMembers:    endpoint.ExcludedMembers,
+			})
+		}
+	}
+	return customEndpoints, nil
+}
+
+// discoverProxies adds the RDS Proxies of the region to the result,
+// with their endpoints and target groups (and the targets therein).
+//
+// Proxies are filtered by the instance tags filters, with their tags listed
+// separately as the describe proxies api call does not return them. They are
+// not filtered by the instance statuses filter, as proxy statuses differ from
+// instance statuses. Failures are warnings rather than errors, as proxies are
+// discovered on top of instances.
+func (rdsd *AwsRdsDiscoverer) discoverProxies(
+	ctx context.Context,
+	result *discovery.Result,
+	awsAccountId string,
+) {
+	proxiesPages, err := collectAwsPages(ctx, rds.NewDescribeDBProxiesPaginator(
+		rdsd.rdsClient,
+		&rds.DescribeDBProxiesInput{},
+	), rdsd.describeInstancesTimeout)
+	if err != nil {
+		result.AddWarningf("failed to describe rds db proxies: %v", err)
+		return
+	}
+
+	// proxies are still added (with only their default endpoint) when this fails
+	endpointsPages, err := collectAwsPages(ctx, rds.NewDescribeDBProxyEndpointsPaginator(
+		rdsd.rdsClient,
+		&rds.DescribeDBProxyEndpointsInput{},
+	), rdsd.describeInstancesTimeout)
+	if err != nil {
+		result.AddWarningf("failed to describe rds db proxy endpoints: %v", err)
+	}
+	endpoints := map[string][]discovery.AwsRdsProxyEndpoint{}
+	for _, page := range endpointsPages {
+		for _, endpoint := range page.DBProxyEndpoints {
+			proxyName := aws.ToString(endpoint.DBProxyName)
+			endpoints[proxyName] = append(endpoints[proxyName], discovery.AwsRdsProxyEndpoint{
+				EndpointName:    aws.ToString(endpoint.DBProxyEndpointName),
+				EndpointAddress: aws.ToString(endpoint.Endpoint),
+				TargetRole:      string(endpoint.TargetRole),
+				Status:          string(endpoint.Status),
+				IsDefault:       aws.ToBool(endpoint.IsDefault),
+			})
+		}
+	}
+
+	for _, page := range proxiesPages {
+		for _, proxy := range page.DBProxies {
+			proxyName := aws.ToString(proxy.DBProxyName)
+
+			// proxies whose tags can't be listed are filtered as having no tags
+			tags, err := rdsd.listTags(ctx, aws.ToString(proxy.DBProxyArn))
+			if err != nil {
+				result.AddWarningf("failed to list tags of rds db proxy %s: %v", proxyName, err)
+				tags = map[string]string{}
+			}
+			// ignore proxies that don't satisfy tag conditions
+			if !maps.MatchesFilters(tags, rdsd.inclusionInstanceTags, rdsd.exclusionInstanceTags) {
+				continue
+			}
+
+			auth := []discovery.AwsRdsProxyAuth{}
+			for _, a := range proxy.Auth {
+				auth = append(auth, discovery.AwsRdsProxyAuth{
+					AuthScheme:             string(a.AuthScheme),
+					IamAuth:                string(a.IAMAuth),
+					ClientPasswordAuthType: string(a.ClientPasswordAuthType),
+					SecretArn:              aws.ToString(a.SecretArn),
+					Username:               aws.ToString(a.UserName),
+				})
+			}
+
+			// the default endpoint is built from the proxy when it is
+			// not returned along with the proxy's additional endpoints
+			proxyEndpoints := endpoints[proxyName]
+			if !slices.ContainsFunc(proxyEndpoints, func(e discovery.AwsRdsProxyEndpoint) bool { return e.IsDefault }) {
+				proxyEndpoints = append([]discovery.AwsRdsProxyEndpoint{{
+					EndpointName:    "default",
+					EndpointAddress: aws.ToString(proxy.Endpoint),
+					TargetRole:      string(types.DBProxyEndpointTargetRoleReadWrite),
+					Status:          string(proxy.Status),
+					IsDefault:       true,
+				}}, proxyEndpoints...)
+			}
+
+			targetGroups, err := rdsd.describeProxyTargetGroups(ctx, proxyName)
+			if err != nil {
+				result.AddWarningf("failed to describe target groups of rds db proxy %s: %v", proxyName, err)
+			}
+
+			rdsProxyDetails := &discovery.AwsRdsProxyDetails{
+				AwsBaseDetails: discovery.AwsBaseDetails{
+					AwsRegion:    rdsd.cfg.Region,
+					AwsAccountId: awsAccountId,
+					AwsArn:       aws.ToString(proxy.DBProxyArn),
+				},
+				Tags:             tags,
+				DbProxyName:      proxyName,
+				Status:           string(proxy.Status),
+				EngineFamily:     aws.ToString(proxy.EngineFamily),
+				VpcId:            aws.ToString(proxy.VpcId),
+				SubnetIds:        proxy.VpcSubnetIds,
+				SecurityGroupIds: proxy.VpcSecurityGroupIds,
+				RequireTls:       aws.ToBool(proxy.RequireTLS),
+				RoleArn:          aws.ToString(proxy.RoleArn),
+				Auth:             auth,
+				Endpoints:        proxyEndpoints,
+				TargetGroups:     targetGroups,
+			}
+
+			result.AddResources(discovery.Resource{
+				ResourceType:       discovery.ResourceTypeAwsRdsProxy,
+				AwsRdsProxyDetails: rdsProxyDetails,
+			})
+		}
+	}
+}
+
+// listTags returns the tags of an RDS resource, by key.
+func (rdsd *AwsRdsDiscoverer) listTags(ctx context.Context, arn string) (map[string]string, error) {
+	listTagsCtx, cancel := context.WithTimeout(ctx, rdsd.describeInstancesTimeout)
+	defer cancel()
+
+	output, err := rdsd.rdsClient.ListTagsForResource(listTagsCtx, &rds.ListTagsForResourceInput{ResourceName: aws.String(arn)})
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]string{}
+	for _, t := range output.TagList {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return tags, nil
+}
+
+// describeProxyTargetGroups returns the target groups of a proxy, with their targets.
+func (rdsd *AwsRdsDiscoverer) describeProxyTargetGroups(ctx context.Context, proxyName string) ([]discovery.AwsRdsProxyTargetGroup, error) {
+	targetGroupsPages, err := collectAwsPages(ctx, rds.NewDescribeDBProxyTargetGroupsPaginator(
+		rdsd.rdsClient,
+		&rds.DescribeDBProxyTargetGroupsInput{DBProxyName: aws.String(proxyName)},
+	), rdsd.describeInstancesTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe target groups: %v", err)
+	}
+	targetGroups := []discovery.AwsRdsProxyTargetGroup{}
+	for _, page := range targetGroupsPages {
+		for _, targetGroup := range page.TargetGroups {
+			targetGroupName := aws.ToString(targetGroup.TargetGroupName)
+
+			targetsPages, err := collectAwsPages(ctx, rds.NewDescribeDBProxyTargetsPaginator(
+				rdsd.rdsClient,
+				&rds.DescribeDBProxyTargetsInput{
+					DBProxyName:     aws.String(proxyName),
+					TargetGroupName: aws.String(targetGroupName),
+				},
+			), rdsd.describeInstancesTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe targets of target group %s: %v", targetGroupName, err)
+			}
+			targets := []discovery.AwsRdsProxyTarget{}
+			for _, targetsPage := range targetsPages {
+				for _, target := range targetsPage.Targets {
+					proxyTarget := discovery.AwsRdsProxyTarget{
+						Type:             string(target.Type),
+						RdsResourceId:    aws.ToString(target.RdsResourceId),
+						TrackedClusterId: aws.ToString(target.TrackedClusterId),
+						EndpointAddress:  aws.ToString(target.Endpoint),
+						EndpointPort:     aws.ToInt32(target.Port),
+						Role:             string(target.Role),
+					}
+					if target.TargetHealth != nil {
+						proxyTarget.HealthState = string(target.TargetHealth.State)
+					}
+					targets = append(targets, proxyTarget)
+				}
+			}
+
+			targetGroups = append(targetGroups, discovery.AwsRdsProxyTargetGroup{
+				TargetGroupName: targetGroupName,
+				Status:          aws.ToString(targetGroup.Status),
+				IsDefault:       aws.ToBool(targetGroup.IsDefault),
+				Targets:         targets,
+			})
+		}
+	}
+	return targetGroups, nil
+}
@@ -0,0 +1,431 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/borderzero/discovery"
+)
+
+func newRdsTestInstance(identifier, status string, tags ...string) types.DBInstance {
+	return types.DBInstance{
+		DBInstanceIdentifier: aws.String(identifier),
+		DBInstanceStatus:     aws.String(status),
+		TagList:              newTestTags(newRdsTestTag, tags...),
+	}
+}
+
+// newRdsTestDiscoverer returns an AwsRdsDiscoverer for an rds client with all
+// optional checks and resources disabled, to be re-enabled by the given options.
+func newRdsTestDiscoverer(rdsClient AwsRdsClient, opts ...AwsRdsDiscovererOption) *AwsRdsDiscoverer {
+	return NewAwsRdsDiscoverer(
+		aws.Config{Region: "us-east-1"},
+		append(
+			[]AwsRdsDiscovererOption{
+				WithAwsRdsDiscovererRdsClient(rdsClient),
+				WithAwsRdsDiscovererStsClient(&fakeStsClient{}),
+				WithAwsRdsDiscovererNetworkReachabilityCheck(false),
+				WithAwsRdsDiscovererClusters(false),
+				WithAwsRdsDiscovererProxies(false),
+			},
+			opts...,
+		)...,
+	)
+}
+
+// rdsInstanceIdentifiers returns the sorted identifiers of the rds instances in a result.
+func rdsInstanceIdentifiers(result *discovery.Result) []string {
+	identifiers := []string{}
+	for _, resource := range result.Resources {
+		if resource.AwsRdsInstanceDetails != nil {
+			identifiers = append(identifiers, resource.AwsRdsInstanceDetails.DbInstanceIdentifier)
+		}
+	}
+	slices.Sort(identifiers)
+	return identifiers
+}
+
+func TestAwsRdsDiscovererDiscoverPagination(t *testing.T) {
+	pages := []*rds.DescribeDBInstancesOutput{
+		{DBInstances: []types.DBInstance{
+			newRdsTestInstance("db-1", "available"),
+			newRdsTestInstance("db-2", "available"),
+		}},
+		{DBInstances: []types.DBInstance{newRdsTestInstance("db-3", "available")}},
+		{DBInstances: []types.DBInstance{newRdsTestInstance("db-4", "available")}},
+	}
+
+	tests := []struct {
+		name              string
+		errs              map[int]error
+		expectedIds       []string
+		expectedRequested []int
+		expectError       bool
+	}{
+		{
+			name:              "collects instances of all pages",
+			expectedIds:       []string{"db-1", "db-2", "db-3", "db-4"},
+			expectedRequested: []int{0, 1, 2},
+		},
+		{
+			name:              "fails when a later page fails",
+			errs:              map[int]error{2: errors.New("throttled")},
+			expectedIds:       []string{},
+			expectedRequested: []int{0, 1, 2},
+			expectError:       true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rdsClient := &fakeRdsClient{}
+			rdsClient.instances.pages = pages
+			rdsClient.instances.errs = test.errs
+
+			result := newRdsTestDiscoverer(rdsClient).Discover(context.Background())
+
+			if ids := rdsInstanceIdentifiers(result); !slices.Equal(ids, test.expectedIds) {
+				t.Fatalf("expected instances %v, got %v", test.expectedIds, ids)
+			}
+			if !slices.Equal(rdsClient.instances.requested, test.expectedRequested) {
+				t.Fatalf("expected pages %v to be requested, got %v", test.expectedRequested, rdsClient.instances.requested)
+			}
+			if test.expectError != (len(result.Errors) > 0) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, result.Errors)
+			}
+			if test.expectError && !strings.Contains(result.Errors[0], "failed to describe rds instances") {
+				t.Fatalf("unexpected error: %s", result.Errors[0])
+			}
+		})
+	}
+}
+
+func TestAwsRdsDiscovererDiscoverFilters(t *testing.T) {
+	instances := []types.DBInstance{
+		newRdsTestInstance("db-web", "available", "team", "web"),
+		newRdsTestInstance("db-data", "available", "team", "data"),
+		newRdsTestInstance("db-scratch", "available", "team", "web", "scratch", "true"),
+		newRdsTestInstance("db-backing-up", "backing-up", "team", "web"),
+		newRdsTestInstance("db-stopped", "stopped"),
+	}
+
+	tests := []struct {
+		name        string
+		opts        []AwsRdsDiscovererOption
+		expectedIds []string
+	}{
+		{
+			name:        "default statuses",
+			expectedIds: []string{"db-backing-up", "db-data", "db-scratch", "db-web"},
+		},
+		{
+			name:        "included statuses",
+			opts:        []AwsRdsDiscovererOption{WithAwsRdsDiscovererIncludedInstanceStatuses("available", "stopped")},
+			expectedIds: []string{"db-data", "db-scratch", "db-stopped", "db-web"},
+		},
+		{
+			name:        "inclusion tags",
+			opts:        []AwsRdsDiscovererOption{WithAwsRdsDiscovererInclusionInstanceTags(map[string][]string{"team": {"web"}})},
+			expectedIds: []string{"db-backing-up", "db-scratch", "db-web"},
+		},
+		{
+			name:        "exclusion tags",
+			opts:        []AwsRdsDiscovererOption{WithAwsRdsDiscovererExclusionInstanceTags(map[string][]string{"scratch": {}})},
+			expectedIds: []string{"db-backing-up", "db-data", "db-web"},
+		},
+		{
+			name: "inclusion and exclusion tags and included statuses",
+			opts: []AwsRdsDiscovererOption{
+				WithAwsRdsDiscovererIncludedInstanceStatuses("available"),
+				WithAwsRdsDiscovererInclusionInstanceTags(map[string][]string{"team": {"web"}}),
+				WithAwsRdsDiscovererExclusionInstanceTags(map[string][]string{"scratch": {"true"}}),
+			},
+			expectedIds: []string{"db-web"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rdsClient := &fakeRdsClient{}
+			rdsClient.instances.pages = []*rds.DescribeDBInstancesOutput{{DBInstances: instances}}
+
+			result := newRdsTestDiscoverer(rdsClient, test.opts...).Discover(context.Background())
+
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if ids := rdsInstanceIdentifiers(result); !slices.Equal(ids, test.expectedIds) {
+				t.Fatalf("expected instances %v, got %v", test.expectedIds, ids)
+			}
+		})
+	}
+}
+
+func TestAwsRdsDiscovererDiscoverReachability(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %v", err)
+	}
+	defer listener.Close()
+	port := int32(listener.Addr().(*net.TCPAddr).Port)
+
+	// nothing listens on 127.0.0.2, so the same port is unreachable there
+	reachable := newRdsTestInstance("db-reachable", "available")
+	reachable.Endpoint = &types.Endpoint{Address: aws.String("127.0.0.1"), Port: aws.Int32(port)}
+	unreachable := newRdsTestInstance("db-unreachable", "available")
+	unreachable.Endpoint = &types.Endpoint{Address: aws.String("127.0.0.2"), Port: aws.Int32(port)}
+	noEndpoint := newRdsTestInstance("db-creating", "creating")
+
+	tests := []struct {
+		name              string
+		opts              []AwsRdsDiscovererOption
+		expectedReachable map[string]*bool // by identifier, nil when not checked
+	}{
+		{
+			name: "not checked when disabled",
+			expectedReachable: map[string]*bool{
+				"db-reachable":   nil,
+				"db-unreachable": nil,
+				"db-creating":    nil,
+			},
+		},
+		{
+			name: "checked when enabled",
+			opts: []AwsRdsDiscovererOption{WithAwsRdsDiscovererNetworkReachabilityCheck(true)},
+			expectedReachable: map[string]*bool{
+				"db-reachable":   aws.Bool(true),
+				"db-unreachable": aws.Bool(false),
+				"db-creating":    nil,
+			},
+		},
+		{
+			name: "only reachable instances when reachability is required",
+			opts: []AwsRdsDiscovererOption{
+				WithAwsRdsDiscovererNetworkReachabilityCheck(true),
+				WithAwsRdsDiscovererReachabilityRequired(true),
+			},
+			expectedReachable: map[string]*bool{
+				"db-reachable": aws.Bool(true),
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rdsClient := &fakeRdsClient{}
+			rdsClient.instances.pages = []*rds.DescribeDBInstancesOutput{
+				{DBInstances: []types.DBInstance{reachable, unreachable, noEndpoint}},
+			}
+
+			result := newRdsTestDiscoverer(rdsClient, test.opts...).Discover(context.Background())
+
+			if len(result.Resources) != len(test.expectedReachable) {
+				t.Fatalf("expected %d instances, got %v", len(test.expectedReachable), rdsInstanceIdentifiers(result))
+			}
+			for _, resource := range result.Resources {
+				details := resource.AwsRdsInstanceDetails
+				expected, ok := test.expectedReachable[details.DbInstanceIdentifier]
+				if !ok {
+					t.Fatalf("unexpected instance %s", details.DbInstanceIdentifier)
+				}
+				if (expected == nil) != (details.NetworkReachable == nil) ||
+					(expected != nil && *expected != *details.NetworkReachable) {
+					t.Fatalf("unexpected reachability for %s: %v", details.DbInstanceIdentifier, details.NetworkReachable)
+				}
+			}
+		})
+	}
+}
+
+func newRdsTestCluster(identifier, status string, tags ...string) types.DBCluster {
+	return types.DBCluster{
+		DBClusterIdentifier: aws.String(identifier),
+		Status:              aws.String(status),
+		TagList:             newTestTags(newRdsTestTag, tags...),
+	}
+}
+
+func TestAwsRdsDiscovererDiscoverClustersAndProxies(t *testing.T) {
+	clusters := []types.DBCluster{
+		newRdsTestCluster("aurora-web", "available", "team", "web"),
+		newRdsTestCluster("aurora-data", "available", "team", "data"),
+		newRdsTestCluster("aurora-stopped", "stopped", "team", "web"),
+	}
+	customEndpoint := types.DBClusterEndpoint{
+		DBClusterIdentifier:         aws.String("aurora-web"),
+		DBClusterEndpointIdentifier: aws.String("analytics"),
+	}
+	proxy := types.DBProxy{
+		DBProxyName: aws.String("proxy"),
+		DBProxyArn:  aws.String("arn:aws:rds:us-east-1:123456789012:db-proxy:prx-1"),
+		Endpoint:    aws.String("proxy.proxy-abc.us-east-1.rds.amazonaws.com"),
+		Status:      types.DBProxyStatusAvailable,
+	}
+	proxyTags := map[string][]types.Tag{
+		aws.ToString(proxy.DBProxyArn): {{Key: aws.String("team"), Value: aws.String("data")}},
+	}
+
+	tests := []struct {
+		name                    string
+		opts                    []AwsRdsDiscovererOption
+		clustersErr             error
+		clusterEndpointsErr     error
+		proxiesErr              error
+		proxyEndpointsErr       error
+		proxyTagsErr            error
+		proxyTargetGroupsErr    error
+		expectedClusters        []string
+		expectedCustomEndpoints int // of aurora-web
+		expectedProxies         int
+		expectedWarnings        int
+	}{
+		{
+			name:                    "clusters with included statuses and proxies",
+			expectedClusters:        []string{"aurora-data", "aurora-web"},
+			expectedCustomEndpoints: 1,
+			expectedProxies:         1,
+		},
+		{
+			name:                    "clusters and proxies filtered by tags",
+			opts:                    []AwsRdsDiscovererOption{WithAwsRdsDiscovererInclusionInstanceTags(map[string][]string{"team": {"web"}})},
+			expectedClusters:        []string{"aurora-web"},
+			expectedCustomEndpoints: 1,
+		},
+		{
+			name:             "proxies included by tags",
+			opts:             []AwsRdsDiscovererOption{WithAwsRdsDiscovererInclusionInstanceTags(map[string][]string{"team": {"data"}})},
+			expectedClusters: []string{"aurora-data"},
+			expectedProxies:  1,
+		},
+		{
+			name:                    "proxies excluded by tags",
+			opts:                    []AwsRdsDiscovererOption{WithAwsRdsDiscovererExclusionInstanceTags(map[string][]string{"team": {"data"}})},
+			expectedClusters:        []string{"aurora-web"},
+			expectedCustomEndpoints: 1,
+		},
+		{
+			name:                    "clusters filtered by statuses",
+			opts:                    []AwsRdsDiscovererOption{WithAwsRdsDiscovererIncludedInstanceStatuses("stopped")},
+			expectedClusters:        []string{"aurora-stopped"},
+			expectedCustomEndpoints: 0,
+			expectedProxies:         1,
+		},
+		{
+			name:             "warning when describing clusters fails",
+			clustersErr:      errors.New("access denied"),
+			expectedClusters: []string{},
+			expectedProxies:  1,
+			expectedWarnings: 1,
+		},
+		{
+			name:                "clusters without custom endpoints when describing these fails",
+			clusterEndpointsErr: errors.New("access denied"),
+			expectedClusters:    []string{"aurora-data", "aurora-web"},
+			expectedProxies:     1,
+			expectedWarnings:    1,
+		},
+		{
+			name:                    "warning when describing proxies fails",
+			proxiesErr:              errors.New("access denied"),
+			expectedClusters:        []string{"aurora-data", "aurora-web"},
+			expectedCustomEndpoints: 1,
+			expectedWarnings:        1,
+		},
+		{
+			name:                    "proxies with default endpoint when describing proxy endpoints fails",
+			proxyEndpointsErr:       errors.New("access denied"),
+			expectedClusters:        []string{"aurora-data", "aurora-web"},
+			expectedCustomEndpoints: 1,
+			expectedProxies:         1,
+			expectedWarnings:        1,
+		},
+		{
+			name:                    "proxies without tags when listing these fails",
+			proxyTagsErr:            errors.New("access denied"),
+			expectedClusters:        []string{"aurora-data", "aurora-web"},
+			expectedCustomEndpoints: 1,
+			expectedProxies:         1,
+			expectedWarnings:        1,
+		},
+		{
+			name:             "proxies whose tags can't be listed are excluded by inclusion tags",
+			opts:             []AwsRdsDiscovererOption{WithAwsRdsDiscovererInclusionInstanceTags(map[string][]string{"team": {"data"}})},
+			proxyTagsErr:     errors.New("access denied"),
+			expectedClusters: []string{"aurora-data"},
+			expectedWarnings: 1,
+		},
+		{
+			name:                    "warning when describing proxy target groups fails",
+			proxyTargetGroupsErr:    errors.New("access denied"),
+			expectedClusters:        []string{"aurora-data", "aurora-web"},
+			expectedCustomEndpoints: 1,
+			expectedProxies:         1,
+			expectedWarnings:        1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rdsClient := &fakeRdsClient{}
+			rdsClient.clusters.pages = []*rds.DescribeDBClustersOutput{{DBClusters: clusters}}
+			rdsClient.clusterEndpoints.pages = []*rds.DescribeDBClusterEndpointsOutput{
+				{DBClusterEndpoints: []types.DBClusterEndpoint{customEndpoint}},
+			}
+			rdsClient.proxies.pages = []*rds.DescribeDBProxiesOutput{{DBProxies: []types.DBProxy{proxy}}}
+			rdsClient.tags, rdsClient.tagsErr = proxyTags, test.proxyTagsErr
+			rdsClient.proxyEndpoints.setErr(test.proxyEndpointsErr)
+			rdsClient.clusters.setErr(test.clustersErr)
+			rdsClient.clusterEndpoints.setErr(test.clusterEndpointsErr)
+			rdsClient.proxies.setErr(test.proxiesErr)
+			rdsClient.proxyTargetGroups.setErr(test.proxyTargetGroupsErr)
+
+			result := newRdsTestDiscoverer(
+				rdsClient,
+				append(
+					[]AwsRdsDiscovererOption{
+						WithAwsRdsDiscovererClusters(true),
+						WithAwsRdsDiscovererProxies(true),
+					},
+					test.opts...,
+				)...,
+			).Discover(context.Background())
+
+			if len(result.Errors) > 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			if len(result.Warnings) != test.expectedWarnings {
+				t.Fatalf("expected %d warnings, got %v", test.expectedWarnings, result.Warnings)
+			}
+			clusterIdentifiers, customEndpoints, proxies := []string{}, 0, 0
+			for _, resource := range result.Resources {
+				switch {
+				case resource.AwsRdsClusterDetails != nil:
+					details := resource.AwsRdsClusterDetails
+					clusterIdentifiers = append(clusterIdentifiers, details.DbClusterIdentifier)
+					if details.DbClusterIdentifier == "aurora-web" {
+						customEndpoints = len(details.CustomEndpoints)
+					}
+				case resource.AwsRdsProxyDetails != nil:
+					proxies++
+					endpoints := resource.AwsRdsProxyDetails.Endpoints
+					if len(endpoints) != 1 || !endpoints[0].IsDefault || endpoints[0].EndpointAddress != aws.ToString(proxy.Endpoint) {
+						t.Fatalf("expected the proxy's default endpoint only, got %v", endpoints)
+					}
+				}
+			}
+			slices.Sort(clusterIdentifiers)
+			if !slices.Equal(clusterIdentifiers, test.expectedClusters) {
+				t.Fatalf("expected clusters %v, got %v", test.expectedClusters, clusterIdentifiers)
+			}
+			if customEndpoints != test.expectedCustomEndpoints {
+				t.Fatalf("expected %d custom endpoints, got %d", test.expectedCustomEndpoints, customEndpoints)
+			}
+			if proxies != test.expectedProxies {
+				t.Fatalf("expected %d proxies, got %d", test.expectedProxies, proxies)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package discoverers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/borderzero/border0-go/lib/types/set"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	defaultAwsSsmDiscovererDiscovererId                       = "aws_ssm_discoverer"
+	defaultAwsSsmDiscovererGetAccountIdTimeout                = time.Second * 10
+	defaultAwsSsmDiscovererDescribeInstanceInformationTimeout = time.Second * 10
+)
+
+var (
+	defaultAwsSsmDiscovererIncludedPingStatuses = set.New(
+		types.PingStatusOnline,
+		types.PingStatusConnectionLost,
+		types.PingStatusInactive,
+	)
+	defaultAwsSsmDiscovererIncludedResourceTypes = set.New(
+		discovery.SsmTargetResourceTypeEc2Instance,
+		discovery.SsmTargetResourceTypeManagedInstance,
+	)
+)
+
+// AwsSsmDiscoverer represents a discoverer for AWS SSM managed nodes (targets), including
+// hybrid activations i.e. on-premises servers and virtual machines with "mi-" instance ids.
+type AwsSsmDiscoverer struct {
+	cfg       aws.Config
+	ssmClient AwsSsmClient
+	stsClient utils.AwsStsClient
+
+	discovererId                       string
+	getAccountIdTimeout                time.Duration
+	describeInstanceInformationTimeout time.Duration
+	includedPingStatuses               set.Set[types.PingStatus]
+	includedResourceTypes              set.Set[string]
+}
+
+// ensure AwsSsmDiscoverer implements discovery.Discoverer at compile-time.
+var _ discovery.Discoverer = (*AwsSsmDiscoverer)(nil)
+
+// AwsSsmDiscovererOption represents a configuration option for an AwsSsmDiscoverer.
+type AwsSsmDiscovererOption func(*AwsSsmDiscoverer)
+
+// WithAwsSsmDiscovererDiscovererId is the AwsSsmDiscovererOption to set a non default discoverer id.
+func WithAwsSsmDiscovererDiscovererId(discovererId string) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.discovererId = discovererId }
+}
+
+// WithAwsSsmDiscovererSsmClient is the AwsSsmDiscovererOption to set a non default aws ssm client.
+func WithAwsSsmDiscovererSsmClient(client AwsSsmClient) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.ssmClient = client }
+}
+
+// WithAwsSsmDiscovererStsClient is the AwsSsmDiscovererOption to set a non default aws sts client.
+func WithAwsSsmDiscovererStsClient(client utils.AwsStsClient) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.stsClient = client }
+}
+
+// WithAwsSsmDiscovererGetAccountIdTimeout is the AwsSsmDiscovererOption
+// to set a non default timeout for getting the aws account id.
+func WithAwsSsmDiscovererGetAccountIdTimeout(timeout time.Duration) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.getAccountIdTimeout = timeout }
+}
+
+// WithAwsSsmDiscovererDescribeInstanceInformationTimeout is the AwsSsmDiscovererOption to
+// set a non default timeout for each page of the describe instance information api call.
+func WithAwsSsmDiscovererDescribeInstanceInformationTimeout(timeout time.Duration) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.describeInstanceInformationTimeout = timeout }
+}
+
+// WithAwsSsmDiscovererIncludedPingStatuses is the AwsSsmDiscovererOption
+// to set a non default list of ping statuses for targets to include in results.
+func WithAwsSsmDiscovererIncludedPingStatuses(statuses ...types.PingStatus) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.includedPingStatuses = set.New(statuses...) }
+}
+
+// WithAwsSsmDiscovererIncludedResourceTypes is the AwsSsmDiscovererOption to set a non default
+// list of SSM resource types (discovery.SsmTargetResourceType*) for targets to include in results,
+// e.g. only hybrid activations when EC2 instances are discovered with an AwsEc2Discoverer.
+func WithAwsSsmDiscovererIncludedResourceTypes(resourceTypes ...string) AwsSsmDiscovererOption {
+	return func(ssmd *AwsSsmDiscoverer) { ssmd.includedResourceTypes = set.New(resourceTypes...) }
+}
+
+// NewAwsSsmDiscoverer returns a new AwsSsmDiscoverer, initialized with the given options.
+func NewAwsSsmDiscoverer(cfg aws.Config, opts ...AwsSsmDiscovererOption) *AwsSsmDiscoverer {
+	ssmd := &AwsSsmDiscoverer{
+		cfg:       cfg,
+		ssmClient: ssm.NewFromConfig(cfg),
+		stsClient: sts.NewFromConfig(cfg),
+
+		discovererId:                       defaultAwsSsmDiscovererDiscovererId,
+		getAccountIdTimeout:                defaultAwsSsmDiscovererGetAccountIdTimeout,
+		describeInstanceInformationTimeout: defaultAwsSsmDiscovererDescribeInstanceInformationTimeout,
+		includedPingStatuses:               defaultAwsSsmDiscovererIncludedPingStatuses,
+		includedResourceTypes:              defaultAwsSsmDiscovererIncludedResourceTypes,
+	}
+	for _, opt := range opts {
+		opt(ssmd)
+	}
+	return ssmd
+}
+
+// Discover runs the AwsSsmDiscoverer.
+func (ssmd *AwsSsmDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := discovery.NewResult(ssmd.discovererId)
+	defer result.Done()
+
+	awsAccountId, awsPartition, err := utils.AwsAccountIdAndPartitionFromStsClient(ctx, ssmd.stsClient, ssmd.getAccountIdTimeout)
+	if err != nil {
+		result.AddErrorf("failed to get AWS account ID from AWS configuration: %v", err)
+		return result
+	}
+
+	pages, err := collectAwsPages(ctx, ssm.NewDescribeInstanceInformationPaginator(
+		ssmd.ssmClient,
+		&ssm.DescribeInstanceInformationInput{},
+	), ssmd.describeInstanceInformationTimeout)
+	if err != nil {
+		result.AddErrorf("failed to describe ssm instance information: %v", err)
+		return result
+	}
+	for _, page := range pages {
+		for _, instanceInfo := range page.InstanceInformationList {
+			ssmd.processInstanceInformation(instanceInfo, result, awsAccountId, awsPartition)
+		}
+	}
+
+	return result
+}
+
+func (ssmd *AwsSsmDiscoverer) processInstanceInformation(
+	instanceInfo types.InstanceInformation,
+	result *discovery.Result,
+	awsAccountId string,
+	awsPartition string,
+) {
+	instanceId := aws.ToString(instanceInfo.InstanceId)
+	if instanceId == "" {
+		result.AddWarning("received ssm instance information with no instance id")
+		return
+	}
+	// ignore targets with un-included ping statuses or resource types
+	if !ssmd.includedPingStatuses.Has(instanceInfo.PingStatus) {
+		return
+	}
+	if !ssmd.includedResourceTypes.Has(string(instanceInfo.ResourceType)) {
+		return
+	}
+
+	// build resource
+	awsArn := fmt.Sprintf("arn:%s:ssm:%s:%s:managed-instance/%s", awsPartition, ssmd.cfg.Region, awsAccountId, instanceId)
+	if string(instanceInfo.ResourceType) == discovery.SsmTargetResourceTypeEc2Instance {
+		awsArn = fmt.Sprintf("arn:%s:ec2:%s:%s:instance/%s", awsPartition, ssmd.cfg.Region, awsAccountId, instanceId)
+	}
+	ssmTargetDetails := &discovery.AwsSsmTargetDetails{
+		AwsBaseDetails: discovery.AwsBaseDetails{
+			AwsRegion:    ssmd.cfg.Region,
+			AwsAccountId: awsAccountId,
+			AwsArn:       awsArn,
+		},
+		InstanceId:       instanceId,
+		SsmResourceType:  string(instanceInfo.ResourceType),
+		Name:             aws.ToString(instanceInfo.Name),
+		ComputerName:     aws.ToString(instanceInfo.ComputerName),
+		IpAddress:        aws.ToString(instanceInfo.IPAddress),
+		PlatformType:     string(instanceInfo.PlatformType),
+		PlatformName:     aws.ToString(instanceInfo.PlatformName),
+		PlatformVersion:  aws.ToString(instanceInfo.PlatformVersion),
+		AgentVersion:     aws.ToString(instanceInfo.AgentVersion),
+		PingStatus:       string(instanceInfo.PingStatus),
+		LastPingDateTime: instanceInfo.LastPingDateTime,
+		ActivationId:     aws.ToString(instanceInfo.ActivationId),
+		IamRole:          aws.ToString(instanceInfo.IamRole),
+	}
+
+	result.AddResources(discovery.Resource{
+		ResourceType:        discovery.ResourceTypeAwsSsmTarget,
+		AwsSsmTargetDetails: ssmTargetDetails,
+	})
+}
@@ -0,0 +1,172 @@
+package discoverers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+	"github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/borderzero/discovery"
+)
+
+func newSsmTestInstanceInformation(
+	instanceId string,
+	resourceType string,
+	pingStatus types.PingStatus,
+	lastPing time.Time,
+) types.InstanceInformation {
+	return types.InstanceInformation{
+		InstanceId:       aws.String(instanceId),
+		ResourceType:     types.ResourceType(resourceType),
+		PingStatus:       pingStatus,
+		LastPingDateTime: aws.Time(lastPing),
+	}
+}
+
+func newSsmTestDiscoverer(ssmClient AwsSsmClient, partition string, opts ...AwsSsmDiscovererOption) *AwsSsmDiscoverer {
+	return NewAwsSsmDiscoverer(
+		aws.Config{Region: "us-east-1"},
+		append(
+			[]AwsSsmDiscovererOption{
+				WithAwsSsmDiscovererSsmClient(ssmClient),
+				WithAwsSsmDiscovererStsClient(&fakeStsClient{partition: partition}),
+			},
+			opts...,
+		)...,
+	)
+}
+
+func TestAwsSsmDiscovererDiscover(t *testing.T) {
+	lastPing := time.Now()
+
+	// targets are spread over pages to cover pagination
+	pages := []*ssm.DescribeInstanceInformationOutput{
+		{InstanceInformationList: []types.InstanceInformation{
+			newSsmTestInstanceInformation("i-1", discovery.SsmTargetResourceTypeEc2Instance, types.PingStatusOnline, lastPing),
+			newSsmTestInstanceInformation("mi-1", discovery.SsmTargetResourceTypeManagedInstance, types.PingStatusOnline, lastPing),
+		}},
+		{InstanceInformationList: []types.InstanceInformation{
+			newSsmTestInstanceInformation("mi-2", discovery.SsmTargetResourceTypeManagedInstance, types.PingStatusConnectionLost, lastPing),
+		}},
+	}
+
+	tests := []struct {
+		name             string
+		opts             []AwsSsmDiscovererOption
+		partition        string
+		extra            []types.InstanceInformation // on the last page
+		errs             map[int]error
+		expectedArns     []string
+		expectedWarnings int
+		expectError      bool
+	}{
+		{
+			name: "ec2 and managed instances",
+			expectedArns: []string{
+				"arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-1",
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-2",
+			},
+		},
+		{
+			name:      "arns in the partition of the caller",
+			partition: "aws-cn",
+			expectedArns: []string{
+				"arn:aws-cn:ec2:us-east-1:123456789012:instance/i-1",
+				"arn:aws-cn:ssm:us-east-1:123456789012:managed-instance/mi-1",
+				"arn:aws-cn:ssm:us-east-1:123456789012:managed-instance/mi-2",
+			},
+		},
+		{
+			name: "included ping statuses",
+			opts: []AwsSsmDiscovererOption{WithAwsSsmDiscovererIncludedPingStatuses(types.PingStatusConnectionLost)},
+			expectedArns: []string{
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-2",
+			},
+		},
+		{
+			name: "included resource types",
+			opts: []AwsSsmDiscovererOption{WithAwsSsmDiscovererIncludedResourceTypes(discovery.SsmTargetResourceTypeManagedInstance)},
+			expectedArns: []string{
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-1",
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-2",
+			},
+		},
+		{
+			name:  "warning for targets without instance id",
+			extra: []types.InstanceInformation{{PingStatus: types.PingStatusOnline}},
+			expectedArns: []string{
+				"arn:aws:ec2:us-east-1:123456789012:instance/i-1",
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-1",
+				"arn:aws:ssm:us-east-1:123456789012:managed-instance/mi-2",
+			},
+			expectedWarnings: 1,
+		},
+		{
+			name:         "error when a later page fails",
+			errs:         map[int]error{1: errors.New("throttled")},
+			expectedArns: []string{},
+			expectError:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			lastPage := *pages[len(pages)-1]
+			lastPage.InstanceInformationList = append(slices.Clone(lastPage.InstanceInformationList), test.extra...)
+
+			ssmClient := &fakeSsmClient{}
+			ssmClient.instanceInformation.pages = []*ssm.DescribeInstanceInformationOutput{pages[0], &lastPage}
+			ssmClient.instanceInformation.errs = test.errs
+
+			result := newSsmTestDiscoverer(ssmClient, test.partition, test.opts...).Discover(context.Background())
+
+			if test.expectError != (len(result.Errors) > 0) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, result.Errors)
+			}
+			if len(result.Warnings) != test.expectedWarnings {
+				t.Fatalf("expected %d warnings, got %v", test.expectedWarnings, result.Warnings)
+			}
+			arns := []string{}
+			for _, resource := range result.Resources {
+				arns = append(arns, resource.AwsSsmTargetDetails.AwsArn)
+			}
+			if !slices.Equal(arns, test.expectedArns) {
+				t.Fatalf("expected arns %v, got %v", test.expectedArns, arns)
+			}
+		})
+	}
+}
+
+func TestAwsSsmDiscovererDiscoverVolatileFields(t *testing.T) {
+	discover := func(lastPing time.Time) discovery.Resource {
+		ssmClient := &fakeSsmClient{}
+		ssmClient.instanceInformation.pages = []*ssm.DescribeInstanceInformationOutput{
+			{InstanceInformationList: []types.InstanceInformation{
+				newSsmTestInstanceInformation("mi-1", discovery.SsmTargetResourceTypeManagedInstance, types.PingStatusOnline, lastPing),
+			}},
+		}
+		result := newSsmTestDiscoverer(ssmClient, "").Discover(context.Background())
+		if len(result.Resources) != 1 {
+			t.Fatalf("expected 1 resource, got %d", len(result.Resources))
+		}
+		return result.Resources[0]
+	}
+
+	lastPing := time.Now()
+	first, second := discover(lastPing.Add(-time.Minute)), discover(lastPing)
+
+	if !second.AwsSsmTargetDetails.LastPingDateTime.Equal(lastPing) {
+		t.Fatalf("expected last ping %s, got %s", lastPing, second.AwsSsmTargetDetails.LastPingDateTime)
+	}
+	if reflect.DeepEqual(first, second) {
+		t.Fatal("expected resources with different last pings to differ")
+	}
+	if !reflect.DeepEqual(first.WithoutVolatileFields(), second.WithoutVolatileFields()) {
+		t.Fatal("expected resources differing only in last pings to be equal without volatile fields")
+	}
+}
@@ -18,9 +18,11 @@ import (
 )
 
 const (
-	defaultNetworkDiscovererDiscovererId   = "network_discoverer"
-	defaultNetworkDiscovererScanTimeout    = time.Second * 120
-	defaultNetworkDiscovererMaxConcurrency = 1000
+	defaultNetworkDiscovererDiscovererId      = "network_discoverer"
+	defaultNetworkDiscovererScanTimeout       = time.Second * 120
+	defaultNetworkDiscovererMaxConcurrency    = 1000
+	defaultNetworkDiscovererSshHostKeyScan    = false
+	defaultNetworkDiscovererSshHostKeyTimeout = time.Second * 5
 )
 
 var (
@@ -83,6 +85,9 @@ type NetworkDiscoverer struct {
 	maxConcurrency int64
 	targets        []string
 	ports          []string
+
+	sshHostKeyScan    bool
+	sshHostKeyTimeout time.Duration
 }
 
 // ensure NetworkDiscoverer implements discovery.Discoverer at compile-time.
@@ -120,6 +125,19 @@ func WithNetworkDiscovererPorts(ports ...string) NetworkDiscovererOption {
 	return func(nd *NetworkDiscoverer) { nd.ports = ports }
 }
 
+// WithNetworkDiscovererSshHostKeyScan is the NetworkDiscovererOption to enable or
+// disable capturing the host keys of discovered SSH servers. Capturing host keys
+// requires an SSH handshake (but no authentication) per supported key type.
+func WithNetworkDiscovererSshHostKeyScan(enabled bool) NetworkDiscovererOption {
+	return func(nd *NetworkDiscoverer) { nd.sshHostKeyScan = enabled }
+}
+
+// WithNetworkDiscovererSshHostKeyTimeout is the NetworkDiscovererOption to
+// set a non default timeout for capturing the host keys of an SSH server.
+func WithNetworkDiscovererSshHostKeyTimeout(timeout time.Duration) NetworkDiscovererOption {
+	return func(nd *NetworkDiscoverer) { nd.sshHostKeyTimeout = timeout }
+}
+
 // NewNetworkDiscoverer returns a new NetworkDiscoverer, initialized with the given options.
 func NewNetworkDiscoverer(opts ...NetworkDiscovererOption) *NetworkDiscoverer {
 	nd := &NetworkDiscoverer{
@@ -128,6 +146,9 @@ func NewNetworkDiscoverer(opts ...NetworkDiscovererOption) *NetworkDiscoverer {
 		maxConcurrency: defaultNetworkDiscovererMaxConcurrency,
 		targets:        defaultNetworkDiscovererTargets,
 		ports:          defaultNetworkDiscovererPorts,
+
+		sshHostKeyScan:    defaultNetworkDiscovererSshHostKeyScan,
+		sshHostKeyTimeout: defaultNetworkDiscovererSshHostKeyTimeout,
 	}
 	for _, opt := range opts {
 		opt(nd)
@@ -216,10 +237,19 @@ func (nd *NetworkDiscoverer) Discover(ctx context.Context) *discovery.Result {
 								},
 							})
 						case "ssh":
+							var hostKeys []string
+							if nd.sshHostKeyScan {
+								var err error
+								hostKeys, err = scanSshHostKeys(ctx, net.JoinHostPort(ip, port), nd.sshHostKeyTimeout)
+								if err != nil {
+									result.AddWarningf("failed to get SSH host keys for %s: %v", net.JoinHostPort(ip, port), err)
+								}
+							}
 							result.AddResources(discovery.Resource{
 								ResourceType: discovery.ResourceTypeNetworkSshServer,
 								NetworkSshServerDetails: &discovery.NetworkSshServerDetails{
 									NetworkBaseDetails: networkBaseDetails,
+									HostKeys:           hostKeys,
 								},
 							})
 						case "vnc":
@@ -0,0 +1,47 @@
+package discoverers
+
+import (
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	rdstypes "github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/borderzero/discovery"
+)
+
+// newTestTags returns AWS tags from key-value pairs, built with newTag
+// since the AWS SDK has a tag type per service (e.g. newEc2TestTag).
+func newTestTags[T any](newTag func(key, value *string) T, pairs ...string) []T {
+	var tags []T
+	for i := 0; i+1 < len(pairs); i += 2 {
+		tags = append(tags, newTag(aws.String(pairs[i]), aws.String(pairs[i+1])))
+	}
+	return tags
+}
+
+func newEc2TestTag(key, value *string) ec2types.Tag { return ec2types.Tag{Key: key, Value: value} }
+
+func newEcsTestTag(key, value *string) ecstypes.Tag { return ecstypes.Tag{Key: key, Value: value} }
+
+func newRdsTestTag(key, value *string) rdstypes.Tag { return rdstypes.Tag{Key: key, Value: value} }
+
+// ec2InstanceIds returns the sorted ids of the ec2 instances in a result.
+func ec2InstanceIds(result *discovery.Result) []string {
+	ids := []string{}
+	for _, resource := range result.Resources {
+		ids = append(ids, resource.AwsEc2InstanceDetails.InstanceId)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// ec2AccountIds returns the sorted account ids of the ec2 instances in a result.
+func ec2AccountIds(result *discovery.Result) []string {
+	ids := []string{}
+	for _, resource := range result.Resources {
+		ids = append(ids, resource.AwsEc2InstanceDetails.AwsAccountId)
+	}
+	slices.Sort(ids)
+	return ids
+}
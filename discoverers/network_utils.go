@@ -2,11 +2,14 @@ package discoverers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"regexp"
 	"strings"
 	"time"
+
+	"golang.org/x/crypto/ssh"
 )
 
 var (
@@ -173,3 +176,60 @@ func incIP(ip net.IP) {
 		}
 	}
 }
+
+// errSshHostKeyCaptured aborts an SSH handshake once the host key has been captured.
+var errSshHostKeyCaptured = errors.New("ssh host key captured")
+
+// sshHostKeyAlgorithms are the host key algorithms for which host keys are captured.
+// A server only presents one host key per handshake, so one handshake is needed per
+// algorithm.
+var sshHostKeyAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256,
+	ssh.KeyAlgoRSASHA512,
+}
+
+// scanSshHostKeys returns the host keys (in the authorized_keys format) of the SSH
+// server at a given address, without authenticating. Algorithms not supported by the
+// server are skipped; an error is returned only if no host key could be captured.
+func scanSshHostKeys(ctx context.Context, address string, timeout time.Duration) ([]string, error) {
+	hostKeys := []string{}
+	var lastErr error
+	for _, algorithm := range sshHostKeyAlgorithms {
+		key, err := scanSshHostKey(ctx, address, algorithm, timeout)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		hostKeys = append(hostKeys, key)
+	}
+	if len(hostKeys) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return hostKeys, nil
+}
+
+func scanSshHostKey(ctx context.Context, address, algorithm string, timeout time.Duration) (string, error) {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	var hostKey ssh.PublicKey
+	config := &ssh.ClientConfig{
+		HostKeyAlgorithms: []string{algorithm},
+		HostKeyCallback: func(_ string, _ net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errSshHostKeyCaptured
+		},
+		Timeout: timeout,
+	}
+	_, _, _, err = ssh.NewClientConn(conn, address, config)
+	if hostKey == nil {
+		return "", fmt.Errorf("failed ssh handshake for %s host key: %v", algorithm, err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(hostKey))), nil
+}
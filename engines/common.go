@@ -46,6 +46,8 @@ func runContinuously(
 		case _, ok := <-triggerC:
 			if ok {
 				ticker.Reset(interval)
+				// runOnce signals the wait group, so every run must be added to it
+				innerWg.Add(1)
 				go runOnce(ctx, &innerWg, discoverer, results)
 			}
 		// handle tick from ticker (run now)
@@ -10,6 +10,8 @@ import (
 
 const (
 	defaultInterval = time.Minute * 5
+
+	snapshotStoreDiscovererId = "snapshot_store"
 )
 
 type discovererConfig struct {
@@ -22,7 +24,8 @@ type discovererConfig struct {
 
 // ContinuousEngine represents an engine which runs multiple discoverers continuously.
 type ContinuousEngine struct {
-	discoverers []*discovererConfig
+	discoverers   []*discovererConfig
+	snapshotStore discovery.SnapshotStore
 }
 
 // ensure MultipleUpstreamDiscoverer implements discovery.Engine at compile-time.
@@ -72,6 +75,13 @@ func WithDiscoverer(discoverer discovery.Discoverer, opts ...DiscovererOption) C
 	}
 }
 
+// WithSnapshotStore is a configuration option to persist the latest result of
+// each discoverer in a SnapshotStore. Stored results are emitted (marked as
+// stale) as soon as the engine starts, before any discoverer has run.
+func WithSnapshotStore(store discovery.SnapshotStore) ContinuousEngineOption {
+	return func(engine *ContinuousEngine) { engine.snapshotStore = store }
+}
+
 // NewContinuousEngine returns a new ContinuousEngine, initialized with the given options.
 func NewContinuousEngine(opts ...ContinuousEngineOption) *ContinuousEngine {
 	engine := &ContinuousEngine{discoverers: []*discovererConfig{}}
@@ -93,16 +103,27 @@ func (cd *ContinuousEngine) Run(
 	var wg sync.WaitGroup
 	defer wg.Wait()
 
+	if cd.snapshotStore != nil {
+		if !emitSnapshots(ctx, cd.snapshotStore, results) {
+			return
+		}
+	}
+
 	for _, discoverer := range cd.discoverers {
 		wg.Add(1)
 
+		var d discovery.Discoverer = discoverer.discoverer
+		if cd.snapshotStore != nil {
+			d = &snapshottingDiscoverer{discoverer: d, store: cd.snapshotStore}
+		}
+
 		go runContinuously(
 			ctx,
 			&wg,
 			discoverer.interval,
 			discoverer.intervalC,
 			discoverer.triggerC,
-			discoverer.discoverer,
+			d,
 			results,
 		)
 	}
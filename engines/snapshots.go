@@ -0,0 +1,70 @@
+package engines
+
+import (
+	"context"
+
+	"github.com/borderzero/discovery"
+)
+
+// snapshottingDiscoverer wraps a discoverer and saves each of its
+// successful results to a snapshot store before returning them.
+type snapshottingDiscoverer struct {
+	discoverer discovery.Discoverer
+	store      discovery.SnapshotStore
+}
+
+// ensure snapshottingDiscoverer implements discovery.Discoverer at compile-time.
+var _ discovery.Discoverer = (*snapshottingDiscoverer)(nil)
+
+// Discover runs the underlying discoverer and saves its result. Results with
+// errors are not saved so that a failed run never replaces a good snapshot.
+func (sd *snapshottingDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := sd.discoverer.Discover(ctx)
+	if len(result.Errors) > 0 {
+		return result
+	}
+	if err := sd.store.Save(ctx, result); err != nil {
+		result.AddWarningf("failed to save result snapshot: %v", err)
+	}
+	return result
+}
+
+// emitSnapshots writes all results in a snapshot store to the results
+// channel, marked as stale. Returns false if the context is done before
+// all the results were written.
+// ** Note that it does not close the results channel **
+func emitSnapshots(
+	ctx context.Context,
+	store discovery.SnapshotStore,
+	results chan<- *discovery.Result,
+) bool {
+	snapshots, err := store.Load(ctx)
+	if err != nil {
+		result := discovery.NewResult(snapshotStoreDiscovererId)
+		result.AddErrorf("failed to load result snapshots: %v", err)
+		result.Done()
+		return emit(ctx, result, results)
+	}
+	for _, snapshot := range snapshots {
+		snapshot.Metadata.Stale = true
+		if !emit(ctx, snapshot, results) {
+			return false
+		}
+	}
+	return true
+}
+
+// emit writes a single result to the results channel
+// and returns false if the context is done first.
+func emit(
+	ctx context.Context,
+	result *discovery.Result,
+	results chan<- *discovery.Result,
+) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case results <- result:
+		return true
+	}
+}
@@ -0,0 +1,190 @@
+package engines
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+// fakeSnapshotStore is an in-memory discovery.SnapshotStore.
+type fakeSnapshotStore struct {
+	sync.Mutex
+
+	saved   []*discovery.Result
+	loadErr error
+	saveErr error
+}
+
+func (fss *fakeSnapshotStore) Save(_ context.Context, result *discovery.Result) error {
+	fss.Lock()
+	defer fss.Unlock()
+
+	if fss.saveErr != nil {
+		return fss.saveErr
+	}
+	fss.saved = append(fss.saved, result)
+	return nil
+}
+
+func (fss *fakeSnapshotStore) Load(_ context.Context) ([]*discovery.Result, error) {
+	fss.Lock()
+	defer fss.Unlock()
+
+	if fss.loadErr != nil {
+		return nil, fss.loadErr
+	}
+	return append([]*discovery.Result{}, fss.saved...), nil
+}
+
+// fakeDiscoverer is a discoverer which returns a result
+// with the given errors once released (if not nil).
+type fakeDiscoverer struct {
+	discovererId string
+	errs         []string
+	released     chan struct{}
+}
+
+func (fd *fakeDiscoverer) Discover(ctx context.Context) *discovery.Result {
+	result := discovery.NewResult(fd.discovererId)
+	defer result.Done()
+
+	if fd.released != nil {
+		select {
+		case <-fd.released:
+		case <-ctx.Done():
+		}
+	}
+	for _, err := range fd.errs {
+		result.AddError(err)
+	}
+	return result
+}
+
+func newSnapshotTestResult(discovererId string) *discovery.Result {
+	result := discovery.NewResult(discovererId)
+	result.Done()
+	return result
+}
+
+func TestContinuousEngineSnapshots(t *testing.T) {
+	store := &fakeSnapshotStore{saved: []*discovery.Result{newSnapshotTestResult("d")}}
+	discoverer := &fakeDiscoverer{discovererId: "d", released: make(chan struct{})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	results := make(chan *discovery.Result)
+	go NewContinuousEngine(
+		WithDiscoverer(discoverer, WithInitialInterval(time.Hour)),
+		WithSnapshotStore(store),
+	).Run(ctx, results)
+
+	// the snapshot is emitted while the discoverer is still running
+	snapshot := <-results
+	if snapshot.Metadata.DiscovererId != "d" || !snapshot.Metadata.Stale {
+		t.Fatalf("expected a stale snapshot of d first, got %+v", snapshot.Metadata)
+	}
+
+	close(discoverer.released)
+	live := <-results
+	if live.Metadata.Stale {
+		t.Fatal("expected the live result not to be stale")
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	if len(store.saved) != 2 || store.saved[1] != live {
+		t.Fatalf("expected the live result to be saved, got %d saved results", len(store.saved))
+	}
+}
+
+func TestSnapshottingDiscoverer(t *testing.T) {
+	tests := []struct {
+		name             string
+		errs             []string
+		saveErr          error
+		expectSaved      bool
+		expectedWarnings int
+	}{
+		{
+			name:        "saves successful results",
+			expectSaved: true,
+		},
+		{
+			name: "does not save errored results",
+			errs: []string{"failed to list some resources"},
+		},
+		{
+			name:             "warns when saving fails",
+			saveErr:          errors.New("disk full"),
+			expectedWarnings: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := &fakeSnapshotStore{saveErr: test.saveErr}
+			sd := &snapshottingDiscoverer{
+				discoverer: &fakeDiscoverer{discovererId: "d", errs: test.errs},
+				store:      store,
+			}
+
+			result := sd.Discover(context.Background())
+
+			if len(result.Warnings) != test.expectedWarnings {
+				t.Fatalf("expected %d warnings, got %v", test.expectedWarnings, result.Warnings)
+			}
+			if saved := len(store.saved) > 0; saved != test.expectSaved {
+				t.Fatalf("expected saved to be %t, got %t", test.expectSaved, saved)
+			}
+		})
+	}
+}
+
+func TestEmitSnapshots(t *testing.T) {
+	t.Run("marks snapshots as stale", func(t *testing.T) {
+		store := &fakeSnapshotStore{saved: []*discovery.Result{newSnapshotTestResult("a"), newSnapshotTestResult("b")}}
+		results := make(chan *discovery.Result, 2)
+
+		if !emitSnapshots(context.Background(), store, results) {
+			t.Fatal("expected all snapshots to be emitted")
+		}
+		close(results)
+		ids := []string{}
+		for result := range results {
+			if !result.Metadata.Stale {
+				t.Fatalf("expected the snapshot of %s to be stale", result.Metadata.DiscovererId)
+			}
+			ids = append(ids, result.Metadata.DiscovererId)
+		}
+		if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+			t.Fatalf("expected the snapshots of a and b, got %v", ids)
+		}
+	})
+
+	t.Run("emits an error result when loading fails", func(t *testing.T) {
+		store := &fakeSnapshotStore{loadErr: errors.New("corrupt")}
+		results := make(chan *discovery.Result, 1)
+
+		if !emitSnapshots(context.Background(), store, results) {
+			t.Fatal("expected the error result to be emitted")
+		}
+		result := <-results
+		if result.Metadata.DiscovererId != snapshotStoreDiscovererId || len(result.Errors) != 1 {
+			t.Fatalf("expected an error result from the snapshot store, got %+v", result)
+		}
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		store := &fakeSnapshotStore{saved: []*discovery.Result{newSnapshotTestResult("a")}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		if emitSnapshots(ctx, store, make(chan *discovery.Result)) {
+			t.Fatal("expected emitting to stop when the context is done")
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package exporters
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/borderzero/discovery"
+)
+
+// ec2Address returns the address to connect to an EC2 instance based on the results
+// of reachability checks. When no reachability checks were done, the private address
+// is preferred (or the public address, if preferPublic). When no address was found to
+// be reachable, the private address is returned only if includeUnreachable.
+func ec2Address(details *discovery.AwsEc2InstanceDetails, preferPublic, includeUnreachable bool) string {
+	candidates := []string{details.PrivateIpAddress, details.PublicIpAddress}
+	reachable := []*bool{details.PrivateIpAddressReachable, details.PublicIpAddressReachable}
+	if preferPublic {
+		candidates[0], candidates[1] = candidates[1], candidates[0]
+		reachable[0], reachable[1] = reachable[1], reachable[0]
+	}
+
+	checked := reachable[0] != nil || reachable[1] != nil
+	for i, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if !checked || (reachable[i] != nil && *reachable[i]) {
+			return candidate
+		}
+	}
+	if includeUnreachable {
+		return details.PrivateIpAddress
+	}
+	return ""
+}
+
+// sanitizeIdentifier replaces all characters which are not valid in identifiers
+// (i.e. not in [a-zA-Z0-9_]) with underscores. Prometheus label names and Ansible
+// group names are both restricted to such identifiers.
+func sanitizeIdentifier(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// awsBaseDetails returns the base details of AWS resources.
+func awsBaseDetails(resource discovery.Resource) discovery.AwsBaseDetails {
+	switch {
+	case resource.AwsEc2InstanceDetails != nil:
+		return resource.AwsEc2InstanceDetails.AwsBaseDetails
+	case resource.AwsEc2InstanceConnectEndpointDetails != nil:
+		return resource.AwsEc2InstanceConnectEndpointDetails.AwsBaseDetails
+	case resource.AwsEcsServiceDetails != nil:
+		return resource.AwsEcsServiceDetails.AwsBaseDetails
+	case resource.AwsEksClusterDetails != nil:
+		return resource.AwsEksClusterDetails.AwsBaseDetails
+	case resource.AwsRdsInstanceDetails != nil:
+		return resource.AwsRdsInstanceDetails.AwsBaseDetails
+	case resource.AwsRdsClusterDetails != nil:
+		return resource.AwsRdsClusterDetails.AwsBaseDetails
+	case resource.AwsRdsProxyDetails != nil:
+		return resource.AwsRdsProxyDetails.AwsBaseDetails
+	case resource.AwsSsmTargetDetails != nil:
+		return resource.AwsSsmTargetDetails.AwsBaseDetails
+	}
+	return discovery.AwsBaseDetails{}
+}
+
+// replaceManagedBlock replaces the block delimited by (and including) the begin and end
+// marker lines in existing contents with a new block (which should include the markers),
+// or appends the block if existing contents have no such block. Contents outside of the
+// block are left untouched, so that updates are idempotent.
+func replaceManagedBlock(existing []byte, begin, end string, block []byte) ([]byte, error) {
+	lines := strings.SplitAfter(string(existing), "\n")
+	beginIndex, endIndex := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case begin:
+			if beginIndex != -1 {
+				return nil, fmt.Errorf("duplicate managed block begin marker \"%s\"", begin)
+			}
+			beginIndex = i
+		case end:
+			if beginIndex == -1 || endIndex != -1 {
+				return nil, fmt.Errorf("unexpected managed block end marker \"%s\"", end)
+			}
+			endIndex = i
+		}
+	}
+	if beginIndex != -1 && endIndex == -1 {
+		return nil, fmt.Errorf("missing managed block end marker \"%s\"", end)
+	}
+
+	var buf bytes.Buffer
+	if beginIndex == -1 {
+		buf.Write(existing)
+		if len(existing) > 0 && !bytes.HasSuffix(existing, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+		buf.Write(block)
+		return buf.Bytes(), nil
+	}
+	buf.WriteString(strings.Join(lines[:beginIndex], ""))
+	buf.Write(block)
+	buf.WriteString(strings.Join(lines[endIndex+1:], ""))
+	return buf.Bytes(), nil
+}
+
+// sortedKeys returns the keys of a map in lexical order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func dedupe(values []string) []string {
+	seen := map[string]struct{}{}
+	deduped := []string{}
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		deduped = append(deduped, value)
+	}
+	return deduped
+}
+
+func atoi(s string) int {
+	i, _ := strconv.Atoi(s)
+	return i
+}
@@ -0,0 +1,266 @@
+package exporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/formats"
+	"github.com/borderzero/discovery/inventory"
+)
+
+const (
+	defaultAnsibleExporterVarPrefix = "discovery_"
+
+	ansibleDefaultSshPort = "22"
+)
+
+// AnsibleGroup represents a group in an Ansible dynamic inventory.
+type AnsibleGroup struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+// AnsibleInventory represents an Ansible dynamic inventory, as printed by
+// inventory scripts invoked with --list (groups and hostvars under _meta).
+type AnsibleInventory struct {
+	Groups   map[string]*AnsibleGroup
+	HostVars map[string]map[string]any
+}
+
+// MarshalJSON encodes an AnsibleInventory in the format expected by Ansible.
+func (ai *AnsibleInventory) MarshalJSON() ([]byte, error) {
+	out := map[string]any{
+		"_meta": map[string]any{"hostvars": ai.HostVars},
+	}
+	for name, group := range ai.Groups {
+		out[name] = group
+	}
+	return json.Marshal(out)
+}
+
+// AnsibleExporter represents an exporter of discovered resources as an Ansible
+// dynamic inventory. Hosts are produced for EC2 instances (named after their
+// instance id), Docker containers (named after their container name, and using
+// the community.docker.docker connection plugin) and hosts on the network (named
+// after their IP address, with one host per IP address for all its services).
+//
+// Hosts are grouped by resource type (e.g. "aws_ec2_instance", "network_ssh_server"),
+// AWS region ("region_<region>"), AWS account ("account_<account id>"), VPC
+// ("vpc_<vpc id>"), instance type ("instance_type_<type>"), EC2 tags
+// ("tag_<key>_<value>") and Docker labels ("label_<key>_<value>"). Group names are
+// sanitized to only contain characters valid in Ansible group names.
+//
+// Hostvars carry the details of resources, with variable names prefixed
+// (by default with "discovery_") to avoid clashes with other variables.
+type AnsibleExporter struct {
+	varPrefix           string
+	preferPublicAddress bool
+	includeUnreachable  bool
+}
+
+// AnsibleExporterOption represents a configuration option for an AnsibleExporter.
+type AnsibleExporterOption func(*AnsibleExporter)
+
+// WithAnsibleExporterVarPrefix is the AnsibleExporterOption
+// to set a non default prefix for the names of hostvars.
+func WithAnsibleExporterVarPrefix(prefix string) AnsibleExporterOption {
+	return func(ae *AnsibleExporter) { ae.varPrefix = prefix }
+}
+
+// WithAnsibleExporterPreferPublicAddress is the AnsibleExporterOption to prefer the
+// public (rather than private) address of EC2 instances when both are reachable.
+func WithAnsibleExporterPreferPublicAddress(preferPublic bool) AnsibleExporterOption {
+	return func(ae *AnsibleExporter) { ae.preferPublicAddress = preferPublic }
+}
+
+// WithAnsibleExporterIncludeUnreachable is the AnsibleExporterOption to also include
+// EC2 instances for which no address was found to be reachable (the private address
+// is then used). By default such instances are skipped.
+func WithAnsibleExporterIncludeUnreachable(include bool) AnsibleExporterOption {
+	return func(ae *AnsibleExporter) { ae.includeUnreachable = include }
+}
+
+// NewAnsibleExporter returns a new AnsibleExporter, initialized with the given options.
+func NewAnsibleExporter(opts ...AnsibleExporterOption) *AnsibleExporter {
+	ae := &AnsibleExporter{
+		varPrefix: defaultAnsibleExporterVarPrefix,
+	}
+	for _, opt := range opts {
+		opt(ae)
+	}
+	return ae
+}
+
+// Inventory returns the Ansible inventory for the resources in results.
+func (ae *AnsibleExporter) Inventory(results []*discovery.Result) *AnsibleInventory {
+	return ae.inventory(formats.SortedEntries(results))
+}
+
+// WriteList writes the Ansible inventory for the resources in results
+// as expected from an inventory script invoked with --list.
+func (ae *AnsibleExporter) WriteList(w io.Writer, results []*discovery.Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(ae.Inventory(results)); err != nil {
+		return fmt.Errorf("failed to json encode ansible inventory: %v", err)
+	}
+	return nil
+}
+
+// WriteHost writes the hostvars of a host as expected from an inventory script
+// invoked with --host. An empty object is written for unknown hosts.
+func (ae *AnsibleExporter) WriteHost(w io.Writer, results []*discovery.Result, host string) error {
+	vars, ok := ae.Inventory(results).HostVars[host]
+	if !ok {
+		vars = map[string]any{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(vars); err != nil {
+		return fmt.Errorf("failed to json encode ansible hostvars: %v", err)
+	}
+	return nil
+}
+
+func (ae *AnsibleExporter) inventory(entries []inventory.Entry) *AnsibleInventory {
+	ai := &AnsibleInventory{
+		Groups:   map[string]*AnsibleGroup{},
+		HostVars: map[string]map[string]any{},
+	}
+
+	addToGroup := func(group, host string) {
+		group = sanitizeIdentifier(group)
+		if _, ok := ai.Groups[group]; !ok {
+			ai.Groups[group] = &AnsibleGroup{}
+		}
+		if !contains(ai.Groups[group].Hosts, host) {
+			ai.Groups[group].Hosts = append(ai.Groups[group].Hosts, host)
+		}
+	}
+	keys := map[string][]string{}
+
+	for _, entry := range entries {
+		resource := entry.Resource
+		switch {
+		case resource.AwsEc2InstanceDetails != nil:
+			details := resource.AwsEc2InstanceDetails
+			address := ec2Address(details, ae.preferPublicAddress, ae.includeUnreachable)
+			if address == "" {
+				continue
+			}
+			host := details.InstanceId
+			if _, ok := ai.HostVars[host]; !ok {
+				ai.HostVars[host] = ae.flatten(details)
+			}
+			ai.HostVars[host]["ansible_host"] = address
+
+			addToGroup(resource.ResourceType, host)
+			if details.AwsRegion != "" {
+				addToGroup("region_"+details.AwsRegion, host)
+			}
+			if details.AwsAccountId != "" {
+				addToGroup("account_"+details.AwsAccountId, host)
+			}
+			if details.VpcId != "" {
+				addToGroup("vpc_"+details.VpcId, host)
+			}
+			if details.InstanceType != "" {
+				addToGroup("instance_type_"+details.InstanceType, host)
+			}
+			for _, key := range sortedKeys(details.Tags) {
+				addToGroup(fmt.Sprintf("tag_%s_%s", key, details.Tags[key]), host)
+			}
+			keys[host] = append(keys[host], entry.Key)
+
+		case resource.DockerContainerDetails != nil:
+			details := resource.DockerContainerDetails
+			host := details.ContainerId
+			if len(details.Names) > 0 {
+				host = strings.TrimPrefix(details.Names[0], "/")
+			}
+			if existing, ok := keys[host]; ok && !contains(existing, entry.Key) {
+				host = details.ContainerId // name collision across Docker daemons
+			}
+			if _, ok := ai.HostVars[host]; !ok {
+				ai.HostVars[host] = ae.flatten(details)
+			}
+			ai.HostVars[host]["ansible_connection"] = "community.docker.docker"
+			ai.HostVars[host]["ansible_host"] = details.ContainerId
+
+			addToGroup(resource.ResourceType, host)
+			for _, key := range sortedKeys(details.Labels) {
+				addToGroup(fmt.Sprintf("label_%s_%s", key, details.Labels[key]), host)
+			}
+			keys[host] = append(keys[host], entry.Key)
+
+		default:
+			base, ok := resource.NetworkBaseDetails()
+			if !ok {
+				continue
+			}
+			host := base.IpAddress
+			if _, ok := ai.HostVars[host]; !ok {
+				ai.HostVars[host] = map[string]any{
+					"ansible_host":                 base.IpAddress,
+					ae.varPrefix + "ip_address":    base.IpAddress,
+					ae.varPrefix + "hostnames":     []string{},
+					ae.varPrefix + "network_ports": map[string][]string{},
+				}
+			}
+			// the services of a host may not all have (the same) hostnames
+			hostnames := ai.HostVars[host][ae.varPrefix+"hostnames"].([]string)
+			for _, hostname := range base.HostNames {
+				if !contains(hostnames, hostname) {
+					hostnames = append(hostnames, hostname)
+				}
+			}
+			ai.HostVars[host][ae.varPrefix+"hostnames"] = hostnames
+			ports := ai.HostVars[host][ae.varPrefix+"network_ports"].(map[string][]string)
+			if !contains(ports[resource.ResourceType], base.Port) {
+				ports[resource.ResourceType] = append(ports[resource.ResourceType], base.Port)
+			}
+			if resource.ResourceType == discovery.ResourceTypeNetworkSshServer && base.Port != ansibleDefaultSshPort {
+				if _, ok := ai.HostVars[host]["ansible_port"]; !ok {
+					ai.HostVars[host]["ansible_port"] = base.Port
+				}
+			}
+
+			addToGroup(resource.ResourceType, host)
+			keys[host] = append(keys[host], entry.Key)
+		}
+	}
+
+	for host, hostKeys := range keys {
+		ai.HostVars[host][ae.varPrefix+"keys"] = dedupe(hostKeys) // e.g. found by multiple discoverers
+	}
+
+	children := sortedKeys(ai.Groups)
+	for _, group := range ai.Groups {
+		sort.Strings(group.Hosts)
+	}
+	ai.Groups["all"] = &AnsibleGroup{Children: children}
+
+	return ai
+}
+
+// flatten returns the fields of resource details as hostvars, named
+// after their json names and prefixed with the configured prefix.
+func (ae *AnsibleExporter) flatten(details any) map[string]any {
+	vars := map[string]any{}
+	byt, err := json.Marshal(details)
+	if err != nil {
+		return vars
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal(byt, &fields); err != nil {
+		return vars
+	}
+	for name, value := range fields {
+		vars[ae.varPrefix+name] = value
+	}
+	return vars
+}
@@ -0,0 +1,48 @@
+package exporters
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestAnsibleExporterWriteList(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewAnsibleExporter().WriteList(&buf, newExporterTestResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertGolden(t, "ansible_list.json", buf.Bytes())
+}
+
+func TestAnsibleExporterWriteHost(t *testing.T) {
+	tests := []struct {
+		name   string
+		host   string
+		golden string
+	}{
+		{
+			name:   "network host with multiple services",
+			host:   "10.0.2.5",
+			golden: "ansible_host_network.json",
+		},
+		{
+			name:   "docker container",
+			host:   "api",
+			golden: "ansible_host_docker.json",
+		},
+		{
+			name:   "unknown host",
+			host:   "missing",
+			golden: "ansible_host_unknown.json",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			exporter := NewAnsibleExporter(WithAnsibleExporterVarPrefix("d_"))
+			if err := exporter.WriteHost(&buf, newExporterTestResults(), test.host); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertGolden(t, test.golden, buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,386 @@
+package exporters
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/formats"
+	"github.com/borderzero/discovery/inventory"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	defaultNamesExporterDomain    = "disco"
+	defaultNamesExporterBlockName = "discovery"
+	defaultNamesExporterTtl       = time.Minute * 5
+	defaultNamesExporterFileMode  = os.FileMode(0644)
+)
+
+// NameRecord represents a name to address mapping derived from a discovered resource.
+type NameRecord struct {
+	Name    string `json:"name"` // relative to the domain
+	Address string `json:"address"`
+	Key     string `json:"key"` // the key of the resource the record was derived from
+}
+
+// NameCollision represents a name derived from more than one resource with
+// different addresses. The record of the first resource (by key) is kept.
+type NameCollision struct {
+	Name            string `json:"name"`
+	Address         string `json:"address"`
+	Key             string `json:"key"`
+	ExistingAddress string `json:"existing_address"`
+	ExistingKey     string `json:"existing_key"`
+}
+
+// String returns a human-readable description of a collision.
+func (c NameCollision) String() string {
+	return fmt.Sprintf(
+		"name \"%s\" of %s (%s) collides with %s (%s), skipped",
+		c.Name, c.Key, c.Address, c.ExistingKey, c.ExistingAddress,
+	)
+}
+
+// NamesExporter represents an exporter of the names of discovered resources as an
+// /etc/hosts block or as an RFC 1035 zone file, e.g. for environments without DNS.
+//
+// Names are derived from the Name tag of EC2 instances, the names of Docker containers
+// (with the address of their port bindings, or the loopback address for bindings on all
+// interfaces), "<name>.<namespace>" for kubernetes services (with their cluster ip) and
+// the reverse DNS names of resources on the network (relative to the domain if within
+// it, otherwise only their first label). Names are lowercased and sanitized to be valid
+// DNS names. All names are relative to a domain ("disco" by default).
+type NamesExporter struct {
+	domain              string
+	blockName           string
+	ttl                 time.Duration
+	zoneSerial          uint32
+	preferPublicAddress bool
+	includeUnreachable  bool
+	fileMode            os.FileMode
+}
+
+// NamesExporterOption represents a configuration option for a NamesExporter.
+type NamesExporterOption func(*NamesExporter)
+
+// WithNamesExporterDomain is the NamesExporterOption to set a non default domain
+// (the origin of zone files). Set to the empty string for hosts file entries
+// with unqualified names only.
+func WithNamesExporterDomain(domain string) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.domain = strings.Trim(domain, ".") }
+}
+
+// WithNamesExporterBlockName is the NamesExporterOption to set a non default name for the
+// managed block markers in hosts files, e.g. to maintain multiple blocks in the same file.
+func WithNamesExporterBlockName(name string) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.blockName = name }
+}
+
+// WithNamesExporterTtl is the NamesExporterOption to set a non default TTL for zone files.
+func WithNamesExporterTtl(ttl time.Duration) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.ttl = ttl }
+}
+
+// WithNamesExporterZoneSerial is the NamesExporterOption to set the serial of zone files.
+// By default the serial is a hash of the records, so that it is stable as long as records
+// do not change but, unlike a counter, it is not guaranteed to increase when they do.
+func WithNamesExporterZoneSerial(serial uint32) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.zoneSerial = serial }
+}
+
+// WithNamesExporterPreferPublicAddress is the NamesExporterOption to prefer the public
+// (rather than private) address of EC2 instances when both are reachable.
+func WithNamesExporterPreferPublicAddress(preferPublic bool) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.preferPublicAddress = preferPublic }
+}
+
+// WithNamesExporterIncludeUnreachable is the NamesExporterOption to also include EC2
+// instances for which no address was found to be reachable (the private address is
+// then used). By default such instances are skipped.
+func WithNamesExporterIncludeUnreachable(include bool) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.includeUnreachable = include }
+}
+
+// WithNamesExporterFileMode is the NamesExporterOption to set a non default
+// file mode for new files (existing files keep their file mode).
+func WithNamesExporterFileMode(mode os.FileMode) NamesExporterOption {
+	return func(ne *NamesExporter) { ne.fileMode = mode }
+}
+
+// NewNamesExporter returns a new NamesExporter, initialized with the given options.
+func NewNamesExporter(opts ...NamesExporterOption) *NamesExporter {
+	ne := &NamesExporter{
+		domain:    defaultNamesExporterDomain,
+		blockName: defaultNamesExporterBlockName,
+		ttl:       defaultNamesExporterTtl,
+		fileMode:  defaultNamesExporterFileMode,
+	}
+	for _, opt := range opts {
+		opt(ne)
+	}
+	return ne
+}
+
+// Records returns the name records for the resources in results, sorted by name,
+// and the collisions found (for which records were skipped).
+func (ne *NamesExporter) Records(results []*discovery.Result) ([]NameRecord, []NameCollision) {
+	return ne.records(formats.SortedEntries(results))
+}
+
+// WriteHostsBlock writes a managed hosts file block (including its markers) for the
+// resources in results, and returns the collisions found.
+func (ne *NamesExporter) WriteHostsBlock(w io.Writer, results []*discovery.Result) ([]NameCollision, error) {
+	records, collisions := ne.Records(results)
+	if _, err := w.Write(ne.hostsBlock(records)); err != nil {
+		return collisions, fmt.Errorf("failed to write hosts block: %v", err)
+	}
+	return collisions, nil
+}
+
+// UpdateHostsFile replaces the managed block in a hosts file (e.g. /etc/hosts) with one
+// for the resources in results, leaving the rest of the file untouched. The block is
+// appended if the file does not have one yet, and the file is created if it does not
+// exist. The file is not written at all if its contents would not change.
+func (ne *NamesExporter) UpdateHostsFile(path string, results []*discovery.Result) ([]NameCollision, error) {
+	records, collisions := ne.Records(results)
+
+	mode := ne.fileMode
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return collisions, fmt.Errorf("failed to read hosts file: %v", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	updated, err := replaceManagedBlock(existing, ne.beginMarker(), ne.endMarker(), ne.hostsBlock(records))
+	if err != nil {
+		return collisions, fmt.Errorf("failed to update hosts file: %v", err)
+	}
+	if bytes.Equal(existing, updated) {
+		return collisions, nil
+	}
+	if err := utils.WriteFileAtomically(path, updated, mode); err != nil {
+		return collisions, fmt.Errorf("failed to write hosts file: %v", err)
+	}
+	return collisions, nil
+}
+
+// WriteZone writes an RFC 1035 zone file (with the domain as origin) for the resources in
+// results, and returns the collisions found. The zone has A and AAAA records only, along
+// with placeholder SOA and NS records pointing at "ns.<domain>".
+func (ne *NamesExporter) WriteZone(w io.Writer, results []*discovery.Result) ([]NameCollision, error) {
+	records, collisions := ne.Records(results)
+	if ne.domain == "" {
+		return collisions, fmt.Errorf("a domain is required for zone files")
+	}
+
+	body := &bytes.Buffer{}
+	for _, record := range records {
+		recordType := "A"
+		if ip := net.ParseIP(record.Address); ip != nil && ip.To4() == nil {
+			recordType = "AAAA"
+		}
+		fmt.Fprintf(body, "%-40s IN %-4s %s ; %s\n", record.Name, recordType, record.Address, record.Key)
+	}
+
+	serial := ne.zoneSerial
+	if serial == 0 {
+		hash := fnv.New32a()
+		hash.Write(body.Bytes())
+		serial = hash.Sum32()
+	}
+	ttl := int(ne.ttl.Seconds())
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "; Generated by discovery, do not edit: changes will be overwritten.")
+	fmt.Fprintf(bw, "$ORIGIN %s.\n", ne.domain)
+	fmt.Fprintf(bw, "$TTL %d\n", ttl)
+	fmt.Fprintf(bw, "@ IN SOA ns.%s. hostmaster.%s. ( %d %d %d %d %d )\n", ne.domain, ne.domain, serial, ttl, ttl/5, ttl*100, ttl)
+	fmt.Fprintf(bw, "@ IN NS ns.%s.\n", ne.domain)
+	fmt.Fprintln(bw)
+	bw.Write(body.Bytes())
+	if err := bw.Flush(); err != nil {
+		return collisions, fmt.Errorf("failed to write zone: %v", err)
+	}
+	return collisions, nil
+}
+
+// WriteZoneFile writes the zone for the resources in results to a file (see WriteZone).
+// The file is replaced atomically, and not written at all if its contents would not change.
+func (ne *NamesExporter) WriteZoneFile(path string, results []*discovery.Result) ([]NameCollision, error) {
+	buf := &bytes.Buffer{}
+	collisions, err := ne.WriteZone(buf, results)
+	if err != nil {
+		return collisions, err
+	}
+	if existing, err := os.ReadFile(path); err == nil && bytes.Equal(existing, buf.Bytes()) {
+		return collisions, nil
+	}
+	if err := utils.WriteFileAtomically(path, buf.Bytes(), ne.fileMode); err != nil {
+		return collisions, fmt.Errorf("failed to write zone file: %v", err)
+	}
+	return collisions, nil
+}
+
+func (ne *NamesExporter) beginMarker() string {
+	return fmt.Sprintf("# BEGIN %s managed block", ne.blockName)
+}
+
+func (ne *NamesExporter) endMarker() string {
+	return fmt.Sprintf("# END %s managed block", ne.blockName)
+}
+
+func (ne *NamesExporter) hostsBlock(records []NameRecord) []byte {
+	// a hosts file line has all the names of an address
+	names := map[string][]string{}
+	for _, record := range records {
+		if ne.domain != "" {
+			names[record.Address] = append(names[record.Address], record.Name+"."+ne.domain)
+		}
+		names[record.Address] = append(names[record.Address], record.Name)
+	}
+	addresses := sortedKeys(names)
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, ne.beginMarker())
+	fmt.Fprintln(buf, "# Generated by discovery, do not edit: changes will be overwritten.")
+	for _, address := range addresses {
+		fmt.Fprintf(buf, "%s\t%s\n", address, strings.Join(names[address], " "))
+	}
+	fmt.Fprintln(buf, ne.endMarker())
+	return buf.Bytes()
+}
+
+func (ne *NamesExporter) records(entries []inventory.Entry) ([]NameRecord, []NameCollision) {
+	byName := map[string]NameRecord{}
+	collisions := []NameCollision{}
+
+	add := func(name, address, key string) {
+		name = sanitizeDnsName(name)
+		if name == "" || address == "" {
+			return
+		}
+		existing, ok := byName[name]
+		if !ok {
+			byName[name] = NameRecord{Name: name, Address: address, Key: key}
+			return
+		}
+		if existing.Address != address {
+			collisions = append(collisions, NameCollision{
+				Name:            name,
+				Address:         address,
+				Key:             key,
+				ExistingAddress: existing.Address,
+				ExistingKey:     existing.Key,
+			})
+		}
+	}
+
+	for _, entry := range entries {
+		resource := entry.Resource
+		switch {
+		case resource.AwsEc2InstanceDetails != nil:
+			details := resource.AwsEc2InstanceDetails
+			name := details.Tags["Name"]
+			if name == "" {
+				continue
+			}
+			add(name, ec2Address(details, ne.preferPublicAddress, ne.includeUnreachable), entry.Key)
+
+		case resource.DockerContainerDetails != nil:
+			details := resource.DockerContainerDetails
+			address := dockerContainerAddress(details)
+			for _, name := range details.Names {
+				add(strings.TrimPrefix(name, "/"), address, entry.Key)
+			}
+
+		case resource.KubernetesServiceDetails != nil:
+			details := resource.KubernetesServiceDetails
+			if details.ClusterIp == "" || details.ClusterIp == "None" { // headless services have no cluster ip
+				continue
+			}
+			add(fmt.Sprintf("%s.%s", details.Name, details.Namespace), details.ClusterIp, entry.Key)
+
+		default:
+			base, ok := resource.NetworkBaseDetails()
+			if !ok {
+				continue
+			}
+			for _, hostname := range base.HostNames {
+				add(ne.relativeName(hostname), base.IpAddress, entry.Key)
+			}
+		}
+	}
+
+	records := make([]NameRecord, 0, len(byName))
+	for _, name := range sortedKeys(byName) {
+		records = append(records, byName[name])
+	}
+	sort.SliceStable(collisions, func(i, j int) bool { return collisions[i].Name < collisions[j].Name })
+	return records, collisions
+}
+
+// relativeName returns a (fully qualified) name relative to the domain if it
+// is within the domain, otherwise the first label of the name.
+func (ne *NamesExporter) relativeName(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if ne.domain != "" {
+		if relative, ok := strings.CutSuffix(name, "."+strings.ToLower(ne.domain)); ok {
+			return relative
+		}
+	}
+	label, _, _ := strings.Cut(name, ".")
+	return label
+}
+
+// dockerContainerAddress returns the address of the port bindings of a Docker container,
+// the loopback address for bindings on all interfaces of the Docker host.
+func dockerContainerAddress(details *discovery.DockerContainerDetails) string {
+	for _, binding := range sortedKeys(details.PortBindings) {
+		host, _, err := net.SplitHostPort(binding)
+		if err != nil {
+			continue
+		}
+		switch host {
+		case "0.0.0.0":
+			return "127.0.0.1"
+		case "::":
+			return "::1"
+		}
+		return host
+	}
+	return ""
+}
+
+// sanitizeDnsName returns a lowercase name with only valid DNS label characters
+// (others are replaced with dashes), and without empty or dash-bounded labels.
+func sanitizeDnsName(name string) string {
+	labels := []string{}
+	for _, label := range strings.Split(strings.ToLower(name), ".") {
+		label = strings.Trim(strings.Map(func(r rune) rune {
+			if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+				return r
+			}
+			return '-'
+		}, label), "-")
+		if len(label) > 63 {
+			label = strings.TrimRight(label[:63], "-")
+		}
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return strings.Join(labels, ".")
+}
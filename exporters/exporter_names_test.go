@@ -0,0 +1,102 @@
+package exporters
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// expectedNameCollisions are the collisions of newExporterTestResults, by name.
+var expectedNameCollisions = []NameCollision{
+	{
+		Name:            "web-1",
+		Address:         "10.0.1.11",
+		Key:             "aws_ec2_instance:arn:aws:ec2:us-east-1:123456789012:instance/i-0b",
+		ExistingAddress: "10.0.1.10",
+		ExistingKey:     "aws_ec2_instance:arn:aws:ec2:us-east-1:123456789012:instance/i-0a",
+	},
+	{
+		Name:            "web-1",
+		Address:         "::1",
+		Key:             "docker_container:c2",
+		ExistingAddress: "10.0.1.10",
+		ExistingKey:     "aws_ec2_instance:arn:aws:ec2:us-east-1:123456789012:instance/i-0a",
+	},
+}
+
+func assertNameCollisions(t *testing.T, collisions []NameCollision) {
+	t.Helper()
+
+	if len(collisions) != len(expectedNameCollisions) {
+		t.Fatalf("expected %d collisions, got %v", len(expectedNameCollisions), collisions)
+	}
+	for i, collision := range collisions {
+		if collision != expectedNameCollisions[i] {
+			t.Fatalf("expected collision %s, got %s", expectedNameCollisions[i], collision)
+		}
+	}
+}
+
+func TestNamesExporterWriteHostsBlock(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := NewNamesExporter(WithNamesExporterDomain("lab.internal."))
+	collisions, err := exporter.WriteHostsBlock(&buf, newExporterTestResults())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertNameCollisions(t, collisions)
+	assertGolden(t, "hosts_block", buf.Bytes())
+}
+
+func TestNamesExporterUpdateHostsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hosts")
+	original := "127.0.0.1\tlocalhost\n# BEGIN discovery managed block\nstale\n# END discovery managed block\n::1\tlocalhost\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("failed to write hosts file: %v", err)
+	}
+
+	exporter := NewNamesExporter(WithNamesExporterDomain("lab.internal"))
+	for i := 0; i < 2; i++ { // updates are idempotent
+		collisions, err := exporter.UpdateHostsFile(path, newExporterTestResults())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assertNameCollisions(t, collisions)
+	}
+
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read hosts file: %v", err)
+	}
+	assertGolden(t, "hosts_file", byt)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat hosts file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the file mode of the existing file to be kept, got %v", info.Mode().Perm())
+	}
+
+	if err := os.WriteFile(path, []byte("# BEGIN discovery managed block\n"), 0600); err != nil {
+		t.Fatalf("failed to write hosts file: %v", err)
+	}
+	if _, err := exporter.UpdateHostsFile(path, newExporterTestResults()); err == nil {
+		t.Fatal("expected an error for a block without an end marker")
+	}
+}
+
+func TestNamesExporterWriteZone(t *testing.T) {
+	var buf bytes.Buffer
+	exporter := NewNamesExporter(WithNamesExporterDomain("lab.internal"), WithNamesExporterZoneSerial(2026010101))
+	collisions, err := exporter.WriteZone(&buf, newExporterTestResults())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertNameCollisions(t, collisions)
+	assertGolden(t, "zone", buf.Bytes())
+
+	if _, err := NewNamesExporter(WithNamesExporterDomain("")).WriteZone(&buf, newExporterTestResults()); err == nil {
+		t.Fatal("expected an error for a zone without a domain")
+	}
+}
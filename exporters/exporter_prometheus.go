@@ -0,0 +1,305 @@
+package exporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/formats"
+	"github.com/borderzero/discovery/inventory"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	defaultPrometheusExporterLabelPrefix = "__meta_discovery_"
+	defaultPrometheusExporterEc2Port     = 9100 // node_exporter
+	defaultPrometheusExporterPortTag     = "prometheus_port"
+	defaultPrometheusExporterFileMode    = os.FileMode(0644)
+)
+
+// PrometheusTargetGroup represents a group of Prometheus targets sharing the same
+// labels, as read by Prometheus' file_sd and http_sd service discovery mechanisms.
+type PrometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// PrometheusExporter represents an exporter of discovered resources as Prometheus
+// service discovery targets. Targets are produced for EC2 instances, kubernetes
+// services, Docker containers and network HTTP(S) servers, one target group per
+// resource.
+//
+// All labels are named with a common prefix (by default "__meta_discovery_") and
+// only contain characters valid in Prometheus label names, so that they can be used
+// in relabel_configs and are dropped after relabeling unless explicitly kept, e.g.
+//
+//   - source_labels: [__meta_discovery_tag_Name]
+//     target_label: instance
+type PrometheusExporter struct {
+	labelPrefix         string
+	portTag             string
+	ec2Port             int
+	ec2UsePublicAddress bool
+	kubernetesPortNames []string
+	dockerPrivatePorts  []int
+	fileMode            os.FileMode
+}
+
+// PrometheusExporterOption represents a configuration option for a PrometheusExporter.
+type PrometheusExporterOption func(*PrometheusExporter)
+
+// WithPrometheusExporterLabelPrefix is the PrometheusExporterOption
+// to set a non default prefix for the names of all labels.
+func WithPrometheusExporterLabelPrefix(prefix string) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.labelPrefix = prefix }
+}
+
+// WithPrometheusExporterPortTag is the PrometheusExporterOption to set a non default
+// tag (or kubernetes/Docker label) which, when present on a resource, overrides the
+// port selected for its targets (for Docker containers, the value selects the port
+// binding of that container port). Set to the empty string to disable overrides.
+func WithPrometheusExporterPortTag(tag string) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.portTag = tag }
+}
+
+// WithPrometheusExporterEc2Port is the PrometheusExporterOption
+// to set a non default port for the targets of EC2 instances.
+func WithPrometheusExporterEc2Port(port int) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.ec2Port = port }
+}
+
+// WithPrometheusExporterEc2UsePublicAddress is the PrometheusExporterOption to use the
+// public (rather than private) IP address of EC2 instances as their target address.
+// Instances without a public IP address are then skipped.
+func WithPrometheusExporterEc2UsePublicAddress(usePublic bool) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.ec2UsePublicAddress = usePublic }
+}
+
+// WithPrometheusExporterKubernetesPortNames is the PrometheusExporterOption to only
+// produce targets for the kubernetes service ports with the given names (e.g. "metrics").
+// By default, a target is produced for every port of a service.
+func WithPrometheusExporterKubernetesPortNames(names ...string) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.kubernetesPortNames = names }
+}
+
+// WithPrometheusExporterDockerPrivatePorts is the PrometheusExporterOption to only produce
+// targets for Docker port bindings of the given container (private) ports. By default, a
+// target is produced for every port binding of a container.
+func WithPrometheusExporterDockerPrivatePorts(ports ...int) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.dockerPrivatePorts = ports }
+}
+
+// WithPrometheusExporterFileMode is the PrometheusExporterOption
+// to set a non default file mode for files written by WriteFileSd.
+func WithPrometheusExporterFileMode(mode os.FileMode) PrometheusExporterOption {
+	return func(pe *PrometheusExporter) { pe.fileMode = mode }
+}
+
+// NewPrometheusExporter returns a new PrometheusExporter, initialized with the given options.
+func NewPrometheusExporter(opts ...PrometheusExporterOption) *PrometheusExporter {
+	pe := &PrometheusExporter{
+		labelPrefix: defaultPrometheusExporterLabelPrefix,
+		portTag:     defaultPrometheusExporterPortTag,
+		ec2Port:     defaultPrometheusExporterEc2Port,
+		fileMode:    defaultPrometheusExporterFileMode,
+	}
+	for _, opt := range opts {
+		opt(pe)
+	}
+	return pe
+}
+
+// TargetGroups returns the target groups for the resources in results, sorted by resource key.
+func (pe *PrometheusExporter) TargetGroups(results []*discovery.Result) []PrometheusTargetGroup {
+	return pe.targetGroups(formats.SortedEntries(results))
+}
+
+// WriteFileSd writes the target groups for the resources in results to a file
+// in the file_sd format. The file is replaced atomically, so Prometheus never
+// reads a partially written file.
+func (pe *PrometheusExporter) WriteFileSd(path string, results []*discovery.Result) error {
+	byt, err := json.MarshalIndent(pe.TargetGroups(results), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to json encode target groups: %v", err)
+	}
+	if err := utils.WriteFileAtomically(path, append(byt, '\n'), pe.fileMode); err != nil {
+		return fmt.Errorf("failed to write file_sd file: %v", err)
+	}
+	return nil
+}
+
+// HttpSdHandler returns an http.Handler serving the target groups for the
+// resources currently in an Inventory, in the http_sd format.
+func (pe *PrometheusExporter) HttpSdHandler(inv *inventory.Inventory) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		byt, err := json.Marshal(pe.targetGroups(inv.List(inventory.Filter{})))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("failed to json encode target groups: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(byt)
+	})
+}
+
+func (pe *PrometheusExporter) targetGroups(entries []inventory.Entry) []PrometheusTargetGroup {
+	groups := []PrometheusTargetGroup{}
+	for _, entry := range entries {
+		targets, labels := pe.targets(entry.Resource)
+		if len(targets) == 0 {
+			continue
+		}
+
+		groupLabels := map[string]string{
+			pe.labelPrefix + "key":           entry.Key,
+			pe.labelPrefix + "discoverer_id": entry.DiscovererId,
+			pe.labelPrefix + "resource_type": entry.Resource.ResourceType,
+		}
+		for name, value := range labels {
+			if value != "" {
+				groupLabels[pe.labelPrefix+name] = value
+			}
+		}
+		for tag, value := range entry.Resource.Tags() {
+			groupLabels[pe.labelPrefix+"tag_"+sanitizeIdentifier(tag)] = value
+		}
+		// the scheme is not prefixed, it is read by Prometheus itself
+		if entry.Resource.ResourceType == discovery.ResourceTypeNetworkHttpsServer {
+			groupLabels["__scheme__"] = "https"
+		}
+
+		groups = append(groups, PrometheusTargetGroup{Targets: targets, Labels: groupLabels})
+	}
+	return groups
+}
+
+// targets returns the targets (host:port) and the type-specific labels for a resource.
+func (pe *PrometheusExporter) targets(resource discovery.Resource) ([]string, map[string]string) {
+	portOverride := 0
+	if pe.portTag != "" {
+		if value, ok := resource.Tags()[pe.portTag]; ok {
+			if port, err := strconv.Atoi(value); err == nil && port > 0 && port < 65536 {
+				portOverride = port
+			}
+		}
+	}
+
+	switch resource.ResourceType {
+	case discovery.ResourceTypeAwsEc2Instance:
+		if resource.AwsEc2InstanceDetails == nil {
+			return nil, nil
+		}
+		details := resource.AwsEc2InstanceDetails
+		address := details.PrivateIpAddress
+		if pe.ec2UsePublicAddress {
+			address = details.PublicIpAddress
+		}
+		if address == "" {
+			return nil, nil
+		}
+		port := pe.ec2Port
+		if portOverride != 0 {
+			port = portOverride
+		}
+		return []string{net.JoinHostPort(address, strconv.Itoa(port))}, map[string]string{
+			"region":            details.AwsRegion,
+			"account":           details.AwsAccountId,
+			"instance_id":       details.InstanceId,
+			"instance_type":     details.InstanceType,
+			"instance_state":    details.InstanceState,
+			"vpc_id":            details.VpcId,
+			"subnet_id":         details.SubnetId,
+			"availability_zone": details.AvailabilityZone,
+			"private_ip":        details.PrivateIpAddress,
+			"public_ip":         details.PublicIpAddress,
+		}
+
+	case discovery.ResourceTypeKubernetesService:
+		if resource.KubernetesServiceDetails == nil {
+			return nil, nil
+		}
+		details := resource.KubernetesServiceDetails
+		if details.ClusterIp == "" || details.ClusterIp == "None" { // headless services have no cluster ip
+			return nil, nil
+		}
+		targets := []string{}
+		if portOverride != 0 {
+			targets = append(targets, net.JoinHostPort(details.ClusterIp, strconv.Itoa(portOverride)))
+		} else {
+			for _, port := range details.Ports {
+				if len(pe.kubernetesPortNames) > 0 && !contains(pe.kubernetesPortNames, port.Name) {
+					continue
+				}
+				targets = append(targets, net.JoinHostPort(details.ClusterIp, strconv.Itoa(int(port.Port))))
+			}
+		}
+		return targets, map[string]string{
+			"namespace":    details.Namespace,
+			"service_name": details.Name,
+			"service_type": details.ServiceType,
+		}
+
+	case discovery.ResourceTypeDockerContainer:
+		if resource.DockerContainerDetails == nil {
+			return nil, nil
+		}
+		details := resource.DockerContainerDetails
+		targets := []string{}
+		for _, hostAddress := range sortedKeys(details.PortBindings) {
+			host, hostPort, err := net.SplitHostPort(hostAddress)
+			if err != nil {
+				continue
+			}
+			// the value of a port binding is "<private port>/<protocol>"
+			privatePort, protocol, _ := strings.Cut(details.PortBindings[hostAddress], "/")
+			if protocol != "" && protocol != "tcp" {
+				continue
+			}
+			if len(pe.dockerPrivatePorts) > 0 && !contains(pe.dockerPrivatePorts, atoi(privatePort)) {
+				continue
+			}
+			if host == "0.0.0.0" || host == "::" {
+				host = "localhost" // bound to all interfaces of the Docker host
+			}
+			if portOverride != 0 && atoi(privatePort) != portOverride {
+				continue
+			}
+			targets = append(targets, net.JoinHostPort(host, hostPort))
+		}
+		containerName := ""
+		if len(details.Names) > 0 {
+			containerName = strings.TrimPrefix(details.Names[0], "/")
+		}
+		return dedupe(targets), map[string]string{
+			"container_id":   details.ContainerId,
+			"container_name": containerName,
+			"image":          details.Image,
+		}
+
+	case discovery.ResourceTypeNetworkHttpServer, discovery.ResourceTypeNetworkHttpsServer:
+		base, ok := resource.NetworkBaseDetails()
+		if !ok {
+			return nil, nil
+		}
+		port := base.Port
+		if portOverride != 0 {
+			port = strconv.Itoa(portOverride)
+		}
+		labels := map[string]string{"ip_address": base.IpAddress}
+		if len(base.HostNames) > 0 {
+			labels["hostname"] = base.HostNames[0]
+		}
+		return []string{net.JoinHostPort(base.IpAddress, port)}, labels
+	}
+	return nil, nil
+}
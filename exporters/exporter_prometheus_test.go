@@ -0,0 +1,132 @@
+package exporters
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/borderzero/discovery/inventory"
+)
+
+func TestPrometheusExporterWriteFileSd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "targets", "discovery.json")
+
+	if err := NewPrometheusExporter().WriteFileSd(path, newExporterTestResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file_sd file: %v", err)
+	}
+	assertGolden(t, "prometheus_file_sd.json", byt)
+}
+
+func TestPrometheusExporterTargets(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            []PrometheusExporterOption
+		expectedTargets []string
+	}{
+		{
+			name: "defaults",
+			expectedTargets: []string{
+				"10.0.1.10:9100",
+				"10.0.1.11:9100",
+				"10.0.1.12:9100",
+				"localhost:8080", // bound to all interfaces, over both ipv4 and ipv6
+				"127.0.0.1:9090",
+				"localhost:80",
+				"10.96.0.10:80",
+				"10.96.0.10:9090",
+				"10.0.2.5:80",
+				"10.0.2.6:443",
+			},
+		},
+		{
+			name: "ec2 public addresses",
+			opts: []PrometheusExporterOption{WithPrometheusExporterEc2UsePublicAddress(true), WithPrometheusExporterEc2Port(9200)},
+			expectedTargets: []string{
+				"54.0.0.10:9200",
+				"localhost:8080",
+				"127.0.0.1:9090",
+				"localhost:80",
+				"10.96.0.10:80",
+				"10.96.0.10:9090",
+				"10.0.2.5:80",
+				"10.0.2.6:443",
+			},
+		},
+		{
+			name: "kubernetes port names and docker private ports",
+			opts: []PrometheusExporterOption{
+				WithPrometheusExporterKubernetesPortNames("metrics"),
+				WithPrometheusExporterDockerPrivatePorts(9090),
+			},
+			expectedTargets: []string{
+				"10.0.1.10:9100",
+				"10.0.1.11:9100",
+				"10.0.1.12:9100",
+				"127.0.0.1:9090",
+				"10.96.0.10:9090",
+				"10.0.2.5:80",
+				"10.0.2.6:443",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			targets := []string{}
+			for _, group := range NewPrometheusExporter(test.opts...).TargetGroups(newExporterTestResults()) {
+				targets = append(targets, group.Targets...)
+			}
+			if !slices.Equal(targets, test.expectedTargets) {
+				t.Fatalf("expected targets %v, got %v", test.expectedTargets, targets)
+			}
+		})
+	}
+}
+
+func TestPrometheusExporterHttpSdHandler(t *testing.T) {
+	results := newExporterTestResults()
+	exporter := NewPrometheusExporter()
+
+	inv := inventory.NewInventory()
+	for _, result := range results {
+		inv.Update(result)
+	}
+	server := httptest.NewServer(exporter.HttpSdHandler(inv))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	// the inventory serves the same targets as the results it was updated with
+	expected, err := json.Marshal(exporter.TargetGroups(results))
+	if err != nil {
+		t.Fatalf("failed to json encode target groups: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK || string(body) != string(expected) {
+		t.Fatalf("expected status %d with the target groups of the results, got %d %s", http.StatusOK, resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(server.URL, "application/json", nil)
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d, got %d", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+}
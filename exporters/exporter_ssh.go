@@ -0,0 +1,235 @@
+package exporters
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/formats"
+	"github.com/borderzero/discovery/inventory"
+)
+
+const (
+	defaultSshExporterNameTag     = "Name"
+	defaultSshExporterDefaultPort = "22"
+
+	sshExporterHeader = "# Generated by discovery, do not edit: changes will be overwritten."
+)
+
+// SshExporter represents an exporter of discovered resources as an OpenSSH client
+// configuration fragment (to be included from ~/.ssh/config with an Include directive)
+// and as known_hosts entries, so that `ssh <name>` works for every discovered EC2
+// instance and SSH server on the network.
+//
+// Host names (aliases) are the value of the Name tag of EC2 instances (or the instance
+// id if missing) and the reverse DNS name of SSH servers on the network (or the IP
+// address if missing). Aliases are sanitized, and made unique by suffixing them with
+// the instance id (EC2 instances) or a counter (SSH servers) in case of collisions.
+type SshExporter struct {
+	hostPrefix          string
+	nameTag             string
+	user                string
+	identityFile        string
+	includeUnreachable  bool
+	preferPublicAddress bool
+}
+
+// SshExporterOption represents a configuration option for an SshExporter.
+type SshExporterOption func(*SshExporter)
+
+// WithSshExporterHostPrefix is the SshExporterOption to set a prefix
+// for all host aliases (e.g. "aws-"), by default aliases have no prefix.
+func WithSshExporterHostPrefix(prefix string) SshExporterOption {
+	return func(se *SshExporter) { se.hostPrefix = prefix }
+}
+
+// WithSshExporterNameTag is the SshExporterOption to set a non default
+// EC2 instance tag from which host aliases are derived.
+func WithSshExporterNameTag(tag string) SshExporterOption {
+	return func(se *SshExporter) { se.nameTag = tag }
+}
+
+// WithSshExporterUser is the SshExporterOption to set the User for all hosts.
+func WithSshExporterUser(user string) SshExporterOption {
+	return func(se *SshExporter) { se.user = user }
+}
+
+// WithSshExporterIdentityFile is the SshExporterOption to set the IdentityFile for all hosts.
+func WithSshExporterIdentityFile(path string) SshExporterOption {
+	return func(se *SshExporter) { se.identityFile = path }
+}
+
+// WithSshExporterIncludeUnreachable is the SshExporterOption to also include EC2
+// instances for which no address was found to be reachable (the private address is
+// then used). By default such instances are skipped.
+func WithSshExporterIncludeUnreachable(include bool) SshExporterOption {
+	return func(se *SshExporter) { se.includeUnreachable = include }
+}
+
+// WithSshExporterPreferPublicAddress is the SshExporterOption to prefer the public
+// (rather than private) address of EC2 instances when both are reachable.
+func WithSshExporterPreferPublicAddress(preferPublic bool) SshExporterOption {
+	return func(se *SshExporter) { se.preferPublicAddress = preferPublic }
+}
+
+// NewSshExporter returns a new SshExporter, initialized with the given options.
+func NewSshExporter(opts ...SshExporterOption) *SshExporter {
+	se := &SshExporter{
+		nameTag: defaultSshExporterNameTag,
+	}
+	for _, opt := range opts {
+		opt(se)
+	}
+	return se
+}
+
+// sshHost represents a Host block of an ssh_config file.
+type sshHost struct {
+	alias    string
+	hostName string
+	port     string
+	key      string
+}
+
+// WriteSshConfig writes an ssh_config fragment with a Host block for every
+// EC2 instance and SSH server on the network in results, sorted by resource key.
+func (se *SshExporter) WriteSshConfig(w io.Writer, results []*discovery.Result) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, sshExporterHeader)
+	for _, host := range se.hosts(formats.SortedEntries(results)) {
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "# %s\n", host.key)
+		fmt.Fprintf(bw, "Host %s\n", host.alias)
+		fmt.Fprintf(bw, "  HostName %s\n", host.hostName)
+		if host.port != "" && host.port != defaultSshExporterDefaultPort {
+			fmt.Fprintf(bw, "  Port %s\n", host.port)
+		}
+		if se.user != "" {
+			fmt.Fprintf(bw, "  User %s\n", se.user)
+		}
+		if se.identityFile != "" {
+			fmt.Fprintf(bw, "  IdentityFile %s\n", se.identityFile)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write ssh config: %v", err)
+	}
+	return nil
+}
+
+// WriteKnownHosts writes known_hosts entries for the host keys captured for
+// SSH servers on the network in results (see discoverers.WithNetworkDiscovererSshHostKeyScan).
+// Entries match both the IP address and the reverse DNS names of servers.
+func (se *SshExporter) WriteKnownHosts(w io.Writer, results []*discovery.Result) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, sshExporterHeader)
+	seen := map[string]struct{}{}
+	for _, entry := range formats.SortedEntries(results) {
+		details := entry.Resource.NetworkSshServerDetails
+		if details == nil || len(details.HostKeys) == 0 {
+			continue
+		}
+		hosts := []string{knownHostsHost(details.IpAddress, details.Port)}
+		for _, hostname := range details.HostNames {
+			hosts = append(hosts, knownHostsHost(strings.TrimSuffix(hostname, "."), details.Port))
+		}
+		for _, hostKey := range details.HostKeys {
+			line := fmt.Sprintf("%s %s", strings.Join(hosts, ","), hostKey)
+			if _, ok := seen[line]; ok {
+				continue // e.g. same server found by multiple discoverers
+			}
+			seen[line] = struct{}{}
+			fmt.Fprintln(bw, line)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write known hosts: %v", err)
+	}
+	return nil
+}
+
+func (se *SshExporter) hosts(entries []inventory.Entry) []sshHost {
+	hosts := []sshHost{}
+	aliases := map[string]struct{}{}
+
+	unique := func(alias, suffix string) string {
+		if _, ok := aliases[alias]; !ok {
+			return alias
+		}
+		if suffix != "" {
+			if _, ok := aliases[alias+"-"+suffix]; !ok {
+				return alias + "-" + suffix
+			}
+		}
+		for i := 2; ; i++ {
+			candidate := fmt.Sprintf("%s-%d", alias, i)
+			if _, ok := aliases[candidate]; !ok {
+				return candidate
+			}
+		}
+	}
+
+	keys := map[string]struct{}{}
+	for _, entry := range entries {
+		if _, ok := keys[entry.Key]; ok {
+			continue // e.g. same server found by multiple discoverers
+		}
+		keys[entry.Key] = struct{}{}
+
+		var host sshHost
+		var suffix string
+
+		switch {
+		case entry.Resource.AwsEc2InstanceDetails != nil:
+			details := entry.Resource.AwsEc2InstanceDetails
+			address := ec2Address(details, se.preferPublicAddress, se.includeUnreachable)
+			if address == "" {
+				continue
+			}
+			name := details.Tags[se.nameTag]
+			if name == "" {
+				name = details.InstanceId
+			}
+			host = sshHost{alias: name, hostName: address}
+			suffix = details.InstanceId
+
+		case entry.Resource.NetworkSshServerDetails != nil:
+			details := entry.Resource.NetworkSshServerDetails
+			name := details.IpAddress
+			if len(details.HostNames) > 0 {
+				name = strings.TrimSuffix(details.HostNames[0], ".")
+			}
+			host = sshHost{alias: name, hostName: details.IpAddress, port: details.Port}
+
+		default:
+			continue
+		}
+
+		host.key = entry.Key
+		host.alias = unique(se.hostPrefix+sanitizeSshAlias(host.alias), suffix)
+		aliases[host.alias] = struct{}{}
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
+// sanitizeSshAlias replaces all characters which are not safe in ssh_config host
+// patterns (whitespace, wildcards, negations, lists, ...) with dashes.
+func sanitizeSshAlias(alias string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '.' || r == '_' || r == '-' {
+			return r
+		}
+		return '-'
+	}, alias)
+}
+
+// knownHostsHost returns the host field of a known_hosts entry for a host and port.
+func knownHostsHost(host, port string) string {
+	if port == "" || port == defaultSshExporterDefaultPort {
+		return host
+	}
+	return fmt.Sprintf("[%s]:%s", host, port)
+}
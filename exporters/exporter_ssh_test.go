@@ -0,0 +1,47 @@
+package exporters
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSshExporterWriteSshConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []SshExporterOption
+		golden string
+	}{
+		{
+			name:   "defaults",
+			golden: "ssh_config",
+		},
+		{
+			name: "with options",
+			opts: []SshExporterOption{
+				WithSshExporterHostPrefix("lab-"),
+				WithSshExporterUser("ec2-user"),
+				WithSshExporterIdentityFile("~/.ssh/lab"),
+				WithSshExporterIncludeUnreachable(true),
+				WithSshExporterPreferPublicAddress(true),
+			},
+			golden: "ssh_config_options",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewSshExporter(test.opts...).WriteSshConfig(&buf, newExporterTestResults()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertGolden(t, test.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestSshExporterWriteKnownHosts(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewSshExporter().WriteKnownHosts(&buf, newExporterTestResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertGolden(t, "known_hosts", buf.Bytes())
+}
@@ -0,0 +1,392 @@
+package exporters
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/formats"
+	"github.com/borderzero/discovery/inventory"
+)
+
+const (
+	defaultTerraformExporterResourceStubs   = true
+	defaultTerraformExporterRegionProviders = false
+
+	terraformExporterHeader = "# Generated by discovery. Review before applying: resource stubs are incomplete\n" +
+		"# skeletons, alternatively remove them and run `terraform plan -generate-config-out=...`."
+)
+
+// terraformResourceTypes are the terraform (AWS provider) resource types of the discovered resource types.
+var terraformResourceTypes = map[string]string{
+	discovery.ResourceTypeAwsEc2Instance: "aws_instance",
+	discovery.ResourceTypeAwsRdsInstance: "aws_db_instance",
+	discovery.ResourceTypeAwsRdsCluster:  "aws_rds_cluster",
+	discovery.ResourceTypeAwsRdsProxy:    "aws_db_proxy",
+	discovery.ResourceTypeAwsEksCluster:  "aws_eks_cluster",
+	discovery.ResourceTypeAwsEcsService:  "aws_ecs_service",
+}
+
+// TerraformExporter represents an exporter of discovered AWS resources (EC2 instances,
+// RDS instances, clusters and proxies, EKS clusters and ECS services) as Terraform import blocks, with
+// skeleton resource blocks, for adopting unmanaged infrastructure. When given local
+// terraform state files, resources already in state are skipped and drift reports
+// (i.e. resources which exist in AWS but not in state) can be produced.
+type TerraformExporter struct {
+	stateFiles      []string
+	resourceStubs   bool
+	regionProviders bool
+}
+
+// TerraformExporterOption represents a configuration option for a TerraformExporter.
+type TerraformExporterOption func(*TerraformExporter)
+
+// WithTerraformExporterStateFiles is the TerraformExporterOption to set local terraform
+// state files (e.g. terraform.tfstate, one per workspace) to compare discovered resources
+// against. Resources found in any of the state files are considered managed.
+func WithTerraformExporterStateFiles(paths ...string) TerraformExporterOption {
+	return func(te *TerraformExporter) { te.stateFiles = paths }
+}
+
+// WithTerraformExporterResourceStubs is the TerraformExporterOption to enable
+// or disable writing skeleton resource blocks alongside the import blocks.
+func WithTerraformExporterResourceStubs(enabled bool) TerraformExporterOption {
+	return func(te *TerraformExporter) { te.resourceStubs = enabled }
+}
+
+// WithTerraformExporterRegionProviders is the TerraformExporterOption to set the provider
+// of every import (and resource) block to a provider alias named after the region of the
+// resource (e.g. aws.us_east_1), for configurations with one aliased provider per region.
+func WithTerraformExporterRegionProviders(enabled bool) TerraformExporterOption {
+	return func(te *TerraformExporter) { te.regionProviders = enabled }
+}
+
+// NewTerraformExporter returns a new TerraformExporter, initialized with the given options.
+func NewTerraformExporter(opts ...TerraformExporterOption) *TerraformExporter {
+	te := &TerraformExporter{
+		resourceStubs:   defaultTerraformExporterResourceStubs,
+		regionProviders: defaultTerraformExporterRegionProviders,
+	}
+	for _, opt := range opts {
+		opt(te)
+	}
+	return te
+}
+
+// TerraformDriftReport represents the result of comparing discovered resources with terraform state.
+type TerraformDriftReport struct {
+	// Managed are the discovered resources found in terraform state.
+	Managed []inventory.Entry `json:"managed"`
+	// Unmanaged are the discovered resources not found in terraform state.
+	Unmanaged []inventory.Entry `json:"unmanaged"`
+}
+
+// WriteText writes a drift report as text, one unmanaged resource per line.
+func (r *TerraformDriftReport) WriteText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%d managed, %d unmanaged resource(s)\n", len(r.Managed), len(r.Unmanaged))
+	for _, entry := range r.Unmanaged {
+		fmt.Fprintf(bw, "unmanaged\t%s\t%s\t%s\n", terraformResourceTypes[entry.Resource.ResourceType], terraformImportId(entry.Resource), entry.Key)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write drift report: %v", err)
+	}
+	return nil
+}
+
+// WriteImports writes import blocks (and, if enabled, skeleton resource blocks) for the
+// supported AWS resources in results, skipping resources already in the state files.
+func (te *TerraformExporter) WriteImports(w io.Writer, results []*discovery.Result) error {
+	entries := terraformEntries(formats.SortedEntries(results))
+	if len(te.stateFiles) > 0 {
+		report, err := te.driftReport(entries)
+		if err != nil {
+			return err
+		}
+		entries = report.Unmanaged
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, terraformExporterHeader)
+
+	names := map[string]struct{}{}
+	for _, entry := range entries {
+		resourceType := terraformResourceTypes[entry.Resource.ResourceType]
+		name := uniqueTerraformName(names, resourceType, terraformName(entry.Resource))
+		address := fmt.Sprintf("%s.%s", resourceType, name)
+
+		provider := ""
+		if te.regionProviders {
+			if region := awsBaseDetails(entry.Resource).AwsRegion; region != "" {
+				provider = "aws." + sanitizeIdentifier(region)
+			}
+		}
+
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "# %s\n", entry.Key)
+		fmt.Fprintln(bw, "import {")
+		fmt.Fprintf(bw, "  to = %s\n", address)
+		fmt.Fprintf(bw, "  id = %s\n", hclString(terraformImportId(entry.Resource)))
+		if provider != "" {
+			fmt.Fprintf(bw, "  provider = %s\n", provider)
+		}
+		fmt.Fprintln(bw, "}")
+
+		if te.resourceStubs {
+			fmt.Fprintln(bw)
+			writeTerraformStub(bw, resourceType, name, provider, entry.Resource)
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write terraform imports: %v", err)
+	}
+	return nil
+}
+
+// DriftReport compares the supported AWS resources in results with the state files.
+func (te *TerraformExporter) DriftReport(results []*discovery.Result) (*TerraformDriftReport, error) {
+	if len(te.stateFiles) == 0 {
+		return nil, fmt.Errorf("no terraform state files configured")
+	}
+	return te.driftReport(terraformEntries(formats.SortedEntries(results)))
+}
+
+func (te *TerraformExporter) driftReport(entries []inventory.Entry) (*TerraformDriftReport, error) {
+	identifiers := map[string]struct{}{}
+	for _, path := range te.stateFiles {
+		if err := readTerraformStateIdentifiers(path, identifiers); err != nil {
+			return nil, err
+		}
+	}
+
+	report := &TerraformDriftReport{
+		Managed:   []inventory.Entry{},
+		Unmanaged: []inventory.Entry{},
+	}
+	for _, entry := range entries {
+		resourceType := terraformResourceTypes[entry.Resource.ResourceType]
+		_, arnFound := identifiers[resourceType+"|"+awsBaseDetails(entry.Resource).AwsArn]
+		_, idFound := identifiers[resourceType+"|"+terraformImportId(entry.Resource)]
+		if arnFound || idFound {
+			report.Managed = append(report.Managed, entry)
+		} else {
+			report.Unmanaged = append(report.Unmanaged, entry)
+		}
+	}
+	return report, nil
+}
+
+// terraformState represents the parts of a (version 4) terraform state file used for drift reports.
+type terraformState struct {
+	Version   int `json:"version"`
+	Resources []struct {
+		Mode      string `json:"mode"`
+		Type      string `json:"type"`
+		Instances []struct {
+			Attributes map[string]any `json:"attributes"`
+		} `json:"instances"`
+	} `json:"resources"`
+}
+
+// readTerraformStateIdentifiers adds the identifiers (arn, id and identifier attributes)
+// of the managed resources in a terraform state file to a set, as "<type>|<identifier>".
+func readTerraformStateIdentifiers(path string, identifiers map[string]struct{}) error {
+	byt, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read terraform state file: %v", err)
+	}
+	var state terraformState
+	if err := json.Unmarshal(byt, &state); err != nil {
+		return fmt.Errorf("failed to decode terraform state file %s: %v", path, err)
+	}
+	if state.Version != 4 {
+		return fmt.Errorf("unsupported terraform state file version %d in %s (must be 4)", state.Version, path)
+	}
+	for _, resource := range state.Resources {
+		if resource.Mode != "managed" {
+			continue
+		}
+		for _, instance := range resource.Instances {
+			for _, attribute := range []string{"arn", "id", "identifier"} {
+				if value, ok := instance.Attributes[attribute].(string); ok && value != "" {
+					identifiers[resource.Type+"|"+value] = struct{}{}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// terraformEntries returns the entries of resource types supported by the TerraformExporter,
+// without duplicates (i.e. the same resource found by multiple discoverers).
+func terraformEntries(entries []inventory.Entry) []inventory.Entry {
+	filtered := []inventory.Entry{}
+	seen := map[string]struct{}{}
+	for _, entry := range entries {
+		if _, ok := terraformResourceTypes[entry.Resource.ResourceType]; !ok {
+			continue
+		}
+		if _, ok := seen[entry.Key]; ok {
+			continue
+		}
+		seen[entry.Key] = struct{}{}
+		filtered = append(filtered, entry)
+	}
+	return filtered
+}
+
+// terraformImportId returns the id with which a resource is imported by the AWS provider.
+func terraformImportId(resource discovery.Resource) string {
+	switch {
+	case resource.AwsEc2InstanceDetails != nil:
+		return resource.AwsEc2InstanceDetails.InstanceId
+	case resource.AwsRdsInstanceDetails != nil:
+		return resource.AwsRdsInstanceDetails.DbInstanceIdentifier
+	case resource.AwsRdsClusterDetails != nil:
+		return resource.AwsRdsClusterDetails.DbClusterIdentifier
+	case resource.AwsRdsProxyDetails != nil:
+		return resource.AwsRdsProxyDetails.DbProxyName
+	case resource.AwsEksClusterDetails != nil:
+		return resource.AwsEksClusterDetails.ClusterName
+	case resource.AwsEcsServiceDetails != nil:
+		return fmt.Sprintf("%s/%s", resource.AwsEcsServiceDetails.ClusterName, resource.AwsEcsServiceDetails.ServiceName)
+	}
+	return ""
+}
+
+// terraformName returns the (not yet sanitized) terraform resource name for a resource.
+func terraformName(resource discovery.Resource) string {
+	if name := resource.Tags()["Name"]; name != "" {
+		return name
+	}
+	if resource.AwsEcsServiceDetails != nil {
+		return fmt.Sprintf("%s_%s", resource.AwsEcsServiceDetails.ClusterName, resource.AwsEcsServiceDetails.ServiceName)
+	}
+	return terraformImportId(resource)
+}
+
+// uniqueTerraformName returns a valid terraform resource name, unique within a resource type.
+func uniqueTerraformName(names map[string]struct{}, resourceType, name string) string {
+	name = strings.ToLower(strings.ReplaceAll(sanitizeIdentifier(name), "__", "_"))
+	if name == "" || !((name[0] >= 'a' && name[0] <= 'z') || name[0] == '_') {
+		name = "r_" + name // names must start with a letter or underscore
+	}
+	candidate := name
+	for i := 2; ; i++ {
+		if _, ok := names[resourceType+"."+candidate]; !ok {
+			break
+		}
+		candidate = fmt.Sprintf("%s_%d", name, i)
+	}
+	names[resourceType+"."+candidate] = struct{}{}
+	return candidate
+}
+
+// writeTerraformStub writes a skeleton resource block with the attributes known from discovery.
+func writeTerraformStub(w io.Writer, resourceType, name, provider string, resource discovery.Resource) {
+	fmt.Fprintf(w, "resource %q %q {\n", resourceType, name)
+	if provider != "" {
+		fmt.Fprintf(w, "  provider = %s\n", provider)
+	}
+	attribute := func(key, value string) {
+		if value != "" {
+			fmt.Fprintf(w, "  %s = %s\n", key, hclString(value))
+		}
+	}
+
+	switch {
+	case resource.AwsEc2InstanceDetails != nil:
+		details := resource.AwsEc2InstanceDetails
+		attribute("ami", details.ImageId)
+		attribute("instance_type", details.InstanceType)
+		attribute("subnet_id", details.SubnetId)
+		attribute("availability_zone", details.AvailabilityZone)
+	case resource.AwsRdsInstanceDetails != nil:
+		details := resource.AwsRdsInstanceDetails
+		attribute("identifier", details.DbInstanceIdentifier)
+		attribute("engine", details.Engine)
+		attribute("engine_version", details.EngineVersion)
+		attribute("db_subnet_group_name", details.DBSubnetGroupName)
+		attribute("db_name", details.DbName)
+		attribute("username", details.MasterUsername)
+		attribute("ca_cert_identifier", details.CaCertificateIdentifier)
+		if details.IamDatabaseAuthenticationEnabled {
+			fmt.Fprintln(w, "  iam_database_authentication_enabled = true")
+		}
+		if details.MultiAZ {
+			fmt.Fprintln(w, "  multi_az = true")
+		}
+		if details.PubliclyAccessible {
+			fmt.Fprintln(w, "  publicly_accessible = true")
+		}
+		fmt.Fprintln(w, "  # instance_class = \"\" # TODO: not known from discovery")
+	case resource.AwsRdsClusterDetails != nil:
+		details := resource.AwsRdsClusterDetails
+		attribute("cluster_identifier", details.DbClusterIdentifier)
+		attribute("engine", details.Engine)
+		attribute("engine_version", details.EngineVersion)
+		attribute("engine_mode", details.EngineMode)
+		attribute("database_name", details.DatabaseName)
+		attribute("db_subnet_group_name", details.DBSubnetGroupName)
+		if details.IamDatabaseAuthenticationEnabled {
+			fmt.Fprintln(w, "  iam_database_authentication_enabled = true")
+		}
+	case resource.AwsRdsProxyDetails != nil:
+		details := resource.AwsRdsProxyDetails
+		attribute("name", details.DbProxyName)
+		attribute("engine_family", details.EngineFamily)
+		attribute("role_arn", details.RoleArn)
+		if details.RequireTls {
+			fmt.Fprintln(w, "  require_tls = true")
+		}
+		fmt.Fprintln(w, "  # vpc_subnet_ids = [] # TODO: see discovery results")
+		fmt.Fprintln(w, "  # auth {} # TODO: see discovery results")
+	case resource.AwsEksClusterDetails != nil:
+		details := resource.AwsEksClusterDetails
+		attribute("name", details.ClusterName)
+		attribute("version", details.KubernetesVersion)
+		fmt.Fprintln(w, "  # role_arn = \"\" # TODO: not known from discovery")
+		fmt.Fprintln(w, "  vpc_config {")
+		fmt.Fprintln(w, "    # subnet_ids = [] # TODO: not known from discovery")
+		fmt.Fprintln(w, "  }")
+	case resource.AwsEcsServiceDetails != nil:
+		details := resource.AwsEcsServiceDetails
+		attribute("name", details.ServiceName)
+		attribute("cluster", details.ClusterArn)
+		attribute("task_definition", details.TaskDefinition)
+		if details.EnableExecuteCommand {
+			fmt.Fprintln(w, "  enable_execute_command = true")
+		}
+	}
+
+	// tags with the reserved aws: prefix can not be managed by terraform
+	tags := map[string]string{}
+	for key, value := range resource.Tags() {
+		if !strings.HasPrefix(key, "aws:") {
+			tags[key] = value
+		}
+	}
+	if len(tags) > 0 {
+		fmt.Fprintln(w, "  tags = {")
+		for _, key := range sortedKeys(tags) {
+			fmt.Fprintf(w, "    %s = %s\n", hclString(key), hclString(tags[key]))
+		}
+		fmt.Fprintln(w, "  }")
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// hclString returns a quoted HCL string literal, escaping template sequences.
+func hclString(s string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s) // can not fail for strings
+	quoted := strings.TrimSuffix(buf.String(), "\n")
+	return strings.NewReplacer("${", "$${", "%{", "%%{").Replace(quoted)
+}
@@ -0,0 +1,97 @@
+package exporters
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTerraformTestState writes a terraform state file managing the given resource.
+func writeTerraformTestState(t *testing.T, state string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "terraform.tfstate")
+	if err := os.WriteFile(path, []byte(state), 0600); err != nil {
+		t.Fatalf("failed to write state file: %v", err)
+	}
+	return path
+}
+
+const terraformTestState = `{
+  "version": 4,
+  "resources": [
+    {
+      "mode": "managed",
+      "type": "aws_instance",
+      "instances": [{"attributes": {"id": "i-0a", "arn": "arn:aws:ec2:us-east-1:123456789012:instance/i-0a"}}]
+    },
+    {
+      "mode": "managed",
+      "type": "aws_db_instance",
+      "instances": [{"attributes": {"arn": "arn:aws:rds:eu-west-1:123456789012:db:orders-db"}}]
+    },
+    {
+      "mode": "data",
+      "type": "aws_eks_cluster",
+      "instances": [{"attributes": {"id": "prod"}}]
+    }
+  ]
+}`
+
+func TestTerraformExporterWriteImports(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []TerraformExporterOption
+		golden string
+	}{
+		{
+			name:   "defaults",
+			golden: "terraform_imports.tf",
+		},
+		{
+			name: "unmanaged resources only, without stubs",
+			opts: []TerraformExporterOption{
+				WithTerraformExporterStateFiles(writeTerraformTestState(t, terraformTestState)),
+				WithTerraformExporterResourceStubs(false),
+				WithTerraformExporterRegionProviders(true),
+			},
+			golden: "terraform_imports_unmanaged.tf",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := NewTerraformExporter(test.opts...).WriteImports(&buf, newExporterTestResults()); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertGolden(t, test.golden, buf.Bytes())
+		})
+	}
+}
+
+func TestTerraformExporterDriftReport(t *testing.T) {
+	results := newExporterTestResults()
+
+	report, err := NewTerraformExporter(
+		WithTerraformExporterStateFiles(writeTerraformTestState(t, terraformTestState)),
+	).DriftReport(results)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertGolden(t, "terraform_drift.txt", buf.Bytes())
+
+	if _, err := NewTerraformExporter().DriftReport(results); err == nil {
+		t.Fatal("expected an error without state files")
+	}
+	_, err = NewTerraformExporter(
+		WithTerraformExporterStateFiles(writeTerraformTestState(t, `{"version": 3}`)),
+	).DriftReport(results)
+	if err == nil {
+		t.Fatal("expected an error for an unsupported state file version")
+	}
+}
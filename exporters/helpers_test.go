@@ -0,0 +1,224 @@
+package exporters
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/borderzero/discovery"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// assertGolden compares output with the golden file of a given name in testdata,
+// or (re)writes the golden file when the tests are run with -update.
+func assertGolden(t *testing.T, name string, output []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.WriteFile(path, output, 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+	if string(output) != string(expected) {
+		t.Fatalf("output does not match %s (run with -update to update it):\n%s", path, output)
+	}
+}
+
+func newExporterTestResult(discovererId string, resources ...discovery.Resource) *discovery.Result {
+	result := discovery.NewResult(discovererId)
+	result.AddResources(resources...)
+	result.Done()
+	return result
+}
+
+func newExporterTestEc2Instance(instanceId, name, privateIp, publicIp string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeAwsEc2Instance,
+		AwsEc2InstanceDetails: &discovery.AwsEc2InstanceDetails{
+			AwsBaseDetails: discovery.AwsBaseDetails{
+				AwsAccountId: "123456789012",
+				AwsRegion:    "us-east-1",
+				AwsArn:       "arn:aws:ec2:us-east-1:123456789012:instance/" + instanceId,
+			},
+			Tags:             map[string]string{"Name": name, "team": "web"},
+			InstanceId:       instanceId,
+			ImageId:          "ami-0123",
+			VpcId:            "vpc-1",
+			SubnetId:         "subnet-1",
+			AvailabilityZone: "us-east-1a",
+			PrivateIpAddress: privateIp,
+			PublicIpAddress:  publicIp,
+			InstanceType:     "t3.micro",
+			InstanceState:    "running",
+		},
+	}
+}
+
+func newExporterTestContainer(containerId, name string, portBindings map[string]string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeDockerContainer,
+		DockerContainerDetails: &discovery.DockerContainerDetails{
+			ContainerId:  containerId,
+			Status:       "running",
+			Image:        name + ":1.0",
+			Names:        []string{"/" + name},
+			PortBindings: portBindings,
+			Labels:       map[string]string{"app": name},
+		},
+	}
+}
+
+func newExporterTestSshServer() discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeNetworkSshServer,
+		NetworkSshServerDetails: &discovery.NetworkSshServerDetails{
+			NetworkBaseDetails: discovery.NetworkBaseDetails{
+				HostNames: []string{"bastion.lab.internal."},
+				IpAddress: "10.0.2.5",
+				Port:      "2222",
+			},
+			HostKeys: []string{"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIBastionKey"},
+		},
+	}
+}
+
+// newExporterTestResults returns results with resources of every type handled by
+// the exporters, including name collisions and resources found by two discoverers.
+func newExporterTestResults() []*discovery.Result {
+	unreachable := newExporterTestEc2Instance("i-0c", "db host", "10.0.1.12", "")
+	unreachable.AwsEc2InstanceDetails.PrivateIpAddressReachable = new(bool)
+
+	return []*discovery.Result{
+		newExporterTestResult(
+			"aws_ec2",
+			newExporterTestEc2Instance("i-0a", "web-1", "10.0.1.10", "54.0.0.10"),
+			newExporterTestEc2Instance("i-0b", "web-1", "10.0.1.11", ""), // same name
+			unreachable,
+		),
+		newExporterTestResult(
+			"aws",
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeAwsRdsInstance,
+				AwsRdsInstanceDetails: &discovery.AwsRdsInstanceDetails{
+					AwsBaseDetails: discovery.AwsBaseDetails{
+						AwsAccountId: "123456789012",
+						AwsRegion:    "eu-west-1",
+						AwsArn:       "arn:aws:rds:eu-west-1:123456789012:db:orders-db",
+					},
+					Tags:                 map[string]string{"team": "data", "aws:cloudformation:stack-name": "orders"},
+					DbInstanceIdentifier: "orders-db",
+					Engine:               "postgres",
+					EngineVersion:        "16.1",
+					DBSubnetGroupName:    "private",
+					MultiAZ:              true,
+				},
+			},
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeAwsRdsProxy,
+				AwsRdsProxyDetails: &discovery.AwsRdsProxyDetails{
+					AwsBaseDetails: discovery.AwsBaseDetails{
+						AwsAccountId: "123456789012",
+						AwsRegion:    "eu-west-1",
+						AwsArn:       "arn:aws:rds:eu-west-1:123456789012:db-proxy:prx-1",
+					},
+					DbProxyName:  "orders-proxy",
+					EngineFamily: "POSTGRESQL",
+					RequireTls:   true,
+				},
+			},
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeAwsEksCluster,
+				AwsEksClusterDetails: &discovery.AwsEksClusterDetails{
+					AwsBaseDetails: discovery.AwsBaseDetails{
+						AwsAccountId: "123456789012",
+						AwsRegion:    "us-east-1",
+						AwsArn:       "arn:aws:eks:us-east-1:123456789012:cluster/prod",
+					},
+					Tags:              map[string]string{"Name": "Prod Cluster"},
+					ClusterName:       "prod",
+					KubernetesVersion: "1.30",
+				},
+			},
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeAwsEcsService,
+				AwsEcsServiceDetails: &discovery.AwsEcsServiceDetails{
+					AwsBaseDetails: discovery.AwsBaseDetails{
+						AwsAccountId: "123456789012",
+						AwsRegion:    "us-east-1",
+						AwsArn:       "arn:aws:ecs:us-east-1:123456789012:service/main/api",
+					},
+					ServiceName:          "api",
+					ClusterArn:           "arn:aws:ecs:us-east-1:123456789012:cluster/main",
+					ClusterName:          "main",
+					TaskDefinition:       "arn:aws:ecs:us-east-1:123456789012:task-definition/api:3",
+					EnableExecuteCommand: true,
+				},
+			},
+		),
+		newExporterTestResult(
+			"docker",
+			newExporterTestContainer("c1", "api", map[string]string{
+				"0.0.0.0:5353":   "53/udp",
+				"0.0.0.0:8080":   "80/tcp",
+				"[::]:8080":      "80/tcp",
+				"127.0.0.1:9090": "9090/tcp",
+			}),
+			newExporterTestContainer("c2", "web-1", map[string]string{"[::]:80": "80/tcp"}), // same name as an instance
+		),
+		newExporterTestResult(
+			"kubernetes",
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeKubernetesService,
+				KubernetesServiceDetails: &discovery.KubernetesServiceDetails{
+					Namespace:   "monitoring",
+					Name:        "prometheus",
+					ServiceType: "ClusterIP",
+					ClusterIp:   "10.96.0.10",
+					Ports: []discovery.KubernetesServicePort{
+						{Name: "http", Port: 80},
+						{Name: "metrics", Port: 9090},
+					},
+					Labels: map[string]string{"app": "prometheus"},
+				},
+			},
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeKubernetesService,
+				KubernetesServiceDetails: &discovery.KubernetesServiceDetails{
+					Namespace: "default",
+					Name:      "headless",
+					ClusterIp: "None",
+					Ports:     []discovery.KubernetesServicePort{{Name: "http", Port: 80}},
+				},
+			},
+		),
+		newExporterTestResult(
+			"network",
+			newExporterTestSshServer(),
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeNetworkHttpServer,
+				NetworkHttpServerDetails: &discovery.NetworkHttpServerDetails{
+					NetworkBaseDetails: discovery.NetworkBaseDetails{IpAddress: "10.0.2.5", Port: "80"},
+				},
+			},
+			discovery.Resource{
+				ResourceType: discovery.ResourceTypeNetworkHttpsServer,
+				NetworkHttpsServerDetails: &discovery.NetworkHttpsServerDetails{
+					NetworkBaseDetails: discovery.NetworkBaseDetails{
+						HostNames: []string{"www.example.com."},
+						IpAddress: "10.0.2.6",
+						Port:      "443",
+					},
+				},
+			},
+		),
+		newExporterTestResult("network_lab", newExporterTestSshServer()), // same server
+	}
+}
@@ -0,0 +1,267 @@
+package formats
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/borderzero/discovery"
+)
+
+// column represents a column of the table and CSV formats.
+type column struct {
+	name  string
+	value func(discovery.Resource) string
+}
+
+// columnsByResourceType are the columns rendered for each resource type.
+var columnsByResourceType = map[string][]column{
+	discovery.ResourceTypeAwsEc2Instance: {
+		{"instance_id", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.InstanceId }},
+		{"name", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.Tags["Name"] }},
+		{"state", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.InstanceState }},
+		{"instance_type", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.InstanceType }},
+		{"platform", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.Platform }},
+		{"private_ip", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.PrivateIpAddress }},
+		{"public_ip", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.PublicIpAddress }},
+		{"vpc_id", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.VpcId }},
+		{"ssm_status", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.InstanceSsmStatus }},
+		{"region", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsEc2InstanceDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsEc2InstanceConnectEndpoint: {
+		{"endpoint_id", func(r discovery.Resource) string {
+			return r.AwsEc2InstanceConnectEndpointDetails.InstanceConnectEndpointId
+		}},
+		{"name", func(r discovery.Resource) string { return r.AwsEc2InstanceConnectEndpointDetails.Tags["Name"] }},
+		{"state", func(r discovery.Resource) string { return r.AwsEc2InstanceConnectEndpointDetails.State }},
+		{"vpc_id", func(r discovery.Resource) string { return r.AwsEc2InstanceConnectEndpointDetails.VpcId }},
+		{"subnet_id", func(r discovery.Resource) string { return r.AwsEc2InstanceConnectEndpointDetails.SubnetId }},
+		{"region", func(r discovery.Resource) string { return r.AwsEc2InstanceConnectEndpointDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsEc2InstanceConnectEndpointDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsEcsService: {
+		{"service_name", func(r discovery.Resource) string { return r.AwsEcsServiceDetails.ServiceName }},
+		{"cluster_name", func(r discovery.Resource) string { return r.AwsEcsServiceDetails.ClusterName }},
+		{"task_definition", func(r discovery.Resource) string { return r.AwsEcsServiceDetails.TaskDefinition }},
+		{"execute_command", func(r discovery.Resource) string {
+			return strconv.FormatBool(r.AwsEcsServiceDetails.EnableExecuteCommand)
+		}},
+		{"region", func(r discovery.Resource) string { return r.AwsEcsServiceDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsEcsServiceDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsEksCluster: {
+		{"cluster_name", func(r discovery.Resource) string { return r.AwsEksClusterDetails.ClusterName }},
+		{"kubernetes_version", func(r discovery.Resource) string { return r.AwsEksClusterDetails.KubernetesVersion }},
+		{"endpoint", func(r discovery.Resource) string { return r.AwsEksClusterDetails.Endpoint }},
+		{"vpc_id", func(r discovery.Resource) string { return r.AwsEksClusterDetails.VpcId }},
+		{"reachable", func(r discovery.Resource) string { return formatBoolPointer(r.AwsEksClusterDetails.EndpointReachable) }},
+		{"region", func(r discovery.Resource) string { return r.AwsEksClusterDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsEksClusterDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsRdsInstance: {
+		{"db_instance_identifier", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.DbInstanceIdentifier }},
+		{"engine", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.Engine }},
+		{"engine_version", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.EngineVersion }},
+		{"status", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.DbInstanceStatus }},
+		{"endpoint", func(r discovery.Resource) string {
+			if r.AwsRdsInstanceDetails.EndpointAddress == "" {
+				return ""
+			}
+			return net.JoinHostPort(
+				r.AwsRdsInstanceDetails.EndpointAddress,
+				strconv.Itoa(int(r.AwsRdsInstanceDetails.EndpointPort)),
+			)
+		}},
+		{"vpc_id", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.VpcId }},
+		{"iam_auth", func(r discovery.Resource) string {
+			return strconv.FormatBool(r.AwsRdsInstanceDetails.IamDatabaseAuthenticationEnabled)
+		}},
+		{"reachable", func(r discovery.Resource) string { return formatBoolPointer(r.AwsRdsInstanceDetails.NetworkReachable) }},
+		{"region", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsRdsInstanceDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsRdsCluster: {
+		{"db_cluster_identifier", func(r discovery.Resource) string { return r.AwsRdsClusterDetails.DbClusterIdentifier }},
+		{"engine", func(r discovery.Resource) string { return r.AwsRdsClusterDetails.Engine }},
+		{"engine_version", func(r discovery.Resource) string { return r.AwsRdsClusterDetails.EngineVersion }},
+		{"status", func(r discovery.Resource) string { return r.AwsRdsClusterDetails.DbClusterStatus }},
+		{"writer_endpoint", func(r discovery.Resource) string {
+			if r.AwsRdsClusterDetails.WriterEndpointAddress == "" {
+				return ""
+			}
+			return net.JoinHostPort(
+				r.AwsRdsClusterDetails.WriterEndpointAddress,
+				strconv.Itoa(int(r.AwsRdsClusterDetails.EndpointPort)),
+			)
+		}},
+		{"members", func(r discovery.Resource) string { return strconv.Itoa(len(r.AwsRdsClusterDetails.Members)) }},
+		{"iam_auth", func(r discovery.Resource) string {
+			return strconv.FormatBool(r.AwsRdsClusterDetails.IamDatabaseAuthenticationEnabled)
+		}},
+		{"region", func(r discovery.Resource) string { return r.AwsRdsClusterDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsRdsClusterDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsRdsProxy: {
+		{"db_proxy_name", func(r discovery.Resource) string { return r.AwsRdsProxyDetails.DbProxyName }},
+		{"engine_family", func(r discovery.Resource) string { return r.AwsRdsProxyDetails.EngineFamily }},
+		{"status", func(r discovery.Resource) string { return r.AwsRdsProxyDetails.Status }},
+		{"endpoints", func(r discovery.Resource) string { return strconv.Itoa(len(r.AwsRdsProxyDetails.Endpoints)) }},
+		{"target_groups", func(r discovery.Resource) string { return strconv.Itoa(len(r.AwsRdsProxyDetails.TargetGroups)) }},
+		{"vpc_id", func(r discovery.Resource) string { return r.AwsRdsProxyDetails.VpcId }},
+		{"require_tls", func(r discovery.Resource) string { return strconv.FormatBool(r.AwsRdsProxyDetails.RequireTls) }},
+		{"region", func(r discovery.Resource) string { return r.AwsRdsProxyDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsRdsProxyDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeAwsSsmTarget: {
+		{"instance_id", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.InstanceId }},
+		{"computer_name", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.ComputerName }},
+		{"ip_address", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.IpAddress }},
+		{"platform", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.PlatformName }},
+		{"agent_version", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.AgentVersion }},
+		{"ping_status", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.PingStatus }},
+		{"region", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.AwsRegion }},
+		{"account", func(r discovery.Resource) string { return r.AwsSsmTargetDetails.AwsAccountId }},
+	},
+	discovery.ResourceTypeKubernetesService: {
+		{"namespace", func(r discovery.Resource) string { return r.KubernetesServiceDetails.Namespace }},
+		{"name", func(r discovery.Resource) string { return r.KubernetesServiceDetails.Name }},
+		{"service_type", func(r discovery.Resource) string { return r.KubernetesServiceDetails.ServiceType }},
+		{"cluster_ip", func(r discovery.Resource) string { return r.KubernetesServiceDetails.ClusterIp }},
+		{"ports", func(r discovery.Resource) string {
+			ports := []string{}
+			for _, port := range r.KubernetesServiceDetails.Ports {
+				ports = append(ports, fmt.Sprintf("%d/%s", port.Port, port.Protocol))
+			}
+			return strings.Join(ports, ",")
+		}},
+	},
+	discovery.ResourceTypeDockerContainer: {
+		{"container_id", func(r discovery.Resource) string { return shortContainerId(r.DockerContainerDetails.ContainerId) }},
+		{"names", func(r discovery.Resource) string { return strings.Join(r.DockerContainerDetails.Names, ",") }},
+		{"image", func(r discovery.Resource) string { return r.DockerContainerDetails.Image }},
+		{"status", func(r discovery.Resource) string { return r.DockerContainerDetails.Status }},
+		{"ports", func(r discovery.Resource) string { return formatMap(r.DockerContainerDetails.PortBindings, "->") }},
+	},
+	discovery.ResourceTypeNetworkHttpServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkHttpServerDetails.NetworkBaseDetails
+	}),
+	discovery.ResourceTypeNetworkHttpsServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkHttpsServerDetails.NetworkBaseDetails
+	}),
+	discovery.ResourceTypeNetworkMysqlServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkMysqlServerDetails.NetworkBaseDetails
+	}),
+	discovery.ResourceTypeNetworkPostgresqlServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkPostgresqlServerDetails.NetworkBaseDetails
+	}),
+	discovery.ResourceTypeNetworkRdpServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkRdpServerDetails.NetworkBaseDetails
+	}),
+	discovery.ResourceTypeNetworkSshServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkSshServerDetails.NetworkBaseDetails
+	}),
+	discovery.ResourceTypeNetworkVncServer: networkColumns(func(r discovery.Resource) discovery.NetworkBaseDetails {
+		return r.NetworkVncServerDetails.NetworkBaseDetails
+	}),
+}
+
+// keyColumn is the column of the table format for resource types without specific columns.
+var keyColumn = column{"key", func(r discovery.Resource) string { return r.Key() }}
+
+// columnsFor returns the columns for a resource type,
+// or nil for resource types without specific columns.
+func columnsFor(resourceType string) []column {
+	return columnsByResourceType[resourceType]
+}
+
+// render returns the value of a column for a resource, or an empty string
+// if the resource does not carry the details of its resource type (e.g.
+// a resource decoded from a malformed or hand-edited snapshot).
+func (c column) render(r discovery.Resource) string {
+	if !hasDetails(r) {
+		return ""
+	}
+	return c.value(r)
+}
+
+// hasDetails returns whether a resource carries the details
+// its resource type's columns are rendered from.
+func hasDetails(r discovery.Resource) bool {
+	switch r.ResourceType {
+	case discovery.ResourceTypeAwsEc2Instance:
+		return r.AwsEc2InstanceDetails != nil
+	case discovery.ResourceTypeAwsEc2InstanceConnectEndpoint:
+		return r.AwsEc2InstanceConnectEndpointDetails != nil
+	case discovery.ResourceTypeAwsEcsService:
+		return r.AwsEcsServiceDetails != nil
+	case discovery.ResourceTypeAwsEksCluster:
+		return r.AwsEksClusterDetails != nil
+	case discovery.ResourceTypeAwsRdsInstance:
+		return r.AwsRdsInstanceDetails != nil
+	case discovery.ResourceTypeAwsRdsCluster:
+		return r.AwsRdsClusterDetails != nil
+	case discovery.ResourceTypeAwsRdsProxy:
+		return r.AwsRdsProxyDetails != nil
+	case discovery.ResourceTypeAwsSsmTarget:
+		return r.AwsSsmTargetDetails != nil
+	case discovery.ResourceTypeKubernetesService:
+		return r.KubernetesServiceDetails != nil
+	case discovery.ResourceTypeDockerContainer:
+		return r.DockerContainerDetails != nil
+	case discovery.ResourceTypeNetworkHttpServer:
+		return r.NetworkHttpServerDetails != nil
+	case discovery.ResourceTypeNetworkHttpsServer:
+		return r.NetworkHttpsServerDetails != nil
+	case discovery.ResourceTypeNetworkMysqlServer:
+		return r.NetworkMysqlServerDetails != nil
+	case discovery.ResourceTypeNetworkPostgresqlServer:
+		return r.NetworkPostgresqlServerDetails != nil
+	case discovery.ResourceTypeNetworkRdpServer:
+		return r.NetworkRdpServerDetails != nil
+	case discovery.ResourceTypeNetworkSshServer:
+		return r.NetworkSshServerDetails != nil
+	case discovery.ResourceTypeNetworkVncServer:
+		return r.NetworkVncServerDetails != nil
+	}
+	return true
+}
+
+func networkColumns(details func(discovery.Resource) discovery.NetworkBaseDetails) []column {
+	return []column{
+		{"ip_address", func(r discovery.Resource) string { return details(r).IpAddress }},
+		{"port", func(r discovery.Resource) string { return details(r).Port }},
+		{"hostnames", func(r discovery.Resource) string { return strings.Join(details(r).HostNames, ",") }},
+	}
+}
+
+func formatBoolPointer(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+// formatMap renders a map as comma separated key-value pairs, sorted by key.
+func formatMap(m map[string]string, separator string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+separator+m[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+func shortContainerId(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,128 @@
+package formats
+
+import (
+	"testing"
+
+	"github.com/borderzero/discovery"
+)
+
+func TestColumnRender(t *testing.T) {
+	truth := true
+	eks := discovery.Resource{
+		ResourceType: discovery.ResourceTypeAwsEksCluster,
+		AwsEksClusterDetails: &discovery.AwsEksClusterDetails{
+			ClusterName:       "prod",
+			EndpointReachable: &truth,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		resource discovery.Resource
+		expected map[string]string // values by column name
+	}{
+		{
+			name:     "resource with details",
+			resource: newFormatsTestContainer("0123456789abcdef", "web", "web-alias"),
+			expected: map[string]string{
+				"container_id": "0123456789ab",
+				"names":        "web,web-alias",
+				"ports":        "443/tcp->8443,80/tcp->8080",
+			},
+		},
+		{
+			name:     "network resource",
+			resource: newFormatsTestSshServer("10.0.0.5", "bastion.lab.internal."),
+			expected: map[string]string{
+				"ip_address": "10.0.0.5",
+				"port":       "22",
+				"hostnames":  "bastion.lab.internal.",
+			},
+		},
+		{
+			name:     "bool pointer",
+			resource: eks,
+			expected: map[string]string{"cluster_name": "prod", "reachable": "true"},
+		},
+		{
+			name: "nil bool pointer",
+			resource: discovery.Resource{
+				ResourceType:         discovery.ResourceTypeAwsEksCluster,
+				AwsEksClusterDetails: &discovery.AwsEksClusterDetails{ClusterName: "dev"},
+			},
+			expected: map[string]string{"cluster_name": "dev", "reachable": ""},
+		},
+		{
+			name:     "resource without details",
+			resource: discovery.Resource{ResourceType: discovery.ResourceTypeAwsEc2Instance},
+			expected: map[string]string{"instance_id": "", "name": "", "region": ""},
+		},
+		{
+			name:     "resource with the details of another type",
+			resource: discovery.Resource{ResourceType: discovery.ResourceTypeNetworkSshServer, NetworkHttpServerDetails: &discovery.NetworkHttpServerDetails{}},
+			expected: map[string]string{"ip_address": "", "port": ""},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			columns := columnsFor(test.resource.ResourceType)
+			if len(columns) == 0 {
+				t.Fatalf("expected columns for %s", test.resource.ResourceType)
+			}
+			found := 0
+			for _, c := range columns {
+				expected, ok := test.expected[c.name]
+				if !ok {
+					continue
+				}
+				found++
+				if value := c.render(test.resource); value != expected {
+					t.Fatalf("expected %s to be %q, got %q", c.name, expected, value)
+				}
+			}
+			if found != len(test.expected) {
+				t.Fatalf("expected %d of the columns to exist, found %d", len(test.expected), found)
+			}
+		})
+	}
+
+	if columns := columnsFor("unknown"); columns != nil {
+		t.Fatalf("expected no columns for an unknown resource type, got %d", len(columns))
+	}
+}
+
+func TestFormatMap(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        map[string]string
+		expected string
+	}{
+		{name: "nil", expected: ""},
+		{name: "single pair", m: map[string]string{"a": "1"}, expected: "a=1"},
+		{name: "sorted by key", m: map[string]string{"b": "2", "a": "1", "c": ""}, expected: "a=1,b=2,c="},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if value := formatMap(test.m, "="); value != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, value)
+			}
+		})
+	}
+}
+
+func TestShortContainerId(t *testing.T) {
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{id: "", expected: ""},
+		{id: "c1", expected: "c1"},
+		{id: "0123456789ab", expected: "0123456789ab"},
+		{id: "0123456789abcdef", expected: "0123456789ab"},
+	}
+	for _, test := range tests {
+		if value := shortContainerId(test.id); value != test.expected {
+			t.Fatalf("expected %q to be shortened to %q, got %q", test.id, test.expected, value)
+		}
+	}
+}
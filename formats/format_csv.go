@@ -0,0 +1,61 @@
+package formats
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"github.com/borderzero/discovery"
+)
+
+// CsvFormatter represents a formatter which renders resources as CSV, one resource
+// per row. The columns are the resource type, key and discoverer id followed by the
+// union of the columns of all the rendered resource types, in order of first use.
+type CsvFormatter struct{}
+
+// ensure CsvFormatter implements Formatter at compile-time.
+var _ Formatter = (*CsvFormatter)(nil)
+
+// NewCsvFormatter returns a new CsvFormatter.
+func NewCsvFormatter() *CsvFormatter {
+	return &CsvFormatter{}
+}
+
+// Format renders the resources in results as CSV.
+func (cf *CsvFormatter) Format(w io.Writer, results []*discovery.Result) error {
+	entries := SortedEntries(results)
+
+	// collect the union of columns, in order of first use
+	headers := []string{"resource_type", "key", "discoverer_id"}
+	indexes := map[string]int{}
+	for _, entry := range entries {
+		for _, c := range columnsFor(entry.Resource.ResourceType) {
+			if _, ok := indexes[c.name]; !ok {
+				indexes[c.name] = len(headers)
+				headers = append(headers, c.name)
+			}
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(headers); err != nil {
+		return fmt.Errorf("failed to write csv header: %v", err)
+	}
+	for _, entry := range entries {
+		row := make([]string, len(headers))
+		row[0] = entry.Resource.ResourceType
+		row[1] = entry.Key
+		row[2] = entry.DiscovererId
+		for _, c := range columnsFor(entry.Resource.ResourceType) {
+			row[indexes[c.name]] = c.render(entry.Resource)
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("failed to write csv row: %v", err)
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %v", err)
+	}
+	return nil
+}
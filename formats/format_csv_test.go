@@ -0,0 +1,24 @@
+package formats
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCsvFormatterFormat(t *testing.T) {
+	var sb strings.Builder
+	if err := NewCsvFormatter().Format(&sb, newFormatsTestResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// columns are the union of the columns of all resource types, in order of first use
+	expected := `resource_type,key,discoverer_id,container_id,names,image,status,ports,ip_address,port,hostnames
+docker_container,docker_container:0123456789abcdef,docker/remote,0123456789ab,web,nginx:latest,running,"443/tcp->8443,80/tcp->8080",,,
+docker_container,docker_container:c2,docker/local,c2,api,nginx:latest,running,"443/tcp->8443,80/tcp->8080",,,
+docker_container,docker_container:c2,docker/remote,c2,,nginx:latest,running,"443/tcp->8443,80/tcp->8080",,,
+network_ssh_server,network_ssh_server:10.0.0.5:22,network,,,,,,10.0.0.5,22,"bastion.lab.internal.,bastion"
+`
+	if sb.String() != expected {
+		t.Fatalf("expected:\n%s\ngot:\n%s", expected, sb.String())
+	}
+}
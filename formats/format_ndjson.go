@@ -0,0 +1,32 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/borderzero/discovery"
+)
+
+// NdjsonFormatter represents a formatter which renders resources as
+// newline-delimited JSON, one entry (resource with its key) per line.
+type NdjsonFormatter struct{}
+
+// ensure NdjsonFormatter implements Formatter at compile-time.
+var _ Formatter = (*NdjsonFormatter)(nil)
+
+// NewNdjsonFormatter returns a new NdjsonFormatter.
+func NewNdjsonFormatter() *NdjsonFormatter {
+	return &NdjsonFormatter{}
+}
+
+// Format renders the resources in results as newline-delimited JSON.
+func (nf *NdjsonFormatter) Format(w io.Writer, results []*discovery.Result) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range SortedEntries(results) {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("failed to json encode entry: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package formats
+
+import (
+	"bufio"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNdjsonFormatterFormat(t *testing.T) {
+	var sb strings.Builder
+	if err := NewNdjsonFormatter().Format(&sb, newFormatsTestResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	keys := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(sb.String()))
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("failed to decode line %q: %v", scanner.Text(), err)
+		}
+		if entry.Resource.Key() != entry.Key {
+			t.Fatalf("expected the key of the resource, got %s", entry.Key)
+		}
+		keys = append(keys, entry.Key+" "+entry.DiscovererId)
+	}
+	expected := []string{
+		"docker_container:0123456789abcdef docker/remote",
+		"docker_container:c2 docker/local",
+		"docker_container:c2 docker/remote",
+		"network_ssh_server:10.0.0.5:22 network",
+	}
+	if !slices.Equal(keys, expected) {
+		t.Fatalf("expected one entry per line %v, got %v", expected, keys)
+	}
+}
@@ -0,0 +1,81 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/borderzero/discovery"
+)
+
+// TableFormatter represents a formatter which renders resources as
+// human-readable tables, one per resource type, with columns chosen
+// per resource type.
+type TableFormatter struct{}
+
+// ensure TableFormatter implements Formatter at compile-time.
+var _ Formatter = (*TableFormatter)(nil)
+
+// NewTableFormatter returns a new TableFormatter.
+func NewTableFormatter() *TableFormatter {
+	return &TableFormatter{}
+}
+
+// Format renders the resources in results as tables.
+func (tf *TableFormatter) Format(w io.Writer, results []*discovery.Result) error {
+	byResourceType := map[string][]Entry{}
+	for _, entry := range SortedEntries(results) {
+		byResourceType[entry.Resource.ResourceType] = append(byResourceType[entry.Resource.ResourceType], entry)
+	}
+
+	resourceTypes := make([]string, 0, len(byResourceType))
+	for resourceType := range byResourceType {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	for i, resourceType := range resourceTypes {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return fmt.Errorf("failed to write table: %v", err)
+			}
+		}
+		entries := byResourceType[resourceType]
+		if _, err := fmt.Fprintf(w, "%s (%d)\n", resourceType, len(entries)); err != nil {
+			return fmt.Errorf("failed to write table: %v", err)
+		}
+
+		columns := columnsFor(resourceType)
+		if len(columns) == 0 {
+			columns = []column{keyColumn}
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		headers := make([]string, 0, len(columns))
+		for _, c := range columns {
+			headers = append(headers, strings.ToUpper(c.name))
+		}
+		fmt.Fprintln(tw, strings.Join(headers, "\t"))
+		for _, entry := range entries {
+			values := make([]string, 0, len(columns))
+			for _, c := range columns {
+				values = append(values, tableCell(c.render(entry.Resource)))
+			}
+			fmt.Fprintln(tw, strings.Join(values, "\t"))
+		}
+		if err := tw.Flush(); err != nil {
+			return fmt.Errorf("failed to write table: %v", err)
+		}
+	}
+	return nil
+}
+
+// tableCell returns a value suitable for a table cell (never empty, no tabs or newlines).
+func tableCell(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return strings.NewReplacer("\t", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,68 @@
+package formats
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/borderzero/discovery"
+)
+
+func TestTableFormatterFormat(t *testing.T) {
+	unknown := discovery.Resource{
+		ResourceType:           "unknown_type",
+		DockerContainerDetails: &discovery.DockerContainerDetails{ContainerId: "c9"},
+	}
+	multiline := newFormatsTestContainer("c3", "line\nbreak\tand tab")
+
+	tests := []struct {
+		name     string
+		results  []*discovery.Result
+		expected string
+	}{
+		{
+			name:    "one table per resource type",
+			results: newFormatsTestResults(),
+			expected: `docker_container (3)
+CONTAINER_ID  NAMES  IMAGE         STATUS   PORTS
+0123456789ab  web    nginx:latest  running  443/tcp->8443,80/tcp->8080
+c2            api    nginx:latest  running  443/tcp->8443,80/tcp->8080
+c2            -      nginx:latest  running  443/tcp->8443,80/tcp->8080
+
+network_ssh_server (1)
+IP_ADDRESS  PORT  HOSTNAMES
+10.0.0.5    22    bastion.lab.internal.,bastion
+`,
+		},
+		{
+			name:    "resource types without specific columns",
+			results: []*discovery.Result{newFormatsTestResult("d", unknown)},
+			expected: `unknown_type (1)
+KEY
+unknown_type:c9
+`,
+		},
+		{
+			name:    "cells without tabs or newlines",
+			results: []*discovery.Result{newFormatsTestResult("d", multiline)},
+			expected: `docker_container (1)
+CONTAINER_ID  NAMES               IMAGE         STATUS   PORTS
+c3            line break and tab  nginx:latest  running  443/tcp->8443,80/tcp->8080
+`,
+		},
+		{
+			name:     "no resources",
+			expected: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var sb strings.Builder
+			if err := NewTableFormatter().Format(&sb, test.results); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sb.String() != test.expected {
+				t.Fatalf("expected:\n%s\ngot:\n%s", test.expected, sb.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/borderzero/discovery"
+	"gopkg.in/yaml.v3"
+)
+
+// YamlFormatter represents a formatter which renders resources as a YAML list.
+// Field names are the same as in the JSON encoding of resources, and map keys
+// are sorted.
+type YamlFormatter struct{}
+
+// ensure YamlFormatter implements Formatter at compile-time.
+var _ Formatter = (*YamlFormatter)(nil)
+
+// NewYamlFormatter returns a new YamlFormatter.
+func NewYamlFormatter() *YamlFormatter {
+	return &YamlFormatter{}
+}
+
+// Format renders the resources in results as YAML.
+func (yf *YamlFormatter) Format(w io.Writer, results []*discovery.Result) error {
+	// round trip through json so that the json field
+	// names (rather than go field names) are used
+	byt, err := json.Marshal(SortedEntries(results))
+	if err != nil {
+		return fmt.Errorf("failed to json encode entries: %v", err)
+	}
+	var generic []any
+	if err := json.Unmarshal(byt, &generic); err != nil {
+		return fmt.Errorf("failed to json decode entries: %v", err)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(generic); err != nil {
+		return fmt.Errorf("failed to yaml encode entries: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to yaml encode entries: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package formats
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestYamlFormatterFormat(t *testing.T) {
+	var sb strings.Builder
+	if err := NewYamlFormatter().Format(&sb, newFormatsTestResults()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// field names are the json field names, rather than the go field names
+	var entries []struct {
+		Key          string `yaml:"key"`
+		DiscovererId string `yaml:"discoverer_id"`
+		Resource     struct {
+			ResourceType            string `yaml:"resource_type"`
+			NetworkSshServerDetails *struct {
+				HostNames []string `yaml:"hostnames"`
+			} `yaml:"network_ssh_server_details"`
+		} `yaml:"resource"`
+	}
+	if err := yaml.Unmarshal([]byte(sb.String()), &entries); err != nil {
+		t.Fatalf("failed to decode output: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 entries, got %d", len(entries))
+	}
+	if entries[1].Key != "docker_container:c2" || entries[1].DiscovererId != "docker/local" {
+		t.Fatalf("expected entries sorted by key and discoverer id, got %+v", entries[1])
+	}
+	ssh := entries[3]
+	if ssh.Resource.ResourceType != "network_ssh_server" || ssh.Resource.NetworkSshServerDetails == nil {
+		t.Fatalf("expected the ssh server last, got %+v", ssh)
+	}
+	if hostNames := ssh.Resource.NetworkSshServerDetails.HostNames; len(hostNames) != 2 {
+		t.Fatalf("expected the details of the ssh server, got %v", hostNames)
+	}
+	if !strings.Contains(sb.String(), "\n  key: ") {
+		t.Fatalf("expected an indentation of 2 spaces, got:\n%s", sb.String())
+	}
+}
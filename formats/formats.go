@@ -0,0 +1,78 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/inventory"
+)
+
+const (
+	// FormatTable is the name of the human-readable table format.
+	FormatTable = "table"
+
+	// FormatCsv is the name of the CSV format.
+	FormatCsv = "csv"
+
+	// FormatYaml is the name of the YAML format.
+	FormatYaml = "yaml"
+
+	// FormatNdjson is the name of the newline-delimited JSON format.
+	FormatNdjson = "ndjson"
+)
+
+// Formatter represents an entity capable of rendering the resources in results.
+// Rendering is deterministic: resources are always sorted by key (and then by
+// discoverer id), so that rendered outputs diff cleanly.
+type Formatter interface {
+	Format(io.Writer, []*discovery.Result) error
+}
+
+// Entry represents a single resource as rendered by a Formatter. It is an inventory
+// entry, so that SortedEntries can be shared with e.g. the exporters package.
+type Entry = inventory.Entry
+
+// NewFormatter returns the Formatter for a given format name.
+func NewFormatter(format string) (Formatter, error) {
+	switch format {
+	case FormatTable:
+		return NewTableFormatter(), nil
+	case FormatCsv:
+		return NewCsvFormatter(), nil
+	case FormatYaml:
+		return NewYamlFormatter(), nil
+	case FormatNdjson:
+		return NewNdjsonFormatter(), nil
+	}
+	return nil, fmt.Errorf(
+		"unknown format \"%s\" (must be one of: %s)",
+		format,
+		strings.Join([]string{FormatTable, FormatCsv, FormatYaml, FormatNdjson}, ", "),
+	)
+}
+
+// SortedEntries returns the resources in results as entries, sorted by key and discoverer id.
+func SortedEntries(results []*discovery.Result) []Entry {
+	entries := []Entry{}
+	for _, result := range results {
+		result.Lock()
+		for _, resource := range result.Resources {
+			entries = append(entries, Entry{
+				Key:          resource.Key(),
+				DiscovererId: result.Metadata.DiscovererId,
+				Resource:     resource,
+			})
+		}
+		result.Unlock()
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Key == entries[j].Key {
+			return entries[i].DiscovererId < entries[j].DiscovererId
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
@@ -0,0 +1,61 @@
+package formats
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestNewFormatter(t *testing.T) {
+	tests := []struct {
+		format       string
+		expectedType string
+		expectErr    bool
+	}{
+		{format: FormatTable, expectedType: "*formats.TableFormatter"},
+		{format: FormatCsv, expectedType: "*formats.CsvFormatter"},
+		{format: FormatYaml, expectedType: "*formats.YamlFormatter"},
+		{format: FormatNdjson, expectedType: "*formats.NdjsonFormatter"},
+		{format: "json", expectErr: true},
+		{format: "", expectErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			formatter, err := NewFormatter(test.format)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected an error for format %q", test.format)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if formatterType := fmt.Sprintf("%T", formatter); formatterType != test.expectedType {
+				t.Fatalf("expected a %s, got a %s", test.expectedType, formatterType)
+			}
+		})
+	}
+}
+
+func TestSortedEntries(t *testing.T) {
+	entries := SortedEntries(newFormatsTestResults())
+
+	got := []string{}
+	for _, entry := range entries {
+		got = append(got, entry.Key+" "+entry.DiscovererId)
+	}
+	expected := []string{
+		"docker_container:0123456789abcdef docker/remote",
+		"docker_container:c2 docker/local",
+		"docker_container:c2 docker/remote",
+		"network_ssh_server:10.0.0.5:22 network",
+	}
+	if !slices.Equal(got, expected) {
+		t.Fatalf("expected entries %v, got %v", expected, got)
+	}
+
+	if entries := SortedEntries(nil); entries == nil || len(entries) != 0 {
+		t.Fatalf("expected an empty (non nil) list of entries, got %v", entries)
+	}
+}
@@ -0,0 +1,52 @@
+package formats
+
+import (
+	"github.com/borderzero/discovery"
+)
+
+func newFormatsTestContainer(containerId string, names ...string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeDockerContainer,
+		DockerContainerDetails: &discovery.DockerContainerDetails{
+			ContainerId:  containerId,
+			Names:        names,
+			Image:        "nginx:latest",
+			Status:       "running",
+			PortBindings: map[string]string{"80/tcp": "8080", "443/tcp": "8443"},
+		},
+	}
+}
+
+func newFormatsTestSshServer(ipAddress string, hostNames ...string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeNetworkSshServer,
+		NetworkSshServerDetails: &discovery.NetworkSshServerDetails{
+			NetworkBaseDetails: discovery.NetworkBaseDetails{
+				IpAddress: ipAddress,
+				Port:      "22",
+				HostNames: hostNames,
+			},
+		},
+	}
+}
+
+func newFormatsTestResult(discovererId string, resources ...discovery.Resource) *discovery.Result {
+	result := discovery.NewResult(discovererId)
+	result.AddResources(resources...)
+	result.Done()
+	return result
+}
+
+// newFormatsTestResults returns results with containers (one of them found by
+// two discoverers) and an ssh server, added out of order.
+func newFormatsTestResults() []*discovery.Result {
+	return []*discovery.Result{
+		newFormatsTestResult("network", newFormatsTestSshServer("10.0.0.5", "bastion.lab.internal.", "bastion")),
+		newFormatsTestResult(
+			"docker/remote",
+			newFormatsTestContainer("c2"),
+			newFormatsTestContainer("0123456789abcdef", "web"),
+		),
+		newFormatsTestResult("docker/local", newFormatsTestContainer("c2", "api")),
+	}
+}
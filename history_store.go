@@ -0,0 +1,35 @@
+package discovery
+
+import (
+	"context"
+	"time"
+)
+
+// ResourceVersion represents a single version of a resource as recorded by a
+// HistoryStore i.e. a period of time during which the resource was observed
+// by a discoverer without any changes to its details.
+type ResourceVersion struct {
+	Key          string    `json:"key"`
+	DiscovererId string    `json:"discoverer_id"`
+	Resource     Resource  `json:"resource"`
+	FirstSeen    time.Time `json:"first_seen"`
+	LastSeen     time.Time `json:"last_seen"`
+}
+
+// HistoryStore represents an entity capable of recording the history
+// of discovered resources and answering point-in-time queries about it.
+type HistoryStore interface {
+	// Record records all the resources in a result as seen at the time
+	// the result ended. Stale results (from a SnapshotStore) are ignored.
+	Record(context.Context, *Result) error
+
+	// ListAt returns the versions of all resources that existed at a given time.
+	ListAt(context.Context, time.Time) ([]ResourceVersion, error)
+
+	// History returns all recorded versions of the resource with a given key, oldest first.
+	History(context.Context, string) ([]ResourceVersion, error)
+
+	// Prune deletes all versions last seen before a given time
+	// and returns the number of versions which were deleted.
+	Prune(context.Context, time.Time) (int, error)
+}
@@ -0,0 +1,247 @@
+package inventory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+// Entry represents a resource in an Inventory.
+type Entry struct {
+	Key          string             `json:"key"`
+	DiscovererId string             `json:"discoverer_id"`
+	Resource     discovery.Resource `json:"resource"`
+}
+
+// DiscovererStatus represents the status of a discoverer
+// as of the last result received from it by an Inventory.
+type DiscovererStatus struct {
+	DiscovererId  string    `json:"discoverer_id"`
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	Stale         bool      `json:"stale"`
+	ResourceCount int       `json:"resource_count"`
+	Errors        []string  `json:"errors"`
+	Warnings      []string  `json:"warnings"`
+}
+
+// Filter represents the criteria for listing entries in an Inventory.
+// Empty fields match everything. Entries must match all non-empty fields.
+type Filter struct {
+	// ResourceTypes matches entries with any of the given resource types.
+	ResourceTypes []string
+
+	// DiscovererIds matches entries from any of the given discoverers.
+	DiscovererIds []string
+
+	// Tags matches entries having all of the given tags (see discovery.Resource.Tags).
+	Tags map[string]string
+}
+
+// Inventory represents the current state of all discovered resources,
+// maintained from the results of one or more discoverers. It implements
+// discovery.Sink so that it can be fed by a sinks.Router.
+type Inventory struct {
+	sync.RWMutex // inherit lock behaviour
+
+	entries  map[string]map[string]Entry // by discoverer id, then key
+	statuses map[string]DiscovererStatus // by discoverer id
+
+	subscribers      map[int]chan []discovery.ResourceDelta
+	nextSubscriberId int
+}
+
+// ensure Inventory implements discovery.Sink at compile-time.
+var _ discovery.Sink = (*Inventory)(nil)
+
+// NewInventory returns a new, empty, Inventory.
+func NewInventory() *Inventory {
+	return &Inventory{
+		entries:     map[string]map[string]Entry{},
+		statuses:    map[string]DiscovererStatus{},
+		subscribers: map[int]chan []discovery.ResourceDelta{},
+	}
+}
+
+// Send updates the Inventory with a result.
+func (inv *Inventory) Send(_ context.Context, result *discovery.Result) error {
+	inv.Update(result)
+	return nil
+}
+
+// Close closes the channels of all subscribers.
+func (inv *Inventory) Close(_ context.Context) error {
+	inv.Lock()
+	defer inv.Unlock()
+
+	for id, subscriber := range inv.subscribers {
+		close(subscriber)
+		delete(inv.subscribers, id)
+	}
+	return nil
+}
+
+// Update replaces the entries of a result's discoverer with the resources in the
+// result and returns the resulting changes, which are also sent to subscribers.
+// If the result has errors, entries missing from it are kept (see discovery.Diff).
+func (inv *Inventory) Update(result *discovery.Result) []discovery.ResourceDelta {
+	inv.Lock()
+	defer inv.Unlock()
+
+	discovererId := result.Metadata.DiscovererId
+
+	// diff against the inventory's own view of the discoverer rather than
+	// its last result, which may be missing resources if it had errors
+	previous := discovery.NewResult(discovererId)
+	for _, entry := range inv.entries[discovererId] {
+		previous.Resources = append(previous.Resources, entry.Resource)
+	}
+	deltas := discovery.Diff(previous, result)
+
+	entries, ok := inv.entries[discovererId]
+	if !ok {
+		entries = map[string]Entry{}
+		inv.entries[discovererId] = entries
+	}
+	for _, delta := range deltas {
+		switch delta.DeltaType {
+		case discovery.DeltaTypeRemoved:
+			delete(entries, delta.Key)
+		default:
+			entries[delta.Key] = Entry{
+				Key:          delta.Key,
+				DiscovererId: discovererId,
+				Resource:     delta.Resource,
+			}
+		}
+	}
+
+	result.Lock()
+	inv.statuses[discovererId] = DiscovererStatus{
+		DiscovererId:  discovererId,
+		StartedAt:     result.Metadata.StartedAt,
+		EndedAt:       result.Metadata.EndedAt,
+		Stale:         result.Metadata.Stale,
+		ResourceCount: len(entries),
+		Errors:        append([]string{}, result.Errors...),
+		Warnings:      append([]string{}, result.Warnings...),
+	}
+	result.Unlock()
+
+	if len(deltas) > 0 {
+		for _, subscriber := range inv.subscribers {
+			select {
+			case subscriber <- deltas:
+			default: // never block on slow subscribers
+			}
+		}
+	}
+
+	return deltas
+}
+
+// List returns all entries matching a filter, sorted by key.
+func (inv *Inventory) List(filter Filter) []Entry {
+	inv.RLock()
+	defer inv.RUnlock()
+
+	matches := []Entry{}
+	for discovererId, entries := range inv.entries {
+		if !matchesAny(discovererId, filter.DiscovererIds) {
+			continue
+		}
+		for _, entry := range entries {
+			if !matchesAny(entry.Resource.ResourceType, filter.ResourceTypes) {
+				continue
+			}
+			if !matchesTags(entry.Resource.Tags(), filter.Tags) {
+				continue
+			}
+			matches = append(matches, entry)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Key == matches[j].Key {
+			return matches[i].DiscovererId < matches[j].DiscovererId
+		}
+		return matches[i].Key < matches[j].Key
+	})
+	return matches
+}
+
+// Get returns all entries with a given key. More than one entry is
+// returned only if multiple discoverers found the same resource.
+func (inv *Inventory) Get(key string) []Entry {
+	inv.RLock()
+	defer inv.RUnlock()
+
+	matches := []Entry{}
+	for _, entries := range inv.entries {
+		if entry, ok := entries[key]; ok {
+			matches = append(matches, entry)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].DiscovererId < matches[j].DiscovererId })
+	return matches
+}
+
+// Statuses returns the status of every discoverer, sorted by discoverer id.
+func (inv *Inventory) Statuses() []DiscovererStatus {
+	inv.RLock()
+	defer inv.RUnlock()
+
+	statuses := make([]DiscovererStatus, 0, len(inv.statuses))
+	for _, status := range inv.statuses {
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].DiscovererId < statuses[j].DiscovererId })
+	return statuses
+}
+
+// Subscribe returns a channel on which the changes from every update are sent,
+// and a function to cancel the subscription. Changes are dropped (rather than
+// blocking updates) for subscribers whose buffer is full.
+func (inv *Inventory) Subscribe(buffer int) (<-chan []discovery.ResourceDelta, func()) {
+	inv.Lock()
+	defer inv.Unlock()
+
+	id := inv.nextSubscriberId
+	inv.nextSubscriberId++
+
+	subscriber := make(chan []discovery.ResourceDelta, buffer)
+	inv.subscribers[id] = subscriber
+
+	return subscriber, func() {
+		inv.Lock()
+		defer inv.Unlock()
+
+		if _, ok := inv.subscribers[id]; ok {
+			close(subscriber)
+			delete(inv.subscribers, id)
+		}
+	}
+}
+
+func matchesAny(value string, values []string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesTags(tags map[string]string, required map[string]string) bool {
+	for k, v := range required {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
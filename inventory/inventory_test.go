@@ -0,0 +1,248 @@
+package inventory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/borderzero/discovery"
+)
+
+func newInventoryTestContainer(containerId string, labels map[string]string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeDockerContainer,
+		DockerContainerDetails: &discovery.DockerContainerDetails{
+			ContainerId: containerId,
+			Labels:      labels,
+		},
+	}
+}
+
+func newInventoryTestProxy(name string, tags map[string]string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeAwsRdsProxy,
+		AwsRdsProxyDetails: &discovery.AwsRdsProxyDetails{
+			AwsBaseDetails: discovery.AwsBaseDetails{AwsArn: "arn:aws:rds:us-east-1:123456789012:db-proxy:" + name},
+			Tags:           tags,
+			DbProxyName:    name,
+		},
+	}
+}
+
+func newInventoryTestResult(discovererId string, errored bool, resources ...discovery.Resource) *discovery.Result {
+	result := discovery.NewResult(discovererId)
+	result.AddResources(resources...)
+	if errored {
+		result.AddError("failed to list some resources")
+	}
+	result.Done()
+	return result
+}
+
+// deltaTypes returns the types of deltas, by key.
+func deltaTypes(deltas []discovery.ResourceDelta) map[string]string {
+	types := map[string]string{}
+	for _, delta := range deltas {
+		types[delta.Key] = delta.DeltaType
+	}
+	return types
+}
+
+func entryKeys(entries []Entry) []string {
+	keys := []string{}
+	for _, entry := range entries {
+		keys = append(keys, entry.Key)
+	}
+	return keys
+}
+
+func TestInventoryUpdate(t *testing.T) {
+	c1 := newInventoryTestContainer("c1", map[string]string{"app": "web"})
+	c1Updated := newInventoryTestContainer("c1", map[string]string{"app": "api"})
+	c2 := newInventoryTestContainer("c2", nil)
+
+	steps := []struct {
+		name           string
+		result         *discovery.Result
+		expectedDeltas map[string]string // delta type by key
+		expectedKeys   []string          // of the discoverer's entries
+	}{
+		{
+			name:   "resources are added",
+			result: newInventoryTestResult("d", false, c1, c2),
+			expectedDeltas: map[string]string{
+				c1.Key(): discovery.DeltaTypeAdded,
+				c2.Key(): discovery.DeltaTypeAdded,
+			},
+			expectedKeys: []string{c1.Key(), c2.Key()},
+		},
+		{
+			name:           "unchanged resources have no deltas",
+			result:         newInventoryTestResult("d", false, c1, c2),
+			expectedDeltas: map[string]string{},
+			expectedKeys:   []string{c1.Key(), c2.Key()},
+		},
+		{
+			name:           "missing resources are kept when the result has errors",
+			result:         newInventoryTestResult("d", true, c1Updated),
+			expectedDeltas: map[string]string{c1.Key(): discovery.DeltaTypeUpdated},
+			expectedKeys:   []string{c1.Key(), c2.Key()},
+		},
+		{
+			name:           "missing resources are removed",
+			result:         newInventoryTestResult("d", false, c1Updated),
+			expectedDeltas: map[string]string{c2.Key(): discovery.DeltaTypeRemoved},
+			expectedKeys:   []string{c1.Key()},
+		},
+		{
+			name:           "results of other discoverers are kept apart",
+			result:         newInventoryTestResult("other", false, c2),
+			expectedDeltas: map[string]string{c2.Key(): discovery.DeltaTypeAdded},
+			expectedKeys:   []string{c1.Key()},
+		},
+	}
+
+	inv := NewInventory()
+	for _, step := range steps {
+		deltas := inv.Update(step.result)
+
+		if types := deltaTypes(deltas); !maps.Equal(types, step.expectedDeltas) {
+			t.Fatalf("%s: expected deltas %v, got %v", step.name, step.expectedDeltas, types)
+		}
+		keys := entryKeys(inv.List(Filter{DiscovererIds: []string{"d"}}))
+		if !slices.Equal(keys, step.expectedKeys) {
+			t.Fatalf("%s: expected entries %v, got %v", step.name, step.expectedKeys, keys)
+		}
+	}
+
+	entries := inv.Get(c1.Key())
+	if len(entries) != 1 || entries[0].Resource.DockerContainerDetails.Labels["app"] != "api" {
+		t.Fatalf("expected the updated resource, got %v", entries)
+	}
+}
+
+func TestInventoryList(t *testing.T) {
+	web := newInventoryTestContainer("web", map[string]string{"team": "web"})
+	data := newInventoryTestContainer("data", map[string]string{"team": "data"})
+	proxy := newInventoryTestProxy("proxy", map[string]string{"team": "data"})
+
+	inv := NewInventory()
+	inv.Update(newInventoryTestResult("docker", false, web, data))
+	inv.Update(newInventoryTestResult("rds", false, proxy))
+	inv.Update(newInventoryTestResult("other", false, web))
+
+	tests := []struct {
+		name         string
+		filter       Filter
+		expectedKeys []string
+	}{
+		{
+			name:         "everything sorted by key then discoverer",
+			expectedKeys: []string{proxy.Key(), data.Key(), web.Key(), web.Key()},
+		},
+		{
+			name:         "by resource type",
+			filter:       Filter{ResourceTypes: []string{discovery.ResourceTypeAwsRdsProxy}},
+			expectedKeys: []string{proxy.Key()},
+		},
+		{
+			name:         "by discoverer",
+			filter:       Filter{DiscovererIds: []string{"docker", "rds"}},
+			expectedKeys: []string{proxy.Key(), data.Key(), web.Key()},
+		},
+		{
+			name:         "by tags",
+			filter:       Filter{Tags: map[string]string{"team": "data"}},
+			expectedKeys: []string{proxy.Key(), data.Key()},
+		},
+		{
+			name: "by all fields",
+			filter: Filter{
+				ResourceTypes: []string{discovery.ResourceTypeDockerContainer},
+				DiscovererIds: []string{"docker"},
+				Tags:          map[string]string{"team": "data"},
+			},
+			expectedKeys: []string{data.Key()},
+		},
+		{
+			name:         "no matches",
+			filter:       Filter{Tags: map[string]string{"team": "ops"}},
+			expectedKeys: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			keys := entryKeys(inv.List(test.filter))
+			if !slices.Equal(keys, test.expectedKeys) {
+				t.Fatalf("expected entries %v, got %v", test.expectedKeys, keys)
+			}
+		})
+	}
+
+	entries := inv.Get(web.Key())
+	if len(entries) != 2 || entries[0].DiscovererId != "docker" || entries[1].DiscovererId != "other" {
+		t.Fatalf("expected the entries of both discoverers sorted by discoverer, got %v", entries)
+	}
+	if entries := inv.Get("missing"); len(entries) != 0 {
+		t.Fatalf("expected no entries, got %v", entries)
+	}
+}
+
+func TestInventoryStatuses(t *testing.T) {
+	inv := NewInventory()
+	inv.Update(newInventoryTestResult("b", false, newInventoryTestContainer("c1", nil)))
+	inv.Update(newInventoryTestResult("a", true, newInventoryTestContainer("c1", nil), newInventoryTestContainer("c2", nil)))
+	inv.Update(newInventoryTestResult("b", true))
+
+	statuses := inv.Statuses()
+	if len(statuses) != 2 || statuses[0].DiscovererId != "a" || statuses[1].DiscovererId != "b" {
+		t.Fatalf("expected the statuses of both discoverers sorted by discoverer, got %v", statuses)
+	}
+	if statuses[0].ResourceCount != 2 || len(statuses[0].Errors) != 1 {
+		t.Fatalf("expected 2 resources and 1 error, got %v", statuses[0])
+	}
+	// the resource missing from the errored result is kept
+	if statuses[1].ResourceCount != 1 || len(statuses[1].Errors) != 1 {
+		t.Fatalf("expected the status of the last result with 1 resource kept, got %v", statuses[1])
+	}
+}
+
+func TestInventorySubscribe(t *testing.T) {
+	inv := NewInventory()
+
+	subscriber, unsubscribe := inv.Subscribe(1)
+	full, _ := inv.Subscribe(0) // never receives, must not block updates
+	closedOnClose, _ := inv.Subscribe(1)
+
+	inv.Update(newInventoryTestResult("d", false, newInventoryTestContainer("c1", nil)))
+	inv.Update(newInventoryTestResult("d", false, newInventoryTestContainer("c1", nil))) // no changes
+
+	select {
+	case deltas := <-subscriber:
+		if len(deltas) != 1 || deltas[0].DeltaType != discovery.DeltaTypeAdded {
+			t.Fatalf("expected a single added delta, got %v", deltas)
+		}
+	default:
+		t.Fatal("expected the subscriber to receive the changes")
+	}
+	select {
+	case deltas := <-subscriber:
+		t.Fatalf("expected no changes to be sent for updates without changes, got %v", deltas)
+	default:
+	}
+
+	unsubscribe()
+	unsubscribe() // must be safe to call twice
+	if _, ok := <-subscriber; ok {
+		t.Fatal("expected the subscriber channel to be closed on unsubscribe")
+	}
+
+	if err := inv.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range []<-chan []discovery.ResourceDelta{full, closedOnClose} {
+		for range c { // drain buffered changes
+		}
+	}
+}
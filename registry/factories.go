@@ -0,0 +1,650 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	ssmtypes "github.com/aws/aws-sdk-go-v2/service/ssm/types"
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/discoverers"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	// KindAwsEc2 is the discoverer kind for discoverers.AwsEc2Discoverer.
+	KindAwsEc2 = "ec2"
+
+	// KindAwsEice is the discoverer kind for discoverers.AwsEiceDiscoverer.
+	KindAwsEice = "eice"
+
+	// KindAwsEcs is the discoverer kind for discoverers.AwsEcsDiscoverer.
+	KindAwsEcs = "ecs"
+
+	// KindAwsEks is the discoverer kind for discoverers.AwsEksDiscoverer.
+	KindAwsEks = "eks"
+
+	// KindAwsRds is the discoverer kind for discoverers.AwsRdsDiscoverer.
+	KindAwsRds = "rds"
+
+	// KindAwsSsm is the discoverer kind for discoverers.AwsSsmDiscoverer.
+	KindAwsSsm = "ssm"
+
+	// KindKubernetes is the discoverer kind for discoverers.KubernetesDiscoverer.
+	KindKubernetes = "kubernetes"
+
+	// KindDocker is the discoverer kind for discoverers.DockerDiscoverer.
+	KindDocker = "docker"
+
+	// KindNetwork is the discoverer kind for discoverers.NetworkDiscoverer.
+	KindNetwork = "network"
+
+	// awsAllRegions is the value of regions for all enabled regions.
+	awsAllRegions = "all"
+)
+
+var builtinFactories = map[string]Factory{
+	KindAwsEc2: func(ctx context.Context, config Config) (discovery.Discoverer, error) {
+		var c ec2Config
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return c.build(ctx, func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			opts := ec2Options(c)
+			if stsClient != nil {
+				opts = append(opts, discoverers.WithAwsEc2DiscovererStsClient(stsClient))
+			}
+			return discoverers.NewAwsEc2Discoverer(cfg, opts...)
+		})
+	},
+	KindAwsEice: func(ctx context.Context, config Config) (discovery.Discoverer, error) {
+		var c eiceConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return c.build(ctx, func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			opts := eiceOptions(c)
+			if stsClient != nil {
+				opts = append(opts, discoverers.WithAwsEiceDiscovererStsClient(stsClient))
+			}
+			return discoverers.NewAwsEiceDiscoverer(cfg, opts...)
+		})
+	},
+	KindAwsEcs: func(ctx context.Context, config Config) (discovery.Discoverer, error) {
+		var c ecsConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return c.build(ctx, func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			opts := ecsOptions(c)
+			if stsClient != nil {
+				opts = append(opts, discoverers.WithAwsEcsDiscovererStsClient(stsClient))
+			}
+			return discoverers.NewAwsEcsDiscoverer(cfg, opts...)
+		})
+	},
+	KindAwsEks: func(ctx context.Context, config Config) (discovery.Discoverer, error) {
+		var c eksConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return c.build(ctx, func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			opts := eksOptions(c)
+			if stsClient != nil {
+				opts = append(opts, discoverers.WithAwsEksDiscovererStsClient(stsClient))
+			}
+			return discoverers.NewAwsEksDiscoverer(cfg, opts...)
+		})
+	},
+	KindAwsRds: func(ctx context.Context, config Config) (discovery.Discoverer, error) {
+		var c rdsConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return c.build(ctx, func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			opts := rdsOptions(c)
+			if stsClient != nil {
+				opts = append(opts, discoverers.WithAwsRdsDiscovererStsClient(stsClient))
+			}
+			return discoverers.NewAwsRdsDiscoverer(cfg, opts...)
+		})
+	},
+	KindAwsSsm: func(ctx context.Context, config Config) (discovery.Discoverer, error) {
+		var c ssmConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return c.build(ctx, func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+			opts := ssmOptions(c)
+			if stsClient != nil {
+				opts = append(opts, discoverers.WithAwsSsmDiscovererStsClient(stsClient))
+			}
+			return discoverers.NewAwsSsmDiscoverer(cfg, opts...)
+		})
+	},
+	KindKubernetes: func(_ context.Context, config Config) (discovery.Discoverer, error) {
+		var c kubernetesConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return discoverers.NewKubernetesDiscoverer(kubernetesOptions(c)...), nil
+	},
+	KindDocker: func(_ context.Context, config Config) (discovery.Discoverer, error) {
+		var c dockerConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return discoverers.NewDockerDiscoverer(dockerOptions(c)...), nil
+	},
+	KindNetwork: func(_ context.Context, config Config) (discovery.Discoverer, error) {
+		var c networkConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return discoverers.NewNetworkDiscoverer(networkOptions(c)...), nil
+	},
+}
+
+// awsCommonConfig selects the AWS credentials, account(s) and region(s) for AWS
+// discoverers. When not set, the defaults of the AWS SDK (environment, shared
+// config) apply. When regions are set (or "all", for all enabled regions), the
+// discoverer runs in each region, wrapped in a discoverers.AwsMultiRegionDiscoverer.
+// When role ARNs or an organization role name are set, the (possibly multi-region)
+// discoverer runs in each account, wrapped in a discoverers.AwsMultiAccountDiscoverer.
+// The multi-region (multi-account) options are ignored unless regions (role ARNs or
+// an organization role name) are set.
+type awsCommonConfig struct {
+	Region                   string         `yaml:"region"`
+	Profile                  string         `yaml:"profile"`
+	Regions                  []string       `yaml:"regions"`
+	MaxRegionConcurrency     *int64         `yaml:"max_region_concurrency"`
+	MultiRegionDiscovererId  *string        `yaml:"multi_region_discoverer_id"`
+	DescribeRegionsTimeout   *time.Duration `yaml:"describe_regions_timeout"`
+	RoleArns                 []string       `yaml:"role_arns"`
+	OrganizationRoleName     string         `yaml:"organization_role_name"`
+	ExternalId               string         `yaml:"external_id"`
+	RoleSessionName          string         `yaml:"role_session_name"`
+	MaxAccountConcurrency    *int64         `yaml:"max_account_concurrency"`
+	MultiAccountDiscovererId *string        `yaml:"multi_account_discoverer_id"`
+	ListAccountsTimeout      *time.Duration `yaml:"list_accounts_timeout"`
+}
+
+// build builds an AWS discoverer with the given factory, wrapped in a
+// discoverers.AwsMultiRegionDiscoverer and/or discoverers.AwsMultiAccountDiscoverer
+// as configured. The factory is given a nil sts client when discovering in a single
+// region of a single account.
+func (c awsCommonConfig) build(
+	ctx context.Context,
+	factory discoverers.AwsRegionalDiscovererFactory,
+) (discovery.Discoverer, error) {
+	cfg, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	regional, err := c.regional(factory)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.RoleArns) == 0 && c.OrganizationRoleName == "" {
+		return regional(cfg, nil), nil
+	}
+	opts := []discoverers.AwsMultiAccountDiscovererOption{}
+	if len(c.RoleArns) > 0 {
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererRoleArns(c.RoleArns...))
+	}
+	if c.OrganizationRoleName != "" {
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererOrganizationRoleName(c.OrganizationRoleName))
+	}
+	if c.ExternalId != "" {
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererExternalId(c.ExternalId))
+	}
+	if c.RoleSessionName != "" {
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererRoleSessionName(c.RoleSessionName))
+	}
+	if c.MultiAccountDiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererDiscovererId(*c.MultiAccountDiscovererId))
+	}
+	if c.ListAccountsTimeout != nil {
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererListAccountsTimeout(*c.ListAccountsTimeout))
+	}
+	if c.MaxAccountConcurrency != nil {
+		if *c.MaxAccountConcurrency < 1 {
+			return nil, fmt.Errorf("max_account_concurrency must be at least 1")
+		}
+		opts = append(opts, discoverers.WithAwsMultiAccountDiscovererMaxConcurrency(*c.MaxAccountConcurrency))
+	}
+	return discoverers.NewAwsMultiAccountDiscoverer(cfg, regional, opts...), nil
+}
+
+// regional returns a factory which builds the discoverer for an account with the
+// given factory, wrapped in a discoverers.AwsMultiRegionDiscoverer when regions
+// are configured.
+func (c awsCommonConfig) regional(
+	factory discoverers.AwsRegionalDiscovererFactory,
+) (func(aws.Config, utils.AwsStsClient) discovery.Discoverer, error) {
+	if len(c.Regions) == 0 {
+		return factory, nil
+	}
+	opts := []discoverers.AwsMultiRegionDiscovererOption{}
+	if c.MaxRegionConcurrency != nil {
+		if *c.MaxRegionConcurrency < 1 {
+			return nil, fmt.Errorf("max_region_concurrency must be at least 1")
+		}
+		opts = append(opts, discoverers.WithAwsMultiRegionDiscovererMaxConcurrency(*c.MaxRegionConcurrency))
+	}
+	if c.MultiRegionDiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsMultiRegionDiscovererDiscovererId(*c.MultiRegionDiscovererId))
+	}
+	if c.DescribeRegionsTimeout != nil {
+		opts = append(opts, discoverers.WithAwsMultiRegionDiscovererDescribeRegionsTimeout(*c.DescribeRegionsTimeout))
+	}
+	if len(c.Regions) != 1 || c.Regions[0] != awsAllRegions {
+		for _, region := range c.Regions {
+			if region == awsAllRegions {
+				return nil, fmt.Errorf("regions must either be \"%s\" or a list of regions", awsAllRegions)
+			}
+		}
+		opts = append(opts, discoverers.WithAwsMultiRegionDiscovererRegions(c.Regions...))
+	}
+	return func(cfg aws.Config, stsClient utils.AwsStsClient) discovery.Discoverer {
+		accountOpts := append([]discoverers.AwsMultiRegionDiscovererOption{}, opts...)
+		if stsClient != nil {
+			accountOpts = append(accountOpts, discoverers.WithAwsMultiRegionDiscovererStsClient(stsClient))
+		}
+		return discoverers.NewAwsMultiRegionDiscoverer(cfg, factory, accountOpts...)
+	}, nil
+}
+
+func (c awsCommonConfig) load(ctx context.Context) (aws.Config, error) {
+	opts := []func(*awsconfig.LoadOptions) error{}
+	if c.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(c.Region))
+	}
+	if c.Profile != "" {
+		opts = append(opts, awsconfig.WithSharedConfigProfile(c.Profile))
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS configuration: %v", err)
+	}
+	return cfg, nil
+}
+
+type ec2Config struct {
+	awsCommonConfig `yaml:",inline"`
+
+	DiscovererId             *string             `yaml:"discoverer_id"`
+	SsmStatusCheckEnabled    *bool               `yaml:"ssm_status_check_enabled"`
+	SsmStatusCheckRequired   *bool               `yaml:"ssm_status_check_required"`
+	NetworkReachabilityCheck *bool               `yaml:"network_reachability_check"`
+	ReachabilityRequired     *bool               `yaml:"reachability_required"`
+	EiceCheckEnabled         *bool               `yaml:"eice_check_enabled"`
+	GetAccountIdTimeout      *time.Duration      `yaml:"get_account_id_timeout"`
+	DescribeInstancesTimeout *time.Duration      `yaml:"describe_instances_timeout"`
+	IncludedInstanceStates   []string            `yaml:"included_instance_states"`
+	InclusionInstanceTags    map[string][]string `yaml:"inclusion_instance_tags"`
+	ExclusionInstanceTags    map[string][]string `yaml:"exclusion_instance_tags"`
+
+	DescribeInstanceConnectEndpointsTimeout *time.Duration `yaml:"describe_instance_connect_endpoints_timeout"`
+
+	StaticReachability *staticReachabilityConfig `yaml:"static_reachability"`
+}
+
+type eiceConfig struct {
+	awsCommonConfig `yaml:",inline"`
+
+	DiscovererId                            *string             `yaml:"discoverer_id"`
+	GetAccountIdTimeout                     *time.Duration      `yaml:"get_account_id_timeout"`
+	DescribeInstanceConnectEndpointsTimeout *time.Duration      `yaml:"describe_instance_connect_endpoints_timeout"`
+	IncludedEndpointStates                  []string            `yaml:"included_endpoint_states"`
+	InclusionEndpointTags                   map[string][]string `yaml:"inclusion_endpoint_tags"`
+	ExclusionEndpointTags                   map[string][]string `yaml:"exclusion_endpoint_tags"`
+}
+
+// staticReachabilityConfig is the configuration of the static reachability
+// analysis, with exactly one of the cidr, security group id and network
+// interface id of the source set. Ports are ignored for rds discoverers.
+type staticReachabilityConfig struct {
+	Cidr               string  `yaml:"cidr"`
+	SecurityGroupId    string  `yaml:"security_group_id"`
+	NetworkInterfaceId string  `yaml:"network_interface_id"`
+	Ports              []int32 `yaml:"ports"`
+}
+
+func (c staticReachabilityConfig) source() discoverers.AwsReachabilitySource {
+	return discoverers.AwsReachabilitySource{
+		Cidr:               c.Cidr,
+		SecurityGroupId:    c.SecurityGroupId,
+		NetworkInterfaceId: c.NetworkInterfaceId,
+	}
+}
+
+type ecsConfig struct {
+	awsCommonConfig `yaml:",inline"`
+
+	DiscovererId         *string             `yaml:"discoverer_id"`
+	GetAccountIdTimeout  *time.Duration      `yaml:"get_account_id_timeout"`
+	InclusionServiceTags map[string][]string `yaml:"inclusion_service_tags"`
+	ExclusionServiceTags map[string][]string `yaml:"exclusion_service_tags"`
+}
+
+type eksConfig struct {
+	awsCommonConfig `yaml:",inline"`
+
+	DiscovererId             *string             `yaml:"discoverer_id"`
+	GetAccountIdTimeout      *time.Duration      `yaml:"get_account_id_timeout"`
+	InclusionClusterTags     map[string][]string `yaml:"inclusion_cluster_tags"`
+	ExclusionClusterTags     map[string][]string `yaml:"exclusion_cluster_tags"`
+	NetworkReachabilityCheck *bool               `yaml:"network_reachability_check"`
+	ReachabilityRequired     *bool               `yaml:"reachability_required"`
+}
+
+type rdsConfig struct {
+	awsCommonConfig `yaml:",inline"`
+
+	DiscovererId             *string             `yaml:"discoverer_id"`
+	GetAccountIdTimeout      *time.Duration      `yaml:"get_account_id_timeout"`
+	DescribeInstancesTimeout *time.Duration      `yaml:"describe_instances_timeout"`
+	NetworkReachabilityCheck *bool               `yaml:"network_reachability_check"`
+	ReachabilityRequired     *bool               `yaml:"reachability_required"`
+	IncludedInstanceStatuses []string            `yaml:"included_instance_statuses"`
+	InclusionInstanceTags    map[string][]string `yaml:"inclusion_instance_tags"`
+	ExclusionInstanceTags    map[string][]string `yaml:"exclusion_instance_tags"`
+	ClustersEnabled          *bool               `yaml:"clusters_enabled"`
+	ProxiesEnabled           *bool               `yaml:"proxies_enabled"`
+
+	StaticReachability *staticReachabilityConfig `yaml:"static_reachability"`
+}
+
+type ssmConfig struct {
+	awsCommonConfig `yaml:",inline"`
+
+	DiscovererId                       *string        `yaml:"discoverer_id"`
+	GetAccountIdTimeout                *time.Duration `yaml:"get_account_id_timeout"`
+	DescribeInstanceInformationTimeout *time.Duration `yaml:"describe_instance_information_timeout"`
+	IncludedPingStatuses               []string       `yaml:"included_ping_statuses"`
+	IncludedResourceTypes              []string       `yaml:"included_resource_types"`
+}
+
+type kubernetesConfig struct {
+	DiscovererId           *string             `yaml:"discoverer_id"`
+	MasterUrl              *string             `yaml:"master_url"`
+	KubeconfigPath         *string             `yaml:"kubeconfig_path"`
+	Namespace              *string             `yaml:"namespace"`
+	InclusionServiceLabels map[string][]string `yaml:"inclusion_service_labels"`
+	ExclusionServiceLabels map[string][]string `yaml:"exclusion_service_labels"`
+}
+
+type dockerConfig struct {
+	DiscovererId             *string             `yaml:"discoverer_id"`
+	ListContainersTimeout    *time.Duration      `yaml:"list_containers_timeout"`
+	InclusionContainerLabels map[string][]string `yaml:"inclusion_container_labels"`
+	ExclusionContainerLabels map[string][]string `yaml:"exclusion_container_labels"`
+}
+
+type networkConfig struct {
+	DiscovererId      *string        `yaml:"discoverer_id"`
+	ScanTimeout       *time.Duration `yaml:"scan_timeout"`
+	MaxConcurrency    *int64         `yaml:"max_concurrency"`
+	Targets           []string       `yaml:"targets"`
+	Ports             []string       `yaml:"ports"`
+	SshHostKeyScan    *bool          `yaml:"ssh_host_key_scan"`
+	SshHostKeyTimeout *time.Duration `yaml:"ssh_host_key_timeout"`
+}
+
+func ec2Options(c ec2Config) []discoverers.AwsEc2DiscovererOption {
+	opts := []discoverers.AwsEc2DiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.SsmStatusCheckEnabled != nil || c.SsmStatusCheckRequired != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererSsmStatusCheck(
+			orDefault(c.SsmStatusCheckEnabled, true),
+			orDefault(c.SsmStatusCheckRequired, false),
+		))
+	}
+	if c.NetworkReachabilityCheck != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererNetworkReachabilityCheck(*c.NetworkReachabilityCheck))
+	}
+	if c.ReachabilityRequired != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererReachabilityRequired(*c.ReachabilityRequired))
+	}
+	if c.EiceCheckEnabled != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererEiceCheck(*c.EiceCheckEnabled))
+	}
+	if c.StaticReachability != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererStaticReachabilityAnalysis(
+			c.StaticReachability.source(),
+			c.StaticReachability.Ports...,
+		))
+	}
+	if c.GetAccountIdTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererGetAccountIdTimeout(*c.GetAccountIdTimeout))
+	}
+	if c.DescribeInstancesTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererDescribeInstancesTimeout(*c.DescribeInstancesTimeout))
+	}
+	if c.DescribeInstanceConnectEndpointsTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererDescribeInstanceConnectEndpointsTimeout(*c.DescribeInstanceConnectEndpointsTimeout))
+	}
+	if c.IncludedInstanceStates != nil {
+		states := []types.InstanceStateName{}
+		for _, state := range c.IncludedInstanceStates {
+			states = append(states, types.InstanceStateName(state))
+		}
+		opts = append(opts, discoverers.WithAwsEc2DiscovererIncludedInstanceStates(states...))
+	}
+	if c.InclusionInstanceTags != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererInclusionInstanceTags(c.InclusionInstanceTags))
+	}
+	if c.ExclusionInstanceTags != nil {
+		opts = append(opts, discoverers.WithAwsEc2DiscovererExclusionInstanceTags(c.ExclusionInstanceTags))
+	}
+	return opts
+}
+
+func eiceOptions(c eiceConfig) []discoverers.AwsEiceDiscovererOption {
+	opts := []discoverers.AwsEiceDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsEiceDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.GetAccountIdTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEiceDiscovererGetAccountIdTimeout(*c.GetAccountIdTimeout))
+	}
+	if c.DescribeInstanceConnectEndpointsTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEiceDiscovererDescribeInstanceConnectEndpointsTimeout(*c.DescribeInstanceConnectEndpointsTimeout))
+	}
+	if c.IncludedEndpointStates != nil {
+		states := []types.Ec2InstanceConnectEndpointState{}
+		for _, state := range c.IncludedEndpointStates {
+			states = append(states, types.Ec2InstanceConnectEndpointState(state))
+		}
+		opts = append(opts, discoverers.WithAwsEiceDiscovererIncludedEndpointStates(states...))
+	}
+	if c.InclusionEndpointTags != nil {
+		opts = append(opts, discoverers.WithAwsEiceDiscovererInclusionEndpointTags(c.InclusionEndpointTags))
+	}
+	if c.ExclusionEndpointTags != nil {
+		opts = append(opts, discoverers.WithAwsEiceDiscovererExclusionEndpointTags(c.ExclusionEndpointTags))
+	}
+	return opts
+}
+
+func ecsOptions(c ecsConfig) []discoverers.AwsEcsDiscovererOption {
+	opts := []discoverers.AwsEcsDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsEcsDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.GetAccountIdTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEcsDiscovererGetAccountIdTimeout(*c.GetAccountIdTimeout))
+	}
+	if c.InclusionServiceTags != nil {
+		opts = append(opts, discoverers.WithAwsEcsDiscovererInclusionServiceTags(c.InclusionServiceTags))
+	}
+	if c.ExclusionServiceTags != nil {
+		opts = append(opts, discoverers.WithAwsEcsDiscovererExclusionServiceTags(c.ExclusionServiceTags))
+	}
+	return opts
+}
+
+func eksOptions(c eksConfig) []discoverers.AwsEksDiscovererOption {
+	opts := []discoverers.AwsEksDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsEksDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.GetAccountIdTimeout != nil {
+		opts = append(opts, discoverers.WithAwsEksDiscovererGetAccountIdTimeout(*c.GetAccountIdTimeout))
+	}
+	if c.InclusionClusterTags != nil {
+		opts = append(opts, discoverers.WithAwsEksDiscovererInclusionServiceTags(c.InclusionClusterTags))
+	}
+	if c.ExclusionClusterTags != nil {
+		opts = append(opts, discoverers.WithAwsEksDiscovererExclusionServiceTags(c.ExclusionClusterTags))
+	}
+	if c.NetworkReachabilityCheck != nil {
+		opts = append(opts, discoverers.WithAwsEksDiscovererNetworkReachabilityCheck(*c.NetworkReachabilityCheck))
+	}
+	if c.ReachabilityRequired != nil {
+		opts = append(opts, discoverers.WithAwsEksDiscovererReachabilityRequired(*c.ReachabilityRequired))
+	}
+	return opts
+}
+
+func rdsOptions(c rdsConfig) []discoverers.AwsRdsDiscovererOption {
+	opts := []discoverers.AwsRdsDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.GetAccountIdTimeout != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererGetAccountIdTimeout(*c.GetAccountIdTimeout))
+	}
+	if c.DescribeInstancesTimeout != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererDescribeInstancesTimeout(*c.DescribeInstancesTimeout))
+	}
+	if c.NetworkReachabilityCheck != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererNetworkReachabilityCheck(*c.NetworkReachabilityCheck))
+	}
+	if c.ReachabilityRequired != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererReachabilityRequired(*c.ReachabilityRequired))
+	}
+	if c.StaticReachability != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererStaticReachabilityAnalysis(c.StaticReachability.source()))
+	}
+	if c.IncludedInstanceStatuses != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererIncludedInstanceStatuses(c.IncludedInstanceStatuses...))
+	}
+	if c.InclusionInstanceTags != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererInclusionInstanceTags(c.InclusionInstanceTags))
+	}
+	if c.ExclusionInstanceTags != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererExclusionInstanceTags(c.ExclusionInstanceTags))
+	}
+	if c.ClustersEnabled != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererClusters(*c.ClustersEnabled))
+	}
+	if c.ProxiesEnabled != nil {
+		opts = append(opts, discoverers.WithAwsRdsDiscovererProxies(*c.ProxiesEnabled))
+	}
+	return opts
+}
+
+func ssmOptions(c ssmConfig) []discoverers.AwsSsmDiscovererOption {
+	opts := []discoverers.AwsSsmDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithAwsSsmDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.GetAccountIdTimeout != nil {
+		opts = append(opts, discoverers.WithAwsSsmDiscovererGetAccountIdTimeout(*c.GetAccountIdTimeout))
+	}
+	if c.DescribeInstanceInformationTimeout != nil {
+		opts = append(opts, discoverers.WithAwsSsmDiscovererDescribeInstanceInformationTimeout(*c.DescribeInstanceInformationTimeout))
+	}
+	if c.IncludedPingStatuses != nil {
+		statuses := []ssmtypes.PingStatus{}
+		for _, status := range c.IncludedPingStatuses {
+			statuses = append(statuses, ssmtypes.PingStatus(status))
+		}
+		opts = append(opts, discoverers.WithAwsSsmDiscovererIncludedPingStatuses(statuses...))
+	}
+	if c.IncludedResourceTypes != nil {
+		opts = append(opts, discoverers.WithAwsSsmDiscovererIncludedResourceTypes(c.IncludedResourceTypes...))
+	}
+	return opts
+}
+
+func kubernetesOptions(c kubernetesConfig) []discoverers.KubernetesDiscovererOption {
+	opts := []discoverers.KubernetesDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithKubernetesDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.MasterUrl != nil {
+		opts = append(opts, discoverers.WithKubernetesDiscovererMasterUrl(*c.MasterUrl))
+	}
+	if c.KubeconfigPath != nil {
+		opts = append(opts, discoverers.WithKubernetesDiscovererKubeconfigPath(*c.KubeconfigPath))
+	}
+	if c.Namespace != nil {
+		opts = append(opts, discoverers.WithKubernetesDiscovererNamespace(*c.Namespace))
+	}
+	if c.InclusionServiceLabels != nil {
+		opts = append(opts, discoverers.WithKubernetesDiscovererInclusionServiceLabels(c.InclusionServiceLabels))
+	}
+	if c.ExclusionServiceLabels != nil {
+		opts = append(opts, discoverers.WithKubernetesDiscovererExclusionServiceLabels(c.ExclusionServiceLabels))
+	}
+	return opts
+}
+
+func dockerOptions(c dockerConfig) []discoverers.DockerDiscovererOption {
+	opts := []discoverers.DockerDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithDockerDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.ListContainersTimeout != nil {
+		opts = append(opts, discoverers.WithDockerDiscovererListContainersTimeout(*c.ListContainersTimeout))
+	}
+	if c.InclusionContainerLabels != nil {
+		opts = append(opts, discoverers.WithDockerDiscovererInclusionContainerLabels(c.InclusionContainerLabels))
+	}
+	if c.ExclusionContainerLabels != nil {
+		opts = append(opts, discoverers.WithDockerDiscovererExclusionContainerLabels(c.ExclusionContainerLabels))
+	}
+	return opts
+}
+
+func networkOptions(c networkConfig) []discoverers.NetworkDiscovererOption {
+	opts := []discoverers.NetworkDiscovererOption{}
+	if c.DiscovererId != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererDiscovererId(*c.DiscovererId))
+	}
+	if c.ScanTimeout != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererScanTimeout(*c.ScanTimeout))
+	}
+	if c.MaxConcurrency != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererMaxConcurrency(*c.MaxConcurrency))
+	}
+	if c.Targets != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererTargets(c.Targets...))
+	}
+	if c.Ports != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererPorts(c.Ports...))
+	}
+	if c.SshHostKeyScan != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererSshHostKeyScan(*c.SshHostKeyScan))
+	}
+	if c.SshHostKeyTimeout != nil {
+		opts = append(opts, discoverers.WithNetworkDiscovererSshHostKeyTimeout(*c.SshHostKeyTimeout))
+	}
+	return opts
+}
+
+func orDefault[T any](p *T, def T) T {
+	if p == nil {
+		return def
+	}
+	return *p
+}
@@ -0,0 +1,185 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// setRegistryTestAwsEnv makes loading the AWS configuration
+// independent of the environment and of shared config files.
+func setRegistryTestAwsEnv(t *testing.T) {
+	t.Helper()
+
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_PROFILE", "")
+}
+
+func TestBuiltinFactories(t *testing.T) {
+	setRegistryTestAwsEnv(t)
+
+	tests := []struct {
+		name         string
+		kind         string
+		config       Config
+		expectedType string
+		expectedErr  string
+	}{
+		{name: "ec2", kind: KindAwsEc2, expectedType: "*discoverers.AwsEc2Discoverer"},
+		{name: "eice", kind: KindAwsEice, expectedType: "*discoverers.AwsEiceDiscoverer"},
+		{name: "ecs", kind: KindAwsEcs, expectedType: "*discoverers.AwsEcsDiscoverer"},
+		{name: "eks", kind: KindAwsEks, expectedType: "*discoverers.AwsEksDiscoverer"},
+		{name: "rds", kind: KindAwsRds, expectedType: "*discoverers.AwsRdsDiscoverer"},
+		{name: "ssm", kind: KindAwsSsm, expectedType: "*discoverers.AwsSsmDiscoverer"},
+		{name: "kubernetes", kind: KindKubernetes, expectedType: "*discoverers.KubernetesDiscoverer"},
+		{name: "docker", kind: KindDocker, expectedType: "*discoverers.DockerDiscoverer"},
+		{name: "network", kind: KindNetwork, expectedType: "*discoverers.NetworkDiscoverer"},
+		{
+			name: "all options",
+			kind: KindAwsEc2,
+			config: Config{
+				"region":                     "eu-west-1",
+				"discoverer_id":              "ec2",
+				"ssm_status_check_required":  true,
+				"describe_instances_timeout": "30s",
+				"included_instance_states":   []any{"running"},
+				"inclusion_instance_tags":    map[string]any{"env": []any{"prod"}},
+				"static_reachability":        map[string]any{"cidr": "10.0.0.0/8", "ports": []any{22}},
+			},
+			expectedType: "*discoverers.AwsEc2Discoverer",
+		},
+		{
+			name:         "regions",
+			kind:         KindAwsRds,
+			config:       Config{"regions": []any{"us-east-1", "eu-west-1"}, "max_region_concurrency": 2},
+			expectedType: "*discoverers.AwsMultiRegionDiscoverer",
+		},
+		{
+			name: "all regions",
+			kind: KindAwsEcs,
+			config: Config{
+				"regions":                    []any{"all"},
+				"multi_region_discoverer_id": "ecs",
+				"describe_regions_timeout":   "5s",
+			},
+			expectedType: "*discoverers.AwsMultiRegionDiscoverer",
+		},
+		{
+			name: "accounts",
+			kind: KindAwsEks,
+			config: Config{
+				"regions":                     []any{"all"},
+				"role_arns":                   []any{"arn:aws:iam::111111111111:role/discovery"},
+				"external_id":                 "external",
+				"role_session_name":           "session",
+				"max_account_concurrency":     2,
+				"multi_account_discoverer_id": "eks",
+			},
+			expectedType: "*discoverers.AwsMultiAccountDiscoverer",
+		},
+		{
+			name:         "organization accounts",
+			kind:         KindAwsSsm,
+			config:       Config{"organization_role_name": "OrganizationAccountAccessRole", "list_accounts_timeout": "5s"},
+			expectedType: "*discoverers.AwsMultiAccountDiscoverer",
+		},
+		{
+			name:        "unknown keys of another kind",
+			kind:        KindDocker,
+			config:      Config{"regions": []any{"all"}},
+			expectedErr: "unknown key(s) \"regions\"",
+		},
+		{
+			name:        "all regions along with other regions",
+			kind:        KindAwsEc2,
+			config:      Config{"regions": []any{"all", "us-east-1"}},
+			expectedErr: "regions must either be \"all\" or a list of regions",
+		},
+		{
+			name:        "invalid region concurrency",
+			kind:        KindAwsEc2,
+			config:      Config{"regions": []any{"all"}, "max_region_concurrency": 0},
+			expectedErr: "max_region_concurrency must be at least 1",
+		},
+		{
+			name:        "invalid account concurrency",
+			kind:        KindAwsEc2,
+			config:      Config{"organization_role_name": "role", "max_account_concurrency": 0},
+			expectedErr: "max_account_concurrency must be at least 1",
+		},
+		{
+			name:        "missing profile",
+			kind:        KindAwsEc2,
+			config:      Config{"profile": "missing"},
+			expectedErr: "failed to load AWS configuration",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discoverer, err := Build(context.Background(), test.kind, test.config)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected an error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if discovererType := fmt.Sprintf("%T", discoverer); discovererType != test.expectedType {
+				t.Fatalf("expected a %s, got a %s", test.expectedType, discovererType)
+			}
+		})
+	}
+}
+
+func TestBuiltinFactoriesDiscovererIds(t *testing.T) {
+	setRegistryTestAwsEnv(t)
+
+	tests := []struct {
+		name                 string
+		kind                 string
+		config               Config
+		expectedDiscovererId string
+	}{
+		{
+			name: "discoverer id",
+			kind: KindNetwork,
+			config: Config{
+				"discoverer_id": "lan",
+				"targets":       []any{"127.0.0.1"},
+				"ports":         []any{"1"},
+				"scan_timeout":  "100ms",
+			},
+			expectedDiscovererId: "lan",
+		},
+		{
+			// an invalid role arn fails without calling the AWS APIs
+			name: "multi-account discoverer id",
+			kind: KindAwsEc2,
+			config: Config{
+				"role_arns":                   []any{"not-an-arn"},
+				"multi_account_discoverer_id": "accounts",
+			},
+			expectedDiscovererId: "accounts",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discoverer, err := Build(context.Background(), test.kind, test.config)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result := discoverer.Discover(context.Background())
+			if result.Metadata.DiscovererId != test.expectedDiscovererId {
+				t.Fatalf("expected discoverer id %s, got %s", test.expectedDiscovererId, result.Metadata.DiscovererId)
+			}
+		})
+	}
+}
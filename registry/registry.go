@@ -0,0 +1,186 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/borderzero/discovery"
+	"gopkg.in/yaml.v3"
+)
+
+// Config represents the generic configuration of a discoverer
+// e.g. as decoded from a YAML or JSON configuration file.
+type Config map[string]any
+
+// Factory represents a function which builds a discoverer from a generic configuration.
+type Factory func(ctx context.Context, config Config) (discovery.Discoverer, error)
+
+// Registry represents a mapping of discoverer kinds to the factories which build them.
+type Registry struct {
+	sync.RWMutex // inherit lock behaviour
+
+	factories map[string]Factory
+}
+
+// defaultRegistry is the registry used by the package-level functions,
+// initialized with a factory for each discoverer in the discoverers package.
+var defaultRegistry = NewRegistry()
+
+func init() {
+	for kind, factory := range builtinFactories {
+		defaultRegistry.MustRegister(kind, factory)
+	}
+}
+
+// NewRegistry returns a new, empty, Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: map[string]Factory{}}
+}
+
+// Register adds the factory for a discoverer kind to the Registry.
+// Returns an error if a factory is already registered for the kind.
+func (r *Registry) Register(kind string, factory Factory) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if _, exists := r.factories[kind]; exists {
+		return fmt.Errorf("a factory for discoverer kind \"%s\" is already registered", kind)
+	}
+	r.factories[kind] = factory
+	return nil
+}
+
+// MustRegister is like Register but panics on error. It is meant to be called from init().
+func (r *Registry) MustRegister(kind string, factory Factory) {
+	if err := r.Register(kind, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Build builds a discoverer of a given kind from a generic configuration.
+func (r *Registry) Build(ctx context.Context, kind string, config Config) (discovery.Discoverer, error) {
+	r.RLock()
+	factory, ok := r.factories[kind]
+	r.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown discoverer kind \"%s\" (known kinds: %s)", kind, strings.Join(r.Kinds(), ", "))
+	}
+
+	discoverer, err := factory(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration for discoverer kind \"%s\": %w", kind, err)
+	}
+	return discoverer, nil
+}
+
+// Kinds returns all the discoverer kinds in the Registry, sorted.
+func (r *Registry) Kinds() []string {
+	r.RLock()
+	defer r.RUnlock()
+
+	kinds := make([]string, 0, len(r.factories))
+	for kind := range r.factories {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Register adds the factory for a discoverer kind to the default registry.
+func Register(kind string, factory Factory) error {
+	return defaultRegistry.Register(kind, factory)
+}
+
+// MustRegister adds the factory for a discoverer kind to the default registry, or panics.
+func MustRegister(kind string, factory Factory) {
+	defaultRegistry.MustRegister(kind, factory)
+}
+
+// Build builds a discoverer of a given kind with the default registry.
+func Build(ctx context.Context, kind string, config Config) (discovery.Discoverer, error) {
+	return defaultRegistry.Build(ctx, kind, config)
+}
+
+// Kinds returns all the discoverer kinds in the default registry, sorted.
+func Kinds() []string {
+	return defaultRegistry.Kinds()
+}
+
+// Decode decodes a generic configuration into a struct with yaml
+// field tags, which factories can use to validate their configuration.
+// Unknown keys are rejected with an error listing the valid keys, and
+// durations may be given as strings such as "30s" or "5m".
+func Decode(config Config, out any) error {
+	valid := yamlKeys(reflect.TypeOf(out))
+	unknown := []string{}
+	for key := range config {
+		if _, ok := valid[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		keys := make([]string, 0, len(valid))
+		for key := range valid {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf(
+			"unknown key(s) \"%s\" (valid keys: %s)",
+			strings.Join(unknown, "\", \""),
+			strings.Join(keys, ", "),
+		)
+	}
+
+	// round trip through yaml to get its type
+	// conversions (e.g. for durations) for free
+	byt, err := yaml.Marshal(map[string]any(config))
+	if err != nil {
+		return fmt.Errorf("failed to encode configuration: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(byt))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil {
+		return fmt.Errorf("failed to decode configuration: %v", err)
+	}
+	return nil
+}
+
+// yamlKeys returns the top-level keys of a struct
+// (or pointer to struct) type based on its yaml tags.
+func yamlKeys(t reflect.Type) map[string]struct{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	keys := map[string]struct{}{}
+	if t.Kind() != reflect.Struct {
+		return keys
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, flags, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if strings.Contains(flags, "inline") {
+			for key := range yamlKeys(field.Type) {
+				keys[key] = struct{}{}
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		keys[name] = struct{}{}
+	}
+	return keys
+}
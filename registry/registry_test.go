@@ -0,0 +1,209 @@
+package registry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+// registryTestDiscoverer is a discoverer which returns empty results with its id.
+type registryTestDiscoverer struct {
+	discovererId string
+}
+
+func (d *registryTestDiscoverer) Discover(_ context.Context) *discovery.Result {
+	result := discovery.NewResult(d.discovererId)
+	result.Done()
+	return result
+}
+
+type registryTestBaseConfig struct {
+	Region string `yaml:"region"`
+}
+
+type registryTestConfig struct {
+	registryTestBaseConfig `yaml:",inline"`
+
+	DiscovererId *string             `yaml:"discoverer_id"`
+	Timeout      *time.Duration      `yaml:"timeout"`
+	Ports        []int32             `yaml:"ports"`
+	Tags         map[string][]string `yaml:"tags"`
+	Ignored      string              `yaml:"-"`
+	Untagged     bool
+	unexported   bool
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       Config
+		expectedErr  string
+		checkDecoded func(registryTestConfig) bool
+	}{
+		{
+			name:         "empty configuration",
+			config:       Config{},
+			checkDecoded: func(c registryTestConfig) bool { return c.DiscovererId == nil && c.Timeout == nil },
+		},
+		{
+			name: "all keys",
+			config: Config{
+				"region":        "us-east-1",
+				"discoverer_id": "mine",
+				"timeout":       "1m30s",
+				"ports":         []any{22, 443},
+				"tags":          map[string]any{"env": []any{"prod"}},
+				"untagged":      true,
+			},
+			checkDecoded: func(c registryTestConfig) bool {
+				return c.Region == "us-east-1" &&
+					*c.DiscovererId == "mine" &&
+					*c.Timeout == time.Minute+30*time.Second &&
+					len(c.Ports) == 2 && c.Ports[1] == 443 &&
+					c.Tags["env"][0] == "prod" &&
+					c.Untagged
+			},
+		},
+		{
+			name:        "unknown keys are rejected with the valid keys",
+			config:      Config{"regoin": "us-east-1", "timeuot": "1s", "region": "us-east-1"},
+			expectedErr: "unknown key(s) \"regoin\", \"timeuot\" (valid keys: discoverer_id, ports, region, tags, timeout, untagged)",
+		},
+		{
+			name:        "ignored fields are not valid keys",
+			config:      Config{"Ignored": "x"},
+			expectedErr: "unknown key(s) \"Ignored\"",
+		},
+		{
+			name:        "invalid durations",
+			config:      Config{"timeout": "soon"},
+			expectedErr: "failed to decode configuration",
+		},
+		{
+			name:        "invalid types",
+			config:      Config{"ports": "22"},
+			expectedErr: "failed to decode configuration",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var c registryTestConfig
+			err := Decode(test.config, &c)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected an error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !test.checkDecoded(c) {
+				t.Fatalf("unexpected decoded configuration %+v", c)
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.MustRegister("test", func(_ context.Context, config Config) (discovery.Discoverer, error) {
+		var c registryTestConfig
+		if err := Decode(config, &c); err != nil {
+			return nil, err
+		}
+		return &registryTestDiscoverer{discovererId: orDefault(c.DiscovererId, "test")}, nil
+	})
+	r.MustRegister("broken", func(_ context.Context, _ Config) (discovery.Discoverer, error) {
+		return nil, errors.New("always broken")
+	})
+
+	if err := r.Register("test", nil); err == nil {
+		t.Fatal("expected an error registering a kind twice")
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected MustRegister to panic when registering a kind twice")
+			}
+		}()
+		r.MustRegister("test", nil)
+	}()
+
+	if kinds := r.Kinds(); len(kinds) != 2 || kinds[0] != "broken" || kinds[1] != "test" {
+		t.Fatalf("expected sorted kinds, got %v", kinds)
+	}
+
+	tests := []struct {
+		name                 string
+		kind                 string
+		config               Config
+		expectedErr          string
+		expectedDiscovererId string
+	}{
+		{
+			name:                 "builds with the factory of the kind",
+			kind:                 "test",
+			config:               Config{"discoverer_id": "mine"},
+			expectedDiscovererId: "mine",
+		},
+		{
+			name:                 "builds without configuration",
+			kind:                 "test",
+			expectedDiscovererId: "test",
+		},
+		{
+			name:        "unknown kinds list the known kinds",
+			kind:        "other",
+			expectedErr: "unknown discoverer kind \"other\" (known kinds: broken, test)",
+		},
+		{
+			name:        "invalid configurations name the kind",
+			kind:        "test",
+			config:      Config{"bogus": true},
+			expectedErr: "invalid configuration for discoverer kind \"test\": unknown key(s) \"bogus\"",
+		},
+		{
+			name:        "factory errors name the kind",
+			kind:        "broken",
+			expectedErr: "invalid configuration for discoverer kind \"broken\": always broken",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discoverer, err := r.Build(context.Background(), test.kind, test.config)
+			if test.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedErr) {
+					t.Fatalf("expected an error containing %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if id := discoverer.Discover(context.Background()).Metadata.DiscovererId; id != test.expectedDiscovererId {
+				t.Fatalf("expected discoverer id %s, got %s", test.expectedDiscovererId, id)
+			}
+		})
+	}
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	kinds := Kinds()
+	for kind := range builtinFactories {
+		found := false
+		for _, k := range kinds {
+			found = found || k == kind
+		}
+		if !found {
+			t.Fatalf("expected the builtin kind %s in the default registry, got %v", kind, kinds)
+		}
+	}
+	if err := Register(KindDocker, nil); err == nil {
+		t.Fatal("expected an error registering a builtin kind")
+	}
+}
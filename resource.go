@@ -1,9 +1,14 @@
 package discovery
 
+import "time"
+
 const (
 	// ResourceTypeAwsEc2Instance is the resource type for AWS EC2 instances.
 	ResourceTypeAwsEc2Instance = "aws_ec2_instance"
 
+	// ResourceTypeAwsEc2InstanceConnectEndpoint is the resource type for AWS EC2 Instance Connect Endpoints.
+	ResourceTypeAwsEc2InstanceConnectEndpoint = "aws_ec2_instance_connect_endpoint"
+
 	// ResourceTypeAwsEcsService is the resource type for AWS ECS services.
 	ResourceTypeAwsEcsService = "aws_ecs_service"
 
@@ -13,6 +18,12 @@ const (
 	// ResourceTypeAwsRdsInstnace is the resource type for AWS RDS instances.
 	ResourceTypeAwsRdsInstance = "aws_rds_instance"
 
+	// ResourceTypeAwsRdsCluster is the resource type for AWS RDS (e.g. Aurora) DB clusters.
+	ResourceTypeAwsRdsCluster = "aws_rds_cluster"
+
+	// ResourceTypeAwsRdsProxy is the resource type for AWS RDS Proxies.
+	ResourceTypeAwsRdsProxy = "aws_rds_proxy"
+
 	// ResourceTypeAwsSsmTarget is the resource type for AWS SSM targets.
 	ResourceTypeAwsSsmTarget = "aws_ssm_target"
 
@@ -54,6 +65,19 @@ const (
 
 	// Ec2InstanceSsmStatusNotAssociated represents the SSM status of an EC2 instance that is not associated.
 	Ec2InstanceSsmStatusNotAssociated = "not_associated"
+
+	// Ec2InstancePlatformLinux represents the platform of EC2 instances running Linux/UNIX.
+	Ec2InstancePlatformLinux = "linux"
+
+	// Ec2InstancePlatformWindows represents the platform of EC2 instances running Windows.
+	Ec2InstancePlatformWindows = "windows"
+
+	// SsmTargetResourceTypeEc2Instance is the SSM resource type of SSM targets which are EC2 instances.
+	SsmTargetResourceTypeEc2Instance = "EC2Instance"
+
+	// SsmTargetResourceTypeManagedInstance is the SSM resource type of SSM targets which are hybrid
+	// activations (i.e. on-premises servers and virtual machines with "mi-" instance ids).
+	SsmTargetResourceTypeManagedInstance = "ManagedInstance"
 )
 
 // AwsBaseDetails represents the details of a discovered generic AWS resource.
@@ -90,14 +114,67 @@ type AwsEc2InstanceDetails struct {
 
 	InstanceSsmStatus string `json:"ssm_status"`
 
+	Platform              string                   `json:"platform"`
+	PlatformDetails       string                   `json:"platform_details,omitempty"`
+	Architecture          string                   `json:"architecture,omitempty"`
+	LaunchTime            *time.Time               `json:"launch_time,omitempty"`
+	KeyName               string                   `json:"key_name,omitempty"`
+	IamInstanceProfileArn string                   `json:"iam_instance_profile_arn,omitempty"`
+	SecurityGroups        []AwsSecurityGroup       `json:"security_groups,omitempty"`
+	NetworkInterfaces     []AwsEc2NetworkInterface `json:"network_interfaces,omitempty"`
+	Ipv6Addresses         []string                 `json:"ipv6_addresses,omitempty"`
+	ImdsV2Required        bool                     `json:"imds_v2_required"`
+	AutoScalingGroupName  string                   `json:"autoscaling_group_name,omitempty"`
+
 	PrivateDnsNameReachable   *bool `json:"private_dns_name_reachable,omitempty"`
 	PrivateIpAddressReachable *bool `json:"private_ip_address_reachable,omitempty"`
 	PublicDnsNameReachable    *bool `json:"public_dns_name_reachable,omitempty"`
 	PublicIpAddressReachable  *bool `json:"public_ip_address_reachable,omitempty"`
 
+	// the EC2 Instance Connect Endpoint in the instance's VPC (if any). Reachable
+	// only means that such an endpoint exists, security groups are not checked.
+	InstanceConnectEndpointId        string `json:"instance_connect_endpoint_id,omitempty"`
+	InstanceConnectEndpointReachable *bool  `json:"instance_connect_endpoint_reachable,omitempty"`
+
+	StaticReachability []AwsStaticReachability `json:"static_reachability,omitempty"`
+
 	// add any new fields as needed here
 }
 
+// AwsSecurityGroup represents a reference to an AWS security group.
+type AwsSecurityGroup struct {
+	GroupId   string `json:"group_id"`
+	GroupName string `json:"group_name,omitempty"`
+}
+
+// AwsStaticReachability represents the result of the static (offline) analysis of whether
+// a source can reach a tcp port of an AWS resource, based on its security group rules,
+// network ACLs and route tables. The rules and routes allowing the traffic are recorded
+// if reachable, and the reason otherwise.
+type AwsStaticReachability struct {
+	Source                  string `json:"source"`
+	Port                    int32  `json:"port"`
+	Reachable               bool   `json:"reachable"`
+	SecurityGroupRule       string `json:"security_group_rule,omitempty"`
+	SourceSecurityGroupRule string `json:"source_security_group_rule,omitempty"`
+	NetworkAclRule          string `json:"network_acl_rule,omitempty"`
+	SourceNetworkAclRule    string `json:"source_network_acl_rule,omitempty"`
+	Route                   string `json:"route,omitempty"`
+	SourceRoute             string `json:"source_route,omitempty"`
+	Reason                  string `json:"reason,omitempty"`
+}
+
+// AwsEc2NetworkInterface represents the details of a network interface attached to an AWS EC2 instance.
+type AwsEc2NetworkInterface struct {
+	NetworkInterfaceId string   `json:"network_interface_id"`
+	DeviceIndex        int32    `json:"device_index"`
+	SubnetId           string   `json:"subnet_id"`
+	PrivateIpAddresses []string `json:"private_ip_addresses"` // primary first
+	Ipv6Addresses      []string `json:"ipv6_addresses,omitempty"`
+	PublicIpAddress    string   `json:"public_ip_address,omitempty"`
+	SecurityGroupIds   []string `json:"security_group_ids,omitempty"`
+}
+
 // AwsEcsServiceDetails represents the details of a discovered AWS ECS service.
 type AwsEcsServiceDetails struct {
 	AwsBaseDetails // extends
@@ -146,6 +223,167 @@ type AwsRdsInstanceDetails struct {
 	EndpointPort         int32  `json:"endpoint_port"`
 	NetworkReachable     *bool  `json:"network_reachable,omitempty"`
 
+	DbName                           string   `json:"db_name,omitempty"`
+	MasterUsername                   string   `json:"master_username,omitempty"`
+	MasterUserSecretArn              string   `json:"master_user_secret_arn,omitempty"`
+	IamDatabaseAuthenticationEnabled bool     `json:"iam_database_authentication_enabled"`
+	CaCertificateIdentifier          string   `json:"ca_certificate_identifier,omitempty"`
+	PubliclyAccessible               bool     `json:"publicly_accessible"`
+	MultiAZ                          bool     `json:"multi_az"`
+	SecurityGroupIds                 []string `json:"security_group_ids,omitempty"`
+
+	StaticReachability []AwsStaticReachability `json:"static_reachability,omitempty"`
+
+	// add any new fields as needed here
+}
+
+// AwsRdsClusterDetails represents the details of a discovered AWS RDS (e.g. Aurora) DB cluster.
+type AwsRdsClusterDetails struct {
+	AwsBaseDetails // extends
+
+	Tags map[string]string `json:"tags"`
+
+	DbClusterIdentifier              string                                  `json:"db_cluster_identifier"`
+	DbClusterStatus                  string                                  `json:"db_cluster_status"`
+	Engine                           string                                  `json:"engine"`
+	EngineVersion                    string                                  `json:"engine_version"`
+	EngineMode                       string                                  `json:"engine_mode,omitempty"`
+	DatabaseName                     string                                  `json:"database_name,omitempty"`
+	DBSubnetGroupName                string                                  `json:"db_subnet_group_name"`
+	SecurityGroupIds                 []string                                `json:"security_group_ids,omitempty"`
+	WriterEndpointAddress            string                                  `json:"writer_endpoint_address"`
+	ReaderEndpointAddress            string                                  `json:"reader_endpoint_address,omitempty"`
+	CustomEndpoints                  []AwsRdsClusterEndpoint                 `json:"custom_endpoints,omitempty"`
+	EndpointPort                     int32                                   `json:"endpoint_port"`
+	Members                          []AwsRdsClusterMember                   `json:"members,omitempty"`
+	IamDatabaseAuthenticationEnabled bool                                    `json:"iam_database_authentication_enabled"`
+	ServerlessV2ScalingConfiguration *AwsRdsServerlessV2ScalingConfiguration `json:"serverless_v2_scaling_configuration,omitempty"`
+
+	// add any new fields as needed here
+}
+
+// AwsRdsClusterEndpoint represents a custom endpoint of an AWS RDS DB cluster.
+type AwsRdsClusterEndpoint struct {
+	EndpointIdentifier string   `json:"endpoint_identifier"`
+	EndpointAddress    string   `json:"endpoint_address"`
+	EndpointType       string   `json:"endpoint_type"` // i.e. READER or ANY
+	Status             string   `json:"status"`
+	StaticMembers      []string `json:"static_members,omitempty"`
+	ExcludedMembers    []string `json:"excluded_members,omitempty"`
+}
+
+// AwsRdsClusterMember represents a DB instance which is a member of an AWS RDS DB cluster.
+type AwsRdsClusterMember struct {
+	DbInstanceIdentifier string `json:"db_instance_identifier"`
+	IsClusterWriter      bool   `json:"is_cluster_writer"`
+	PromotionTier        int32  `json:"promotion_tier"`
+}
+
+// AwsRdsServerlessV2ScalingConfiguration represents the (Aurora Capacity Units)
+// scaling configuration of the Aurora Serverless v2 instances of a DB cluster.
+type AwsRdsServerlessV2ScalingConfiguration struct {
+	MinCapacity float64 `json:"min_capacity"`
+	MaxCapacity float64 `json:"max_capacity"`
+}
+
+// AwsRdsProxyDetails represents the details of a discovered AWS RDS Proxy.
+type AwsRdsProxyDetails struct {
+	AwsBaseDetails // extends
+
+	Tags map[string]string `json:"tags"`
+
+	DbProxyName      string                   `json:"db_proxy_name"`
+	Status           string                   `json:"status"`
+	EngineFamily     string                   `json:"engine_family"`
+	VpcId            string                   `json:"vpc_id"`
+	SubnetIds        []string                 `json:"subnet_ids,omitempty"`
+	SecurityGroupIds []string                 `json:"security_group_ids,omitempty"`
+	RequireTls       bool                     `json:"require_tls"`
+	RoleArn          string                   `json:"role_arn,omitempty"`
+	Auth             []AwsRdsProxyAuth        `json:"auth,omitempty"`
+	Endpoints        []AwsRdsProxyEndpoint    `json:"endpoints,omitempty"` // including the default endpoint
+	TargetGroups     []AwsRdsProxyTargetGroup `json:"target_groups,omitempty"`
+
+	// add any new fields as needed here
+}
+
+// AwsRdsProxyAuth represents an authentication method of an AWS RDS Proxy.
+type AwsRdsProxyAuth struct {
+	AuthScheme             string `json:"auth_scheme"`
+	IamAuth                string `json:"iam_auth"` // i.e. DISABLED, REQUIRED or ENABLED
+	ClientPasswordAuthType string `json:"client_password_auth_type,omitempty"`
+	SecretArn              string `json:"secret_arn,omitempty"`
+	Username               string `json:"username,omitempty"`
+}
+
+// AwsRdsProxyEndpoint represents an endpoint of an AWS RDS Proxy.
+type AwsRdsProxyEndpoint struct {
+	EndpointName    string `json:"endpoint_name"`
+	EndpointAddress string `json:"endpoint_address"`
+	TargetRole      string `json:"target_role"` // i.e. READ_WRITE or READ_ONLY
+	Status          string `json:"status"`
+	IsDefault       bool   `json:"is_default"`
+}
+
+// AwsRdsProxyTargetGroup represents a target group of an AWS RDS Proxy.
+type AwsRdsProxyTargetGroup struct {
+	TargetGroupName string              `json:"target_group_name"`
+	Status          string              `json:"status"`
+	IsDefault       bool                `json:"is_default"`
+	Targets         []AwsRdsProxyTarget `json:"targets,omitempty"`
+}
+
+// AwsRdsProxyTarget represents a DB instance or cluster in the target group of an AWS RDS Proxy.
+type AwsRdsProxyTarget struct {
+	Type             string `json:"type"` // i.e. RDS_INSTANCE, RDS_SERVERLESS_ENDPOINT or TRACKED_CLUSTER
+	RdsResourceId    string `json:"rds_resource_id"`
+	TrackedClusterId string `json:"tracked_cluster_id,omitempty"`
+	EndpointAddress  string `json:"endpoint_address,omitempty"`
+	EndpointPort     int32  `json:"endpoint_port,omitempty"`
+	Role             string `json:"role,omitempty"`
+	HealthState      string `json:"health_state,omitempty"`
+}
+
+// AwsEc2InstanceConnectEndpointDetails represents the details of a discovered AWS EC2 Instance Connect Endpoint.
+type AwsEc2InstanceConnectEndpointDetails struct {
+	AwsBaseDetails // extends
+
+	Tags map[string]string `json:"tags"`
+
+	InstanceConnectEndpointId string     `json:"instance_connect_endpoint_id"`
+	VpcId                     string     `json:"vpc_id"`
+	SubnetId                  string     `json:"subnet_id"`
+	AvailabilityZone          string     `json:"availability_zone"`
+	State                     string     `json:"state"`
+	StateMessage              string     `json:"state_message,omitempty"`
+	DnsName                   string     `json:"dns_name"`
+	FipsDnsName               string     `json:"fips_dns_name,omitempty"`
+	PreserveClientIp          bool       `json:"preserve_client_ip"`
+	SecurityGroupIds          []string   `json:"security_group_ids,omitempty"`
+	CreatedAt                 *time.Time `json:"created_at,omitempty"`
+
+	// add any new fields as needed here
+}
+
+// AwsSsmTargetDetails represents the details of a discovered AWS SSM managed node,
+// i.e. an EC2 instance or a hybrid activation (on-premises server or virtual machine).
+type AwsSsmTargetDetails struct {
+	AwsBaseDetails // extends
+
+	InstanceId       string     `json:"instance_id"`
+	SsmResourceType  string     `json:"ssm_resource_type"`
+	Name             string     `json:"name,omitempty"`
+	ComputerName     string     `json:"computer_name"`
+	IpAddress        string     `json:"ip_address"`
+	PlatformType     string     `json:"platform_type"`
+	PlatformName     string     `json:"platform_name"`
+	PlatformVersion  string     `json:"platform_version"`
+	AgentVersion     string     `json:"agent_version"`
+	PingStatus       string     `json:"ping_status"`
+	LastPingDateTime *time.Time `json:"last_ping_date_time,omitempty"`
+	ActivationId     string     `json:"activation_id,omitempty"`
+	IamRole          string     `json:"iam_role,omitempty"`
+
 	// add any new fields as needed here
 }
 
@@ -234,6 +472,11 @@ type NetworkRdpServerDetails struct {
 type NetworkSshServerDetails struct {
 	NetworkBaseDetails // extends
 
+	// HostKeys are the public host keys of the server in the
+	// authorized_keys format (e.g. "ssh-ed25519 AAAA..."), only
+	// captured when host key scanning is enabled.
+	HostKeys []string `json:"host_keys,omitempty"`
+
 	// add any new fields as needed here
 }
 
@@ -249,19 +492,23 @@ type NetworkVncServerDetails struct {
 type Resource struct {
 	ResourceType string `json:"resource_type"`
 
-	AwsEc2InstanceDetails          *AwsEc2InstanceDetails          `json:"aws_ec2_instance_details,omitempty"`
-	AwsEcsServiceDetails           *AwsEcsServiceDetails           `json:"aws_ecs_service_details,omitempty"`
-	AwsEksClusterDetails           *AwsEksClusterDetails           `json:"aws_eks_cluster_details,omitempty"`
-	AwsRdsInstanceDetails          *AwsRdsInstanceDetails          `json:"aws_rds_instance_details,omitempty"`
-	KubernetesServiceDetails       *KubernetesServiceDetails       `json:"kubernetes_service_details,omitempty"`
-	DockerContainerDetails         *DockerContainerDetails         `json:"docker_container_details,omitempty"`
-	NetworkHttpServerDetails       *NetworkHttpServerDetails       `json:"network_http_server_details,omitempty"`
-	NetworkHttpsServerDetails      *NetworkHttpsServerDetails      `json:"network_https_server_details,omitempty"`
-	NetworkMysqlServerDetails      *NetworkMysqlServerDetails      `json:"network_mysql_server_details,omitempty"`
-	NetworkPostgresqlServerDetails *NetworkPostgresqlServerDetails `json:"network_postgresql_server_details,omitempty"`
-	NetworkRdpServerDetails        *NetworkRdpServerDetails        `json:"network_rdp_server_details,omitempty"`
-	NetworkSshServerDetails        *NetworkSshServerDetails        `json:"network_ssh_server_details,omitempty"`
-	NetworkVncServerDetails        *NetworkVncServerDetails        `json:"network_vnc_server_details,omitempty"`
+	AwsEc2InstanceDetails                *AwsEc2InstanceDetails                `json:"aws_ec2_instance_details,omitempty"`
+	AwsEc2InstanceConnectEndpointDetails *AwsEc2InstanceConnectEndpointDetails `json:"aws_ec2_instance_connect_endpoint_details,omitempty"`
+	AwsEcsServiceDetails                 *AwsEcsServiceDetails                 `json:"aws_ecs_service_details,omitempty"`
+	AwsEksClusterDetails                 *AwsEksClusterDetails                 `json:"aws_eks_cluster_details,omitempty"`
+	AwsRdsInstanceDetails                *AwsRdsInstanceDetails                `json:"aws_rds_instance_details,omitempty"`
+	AwsRdsClusterDetails                 *AwsRdsClusterDetails                 `json:"aws_rds_cluster_details,omitempty"`
+	AwsRdsProxyDetails                   *AwsRdsProxyDetails                   `json:"aws_rds_proxy_details,omitempty"`
+	AwsSsmTargetDetails                  *AwsSsmTargetDetails                  `json:"aws_ssm_target_details,omitempty"`
+	KubernetesServiceDetails             *KubernetesServiceDetails             `json:"kubernetes_service_details,omitempty"`
+	DockerContainerDetails               *DockerContainerDetails               `json:"docker_container_details,omitempty"`
+	NetworkHttpServerDetails             *NetworkHttpServerDetails             `json:"network_http_server_details,omitempty"`
+	NetworkHttpsServerDetails            *NetworkHttpsServerDetails            `json:"network_https_server_details,omitempty"`
+	NetworkMysqlServerDetails            *NetworkMysqlServerDetails            `json:"network_mysql_server_details,omitempty"`
+	NetworkPostgresqlServerDetails       *NetworkPostgresqlServerDetails       `json:"network_postgresql_server_details,omitempty"`
+	NetworkRdpServerDetails              *NetworkRdpServerDetails              `json:"network_rdp_server_details,omitempty"`
+	NetworkSshServerDetails              *NetworkSshServerDetails              `json:"network_ssh_server_details,omitempty"`
+	NetworkVncServerDetails              *NetworkVncServerDetails              `json:"network_vnc_server_details,omitempty"`
 
 	// add any new resource details here
 }
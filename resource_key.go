@@ -0,0 +1,57 @@
+package discovery
+
+import (
+	"fmt"
+	"net"
+)
+
+// Key returns an identifier for a resource which is stable across discovery runs
+// and unique within a resource type. The key is prefixed with the resource type
+// so that keys are also unique across resource types.
+func (r Resource) Key() string {
+	return fmt.Sprintf("%s:%s", r.ResourceType, r.identifier())
+}
+
+func (r Resource) identifier() string {
+	switch {
+	case r.AwsEc2InstanceDetails != nil:
+		return r.AwsEc2InstanceDetails.AwsArn
+	case r.AwsEc2InstanceConnectEndpointDetails != nil:
+		return r.AwsEc2InstanceConnectEndpointDetails.AwsArn
+	case r.AwsEcsServiceDetails != nil:
+		return r.AwsEcsServiceDetails.AwsArn
+	case r.AwsEksClusterDetails != nil:
+		return r.AwsEksClusterDetails.AwsArn
+	case r.AwsRdsInstanceDetails != nil:
+		return r.AwsRdsInstanceDetails.AwsArn
+	case r.AwsRdsClusterDetails != nil:
+		return r.AwsRdsClusterDetails.AwsArn
+	case r.AwsRdsProxyDetails != nil:
+		return r.AwsRdsProxyDetails.AwsArn
+	case r.AwsSsmTargetDetails != nil:
+		return r.AwsSsmTargetDetails.AwsArn
+	case r.KubernetesServiceDetails != nil:
+		return fmt.Sprintf("%s/%s", r.KubernetesServiceDetails.Namespace, r.KubernetesServiceDetails.Name)
+	case r.DockerContainerDetails != nil:
+		return r.DockerContainerDetails.ContainerId
+	case r.NetworkHttpServerDetails != nil:
+		return r.NetworkHttpServerDetails.address()
+	case r.NetworkHttpsServerDetails != nil:
+		return r.NetworkHttpsServerDetails.address()
+	case r.NetworkMysqlServerDetails != nil:
+		return r.NetworkMysqlServerDetails.address()
+	case r.NetworkPostgresqlServerDetails != nil:
+		return r.NetworkPostgresqlServerDetails.address()
+	case r.NetworkRdpServerDetails != nil:
+		return r.NetworkRdpServerDetails.address()
+	case r.NetworkSshServerDetails != nil:
+		return r.NetworkSshServerDetails.address()
+	case r.NetworkVncServerDetails != nil:
+		return r.NetworkVncServerDetails.address()
+	}
+	return ""
+}
+
+func (d NetworkBaseDetails) address() string {
+	return net.JoinHostPort(d.IpAddress, d.Port)
+}
@@ -0,0 +1,23 @@
+package discovery
+
+// NetworkBaseDetails returns the generic details of a service discovered on the
+// network, and false for resources which are not services discovered on the network.
+func (r Resource) NetworkBaseDetails() (NetworkBaseDetails, bool) {
+	switch {
+	case r.NetworkHttpServerDetails != nil:
+		return r.NetworkHttpServerDetails.NetworkBaseDetails, true
+	case r.NetworkHttpsServerDetails != nil:
+		return r.NetworkHttpsServerDetails.NetworkBaseDetails, true
+	case r.NetworkMysqlServerDetails != nil:
+		return r.NetworkMysqlServerDetails.NetworkBaseDetails, true
+	case r.NetworkPostgresqlServerDetails != nil:
+		return r.NetworkPostgresqlServerDetails.NetworkBaseDetails, true
+	case r.NetworkRdpServerDetails != nil:
+		return r.NetworkRdpServerDetails.NetworkBaseDetails, true
+	case r.NetworkSshServerDetails != nil:
+		return r.NetworkSshServerDetails.NetworkBaseDetails, true
+	case r.NetworkVncServerDetails != nil:
+		return r.NetworkVncServerDetails.NetworkBaseDetails, true
+	}
+	return NetworkBaseDetails{}, false
+}
@@ -0,0 +1,30 @@
+package discovery
+
+// Tags returns the user-defined key-value metadata of a resource i.e. the
+// tags of AWS resources and the labels of kubernetes services and Docker
+// containers. Returns nil for resources which do not carry any such metadata,
+// including SSM targets (the SSM API does not return the tags of managed nodes)
+// and network servers.
+func (r Resource) Tags() map[string]string {
+	switch {
+	case r.AwsEc2InstanceDetails != nil:
+		return r.AwsEc2InstanceDetails.Tags
+	case r.AwsEc2InstanceConnectEndpointDetails != nil:
+		return r.AwsEc2InstanceConnectEndpointDetails.Tags
+	case r.AwsEcsServiceDetails != nil:
+		return r.AwsEcsServiceDetails.Tags
+	case r.AwsEksClusterDetails != nil:
+		return r.AwsEksClusterDetails.Tags
+	case r.AwsRdsInstanceDetails != nil:
+		return r.AwsRdsInstanceDetails.Tags
+	case r.AwsRdsClusterDetails != nil:
+		return r.AwsRdsClusterDetails.Tags
+	case r.AwsRdsProxyDetails != nil:
+		return r.AwsRdsProxyDetails.Tags
+	case r.KubernetesServiceDetails != nil:
+		return r.KubernetesServiceDetails.Labels
+	case r.DockerContainerDetails != nil:
+		return r.DockerContainerDetails.Labels
+	}
+	return nil
+}
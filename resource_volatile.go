@@ -0,0 +1,32 @@
+package discovery
+
+// WithoutVolatileFields returns a copy of a resource in which the fields that
+// may change between discovery runs without the resource itself changing (i.e.
+// the results of reachability checks made by discoverers, and the last time SSM
+// managed nodes pinged SSM) are zeroed. It is meant for telling whether a resource
+// changed, not for presenting resources.
+func (r Resource) WithoutVolatileFields() Resource {
+	switch {
+	case r.AwsEc2InstanceDetails != nil:
+		details := *r.AwsEc2InstanceDetails
+		details.PrivateDnsNameReachable = nil
+		details.PrivateIpAddressReachable = nil
+		details.PublicDnsNameReachable = nil
+		details.PublicIpAddressReachable = nil
+		details.InstanceConnectEndpointReachable = nil
+		r.AwsEc2InstanceDetails = &details
+	case r.AwsEksClusterDetails != nil:
+		details := *r.AwsEksClusterDetails
+		details.EndpointReachable = nil
+		r.AwsEksClusterDetails = &details
+	case r.AwsRdsInstanceDetails != nil:
+		details := *r.AwsRdsInstanceDetails
+		details.NetworkReachable = nil
+		r.AwsRdsInstanceDetails = &details
+	case r.AwsSsmTargetDetails != nil:
+		details := *r.AwsSsmTargetDetails
+		details.LastPingDateTime = nil
+		r.AwsSsmTargetDetails = &details
+	}
+	return r
+}
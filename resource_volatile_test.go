@@ -0,0 +1,97 @@
+package discovery
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestResourceWithoutVolatileFields(t *testing.T) {
+	reachable := true
+	lastPing := time.Now()
+
+	tests := []struct {
+		name     string
+		resource Resource
+		expected Resource
+	}{
+		{
+			name: "ec2 instance reachability",
+			resource: Resource{
+				ResourceType: ResourceTypeAwsEc2Instance,
+				AwsEc2InstanceDetails: &AwsEc2InstanceDetails{
+					InstanceId:                       "i-1",
+					PrivateDnsNameReachable:          &reachable,
+					PrivateIpAddressReachable:        &reachable,
+					PublicDnsNameReachable:           &reachable,
+					PublicIpAddressReachable:         &reachable,
+					InstanceConnectEndpointReachable: &reachable,
+				},
+			},
+			expected: Resource{
+				ResourceType:          ResourceTypeAwsEc2Instance,
+				AwsEc2InstanceDetails: &AwsEc2InstanceDetails{InstanceId: "i-1"},
+			},
+		},
+		{
+			name: "eks cluster reachability",
+			resource: Resource{
+				ResourceType:         ResourceTypeAwsEksCluster,
+				AwsEksClusterDetails: &AwsEksClusterDetails{ClusterName: "c", EndpointReachable: &reachable},
+			},
+			expected: Resource{
+				ResourceType:         ResourceTypeAwsEksCluster,
+				AwsEksClusterDetails: &AwsEksClusterDetails{ClusterName: "c"},
+			},
+		},
+		{
+			name: "rds instance reachability",
+			resource: Resource{
+				ResourceType:          ResourceTypeAwsRdsInstance,
+				AwsRdsInstanceDetails: &AwsRdsInstanceDetails{VpcId: "vpc-1", NetworkReachable: &reachable},
+			},
+			expected: Resource{
+				ResourceType:          ResourceTypeAwsRdsInstance,
+				AwsRdsInstanceDetails: &AwsRdsInstanceDetails{VpcId: "vpc-1"},
+			},
+		},
+		{
+			name: "ssm target last ping",
+			resource: Resource{
+				ResourceType:        ResourceTypeAwsSsmTarget,
+				AwsSsmTargetDetails: &AwsSsmTargetDetails{InstanceId: "mi-1", PingStatus: "Online", LastPingDateTime: &lastPing},
+			},
+			expected: Resource{
+				ResourceType:        ResourceTypeAwsSsmTarget,
+				AwsSsmTargetDetails: &AwsSsmTargetDetails{InstanceId: "mi-1", PingStatus: "Online"},
+			},
+		},
+		{
+			name: "resource without volatile fields",
+			resource: Resource{
+				ResourceType:           ResourceTypeDockerContainer,
+				DockerContainerDetails: &DockerContainerDetails{ContainerId: "c1"},
+			},
+			expected: Resource{
+				ResourceType:           ResourceTypeDockerContainer,
+				DockerContainerDetails: &DockerContainerDetails{ContainerId: "c1"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original, err := json.Marshal(test.resource)
+			if err != nil {
+				t.Fatalf("failed to json encode resource: %v", err)
+			}
+
+			if got := test.resource.WithoutVolatileFields(); !reflect.DeepEqual(got, test.expected) {
+				t.Fatalf("expected %+v, got %+v", test.expected, got)
+			}
+			if after, _ := json.Marshal(test.resource); string(after) != string(original) {
+				t.Fatal("expected the original resource to be left untouched")
+			}
+		})
+	}
+}
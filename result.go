@@ -11,6 +11,10 @@ type Metadata struct {
 	DiscovererId string    `json:"discoverer_id"`
 	StartedAt    time.Time `json:"started_at"`
 	EndedAt      time.Time `json:"ended_at"`
+
+	// Stale is set on results which were loaded from a
+	// SnapshotStore rather than produced by a discoverer run.
+	Stale bool `json:"stale,omitempty"`
 }
 
 // Result represents the result of a discoverer.
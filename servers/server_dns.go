@@ -0,0 +1,537 @@
+package servers
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/inventory"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	defaultDnsServerZone        = "disco."
+	defaultDnsServerTtl         = time.Second * 30
+	defaultDnsServerTcpTimeout  = time.Second * 10
+	defaultDnsServerMaxUdpBytes = 512
+)
+
+// dnsServiceNames are the service names (as in "_<service>._tcp.<zone>")
+// of SRV records for the resource types of services on the network.
+var dnsServiceNames = map[string]string{
+	discovery.ResourceTypeNetworkHttpServer:       "http",
+	discovery.ResourceTypeNetworkHttpsServer:      "https",
+	discovery.ResourceTypeNetworkMysqlServer:      "mysql",
+	discovery.ResourceTypeNetworkPostgresqlServer: "postgresql",
+	discovery.ResourceTypeNetworkRdpServer:        "rdp",
+	discovery.ResourceTypeNetworkSshServer:        "ssh",
+	discovery.ResourceTypeNetworkVncServer:        "vnc",
+}
+
+// DnsServer represents an authoritative DNS server for a zone (by default "disco.")
+// answering for the resources in an Inventory. Records are updated as the inventory
+// changes. Answers are always authoritative and never recursive: queries for names
+// outside of the zone are refused.
+//
+// Records:
+//   - A/AAAA <name>.ec2.<region>.<zone>            EC2 instances by Name tag and by instance id
+//     (private address, unless only the public address was found to be reachable)
+//   - A/AAAA <name>.docker.<zone>                  Docker containers by name (bound address)
+//   - A/AAAA <name>.<namespace>.k8s.<zone>         kubernetes services (cluster ip)
+//   - A/AAAA <a-b-c-d>.net.<zone>                  hosts on the network, by dashed address
+//   - SRV    _<service>._tcp.<zone>                services on the network, e.g. _postgresql._tcp
+//     (services: http, https, mysql, postgresql, rdp, ssh, vnc)
+type DnsServer struct {
+	inventory *inventory.Inventory
+
+	zone       string
+	ttl        time.Duration
+	tcpTimeout time.Duration
+
+	records atomic.Pointer[dnsRecords]
+}
+
+// DnsServerOption represents a configuration option for a DnsServer.
+type DnsServerOption func(*DnsServer)
+
+// WithDnsServerZone is the DnsServerOption to set a non default zone.
+func WithDnsServerZone(zone string) DnsServerOption {
+	return func(ds *DnsServer) { ds.zone = strings.ToLower(strings.TrimSuffix(zone, ".")) + "." }
+}
+
+// WithDnsServerTtl is the DnsServerOption to set a non default TTL for records.
+func WithDnsServerTtl(ttl time.Duration) DnsServerOption {
+	return func(ds *DnsServer) { ds.ttl = ttl }
+}
+
+// WithDnsServerTcpTimeout is the DnsServerOption to set a non
+// default idle timeout for connections of DNS over TCP clients.
+func WithDnsServerTcpTimeout(timeout time.Duration) DnsServerOption {
+	return func(ds *DnsServer) { ds.tcpTimeout = timeout }
+}
+
+// NewDnsServer returns a new DnsServer, initialized with the given options.
+func NewDnsServer(inv *inventory.Inventory, opts ...DnsServerOption) *DnsServer {
+	ds := &DnsServer{
+		inventory:  inv,
+		zone:       defaultDnsServerZone,
+		ttl:        defaultDnsServerTtl,
+		tcpTimeout: defaultDnsServerTcpTimeout,
+	}
+	for _, opt := range opts {
+		opt(ds)
+	}
+	ds.records.Store(newDnsRecords(ds.zone))
+	return ds
+}
+
+// Run serves DNS over UDP and TCP on a given address until the context is done.
+func (ds *DnsServer) Run(ctx context.Context, address string) error {
+	packetConn, err := net.ListenPacket("udp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %v", address, err)
+	}
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		packetConn.Close()
+		return fmt.Errorf("failed to listen on tcp %s: %v", address, err)
+	}
+	return ds.Serve(ctx, packetConn, listener)
+}
+
+// Serve serves DNS over UDP on a given packet connection and, if not nil, over
+// TCP on a given listener until the context is done. Both are closed on return.
+func (ds *DnsServer) Serve(ctx context.Context, packetConn net.PacketConn, listener net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// a buffer of one is enough: changes only signal that records must be
+	// rebuilt from the whole inventory, so a dropped signal always follows
+	// one which has not been handled yet.
+	changes, unsubscribe := ds.inventory.Subscribe(1)
+	defer unsubscribe()
+	ds.refresh()
+
+	var wg sync.WaitGroup
+	errC := make(chan error, 2)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-changes:
+				if !ok {
+					return
+				}
+				ds.refresh()
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errC <- ds.serveUdp(ctx, packetConn)
+	}()
+
+	if listener != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errC <- ds.serveTcp(ctx, listener)
+		}()
+	}
+
+	// serving only stops early (i.e. before the context is done) on errors
+	var err error
+	select {
+	case err = <-errC:
+	case <-ctx.Done():
+	}
+	cancel()
+	packetConn.Close()
+	if listener != nil {
+		listener.Close()
+	}
+	wg.Wait()
+	return err
+}
+
+func (ds *DnsServer) serveUdp(ctx context.Context, packetConn net.PacketConn) error {
+	buf := make([]byte, 65535)
+	for {
+		n, addr, err := packetConn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to read udp packet: %v", err)
+		}
+		response, ok := ds.handle(buf[:n], defaultDnsServerMaxUdpBytes)
+		if !ok {
+			continue
+		}
+		packetConn.WriteTo(response, addr) // best effort
+	}
+}
+
+func (ds *DnsServer) serveTcp(ctx context.Context, listener net.Listener) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept tcp connection: %v", err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+
+			// each message is prefixed with its length (RFC 1035 section 4.2.2)
+			for {
+				conn.SetDeadline(time.Now().Add(ds.tcpTimeout))
+				var length uint16
+				if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+					return
+				}
+				query := make([]byte, length)
+				if _, err := io.ReadFull(conn, query); err != nil {
+					return
+				}
+				response, ok := ds.handle(query, 65535)
+				if !ok {
+					return
+				}
+				if err := binary.Write(conn, binary.BigEndian, uint16(len(response))); err != nil {
+					return
+				}
+				if _, err := conn.Write(response); err != nil {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// handle returns the response to a query, truncated (without records and with the
+// TC bit set) if larger than maxBytes. Returns false if the query must be dropped.
+func (ds *DnsServer) handle(query []byte, maxBytes int) ([]byte, bool) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil || header.Response {
+		return nil, false
+	}
+	questions, err := parser.AllQuestions()
+	if err != nil {
+		return nil, false
+	}
+
+	responseHeader := dnsmessage.Header{
+		ID:               header.ID,
+		Response:         true,
+		OpCode:           header.OpCode,
+		RecursionDesired: header.RecursionDesired,
+	}
+
+	// only standard queries with a single question are supported, as most servers do
+	if header.OpCode != 0 {
+		responseHeader.RCode = dnsmessage.RCodeNotImplemented
+		return ds.build(responseHeader, questions, nil, maxBytes)
+	}
+	if len(questions) != 1 {
+		responseHeader.RCode = dnsmessage.RCodeFormatError
+		return ds.build(responseHeader, questions, nil, maxBytes)
+	}
+
+	question := questions[0]
+	answer := ds.records.Load().answer(question, uint32(ds.ttl.Seconds()))
+	responseHeader.RCode = answer.rcode
+	responseHeader.Authoritative = answer.rcode != dnsmessage.RCodeRefused
+	return ds.build(responseHeader, questions, answer, maxBytes)
+}
+
+func (ds *DnsServer) build(header dnsmessage.Header, questions []dnsmessage.Question, answer *dnsAnswer, maxBytes int) ([]byte, bool) {
+	msg, err := buildDnsMessage(header, questions, answer)
+	if err != nil {
+		return nil, false
+	}
+	if len(msg) > maxBytes {
+		header.Truncated = true
+		if msg, err = buildDnsMessage(header, questions, nil); err != nil {
+			return nil, false
+		}
+	}
+	return msg, true
+}
+
+func (ds *DnsServer) refresh() {
+	records := newDnsRecords(ds.zone)
+	for _, entry := range ds.inventory.List(inventory.Filter{}) {
+		records.add(entry.Resource)
+	}
+	ds.records.Store(records)
+}
+
+// dnsSrvTarget represents the target of an SRV record.
+type dnsSrvTarget struct {
+	target string
+	port   uint16
+}
+
+// dnsRecords represents all the records of a zone.
+type dnsRecords struct {
+	zone   string
+	serial uint32 // the time records were built at
+	ips    map[string][]net.IP
+	srvs   map[string][]dnsSrvTarget
+	names  map[string]struct{} // all names with records, and their parents within the zone
+}
+
+func newDnsRecords(zone string) *dnsRecords {
+	return &dnsRecords{
+		zone:   zone,
+		serial: uint32(time.Now().Unix()),
+		ips:    map[string][]net.IP{},
+		srvs:   map[string][]dnsSrvTarget{},
+		names:  map[string]struct{}{zone: {}},
+	}
+}
+
+func (r *dnsRecords) add(resource discovery.Resource) {
+	switch {
+	case resource.AwsEc2InstanceDetails != nil:
+		details := resource.AwsEc2InstanceDetails
+		address := details.PrivateIpAddress
+		privateUnreachable := details.PrivateIpAddressReachable != nil && !*details.PrivateIpAddressReachable
+		publicReachable := details.PublicIpAddressReachable != nil && *details.PublicIpAddressReachable
+		if address == "" || (privateUnreachable && publicReachable) {
+			address = details.PublicIpAddress
+		}
+		suffix := "ec2." + dnsLabel(details.AwsRegion)
+		if name := details.Tags["Name"]; name != "" {
+			r.addIp(dnsLabel(name)+"."+suffix, address)
+		}
+		r.addIp(dnsLabel(details.InstanceId)+"."+suffix, address)
+
+	case resource.DockerContainerDetails != nil:
+		details := resource.DockerContainerDetails
+		address := ""
+		for _, binding := range slices.Sorted(maps.Keys(details.PortBindings)) {
+			if host, _, err := net.SplitHostPort(binding); err == nil {
+				address = host
+				break
+			}
+		}
+		switch address {
+		case "0.0.0.0":
+			address = "127.0.0.1" // bound to all interfaces of the Docker host
+		case "::":
+			address = "::1"
+		}
+		for _, name := range details.Names {
+			r.addIp(dnsLabel(strings.TrimPrefix(name, "/"))+".docker", address)
+		}
+
+	case resource.KubernetesServiceDetails != nil:
+		details := resource.KubernetesServiceDetails
+		if details.ClusterIp != "None" { // headless services have no cluster ip
+			r.addIp(fmt.Sprintf("%s.%s.k8s", dnsLabel(details.Name), dnsLabel(details.Namespace)), details.ClusterIp)
+		}
+
+	default:
+		service, ok := dnsServiceNames[resource.ResourceType]
+		if !ok {
+			return
+		}
+		base, _ := resource.NetworkBaseDetails()
+		parsed := net.ParseIP(base.IpAddress)
+		if parsed == nil {
+			return
+		}
+		portNumber, err := strconv.ParseUint(base.Port, 10, 16)
+		if err != nil {
+			return
+		}
+		host := strings.NewReplacer(".", "-", ":", "-").Replace(parsed.String()) + ".net"
+		r.addIp(host, base.IpAddress)
+		name := r.qualify(fmt.Sprintf("_%s._tcp", service))
+		r.srvs[name] = append(r.srvs[name], dnsSrvTarget{target: r.qualify(host), port: uint16(portNumber)})
+		r.addName(name)
+	}
+}
+
+// addIp adds an A or AAAA record for a (relative) name.
+func (r *dnsRecords) addIp(name, address string) {
+	ip := net.ParseIP(address)
+	if ip == nil || strings.HasPrefix(name, ".") || strings.Contains(name, "..") {
+		return
+	}
+	name = r.qualify(name)
+	for _, existing := range r.ips[name] {
+		if existing.Equal(ip) {
+			return
+		}
+	}
+	r.ips[name] = append(r.ips[name], ip)
+	r.addName(name)
+}
+
+// addName adds a name, and all its parents within the zone, to the existing names.
+func (r *dnsRecords) addName(name string) {
+	for name != r.zone && strings.HasSuffix(name, "."+r.zone) {
+		r.names[name] = struct{}{}
+		_, name, _ = strings.Cut(name, ".")
+	}
+}
+
+func (r *dnsRecords) qualify(name string) string {
+	return strings.ToLower(name) + "." + r.zone
+}
+
+// dnsAnswer represents the records and response code for a question.
+type dnsAnswer struct {
+	rcode       dnsmessage.RCode
+	answers     []dnsmessage.Resource
+	authorities []dnsmessage.Resource
+	additionals []dnsmessage.Resource
+}
+
+func (r *dnsRecords) answer(question dnsmessage.Question, ttl uint32) *dnsAnswer {
+	name := strings.ToLower(question.Name.String())
+	if question.Class != dnsmessage.ClassINET && question.Class != dnsmessage.ClassANY {
+		return &dnsAnswer{rcode: dnsmessage.RCodeRefused}
+	}
+	if name != r.zone && !strings.HasSuffix(name, "."+r.zone) {
+		return &dnsAnswer{rcode: dnsmessage.RCodeRefused}
+	}
+
+	answer := &dnsAnswer{rcode: dnsmessage.RCodeSuccess}
+	if _, ok := r.names[name]; !ok {
+		answer.rcode = dnsmessage.RCodeNameError
+	}
+
+	wants := func(t dnsmessage.Type) bool {
+		return question.Type == t || question.Type == dnsmessage.TypeALL
+	}
+	header := func(t dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: question.Name, Type: t, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+
+	for _, ip := range r.ips[name] {
+		if ip4 := ip.To4(); ip4 != nil && wants(dnsmessage.TypeA) {
+			answer.answers = append(answer.answers, dnsmessage.Resource{
+				Header: header(dnsmessage.TypeA),
+				Body:   &dnsmessage.AResource{A: [4]byte(ip4)},
+			})
+		} else if ip4 == nil && wants(dnsmessage.TypeAAAA) {
+			answer.answers = append(answer.answers, dnsmessage.Resource{
+				Header: header(dnsmessage.TypeAAAA),
+				Body:   &dnsmessage.AAAAResource{AAAA: [16]byte(ip.To16())},
+			})
+		}
+	}
+	if wants(dnsmessage.TypeSRV) {
+		targets := map[string]struct{}{}
+		for _, srv := range r.srvs[name] {
+			target, err := dnsmessage.NewName(srv.target)
+			if err != nil {
+				continue
+			}
+			answer.answers = append(answer.answers, dnsmessage.Resource{
+				Header: header(dnsmessage.TypeSRV),
+				Body:   &dnsmessage.SRVResource{Target: target, Port: srv.port},
+			})
+			// the addresses of targets, to save clients a query
+			if _, ok := targets[srv.target]; ok {
+				continue
+			}
+			targets[srv.target] = struct{}{}
+			for _, ip := range r.ips[srv.target] {
+				if ip4 := ip.To4(); ip4 != nil {
+					answer.additionals = append(answer.additionals, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: target, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ttl},
+						Body:   &dnsmessage.AResource{A: [4]byte(ip4)},
+					})
+				} else {
+					answer.additionals = append(answer.additionals, dnsmessage.Resource{
+						Header: dnsmessage.ResourceHeader{Name: target, Type: dnsmessage.TypeAAAA, Class: dnsmessage.ClassINET, TTL: ttl},
+						Body:   &dnsmessage.AAAAResource{AAAA: [16]byte(ip.To16())},
+					})
+				}
+			}
+		}
+	}
+
+	soa := r.soa(ttl)
+	if name == r.zone && wants(dnsmessage.TypeSOA) {
+		answer.answers = append(answer.answers, soa)
+	}
+	// negative answers carry the SOA record for negative caching (RFC 2308)
+	if len(answer.answers) == 0 {
+		answer.authorities = append(answer.authorities, soa)
+	}
+	return answer
+}
+
+func (r *dnsRecords) soa(ttl uint32) dnsmessage.Resource {
+	zone := dnsmessage.MustNewName(r.zone)
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: ttl},
+		Body: &dnsmessage.SOAResource{
+			NS:      dnsmessage.MustNewName("ns." + r.zone),
+			MBox:    dnsmessage.MustNewName("hostmaster." + r.zone),
+			Serial:  r.serial,
+			Refresh: ttl,
+			Retry:   ttl,
+			Expire:  ttl * 100,
+			MinTTL:  ttl,
+		},
+	}
+}
+
+func buildDnsMessage(header dnsmessage.Header, questions []dnsmessage.Question, answer *dnsAnswer) ([]byte, error) {
+	msg := dnsmessage.Message{Header: header, Questions: questions}
+	if answer != nil {
+		msg.Answers = answer.answers
+		msg.Authorities = answer.authorities
+		msg.Additionals = answer.additionals
+	}
+	return msg.Pack()
+}
+
+// dnsLabel returns a lowercase DNS label with only valid characters (others are
+// replaced with dashes). Returns the empty string if nothing valid is left.
+func dnsLabel(s string) string {
+	label := strings.Trim(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(s)), "-")
+	if len(label) > 63 {
+		label = strings.TrimRight(label[:63], "-")
+	}
+	return label
+}
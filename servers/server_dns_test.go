@@ -0,0 +1,228 @@
+package servers
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/inventory"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// startDnsServer serves DNS for an inventory on a loopback packet
+// connection and returns the address to send queries to.
+func startDnsServer(t *testing.T, inv *inventory.Inventory) net.Addr {
+	t.Helper()
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen on loopback: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- NewDnsServer(inv).Serve(ctx, packetConn, nil) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("unexpected error from Serve: %v", err)
+		}
+	})
+	return packetConn.LocalAddr()
+}
+
+// queryDns sends a single question to a DNS server over UDP and returns the response.
+func queryDns(t *testing.T, addr net.Addr, name string, qtype dnsmessage.Type) dnsmessage.Message {
+	t.Helper()
+
+	query, err := (&dnsmessage.Message{
+		Header: dnsmessage.Header{ID: 42},
+		Questions: []dnsmessage.Question{{
+			Name:  dnsmessage.MustNewName(name),
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}).Pack()
+	if err != nil {
+		t.Fatalf("failed to pack query: %v", err)
+	}
+
+	conn, err := net.Dial("udp", addr.String())
+	if err != nil {
+		t.Fatalf("failed to dial dns server: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+
+	if _, err := conn.Write(query); err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	var response dnsmessage.Message
+	if err := response.Unpack(buf[:n]); err != nil {
+		t.Fatalf("failed to unpack response: %v", err)
+	}
+	if response.Header.ID != 42 {
+		t.Fatalf("expected response id 42, got %d", response.Header.ID)
+	}
+	return response
+}
+
+func newDnsTestInventory() *inventory.Inventory {
+	result := discovery.NewResult("test")
+	result.AddResources(
+		discovery.Resource{
+			ResourceType: discovery.ResourceTypeAwsEc2Instance,
+			AwsEc2InstanceDetails: &discovery.AwsEc2InstanceDetails{
+				AwsBaseDetails:   discovery.AwsBaseDetails{AwsRegion: "us-east-1", AwsArn: "arn:i-0abc"},
+				Tags:             map[string]string{"Name": "Web"},
+				InstanceId:       "i-0abc",
+				PrivateIpAddress: "10.0.0.5",
+			},
+		},
+		discovery.Resource{
+			ResourceType: discovery.ResourceTypeNetworkSshServer,
+			NetworkSshServerDetails: &discovery.NetworkSshServerDetails{
+				NetworkBaseDetails: discovery.NetworkBaseDetails{IpAddress: "10.0.0.9", Port: "2222"},
+			},
+		},
+	)
+	result.Done()
+
+	inv := inventory.NewInventory()
+	inv.Update(result)
+	return inv
+}
+
+func TestDnsServerServe(t *testing.T) {
+	addr := startDnsServer(t, newDnsTestInventory())
+
+	tests := []struct {
+		name          string
+		question      string
+		qtype         dnsmessage.Type
+		expectedRCode dnsmessage.RCode
+		expectedA     []string
+		expectedSrv   []string // target:port
+	}{
+		{
+			name:          "A record by name tag",
+			question:      "web.ec2.us-east-1.disco.",
+			qtype:         dnsmessage.TypeA,
+			expectedRCode: dnsmessage.RCodeSuccess,
+			expectedA:     []string{"10.0.0.5"},
+		},
+		{
+			name:          "A record by instance id is case insensitive",
+			question:      "I-0ABC.ec2.us-east-1.disco.",
+			qtype:         dnsmessage.TypeA,
+			expectedRCode: dnsmessage.RCodeSuccess,
+			expectedA:     []string{"10.0.0.5"},
+		},
+		{
+			name:          "A record of a host on the network",
+			question:      "10-0-0-9.net.disco.",
+			qtype:         dnsmessage.TypeA,
+			expectedRCode: dnsmessage.RCodeSuccess,
+			expectedA:     []string{"10.0.0.9"},
+		},
+		{
+			name:          "SRV record of services on the network",
+			question:      "_ssh._tcp.disco.",
+			qtype:         dnsmessage.TypeSRV,
+			expectedRCode: dnsmessage.RCodeSuccess,
+			expectedSrv:   []string{"10-0-0-9.net.disco.:2222"},
+		},
+		{
+			name:          "existing name without records of the type",
+			question:      "web.ec2.us-east-1.disco.",
+			qtype:         dnsmessage.TypeAAAA,
+			expectedRCode: dnsmessage.RCodeSuccess,
+		},
+		{
+			name:          "parent of existing names",
+			question:      "ec2.us-east-1.disco.",
+			qtype:         dnsmessage.TypeA,
+			expectedRCode: dnsmessage.RCodeSuccess,
+		},
+		{
+			name:          "NXDOMAIN for unknown names",
+			question:      "db.ec2.us-east-1.disco.",
+			qtype:         dnsmessage.TypeA,
+			expectedRCode: dnsmessage.RCodeNameError,
+		},
+		{
+			name:          "REFUSED for names outside of the zone",
+			question:      "example.com.",
+			qtype:         dnsmessage.TypeA,
+			expectedRCode: dnsmessage.RCodeRefused,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := queryDns(t, addr, test.question, test.qtype)
+
+			if response.Header.RCode != test.expectedRCode {
+				t.Fatalf("expected rcode %v, got %v", test.expectedRCode, response.Header.RCode)
+			}
+			a, srv := []string{}, []string{}
+			for _, answer := range response.Answers {
+				switch body := answer.Body.(type) {
+				case *dnsmessage.AResource:
+					a = append(a, net.IP(body.A[:]).String())
+				case *dnsmessage.SRVResource:
+					srv = append(srv, body.Target.String()+":"+strconv.Itoa(int(body.Port)))
+				}
+			}
+			if !slices.Equal(a, test.expectedA) {
+				t.Fatalf("expected A records %v, got %v", test.expectedA, a)
+			}
+			if !slices.Equal(srv, test.expectedSrv) {
+				t.Fatalf("expected SRV records %v, got %v", test.expectedSrv, srv)
+			}
+			if len(response.Answers) == 0 && test.expectedRCode != dnsmessage.RCodeRefused && len(response.Authorities) == 0 {
+				t.Fatal("expected negative answers to carry the SOA record")
+			}
+		})
+	}
+}
+
+func TestDnsServerServeRefreshesRecords(t *testing.T) {
+	inv := inventory.NewInventory()
+	addr := startDnsServer(t, inv)
+
+	if response := queryDns(t, addr, "10-0-0-9.net.disco.", dnsmessage.TypeA); response.Header.RCode != dnsmessage.RCodeNameError {
+		t.Fatalf("expected NXDOMAIN before the inventory changes, got %v", response.Header.RCode)
+	}
+
+	result := discovery.NewResult("test")
+	result.AddResources(discovery.Resource{
+		ResourceType: discovery.ResourceTypeNetworkSshServer,
+		NetworkSshServerDetails: &discovery.NetworkSshServerDetails{
+			NetworkBaseDetails: discovery.NetworkBaseDetails{IpAddress: "10.0.0.9", Port: "22"},
+		},
+	})
+	result.Done()
+	inv.Update(result)
+
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		response := queryDns(t, addr, "10-0-0-9.net.disco.", dnsmessage.TypeA)
+		if response.Header.RCode == dnsmessage.RCodeSuccess && len(response.Answers) == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected records to be refreshed, got rcode %v", response.Header.RCode)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
@@ -0,0 +1,245 @@
+package servers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/borderzero/discovery/inventory"
+)
+
+const (
+	defaultHttpServerSubscriberBuffer  = 100
+	defaultHttpServerKeepAliveInterval = time.Second * 15
+	defaultHttpServerShutdownTimeout   = time.Second * 5
+)
+
+// HttpServer represents a read-only HTTP API over an Inventory.
+//
+// Endpoints:
+//   - GET  /v1/resources                     list resources, filtered by the optional (repeatable)
+//     query parameters "type", "discoverer" and "tag" (as key=value)
+//   - GET  /v1/resources/{key}               get the resource(s) with a given key
+//   - GET  /v1/discoverers                   get the status of every discoverer
+//   - POST /v1/discoverers/{id}/trigger      trigger an on-demand run of a discoverer
+//   - GET  /v1/events                        server-sent events stream of resource changes
+type HttpServer struct {
+	inventory *inventory.Inventory
+	triggers  map[string]chan<- struct{}
+
+	subscriberBuffer  int
+	keepAliveInterval time.Duration
+	shutdownTimeout   time.Duration
+
+	mux *http.ServeMux
+}
+
+// ensure HttpServer implements http.Handler at compile-time.
+var _ http.Handler = (*HttpServer)(nil)
+
+// HttpServerOption represents a configuration option for an HttpServer.
+type HttpServerOption func(*HttpServer)
+
+// WithHttpServerTrigger is the HttpServerOption to expose the trigger endpoint for a
+// discoverer. The channel must be the one passed to engines.WithTriggerChannel for
+// the same discoverer, and should be buffered (of size 1) so that triggering never
+// blocks; a trigger sent while another one is still pending is a no-op.
+func WithHttpServerTrigger(discovererId string, triggerC chan<- struct{}) HttpServerOption {
+	return func(hs *HttpServer) { hs.triggers[discovererId] = triggerC }
+}
+
+// WithHttpServerSubscriberBuffer is the HttpServerOption to set a non default
+// buffer size (in batches of changes) for each server-sent events client.
+func WithHttpServerSubscriberBuffer(buffer int) HttpServerOption {
+	return func(hs *HttpServer) { hs.subscriberBuffer = buffer }
+}
+
+// WithHttpServerKeepAliveInterval is the HttpServerOption to set a non
+// default interval for keep-alive comments in server-sent events streams.
+func WithHttpServerKeepAliveInterval(interval time.Duration) HttpServerOption {
+	return func(hs *HttpServer) { hs.keepAliveInterval = interval }
+}
+
+// WithHttpServerShutdownTimeout is the HttpServerOption to set a non default
+// timeout for graceful shutdown when the context given to Run is done.
+func WithHttpServerShutdownTimeout(timeout time.Duration) HttpServerOption {
+	return func(hs *HttpServer) { hs.shutdownTimeout = timeout }
+}
+
+// NewHttpServer returns a new HttpServer, initialized with the given options.
+func NewHttpServer(inv *inventory.Inventory, opts ...HttpServerOption) *HttpServer {
+	hs := &HttpServer{
+		inventory:         inv,
+		triggers:          map[string]chan<- struct{}{},
+		subscriberBuffer:  defaultHttpServerSubscriberBuffer,
+		keepAliveInterval: defaultHttpServerKeepAliveInterval,
+		shutdownTimeout:   defaultHttpServerShutdownTimeout,
+	}
+	for _, opt := range opts {
+		opt(hs)
+	}
+
+	hs.mux = http.NewServeMux()
+	hs.mux.HandleFunc("GET /v1/resources", hs.handleListResources)
+	hs.mux.HandleFunc("GET /v1/resources/{key...}", hs.handleGetResource)
+	hs.mux.HandleFunc("GET /v1/discoverers", hs.handleListDiscoverers)
+	hs.mux.HandleFunc("POST /v1/discoverers/{id}/trigger", hs.handleTriggerDiscoverer)
+	hs.mux.HandleFunc("GET /v1/events", hs.handleEvents)
+
+	return hs
+}
+
+// ServeHTTP serves an HTTP request.
+func (hs *HttpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	hs.mux.ServeHTTP(w, r)
+}
+
+// Run serves HTTP on a given address until the context is done.
+func (hs *HttpServer) Run(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %v", address, err)
+	}
+	return hs.Serve(ctx, listener)
+}
+
+// Serve serves HTTP on a given listener until the context is done.
+func (hs *HttpServer) Serve(ctx context.Context, listener net.Listener) error {
+	server := &http.Server{
+		Handler:     hs,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errC := make(chan error, 1)
+	go func() { errC <- server.Serve(listener) }()
+
+	select {
+	case err := <-errC:
+		return fmt.Errorf("failed to serve: %v", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), hs.shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down gracefully: %v", err)
+		}
+		if err := <-errC; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return fmt.Errorf("failed to serve: %v", err)
+		}
+		return nil
+	}
+}
+
+func (hs *HttpServer) handleListResources(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter := inventory.Filter{
+		ResourceTypes: query["type"],
+		DiscovererIds: query["discoverer"],
+		Tags:          map[string]string{},
+	}
+	for _, tag := range query["tag"] {
+		key, value, ok := strings.Cut(tag, "=")
+		if !ok {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("tag filter \"%s\" is not of the form key=value", tag))
+			return
+		}
+		filter.Tags[key] = value
+	}
+
+	writeJson(w, http.StatusOK, map[string]any{"resources": hs.inventory.List(filter)})
+}
+
+func (hs *HttpServer) handleGetResource(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+
+	entries := hs.inventory.Get(key)
+	if len(entries) == 0 {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no resource with key \"%s\"", key))
+		return
+	}
+
+	writeJson(w, http.StatusOK, map[string]any{"resources": entries})
+}
+
+func (hs *HttpServer) handleListDiscoverers(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]any{"discoverers": hs.inventory.Statuses()})
+}
+
+func (hs *HttpServer) handleTriggerDiscoverer(w http.ResponseWriter, r *http.Request) {
+	discovererId := r.PathValue("id")
+
+	triggerC, ok := hs.triggers[discovererId]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no trigger for discoverer \"%s\"", discovererId))
+		return
+	}
+
+	select {
+	case triggerC <- struct{}{}:
+	default: // a trigger is already pending
+	}
+
+	writeJson(w, http.StatusAccepted, map[string]any{"discoverer_id": discovererId, "triggered": true})
+}
+
+func (hs *HttpServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported by the underlying connection")
+		return
+	}
+
+	deltasC, unsubscribe := hs.inventory.Subscribe(hs.subscriberBuffer)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(hs.keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case deltas, ok := <-deltasC:
+			if !ok {
+				return
+			}
+			for _, delta := range deltas {
+				byt, err := json.Marshal(delta)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", delta.DeltaType, byt); err != nil {
+					return
+				}
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeJson(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJson(w, status, map[string]string{"error": message})
+}
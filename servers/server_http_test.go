@@ -0,0 +1,291 @@
+package servers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/inventory"
+)
+
+func newHttpTestInventory() *inventory.Inventory {
+	ec2 := discovery.NewResult("ec2")
+	ec2.AddResources(discovery.Resource{
+		ResourceType: discovery.ResourceTypeAwsEc2Instance,
+		AwsEc2InstanceDetails: &discovery.AwsEc2InstanceDetails{
+			AwsBaseDetails: discovery.AwsBaseDetails{AwsArn: "arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"},
+			Tags:           map[string]string{"team": "web"},
+			InstanceId:     "i-0abc",
+		},
+	})
+	ec2.Done()
+
+	docker := discovery.NewResult("docker")
+	docker.AddResources(
+		discovery.Resource{
+			ResourceType:           discovery.ResourceTypeDockerContainer,
+			DockerContainerDetails: &discovery.DockerContainerDetails{ContainerId: "c1", Labels: map[string]string{"team": "web"}},
+		},
+		discovery.Resource{
+			ResourceType:           discovery.ResourceTypeDockerContainer,
+			DockerContainerDetails: &discovery.DockerContainerDetails{ContainerId: "c2", Labels: map[string]string{"team": "data"}},
+		},
+	)
+	docker.AddWarning("skipped a container")
+	docker.Done()
+
+	inv := inventory.NewInventory()
+	inv.Update(ec2)
+	inv.Update(docker)
+	return inv
+}
+
+// doJson makes a request to a test server and decodes its json response.
+func doJson(t *testing.T, method, url string, body any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "application/json" {
+		t.Fatalf("expected a json response, got content type %s", contentType)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return resp.StatusCode
+}
+
+func TestHttpServerListResources(t *testing.T) {
+	server := httptest.NewServer(NewHttpServer(newHttpTestInventory()))
+	defer server.Close()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedKeys   []string
+	}{
+		{
+			name:           "all resources",
+			expectedStatus: http.StatusOK,
+			expectedKeys: []string{
+				"aws_ec2_instance:arn:aws:ec2:us-east-1:123456789012:instance/i-0abc",
+				"docker_container:c1",
+				"docker_container:c2",
+			},
+		},
+		{
+			name:           "by type",
+			query:          "type=aws_ec2_instance",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"aws_ec2_instance:arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"},
+		},
+		{
+			name:           "by discoverers",
+			query:          "discoverer=docker&discoverer=other",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"docker_container:c1", "docker_container:c2"},
+		},
+		{
+			name:           "by tags",
+			query:          "tag=team=web&type=docker_container",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{"docker_container:c1"},
+		},
+		{
+			name:           "no matches",
+			query:          "tag=team=ops",
+			expectedStatus: http.StatusOK,
+			expectedKeys:   []string{},
+		},
+		{
+			name:           "invalid tag filter",
+			query:          "tag=team",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var body struct {
+				Resources []inventory.Entry `json:"resources"`
+				Error     string            `json:"error"`
+			}
+			status := doJson(t, http.MethodGet, server.URL+"/v1/resources?"+test.query, &body)
+			if status != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d", test.expectedStatus, status)
+			}
+			if status != http.StatusOK {
+				if body.Error == "" {
+					t.Fatal("expected an error message")
+				}
+				return
+			}
+			if body.Resources == nil {
+				t.Fatal("expected a resources list, even if empty")
+			}
+			keys := []string{}
+			for _, entry := range body.Resources {
+				keys = append(keys, entry.Key)
+			}
+			if !slices.Equal(keys, test.expectedKeys) {
+				t.Fatalf("expected resources %v, got %v", test.expectedKeys, keys)
+			}
+		})
+	}
+}
+
+func TestHttpServerGetResource(t *testing.T) {
+	server := httptest.NewServer(NewHttpServer(newHttpTestInventory()))
+	defer server.Close()
+
+	// keys of aws resources hold arns, with slashes
+	key := "aws_ec2_instance:arn:aws:ec2:us-east-1:123456789012:instance/i-0abc"
+
+	var body struct {
+		Resources []inventory.Entry `json:"resources"`
+	}
+	if status := doJson(t, http.MethodGet, server.URL+"/v1/resources/"+key, &body); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+	if len(body.Resources) != 1 {
+		t.Fatalf("expected 1 resource, got %d", len(body.Resources))
+	}
+	entry := body.Resources[0]
+	if entry.Key != key || entry.DiscovererId != "ec2" || entry.Resource.AwsEc2InstanceDetails.InstanceId != "i-0abc" {
+		t.Fatalf("unexpected entry %+v", entry)
+	}
+
+	var notFound struct {
+		Error string `json:"error"`
+	}
+	status := doJson(t, http.MethodGet, server.URL+"/v1/resources/"+url.PathEscape("docker_container:missing"), &notFound)
+	if status != http.StatusNotFound || notFound.Error == "" {
+		t.Fatalf("expected status %d with an error, got %d %+v", http.StatusNotFound, status, notFound)
+	}
+}
+
+func TestHttpServerListDiscoverers(t *testing.T) {
+	server := httptest.NewServer(NewHttpServer(newHttpTestInventory()))
+	defer server.Close()
+
+	var body struct {
+		Discoverers []inventory.DiscovererStatus `json:"discoverers"`
+	}
+	if status := doJson(t, http.MethodGet, server.URL+"/v1/discoverers", &body); status != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, status)
+	}
+	if len(body.Discoverers) != 2 {
+		t.Fatalf("expected 2 discoverers, got %v", body.Discoverers)
+	}
+	docker := body.Discoverers[0]
+	if docker.DiscovererId != "docker" || docker.ResourceCount != 2 || !slices.Equal(docker.Warnings, []string{"skipped a container"}) {
+		t.Fatalf("unexpected status %+v", docker)
+	}
+}
+
+func TestHttpServerTriggerDiscoverer(t *testing.T) {
+	triggerC := make(chan struct{}, 1)
+	server := httptest.NewServer(NewHttpServer(inventory.NewInventory(), WithHttpServerTrigger("ec2", triggerC)))
+	defer server.Close()
+
+	// the second trigger is a no-op while the first one is pending
+	for i := 0; i < 2; i++ {
+		var body struct {
+			DiscovererId string `json:"discoverer_id"`
+			Triggered    bool   `json:"triggered"`
+		}
+		if status := doJson(t, http.MethodPost, server.URL+"/v1/discoverers/ec2/trigger", &body); status != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d", http.StatusAccepted, status)
+		}
+		if body.DiscovererId != "ec2" || !body.Triggered {
+			t.Fatalf("unexpected response %+v", body)
+		}
+	}
+	if len(triggerC) != 1 {
+		t.Fatalf("expected 1 pending trigger, got %d", len(triggerC))
+	}
+
+	var notFound struct {
+		Error string `json:"error"`
+	}
+	if status := doJson(t, http.MethodPost, server.URL+"/v1/discoverers/other/trigger", &notFound); status != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, status)
+	}
+}
+
+func TestHttpServerEvents(t *testing.T) {
+	inv := inventory.NewInventory()
+	server := httptest.NewServer(NewHttpServer(inv, WithHttpServerKeepAliveInterval(time.Millisecond*50)))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v1/events", nil)
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("expected an event stream, got content type %s", contentType)
+	}
+
+	// the server subscribed before responding, so no changes are missed
+	result := discovery.NewResult("docker")
+	result.AddResources(discovery.Resource{
+		ResourceType:           discovery.ResourceTypeDockerContainer,
+		DockerContainerDetails: &discovery.DockerContainerDetails{ContainerId: "c1"},
+	})
+	result.Done()
+	inv.Update(result)
+
+	var event, data string
+	keepAlives := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() && (event == "" || keepAlives == 0) {
+		line := scanner.Text()
+		switch {
+		case line == ": keep-alive":
+			keepAlives++
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	if event != discovery.DeltaTypeAdded {
+		t.Fatalf("expected an %s event, got %q", discovery.DeltaTypeAdded, event)
+	}
+	if keepAlives == 0 {
+		t.Fatal("expected keep-alive comments")
+	}
+
+	var delta discovery.ResourceDelta
+	if err := json.Unmarshal([]byte(data), &delta); err != nil {
+		t.Fatalf("failed to decode event data: %v", err)
+	}
+	if delta.Key != "docker_container:c1" || delta.DiscovererId != "docker" || delta.DeltaType != discovery.DeltaTypeAdded {
+		t.Fatalf("unexpected delta %+v", delta)
+	}
+}
@@ -0,0 +1,16 @@
+package discovery
+
+import "context"
+
+// Sink represents an entity capable of consuming results.
+//
+// Results handed to a sink may be shared with other sinks,
+// so a sink must treat them as read-only.
+type Sink interface {
+	// Send delivers a single result to the sink.
+	Send(context.Context, *Result) error
+
+	// Close flushes anything the sink has buffered and releases
+	// its resources. Send is never called after Close.
+	Close(context.Context) error
+}
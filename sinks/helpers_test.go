@@ -0,0 +1,36 @@
+package sinks
+
+import (
+	"github.com/borderzero/discovery"
+)
+
+// newSinkTestContainers returns docker container resources with the given ids.
+func newSinkTestContainers(containerIds ...string) []discovery.Resource {
+	resources := []discovery.Resource{}
+	for _, containerId := range containerIds {
+		resources = append(resources, discovery.Resource{
+			ResourceType:           discovery.ResourceTypeDockerContainer,
+			DockerContainerDetails: &discovery.DockerContainerDetails{ContainerId: containerId},
+		})
+	}
+	return resources
+}
+
+func newSinkTestResult(discovererId string, resources ...discovery.Resource) *discovery.Result {
+	result := discovery.NewResult(discovererId)
+	result.AddResources(resources...)
+	result.Done()
+	return result
+}
+
+// errored adds an error to a result, as for discoverers which failed to list some resources.
+func errored(result *discovery.Result) *discovery.Result {
+	result.AddError("failed to list some resources")
+	return result
+}
+
+// stale marks a result as stale, as for results loaded from snapshots.
+func stale(result *discovery.Result) *discovery.Result {
+	result.Metadata.Stale = true
+	return result
+}
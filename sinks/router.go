@@ -0,0 +1,211 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+const (
+	// BackpressurePolicyBlock makes the router wait for room in a sink's
+	// buffer, which in turn slows down reading from the results channel.
+	BackpressurePolicyBlock BackpressurePolicy = "block"
+
+	// BackpressurePolicyDropOldest makes the router discard the oldest
+	// buffered result of a sink to make room for a new result.
+	BackpressurePolicyDropOldest BackpressurePolicy = "drop_oldest"
+
+	defaultBufferSize         = 10
+	defaultBackpressurePolicy = BackpressurePolicyBlock
+	defaultCloseTimeout       = time.Second * 10
+)
+
+// ErrResultDropped is reported to a sink's error handler
+// whenever a result is dropped because its buffer was full.
+var ErrResultDropped = errors.New("result dropped because the sink buffer was full")
+
+// BackpressurePolicy represents what the router does when a sink's buffer is full.
+type BackpressurePolicy string
+
+type sinkConfig struct {
+	bufferSize         int
+	backpressurePolicy BackpressurePolicy
+	errorHandler       func(error)
+
+	sink discovery.Sink
+}
+
+// Router represents an entity which delivers every result
+// from an engine's results channel to multiple sinks.
+type Router struct {
+	sinks        []*sinkConfig
+	closeTimeout time.Duration
+}
+
+// RouterOption is an input option for the Router constructor.
+type RouterOption func(*Router)
+
+// SinkOption is an input option for Router's WithSink().
+type SinkOption func(*sinkConfig)
+
+// WithBufferSize sets a non-default buffer size for a Router's sink.
+func WithBufferSize(size int) SinkOption {
+	return func(sc *sinkConfig) { sc.bufferSize = size }
+}
+
+// WithBackpressurePolicy sets a non-default backpressure policy for a Router's
+// sink. Policies other than the ones defined here fall back to the default.
+func WithBackpressurePolicy(policy BackpressurePolicy) SinkOption {
+	return func(sc *sinkConfig) { sc.backpressurePolicy = policy }
+}
+
+// WithErrorHandler sets the function to which errors returned by a Router's sink
+// (and dropped results) are reported. By default errors are discarded.
+func WithErrorHandler(handler func(error)) SinkOption {
+	return func(sc *sinkConfig) { sc.errorHandler = handler }
+}
+
+// WithSink is a configuration option to include an additional Sink in a Router's sinks.
+func WithSink(sink discovery.Sink, opts ...SinkOption) RouterOption {
+	return func(router *Router) {
+		sc := &sinkConfig{
+			sink:               sink,
+			bufferSize:         defaultBufferSize,
+			backpressurePolicy: defaultBackpressurePolicy,
+			errorHandler:       func(error) {},
+		}
+		for _, opt := range opts {
+			opt(sc)
+		}
+		if sc.backpressurePolicy != BackpressurePolicyDropOldest {
+			sc.backpressurePolicy = defaultBackpressurePolicy
+		}
+		router.sinks = append(router.sinks, sc)
+	}
+}
+
+// WithCloseTimeout sets a non-default timeout for closing
+// the Router's sinks once there are no more results.
+func WithCloseTimeout(timeout time.Duration) RouterOption {
+	return func(router *Router) { router.closeTimeout = timeout }
+}
+
+// NewRouter returns a new Router, initialized with the given options.
+func NewRouter(opts ...RouterOption) *Router {
+	router := &Router{
+		sinks:        []*sinkConfig{},
+		closeTimeout: defaultCloseTimeout,
+	}
+	for _, opt := range opts {
+		opt(router)
+	}
+	return router
+}
+
+// Run reads results until the results channel is closed or the context
+// is done, delivering each result to every sink concurrently. When the
+// results channel is closed, each sink's remaining buffered results are
+// delivered before the sink is closed. When the context is done, remaining
+// buffered results are dropped without being delivered and each sink is
+// closed right away (sinks may still flush what they hold on Close).
+func (r *Router) Run(ctx context.Context, results <-chan *discovery.Result) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	buffers := make([]chan *discovery.Result, len(r.sinks))
+	for i, sc := range r.sinks {
+		size := sc.bufferSize
+		if sc.backpressurePolicy == BackpressurePolicyDropOldest && size < 1 {
+			size = 1 // there must be room for at least one result to drop
+		}
+		buffers[i] = make(chan *discovery.Result, size)
+
+		wg.Add(1)
+		go r.runSink(ctx, &wg, sc, buffers[i])
+	}
+	defer func() {
+		for _, buffer := range buffers {
+			close(buffer)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case result, ok := <-results:
+			if !ok {
+				return
+			}
+			for i, sc := range r.sinks {
+				if !enqueue(ctx, sc, buffers[i], result) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// runSink delivers results from a buffer to a sink until the buffer is
+// closed, then closes the sink and signals a wait group when done.
+func (r *Router) runSink(
+	ctx context.Context,
+	wg *sync.WaitGroup,
+	sc *sinkConfig,
+	buffer <-chan *discovery.Result,
+) {
+	defer wg.Done()
+
+	for result := range buffer {
+		if ctx.Err() != nil {
+			continue // drain without delivering
+		}
+		if err := sc.sink.Send(ctx, result); err != nil {
+			sc.errorHandler(fmt.Errorf("failed to send result from discoverer %s: %w", result.Metadata.DiscovererId, err))
+		}
+	}
+
+	closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), r.closeTimeout)
+	defer cancel()
+
+	if err := sc.sink.Close(closeCtx); err != nil {
+		sc.errorHandler(fmt.Errorf("failed to close sink: %w", err))
+	}
+}
+
+// enqueue adds a result to a sink's buffer, honoring the sink's backpressure
+// policy. Returns false if the context is done before the result is enqueued.
+func enqueue(
+	ctx context.Context,
+	sc *sinkConfig,
+	buffer chan *discovery.Result,
+	result *discovery.Result,
+) bool {
+	if sc.backpressurePolicy == BackpressurePolicyDropOldest {
+		for {
+			select {
+			case buffer <- result:
+				return true
+			default:
+			}
+			// note: the router is the only writer, so once the oldest result is
+			// dropped (or consumed by the sink) there is room for the new one.
+			select {
+			case dropped := <-buffer:
+				sc.errorHandler(fmt.Errorf("%w (discoverer %s)", ErrResultDropped, dropped.Metadata.DiscovererId))
+			default:
+			}
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case buffer <- result:
+		return true
+	}
+}
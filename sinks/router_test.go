@@ -0,0 +1,273 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+// fakeSink is a sink which records the discoverer ids of the results it
+// receives. Sends signal started and then block while blocked is open, and
+// Close waits for its context to be done when closeBlocks is set.
+type fakeSink struct {
+	sync.Mutex
+
+	started     chan struct{}
+	blocked     chan struct{}
+	closeBlocks bool
+
+	sent   []string
+	closed bool
+}
+
+func (fs *fakeSink) Send(ctx context.Context, result *discovery.Result) error {
+	if fs.started != nil {
+		fs.started <- struct{}{}
+	}
+	if fs.blocked != nil {
+		<-fs.blocked
+	}
+
+	fs.Lock()
+	defer fs.Unlock()
+
+	fs.sent = append(fs.sent, result.Metadata.DiscovererId)
+	return nil
+}
+
+func (fs *fakeSink) Close(ctx context.Context) error {
+	if fs.closeBlocks {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	fs.Lock()
+	defer fs.Unlock()
+
+	fs.closed = true
+	return nil
+}
+
+func (fs *fakeSink) received() ([]string, bool) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	return append([]string{}, fs.sent...), fs.closed
+}
+
+// errorRecorder collects the errors reported to a sink's error handler.
+type errorRecorder struct {
+	sync.Mutex
+
+	errs []error
+}
+
+func (er *errorRecorder) handle(err error) {
+	er.Lock()
+	defer er.Unlock()
+
+	er.errs = append(er.errs, err)
+}
+
+func (er *errorRecorder) recorded() []error {
+	er.Lock()
+	defer er.Unlock()
+
+	return append([]error{}, er.errs...)
+}
+
+// runRouter runs a router in the background, returning a channel
+// which is closed once the router's Run returns.
+func runRouter(ctx context.Context, router *Router, results <-chan *discovery.Result) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		router.Run(ctx, results)
+	}()
+	return done
+}
+
+// newBlockedFakeSink returns a sink whose sends block until blocked is closed.
+func newBlockedFakeSink() *fakeSink {
+	return &fakeSink{started: make(chan struct{}, 10), blocked: make(chan struct{})}
+}
+
+func waitForRouter(t *testing.T, done <-chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected the router to return")
+	}
+}
+
+func TestRouterDeliversToEverySink(t *testing.T) {
+	first, second := &fakeSink{}, &fakeSink{}
+	router := NewRouter(WithSink(first), WithSink(second, WithBufferSize(0)))
+
+	results := make(chan *discovery.Result)
+	done := runRouter(context.Background(), router, results)
+	for _, id := range []string{"a", "b", "c"} {
+		results <- discovery.NewResult(id)
+	}
+	close(results)
+	waitForRouter(t, done)
+
+	for i, sink := range []*fakeSink{first, second} {
+		sent, closed := sink.received()
+		if !slices.Equal(sent, []string{"a", "b", "c"}) {
+			t.Fatalf("expected sink %d to receive every result in order, got %v", i, sent)
+		}
+		if !closed {
+			t.Fatalf("expected sink %d to be closed", i)
+		}
+	}
+}
+
+func TestRouterBackpressure(t *testing.T) {
+	tests := []struct {
+		name            string
+		policy          BackpressurePolicy
+		expectBlocked   bool
+		expectedSent    []string
+		expectedDropped int
+	}{
+		{
+			name:          "block waits for room in the buffer",
+			policy:        BackpressurePolicyBlock,
+			expectBlocked: true,
+			expectedSent:  []string{"a", "b", "c"},
+		},
+		{
+			name:          "unknown policy falls back to block",
+			policy:        BackpressurePolicy("unknown"),
+			expectBlocked: true,
+			expectedSent:  []string{"a", "b", "c"},
+		},
+		{
+			// "a" is held by the sink, "b" and "c" are dropped to make room for "d"
+			name:            "drop oldest makes room in the buffer",
+			policy:          BackpressurePolicyDropOldest,
+			expectedSent:    []string{"a", "d"},
+			expectedDropped: 2,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sink := newBlockedFakeSink()
+			errs := &errorRecorder{}
+			router := NewRouter(WithSink(
+				sink,
+				WithBufferSize(1),
+				WithBackpressurePolicy(test.policy),
+				WithErrorHandler(errs.handle),
+			))
+
+			results := make(chan *discovery.Result)
+			done := runRouter(context.Background(), router, results)
+
+			// the sink holds the first result, the buffer the second
+			results <- discovery.NewResult("a")
+			<-sink.started
+			results <- discovery.NewResult("b")
+
+			select {
+			case results <- discovery.NewResult("c"):
+			case <-time.After(time.Second * 5):
+				t.Fatal("expected the router to read the third result")
+			}
+			// unless it drops results, the router must not read
+			// any more results while it waits for room
+			select {
+			case results <- discovery.NewResult("d"):
+				if test.expectBlocked {
+					t.Fatal("expected the router to wait for room in the buffer")
+				}
+			case <-time.After(time.Millisecond * 50):
+				if !test.expectBlocked {
+					t.Fatal("expected the router not to wait for room in the buffer")
+				}
+			}
+
+			// wait for results to be dropped (if any) before the sink makes room
+			for deadline := time.Now().Add(time.Second * 5); len(errs.recorded()) < test.expectedDropped; {
+				if time.Now().After(deadline) {
+					t.Fatalf("expected %d results to be dropped", test.expectedDropped)
+				}
+				time.Sleep(time.Millisecond)
+			}
+			close(sink.blocked)
+			close(results)
+			waitForRouter(t, done)
+
+			sent, _ := sink.received()
+			if !slices.Equal(sent, test.expectedSent) {
+				t.Fatalf("expected results %v to be sent, got %v", test.expectedSent, sent)
+			}
+			recorded := errs.recorded()
+			if len(recorded) != test.expectedDropped {
+				t.Fatalf("expected %d errors, got %v", test.expectedDropped, recorded)
+			}
+			for _, err := range recorded {
+				if !errors.Is(err, ErrResultDropped) {
+					t.Fatalf("expected dropped result error, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRouterShutdown(t *testing.T) {
+	sink := newBlockedFakeSink()
+	router := NewRouter(WithSink(sink, WithBufferSize(5)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	results := make(chan *discovery.Result)
+	done := runRouter(ctx, router, results)
+
+	// the sink holds the first result, the buffer the others
+	results <- discovery.NewResult("a")
+	<-sink.started
+	results <- discovery.NewResult("b")
+	results <- discovery.NewResult("c")
+	cancel()
+	close(sink.blocked)
+	waitForRouter(t, done)
+
+	sent, closed := sink.received()
+	if !slices.Equal(sent, []string{"a"}) {
+		t.Fatalf("expected buffered results to be dropped, got %v sent", sent)
+	}
+	if !closed {
+		t.Fatal("expected the sink to be closed")
+	}
+}
+
+func TestRouterCloseTimeout(t *testing.T) {
+	sink := &fakeSink{closeBlocks: true}
+	errs := &errorRecorder{}
+	router := NewRouter(
+		WithSink(sink, WithErrorHandler(errs.handle)),
+		WithCloseTimeout(time.Millisecond*50),
+	)
+
+	results := make(chan *discovery.Result)
+	close(results)
+
+	start := time.Now()
+	waitForRouter(t, runRouter(context.Background(), router, results))
+	if elapsed := time.Since(start); elapsed < time.Millisecond*50 {
+		t.Fatalf("expected the router to wait for the close timeout, returned after %s", elapsed)
+	}
+
+	recorded := errs.recorded()
+	if len(recorded) != 1 || !errors.Is(recorded[0], context.DeadlineExceeded) {
+		t.Fatalf("expected a single close timeout error, got %v", recorded)
+	}
+}
@@ -0,0 +1,419 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+const (
+	// ConsulMetaDiscovererId is the service meta key holding the id of the discoverer
+	// which found the resource of a registration. Only registrations with this key (and
+	// the sink's node) are ever deregistered by a ConsulSink.
+	ConsulMetaDiscovererId = "discovery-discoverer-id"
+
+	// ConsulMetaKey is the service meta key holding the key of the resource of a registration.
+	ConsulMetaKey = "discovery-key"
+
+	defaultConsulSinkNodeName       = "discovery"
+	defaultConsulSinkNodeAddress    = "127.0.0.1"
+	defaultConsulSinkRequestTimeout = time.Second * 10
+
+	consulTokenHeader    = "X-Consul-Token"
+	consulMaxMetaEntries = 64
+	consulMaxMetaKey     = 128
+	consulMaxMetaValue   = 512
+)
+
+// consulServiceNames are the service names of the resource types of services on the network.
+var consulServiceNames = map[string]string{
+	discovery.ResourceTypeNetworkHttpServer:       "http",
+	discovery.ResourceTypeNetworkHttpsServer:      "https",
+	discovery.ResourceTypeNetworkMysqlServer:      "mysql",
+	discovery.ResourceTypeNetworkPostgresqlServer: "postgresql",
+	discovery.ResourceTypeNetworkRdpServer:        "rdp",
+	discovery.ResourceTypeNetworkSshServer:        "ssh",
+	discovery.ResourceTypeNetworkVncServer:        "vnc",
+}
+
+// consulService represents a service registration in the Consul catalog.
+type consulService struct {
+	ID      string            `json:"ID"`
+	Service string            `json:"Service"`
+	Tags    []string          `json:"Tags"`
+	Address string            `json:"Address"`
+	Port    int               `json:"Port"`
+	Meta    map[string]string `json:"Meta"`
+}
+
+// ConsulSink represents a sink which registers discovered services as external services
+// (i.e. on a node which does not run a Consul agent) in a Consul catalog, and deregisters
+// them when their resources disappear.
+//
+// Registrations are made for services on the network (named after the service e.g.
+// "postgresql"), kubernetes services (named after the service, one per port) and Docker
+// port bindings (named after the container, one per binding). Registrations are tagged
+// with the resource type, and with "<key>=<value>" for every label of kubernetes services
+// and Docker containers, which are also added to the registrations' meta.
+//
+// Every result is reconciled against the registrations of its discoverer in the catalog,
+// so registrations left behind (e.g. by a previous process) are also deregistered. As with
+// discovery.Diff, nothing is deregistered for results with errors (which may be partial)
+// or for stale results.
+type ConsulSink struct {
+	address     string
+	httpClient  *http.Client
+	token       string
+	datacenter  string
+	nodeName    string
+	nodeAddress string
+
+	mu sync.Mutex // serializes reconciliations
+}
+
+// ensure ConsulSink implements discovery.Sink at compile-time.
+var _ discovery.Sink = (*ConsulSink)(nil)
+
+// ConsulSinkOption represents a configuration option for a ConsulSink.
+type ConsulSinkOption func(*ConsulSink)
+
+// WithConsulSinkHttpClient is the ConsulSinkOption to set a non default http client.
+func WithConsulSinkHttpClient(client *http.Client) ConsulSinkOption {
+	return func(cs *ConsulSink) { cs.httpClient = client }
+}
+
+// WithConsulSinkToken is the ConsulSinkOption to set the ACL token for requests.
+func WithConsulSinkToken(token string) ConsulSinkOption {
+	return func(cs *ConsulSink) { cs.token = token }
+}
+
+// WithConsulSinkDatacenter is the ConsulSinkOption to set the datacenter to register
+// services in. By default, services are registered in the datacenter of the agent.
+func WithConsulSinkDatacenter(datacenter string) ConsulSinkOption {
+	return func(cs *ConsulSink) { cs.datacenter = datacenter }
+}
+
+// WithConsulSinkNode is the ConsulSinkOption to set the name and address of the external
+// node services are registered on. The address is also used for Docker port bindings on
+// all interfaces of the Docker host.
+func WithConsulSinkNode(name, address string) ConsulSinkOption {
+	return func(cs *ConsulSink) {
+		cs.nodeName = name
+		cs.nodeAddress = address
+	}
+}
+
+// NewConsulSink returns a new ConsulSink for the Consul HTTP API at the given
+// address (e.g. "http://127.0.0.1:8500"), initialized with the given options.
+func NewConsulSink(address string, opts ...ConsulSinkOption) *ConsulSink {
+	cs := &ConsulSink{
+		address:     strings.TrimSuffix(address, "/"),
+		httpClient:  &http.Client{Timeout: defaultConsulSinkRequestTimeout},
+		nodeName:    defaultConsulSinkNodeName,
+		nodeAddress: defaultConsulSinkNodeAddress,
+	}
+	for _, opt := range opts {
+		opt(cs)
+	}
+	return cs
+}
+
+// Send reconciles the registrations of a result's discoverer with the services in the result.
+func (cs *ConsulSink) Send(ctx context.Context, result *discovery.Result) error {
+	result.Lock()
+	discovererId := result.Metadata.DiscovererId
+	deregister := len(result.Errors) == 0 && !result.Metadata.Stale
+	desired := map[string]consulService{}
+	for _, resource := range result.Resources {
+		for _, service := range cs.services(discovererId, resource) {
+			desired[service.ID] = service
+		}
+	}
+	result.Unlock()
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	existing, err := cs.registeredServices(ctx, discovererId)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, id := range slices.Sorted(maps.Keys(desired)) {
+		if current, ok := existing[id]; ok && reflect.DeepEqual(current, desired[id]) {
+			continue
+		}
+		if err := cs.register(ctx, desired[id]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if deregister {
+		for _, id := range slices.Sorted(maps.Keys(existing)) {
+			if _, ok := desired[id]; ok {
+				continue
+			}
+			if err := cs.deregister(ctx, id); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close is a no-op, registrations are kept until resources disappear.
+func (cs *ConsulSink) Close(_ context.Context) error {
+	return nil
+}
+
+// services returns the registrations for a resource.
+func (cs *ConsulSink) services(discovererId string, resource discovery.Resource) []consulService {
+	key := resource.Key()
+	newService := func(idSuffix, name, address string, port int, labels map[string]string) consulService {
+		id := "discovery:" + key
+		if idSuffix != "" {
+			id += ":" + idSuffix
+		}
+		tags := []string{resource.ResourceType}
+		meta := map[string]string{}
+		for _, k := range slices.Sorted(maps.Keys(labels)) {
+			tags = append(tags, fmt.Sprintf("%s=%s", k, labels[k]))
+			if len(meta) < consulMaxMetaEntries-2 {
+				metaKey := consulMetaKey(k)
+				if metaKey != "" {
+					meta[metaKey] = truncate(labels[k], consulMaxMetaValue)
+				}
+			}
+		}
+		meta[ConsulMetaDiscovererId] = discovererId
+		meta[ConsulMetaKey] = truncate(key, consulMaxMetaValue)
+		return consulService{
+			ID:      consulServiceId(id),
+			Service: consulServiceName(name),
+			Tags:    tags,
+			Address: address,
+			Port:    port,
+			Meta:    meta,
+		}
+	}
+
+	services := []consulService{}
+	switch {
+	case resource.KubernetesServiceDetails != nil:
+		details := resource.KubernetesServiceDetails
+		if details.ClusterIp == "" || details.ClusterIp == "None" { // headless services have no cluster ip
+			return services
+		}
+		for _, port := range details.Ports {
+			service := newService(strconv.Itoa(int(port.Port)), details.Name, details.ClusterIp, int(port.Port), details.Labels)
+			service.Tags = append(service.Tags, "namespace="+details.Namespace)
+			if port.Name != "" {
+				service.Tags = append(service.Tags, "port="+port.Name)
+			}
+			services = append(services, service)
+		}
+
+	case resource.DockerContainerDetails != nil:
+		details := resource.DockerContainerDetails
+		name := details.ContainerId
+		if len(details.Names) > 0 {
+			name = strings.TrimPrefix(details.Names[0], "/")
+		}
+		for _, binding := range slices.Sorted(maps.Keys(details.PortBindings)) {
+			host, port, err := net.SplitHostPort(binding)
+			if err != nil {
+				continue
+			}
+			portNumber, err := strconv.Atoi(port)
+			if err != nil {
+				continue
+			}
+			if host == "0.0.0.0" || host == "::" {
+				host = cs.nodeAddress // bound to all interfaces of the Docker host
+			}
+			service := newService(binding, name, host, portNumber, details.Labels)
+			service.Tags = append(service.Tags, "container_port="+details.PortBindings[binding])
+			services = append(services, service)
+		}
+
+	default:
+		name, ok := consulServiceNames[resource.ResourceType]
+		if !ok {
+			return services
+		}
+		base, _ := resource.NetworkBaseDetails()
+		portNumber, err := strconv.Atoi(base.Port)
+		if err != nil {
+			return services
+		}
+		services = append(services, newService("", name, base.IpAddress, portNumber, nil))
+	}
+	return services
+}
+
+// registeredServices returns the registrations of a discoverer on the sink's node, by id.
+func (cs *ConsulSink) registeredServices(ctx context.Context, discovererId string) (map[string]consulService, error) {
+	var node struct {
+		Services map[string]consulService `json:"Services"`
+	}
+	status, body, err := cs.do(ctx, http.MethodGet, "/v1/catalog/node/"+url.PathEscape(cs.nodeName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consul catalog node: %v", err)
+	}
+	services := map[string]consulService{}
+	if status == http.StatusNotFound || string(bytes.TrimSpace(body)) == "null" {
+		return services, nil // node not registered yet
+	}
+	if err := json.Unmarshal(body, &node); err != nil {
+		return nil, fmt.Errorf("failed to decode consul catalog node: %v", err)
+	}
+	for id, service := range node.Services {
+		if service.Meta[ConsulMetaDiscovererId] != discovererId {
+			continue
+		}
+		if service.Tags == nil {
+			service.Tags = []string{}
+		}
+		services[id] = service
+	}
+	return services, nil
+}
+
+func (cs *ConsulSink) register(ctx context.Context, service consulService) error {
+	registration := map[string]any{
+		"Node":    cs.nodeName,
+		"Address": cs.nodeAddress,
+		"NodeMeta": map[string]string{
+			"external-node":  "true",
+			"external-probe": "false",
+		},
+		"Service":        service,
+		"SkipNodeUpdate": true,
+	}
+	if cs.datacenter != "" {
+		registration["Datacenter"] = cs.datacenter
+	}
+	if _, _, err := cs.do(ctx, http.MethodPut, "/v1/catalog/register", registration); err != nil {
+		return fmt.Errorf("failed to register consul service %s: %v", service.ID, err)
+	}
+	return nil
+}
+
+func (cs *ConsulSink) deregister(ctx context.Context, serviceId string) error {
+	deregistration := map[string]any{
+		"Node":      cs.nodeName,
+		"ServiceID": serviceId,
+	}
+	if cs.datacenter != "" {
+		deregistration["Datacenter"] = cs.datacenter
+	}
+	if _, _, err := cs.do(ctx, http.MethodPut, "/v1/catalog/deregister", deregistration); err != nil {
+		return fmt.Errorf("failed to deregister consul service %s: %v", serviceId, err)
+	}
+	return nil
+}
+
+// do sends a request to the Consul HTTP API, and returns the status code and body of the
+// response. Responses with status codes other than 2xx and 404 are returned as errors.
+func (cs *ConsulSink) do(ctx context.Context, method, path string, body any) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		byt, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to json encode request body: %v", err)
+		}
+		reader = bytes.NewReader(byt)
+	}
+
+	u := cs.address + path
+	if cs.datacenter != "" && method == http.MethodGet {
+		u += "?dc=" + url.QueryEscape(cs.datacenter)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to build request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cs.token != "" {
+		req.Header.Set(consulTokenHeader, cs.token)
+	}
+
+	resp, err := cs.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("failed to read response body: %v", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return resp.StatusCode, respBody, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, respBody, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return resp.StatusCode, respBody, nil
+}
+
+// consulServiceId returns a service id without characters which
+// would need escaping when used in Consul API paths.
+func consulServiceId(id string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("_.:-", r) {
+			return r
+		}
+		return '-'
+	}, id)
+}
+
+// consulServiceName returns a service name which is also a valid DNS label, so
+// that services can be looked up through Consul DNS (<name>.service.consul).
+func consulServiceName(name string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return '-'
+	}, strings.ToLower(name)), "-")
+}
+
+// consulMetaKey returns a valid meta key (i.e. only [A-Za-z0-9_-], of at most 128
+// characters and without the reserved "consul-" prefix) for a label key.
+func consulMetaKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, truncate(key, consulMaxMetaKey))
+	if strings.HasPrefix(key, "consul-") || key == ConsulMetaDiscovererId || key == ConsulMetaKey {
+		return ""
+	}
+	return key
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
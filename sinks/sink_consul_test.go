@@ -0,0 +1,156 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/borderzero/discovery"
+)
+
+// fakeConsul is an httptest handler implementing the parts of
+// the Consul catalog HTTP API used by the ConsulSink.
+type fakeConsul struct {
+	sync.Mutex
+
+	services        map[string]consulService // by id, on the sink's node
+	registrations   []string                 // service ids, in order
+	deregistrations []string                 // service ids, in order
+}
+
+func (fc *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fc.Lock()
+	defer fc.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/catalog/node/"):
+		if len(fc.services) == 0 {
+			w.Write([]byte("null")) // as for nodes which are not registered
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Services": fc.services})
+
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/catalog/register":
+		var registration struct {
+			Service consulService `json:"Service"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fc.services[registration.Service.ID] = registration.Service
+		fc.registrations = append(fc.registrations, registration.Service.ID)
+		w.Write([]byte("true"))
+
+	case r.Method == http.MethodPut && r.URL.Path == "/v1/catalog/deregister":
+		var deregistration struct {
+			ServiceID string `json:"ServiceID"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&deregistration); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		delete(fc.services, deregistration.ServiceID)
+		fc.deregistrations = append(fc.deregistrations, deregistration.ServiceID)
+		w.Write([]byte("true"))
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// calls returns and resets the ids of the services registered and deregistered so far.
+func (fc *fakeConsul) calls() ([]string, []string) {
+	fc.Lock()
+	defer fc.Unlock()
+
+	registrations, deregistrations := fc.registrations, fc.deregistrations
+	fc.registrations, fc.deregistrations = nil, nil
+	return registrations, deregistrations
+}
+
+func TestConsulSinkSend(t *testing.T) {
+	ssh := discovery.Resource{
+		ResourceType: discovery.ResourceTypeNetworkSshServer,
+		NetworkSshServerDetails: &discovery.NetworkSshServerDetails{
+			NetworkBaseDetails: discovery.NetworkBaseDetails{IpAddress: "10.0.0.9", Port: "22"},
+		},
+	}
+	sshId := "discovery:network_ssh_server:10.0.0.9:22"
+	container := discovery.Resource{
+		ResourceType: discovery.ResourceTypeDockerContainer,
+		DockerContainerDetails: &discovery.DockerContainerDetails{
+			ContainerId:  "abc",
+			Names:        []string{"/web"},
+			PortBindings: map[string]string{"0.0.0.0:8080": "80/tcp"},
+			Labels:       map[string]string{"team": "a"},
+		},
+	}
+	containerId := "discovery:docker_container:abc:0.0.0.0:8080"
+
+	fake := &fakeConsul{services: map[string]consulService{
+		// registered by another discoverer, never touched
+		"other": {ID: "other", Service: "other", Tags: []string{}, Meta: map[string]string{ConsulMetaDiscovererId: "other"}},
+	}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	sink := NewConsulSink(server.URL)
+
+	steps := []struct {
+		name                    string
+		result                  *discovery.Result
+		expectedRegistrations   []string
+		expectedDeregistrations []string
+	}{
+		{
+			name:                  "registers services",
+			result:                newSinkTestResult("d", ssh, container),
+			expectedRegistrations: []string{containerId, sshId},
+		},
+		{
+			name:   "skips unchanged services",
+			result: newSinkTestResult("d", ssh, container),
+		},
+		{
+			name:   "keeps services missing from errored results",
+			result: errored(newSinkTestResult("d", ssh)),
+		},
+		{
+			name:                    "deregisters services which disappear",
+			result:                  newSinkTestResult("d", ssh),
+			expectedDeregistrations: []string{containerId},
+		},
+	}
+	for _, step := range steps {
+		if err := sink.Send(context.Background(), step.result); err != nil {
+			t.Fatalf("%s: unexpected error: %v", step.name, err)
+		}
+		registrations, deregistrations := fake.calls()
+		if !slices.Equal(registrations, step.expectedRegistrations) {
+			t.Fatalf("%s: expected registrations %v, got %v", step.name, step.expectedRegistrations, registrations)
+		}
+		if !slices.Equal(deregistrations, step.expectedDeregistrations) {
+			t.Fatalf("%s: expected deregistrations %v, got %v", step.name, step.expectedDeregistrations, deregistrations)
+		}
+	}
+
+	fake.Lock()
+	defer fake.Unlock()
+
+	if _, ok := fake.services["other"]; !ok {
+		t.Fatal("expected the registration of another discoverer to be kept")
+	}
+	service, ok := fake.services[sshId]
+	if !ok {
+		t.Fatal("expected the ssh service to still be registered")
+	}
+	if service.Service != "ssh" || service.Address != "10.0.0.9" || service.Port != 22 {
+		t.Fatalf("unexpected ssh registration: %+v", service)
+	}
+}
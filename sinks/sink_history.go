@@ -0,0 +1,32 @@
+package sinks
+
+import (
+	"context"
+
+	"github.com/borderzero/discovery"
+)
+
+// HistorySink represents a sink which records every result it is sent in
+// a discovery.HistoryStore (e.g. a stores.FileHistoryStore), so that the
+// history of resources can be recorded from the results of any engine.
+type HistorySink struct {
+	store discovery.HistoryStore
+}
+
+// ensure HistorySink implements discovery.Sink at compile-time.
+var _ discovery.Sink = (*HistorySink)(nil)
+
+// NewHistorySink returns a new HistorySink which records results in the given history store.
+func NewHistorySink(store discovery.HistoryStore) *HistorySink {
+	return &HistorySink{store: store}
+}
+
+// Send records a result in the history store. Stale results are ignored by the store.
+func (hs *HistorySink) Send(ctx context.Context, result *discovery.Result) error {
+	return hs.store.Record(ctx, result)
+}
+
+// Close is a no-op, the history store is owned by the caller.
+func (hs *HistorySink) Close(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package sinks
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/stores"
+)
+
+func TestHistorySink(t *testing.T) {
+	ctx := context.Background()
+	store := stores.NewFileHistoryStore(filepath.Join(t.TempDir(), "history.json"))
+
+	live := newSinkTestResult("docker", newSinkTestContainers("c1", "c2")...)
+	results := make(chan *discovery.Result, 2)
+	results <- live
+	results <- stale(newSinkTestResult("docker", newSinkTestContainers("c3")...))
+	close(results)
+
+	NewRouter(WithSink(NewHistorySink(store))).Run(ctx, results)
+
+	versions, err := store.ListAt(ctx, live.Metadata.EndedAt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	keys := []string{}
+	for _, version := range versions {
+		keys = append(keys, version.Key)
+	}
+	if len(keys) != 2 || keys[0] != "docker_container:c1" || keys[1] != "docker_container:c2" {
+		t.Fatalf("expected the versions of the live result only, got %v", keys)
+	}
+}
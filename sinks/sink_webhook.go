@@ -0,0 +1,428 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+const (
+	// WebhookPayloadResults makes a WebhookSink POST whole results.
+	WebhookPayloadResults WebhookPayload = "results"
+
+	// WebhookPayloadDeltas makes a WebhookSink POST only the resources
+	// which were added, updated or removed since the previous result.
+	WebhookPayloadDeltas WebhookPayload = "deltas"
+
+	// WebhookSignatureHeader is the header carrying the HMAC-SHA256 signature of a request,
+	// computed over the timestamp header value, a period, and the request body.
+	WebhookSignatureHeader = "X-Discovery-Signature"
+
+	// WebhookTimestampHeader is the header carrying the unix time at which a request was signed.
+	WebhookTimestampHeader = "X-Discovery-Timestamp"
+
+	defaultWebhookSinkPayload         = WebhookPayloadResults
+	defaultWebhookSinkRequestTimeout  = time.Second * 10
+	defaultWebhookSinkBatchSize       = 1
+	defaultWebhookSinkBatchInterval   = time.Second * 5
+	defaultWebhookSinkMaxRetries      = 5
+	defaultWebhookSinkInitialBackoff  = time.Millisecond * 500
+	defaultWebhookSinkMaxBackoff      = time.Second * 30
+	webhookSinkDeadLetterFileSuffix   = ".json"
+	webhookSinkDeadLetterFilePrefix   = "webhook-"
+	webhookSinkDeadLetterFileMode     = os.FileMode(0600)
+	webhookSinkDeadLetterDirMode      = os.FileMode(0700)
+	webhookSinkContentTypeHeaderValue = "application/json"
+)
+
+// WebhookPayload represents what a WebhookSink sends.
+type WebhookPayload string
+
+// webhookBody is the JSON body of a webhook request.
+type webhookBody struct {
+	SentAt  time.Time                 `json:"sent_at"`
+	Results []*discovery.Result       `json:"results,omitempty"`
+	Deltas  []discovery.ResourceDelta `json:"deltas,omitempty"`
+}
+
+// WebhookSink represents a sink which POSTs results (or deltas) as JSON to an HTTP endpoint.
+type WebhookSink struct {
+	url        string
+	httpClient *http.Client
+	headers    map[string]string
+	payload    WebhookPayload
+
+	signingSecret []byte
+
+	batchSize     int
+	batchInterval time.Duration
+
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+
+	deadLetterDirectory string
+	errorHandler        func(error)
+
+	mu       sync.Mutex // guards the fields below
+	results  []*discovery.Result
+	deltas   []discovery.ResourceDelta
+	timer    *time.Timer
+	previous map[string]*discovery.Result
+
+	deliverMu sync.Mutex // serializes deliveries
+
+	// the context of deliveries made on the batch interval, canceled
+	// when the context given to Close is done before they finish
+	backgroundCtx    context.Context
+	cancelBackground context.CancelFunc
+}
+
+// ensure WebhookSink implements discovery.Sink at compile-time.
+var _ discovery.Sink = (*WebhookSink)(nil)
+
+// WebhookSinkOption represents a configuration option for a WebhookSink.
+type WebhookSinkOption func(*WebhookSink)
+
+// WithWebhookSinkHttpClient is the WebhookSinkOption to set a non default http client.
+func WithWebhookSinkHttpClient(client *http.Client) WebhookSinkOption {
+	return func(ws *WebhookSink) { ws.httpClient = client }
+}
+
+// WithWebhookSinkHeaders is the WebhookSinkOption to set additional request headers
+// e.g. for authentication with the endpoint.
+func WithWebhookSinkHeaders(headers map[string]string) WebhookSinkOption {
+	return func(ws *WebhookSink) { ws.headers = headers }
+}
+
+// WithWebhookSinkPayload is the WebhookSinkOption to set a non default payload.
+func WithWebhookSinkPayload(payload WebhookPayload) WebhookSinkOption {
+	return func(ws *WebhookSink) { ws.payload = payload }
+}
+
+// WithWebhookSinkSigningSecret is the WebhookSinkOption to sign
+// requests with HMAC-SHA256 using the given shared secret.
+func WithWebhookSinkSigningSecret(secret []byte) WebhookSinkOption {
+	return func(ws *WebhookSink) { ws.signingSecret = secret }
+}
+
+// WithWebhookSinkBatching is the WebhookSinkOption to set non default batching.
+// Requests are sent once size items (results or deltas) are buffered, or once
+// the oldest buffered item is interval old, whichever happens first.
+func WithWebhookSinkBatching(size int, interval time.Duration) WebhookSinkOption {
+	return func(ws *WebhookSink) {
+		ws.batchSize = size
+		ws.batchInterval = interval
+	}
+}
+
+// WithWebhookSinkRetries is the WebhookSinkOption to set non default retry settings.
+// Backoff starts at initialBackoff and doubles on every attempt up to maxBackoff.
+func WithWebhookSinkRetries(maxRetries int, initialBackoff, maxBackoff time.Duration) WebhookSinkOption {
+	return func(ws *WebhookSink) {
+		ws.maxRetries = maxRetries
+		ws.initialBackoff = initialBackoff
+		ws.maxBackoff = maxBackoff
+	}
+}
+
+// WithWebhookSinkDeadLetterDirectory is the WebhookSinkOption to write requests which
+// could not be delivered to files in a directory, for later redelivery with Redeliver().
+func WithWebhookSinkDeadLetterDirectory(directory string) WebhookSinkOption {
+	return func(ws *WebhookSink) { ws.deadLetterDirectory = directory }
+}
+
+// WithWebhookSinkErrorHandler is the WebhookSinkOption to set the function to which
+// errors from batches sent in the background (on the batch interval) are reported.
+func WithWebhookSinkErrorHandler(handler func(error)) WebhookSinkOption {
+	return func(ws *WebhookSink) { ws.errorHandler = handler }
+}
+
+// NewWebhookSink returns a new WebhookSink for the given url, initialized with the given options.
+func NewWebhookSink(url string, opts ...WebhookSinkOption) *WebhookSink {
+	ws := &WebhookSink{
+		url:            url,
+		httpClient:     &http.Client{Timeout: defaultWebhookSinkRequestTimeout},
+		headers:        map[string]string{},
+		payload:        defaultWebhookSinkPayload,
+		batchSize:      defaultWebhookSinkBatchSize,
+		batchInterval:  defaultWebhookSinkBatchInterval,
+		maxRetries:     defaultWebhookSinkMaxRetries,
+		initialBackoff: defaultWebhookSinkInitialBackoff,
+		maxBackoff:     defaultWebhookSinkMaxBackoff,
+		errorHandler:   func(error) {},
+		previous:       map[string]*discovery.Result{},
+	}
+	ws.backgroundCtx, ws.cancelBackground = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(ws)
+	}
+	return ws
+}
+
+// Send buffers a result and sends the current batch if it is full.
+func (ws *WebhookSink) Send(ctx context.Context, result *discovery.Result) error {
+	ws.mu.Lock()
+	switch ws.payload {
+	case WebhookPayloadDeltas:
+		// stale results are only used as the baseline for future deltas
+		if !result.Metadata.Stale {
+			ws.deltas = append(ws.deltas, discovery.Diff(ws.previous[result.Metadata.DiscovererId], result)...)
+		}
+		ws.previous[result.Metadata.DiscovererId] = deltasBaseline(ws.previous[result.Metadata.DiscovererId], result)
+	default:
+		ws.results = append(ws.results, result)
+	}
+	full := len(ws.results)+len(ws.deltas) >= ws.batchSize
+	if !full && ws.timer == nil && len(ws.results)+len(ws.deltas) > 0 {
+		ws.timer = time.AfterFunc(ws.batchInterval, func() {
+			if err := ws.flush(ws.backgroundCtx); err != nil {
+				ws.errorHandler(err)
+			}
+		})
+	}
+	ws.mu.Unlock()
+
+	if full {
+		return ws.flush(ctx)
+	}
+	return nil
+}
+
+// Close sends any buffered results, and waits for deliveries made on the
+// batch interval to finish. Those are canceled if ctx is done before then.
+func (ws *WebhookSink) Close(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, ws.cancelBackground)
+	defer stop()
+
+	err := ws.flush(ctx)
+
+	// the batch may have been taken by a delivery on the batch interval
+	// which is still in flight, in which case flush returned right away
+	ws.deliverMu.Lock()
+	defer ws.deliverMu.Unlock()
+
+	return err
+}
+
+// Redeliver attempts to deliver all the requests in the dead letter
+// directory, removing the files of requests which are delivered.
+func (ws *WebhookSink) Redeliver(ctx context.Context) error {
+	if ws.deadLetterDirectory == "" {
+		return nil
+	}
+
+	ws.deliverMu.Lock()
+	defer ws.deliverMu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(
+		ws.deadLetterDirectory,
+		webhookSinkDeadLetterFilePrefix+"*"+webhookSinkDeadLetterFileSuffix,
+	))
+	if err != nil {
+		return fmt.Errorf("failed to list dead letter files: %v", err)
+	}
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read dead letter file %s: %v", path, err)
+		}
+		if err := ws.postWithRetries(ctx, body); err != nil {
+			return fmt.Errorf("failed to redeliver dead letter file %s: %w", path, err)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove dead letter file %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// flush sends the current batch, if any.
+func (ws *WebhookSink) flush(ctx context.Context) error {
+	ws.mu.Lock()
+	if ws.timer != nil {
+		ws.timer.Stop()
+		ws.timer = nil
+	}
+	body := webhookBody{Results: ws.results, Deltas: ws.deltas}
+	ws.results, ws.deltas = nil, nil
+	ws.mu.Unlock()
+
+	if len(body.Results)+len(body.Deltas) == 0 {
+		return nil
+	}
+
+	ws.deliverMu.Lock()
+	defer ws.deliverMu.Unlock()
+
+	body.SentAt = time.Now()
+	byt, err := marshalWebhookBody(body)
+	if err != nil {
+		return fmt.Errorf("failed to json encode webhook body: %v", err)
+	}
+
+	err = ws.postWithRetries(ctx, byt)
+	if err == nil {
+		return nil
+	}
+	if ws.deadLetterDirectory == "" {
+		return err
+	}
+	path, dlErr := ws.deadLetter(byt)
+	if dlErr != nil {
+		return fmt.Errorf("%w (and failed to write dead letter: %v)", err, dlErr)
+	}
+	return fmt.Errorf("%w (written to dead letter file %s)", err, path)
+}
+
+// postWithRetries posts a body to the webhook url, retrying
+// with exponential backoff on network errors and retryable
+// status codes (429 and 5xx).
+func (ws *WebhookSink) postWithRetries(ctx context.Context, body []byte) error {
+	backoff := ws.initialBackoff
+	for attempt := 0; ; attempt++ {
+		retryable, err := ws.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= ws.maxRetries {
+			return fmt.Errorf("failed to deliver webhook after %d attempt(s): %w", attempt+1, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up delivering webhook: %w", errors.Join(err, ctx.Err()))
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, ws.maxBackoff)
+	}
+}
+
+// post makes a single webhook request. Returns whether the error (if any) is retryable.
+func (ws *WebhookSink) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ws.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to build request: %v", err)
+	}
+	req.Header.Set("Content-Type", webhookSinkContentTypeHeaderValue)
+	for name, value := range ws.headers {
+		req.Header.Set(name, value)
+	}
+	if len(ws.signingSecret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(ws.signingSecret, timestamp, body))
+	}
+
+	resp, err := ws.httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf(
+		"received non-2xx status code %d: %s",
+		resp.StatusCode,
+		strings.TrimSpace(string(snippet)),
+	)
+}
+
+// deadLetter writes an undeliverable body to the dead letter directory.
+func (ws *WebhookSink) deadLetter(body []byte) (string, error) {
+	if err := os.MkdirAll(ws.deadLetterDirectory, webhookSinkDeadLetterDirMode); err != nil {
+		return "", fmt.Errorf("failed to create dead letter directory: %v", err)
+	}
+	path := filepath.Join(
+		ws.deadLetterDirectory,
+		fmt.Sprintf("%s%d%s", webhookSinkDeadLetterFilePrefix, time.Now().UnixNano(), webhookSinkDeadLetterFileSuffix),
+	)
+	if err := os.WriteFile(path, body, webhookSinkDeadLetterFileMode); err != nil {
+		return "", fmt.Errorf("failed to write dead letter file: %v", err)
+	}
+	return path, nil
+}
+
+// deltasBaseline returns the result against which the next result of a discoverer
+// is diffed. A result with errors may be missing resources which still exist, so
+// its resources are merged into the previous baseline rather than replacing it,
+// lest the missing resources be reported as added again by the next result.
+func deltasBaseline(previous, current *discovery.Result) *discovery.Result {
+	current.Lock()
+	if len(current.Errors) == 0 || previous == nil {
+		current.Unlock()
+		return current
+	}
+	baseline := &discovery.Result{
+		Resources: append([]discovery.Resource{}, current.Resources...),
+		Metadata:  current.Metadata,
+		Errors:    append([]string{}, current.Errors...),
+		Warnings:  append([]string{}, current.Warnings...),
+	}
+	current.Unlock()
+
+	merged := map[string]struct{}{}
+	for _, resource := range baseline.Resources {
+		merged[resource.Key()] = struct{}{}
+	}
+
+	previous.Lock()
+	defer previous.Unlock()
+
+	for _, resource := range previous.Resources {
+		if _, ok := merged[resource.Key()]; !ok {
+			baseline.Resources = append(baseline.Resources, resource)
+		}
+	}
+	return baseline
+}
+
+// marshalWebhookBody json encodes a webhook body while holding the locks of its results.
+func marshalWebhookBody(body webhookBody) ([]byte, error) {
+	locked := map[*discovery.Result]struct{}{}
+	for _, result := range body.Results {
+		if _, ok := locked[result]; ok {
+			continue
+		}
+		result.Lock()
+		locked[result] = struct{}{}
+	}
+	defer func() {
+		for result := range locked {
+			result.Unlock()
+		}
+	}()
+	return json.Marshal(body)
+}
+
+// SignWebhook returns the value of the signature header for a webhook request,
+// i.e. "sha256=" followed by the hex encoded HMAC-SHA256 of the timestamp, a
+// period, and the body. Receivers can use it to verify incoming requests.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
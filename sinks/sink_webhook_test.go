@@ -0,0 +1,474 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+// webhookRecorder is an httptest handler which records the bodies of the
+// requests it receives and responds with the next of a list of status codes.
+type webhookRecorder struct {
+	sync.Mutex
+
+	statuses []int // the last status is repeated once all others were used
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (wr *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	wr.Lock()
+	defer wr.Unlock()
+
+	wr.requests = append(wr.requests, r)
+	wr.bodies = append(wr.bodies, body)
+
+	status := http.StatusOK
+	if len(wr.statuses) > 0 {
+		status = wr.statuses[0]
+		if len(wr.statuses) > 1 {
+			wr.statuses = wr.statuses[1:]
+		}
+	}
+	w.WriteHeader(status)
+}
+
+func (wr *webhookRecorder) received() ([]*http.Request, []webhookBody) {
+	wr.Lock()
+	defer wr.Unlock()
+
+	bodies := []webhookBody{}
+	for _, byt := range wr.bodies {
+		var body webhookBody
+		if err := json.Unmarshal(byt, &body); err == nil {
+			bodies = append(bodies, body)
+		}
+	}
+	return append([]*http.Request{}, wr.requests...), bodies
+}
+
+func newWebhookTestServer(t *testing.T, statuses ...int) (*httptest.Server, *webhookRecorder) {
+	t.Helper()
+
+	recorder := &webhookRecorder{statuses: statuses}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+	return server, recorder
+}
+
+func TestWebhookSinkSigning(t *testing.T) {
+	secret := []byte("s3cr3t")
+
+	var signatureMatches bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := SignWebhook(secret, r.Header.Get(WebhookTimestampHeader), body)
+		signatureMatches = r.Header.Get(WebhookSignatureHeader) == expected
+	}))
+	defer server.Close()
+
+	sink := NewWebhookSink(server.URL, WithWebhookSinkSigningSecret(secret))
+	if err := sink.Send(context.Background(), newSinkTestResult("d", newSinkTestContainers("c1")...)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !signatureMatches {
+		t.Fatal("expected the signature header to match the signature of the timestamp and body")
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	signature := SignWebhook([]byte("secret"), "1700000000", []byte(`{}`))
+	if signature != SignWebhook([]byte("secret"), "1700000000", []byte(`{}`)) {
+		t.Fatal("expected signatures to be deterministic")
+	}
+	for _, other := range []string{
+		SignWebhook([]byte("other"), "1700000000", []byte(`{}`)),
+		SignWebhook([]byte("secret"), "1700000001", []byte(`{}`)),
+		SignWebhook([]byte("secret"), "1700000000", []byte(`{"a":1}`)),
+	} {
+		if signature == other {
+			t.Fatal("expected the signature to depend on the secret, timestamp and body")
+		}
+	}
+}
+
+func TestWebhookSinkBatching(t *testing.T) {
+	tests := []struct {
+		name             string
+		batchSize        int
+		batchInterval    time.Duration
+		sends            int
+		close            bool
+		wait             time.Duration
+		expectedRequests []int // number of results in each request
+	}{
+		{
+			name:             "sends once batch is full",
+			batchSize:        2,
+			batchInterval:    time.Hour,
+			sends:            4,
+			expectedRequests: []int{2, 2},
+		},
+		{
+			name:             "holds partial batch",
+			batchSize:        2,
+			batchInterval:    time.Hour,
+			sends:            3,
+			expectedRequests: []int{2},
+		},
+		{
+			name:             "sends partial batch on close",
+			batchSize:        2,
+			batchInterval:    time.Hour,
+			sends:            3,
+			close:            true,
+			expectedRequests: []int{2, 1},
+		},
+		{
+			name:             "sends partial batch on interval",
+			batchSize:        10,
+			batchInterval:    time.Millisecond * 10,
+			sends:            3,
+			wait:             time.Millisecond * 200,
+			expectedRequests: []int{3},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, recorder := newWebhookTestServer(t)
+			sink := NewWebhookSink(server.URL, WithWebhookSinkBatching(test.batchSize, test.batchInterval))
+
+			ctx := context.Background()
+			for i := 0; i < test.sends; i++ {
+				if err := sink.Send(ctx, newSinkTestResult("d", newSinkTestContainers("c1")...)); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if test.close {
+				if err := sink.Close(ctx); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			time.Sleep(test.wait)
+
+			_, bodies := recorder.received()
+			if len(bodies) != len(test.expectedRequests) {
+				t.Fatalf("expected %d requests, got %d", len(test.expectedRequests), len(bodies))
+			}
+			for i, body := range bodies {
+				if len(body.Results) != test.expectedRequests[i] {
+					t.Fatalf("expected %d results in request %d, got %d", test.expectedRequests[i], i, len(body.Results))
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookSinkCloseDuringIntervalDelivery(t *testing.T) {
+	tests := []struct {
+		name             string
+		release          bool // whether the server responds to the delivery in flight
+		closeTimeout     time.Duration
+		expectedAttempts int
+		expectDelivered  bool
+	}{
+		{
+			name:             "waits for delivery in flight",
+			release:          true,
+			closeTimeout:     time.Second * 5,
+			expectedAttempts: 1,
+			expectDelivered:  true,
+		},
+		{
+			name:             "cancels delivery in flight once close times out",
+			closeTimeout:     time.Millisecond * 50,
+			expectedAttempts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int32
+			var attemptsMu sync.Mutex
+			arrived := make(chan struct{}, 10)
+			release, stop := make(chan struct{}), make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attemptsMu.Lock()
+				attempts++
+				attemptsMu.Unlock()
+				arrived <- struct{}{}
+				select {
+				case <-release:
+					w.WriteHeader(http.StatusOK)
+				case <-r.Context().Done():
+				case <-stop:
+				}
+			}))
+			defer server.Close()
+			defer close(stop)
+
+			var handlerErrs []error
+			var handlerErrsMu sync.Mutex
+			sink := NewWebhookSink(
+				server.URL,
+				WithWebhookSinkBatching(10, time.Millisecond*10),
+				WithWebhookSinkRetries(3, time.Millisecond*10, time.Millisecond*10),
+				WithWebhookSinkErrorHandler(func(err error) {
+					handlerErrsMu.Lock()
+					defer handlerErrsMu.Unlock()
+					handlerErrs = append(handlerErrs, err)
+				}),
+			)
+			if err := sink.Send(context.Background(), newSinkTestResult("d", newSinkTestContainers("c1")...)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			select {
+			case <-arrived:
+			case <-time.After(time.Second * 5):
+				t.Fatal("expected a delivery on the batch interval")
+			}
+
+			closed := make(chan error, 1)
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), test.closeTimeout)
+				defer cancel()
+				closed <- sink.Close(ctx)
+			}()
+
+			if test.release {
+				select {
+				case <-closed:
+					t.Fatal("expected close to wait for the delivery in flight")
+				case <-time.After(time.Millisecond * 100):
+				}
+				close(release)
+			}
+
+			select {
+			case err := <-closed:
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			case <-time.After(time.Second * 5):
+				t.Fatal("expected close to return")
+			}
+
+			// nothing may be attempted once close returned
+			time.Sleep(time.Millisecond * 50)
+			attemptsMu.Lock()
+			defer attemptsMu.Unlock()
+			if int(attempts) != test.expectedAttempts {
+				t.Fatalf("expected %d attempts, got %d", test.expectedAttempts, attempts)
+			}
+
+			handlerErrsMu.Lock()
+			defer handlerErrsMu.Unlock()
+			if test.expectDelivered != (len(handlerErrs) == 0) {
+				t.Fatalf("expected delivered to be %t, got errors %v", test.expectDelivered, handlerErrs)
+			}
+		})
+	}
+}
+
+func TestWebhookSinkRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		maxRetries       int
+		expectError      bool
+		expectedAttempts int
+	}{
+		{
+			name:             "delivered first time",
+			statuses:         []int{http.StatusOK},
+			maxRetries:       3,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "retries server errors",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			maxRetries:       3,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "retries too many requests",
+			statuses:         []int{http.StatusTooManyRequests, http.StatusOK},
+			maxRetries:       3,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "gives up after max retries",
+			statuses:         []int{http.StatusInternalServerError},
+			maxRetries:       2,
+			expectError:      true,
+			expectedAttempts: 3,
+		},
+		{
+			name:             "does not retry client errors",
+			statuses:         []int{http.StatusBadRequest},
+			maxRetries:       3,
+			expectError:      true,
+			expectedAttempts: 1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, recorder := newWebhookTestServer(t, test.statuses...)
+			sink := NewWebhookSink(
+				server.URL,
+				WithWebhookSinkRetries(test.maxRetries, time.Millisecond, time.Millisecond*2),
+			)
+
+			err := sink.Send(context.Background(), newSinkTestResult("d", newSinkTestContainers("c1")...))
+			if test.expectError != (err != nil) {
+				t.Fatalf("expected error: %t, got: %v", test.expectError, err)
+			}
+			requests, _ := recorder.received()
+			if len(requests) != test.expectedAttempts {
+				t.Fatalf("expected %d attempts, got %d", test.expectedAttempts, len(requests))
+			}
+		})
+	}
+}
+
+func TestWebhookSinkDeadLetters(t *testing.T) {
+	server, recorder := newWebhookTestServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	directory := t.TempDir()
+
+	sink := NewWebhookSink(
+		server.URL,
+		WithWebhookSinkRetries(1, time.Millisecond, time.Millisecond),
+		WithWebhookSinkDeadLetterDirectory(directory),
+	)
+
+	ctx := context.Background()
+	if err := sink.Send(ctx, newSinkTestResult("d", newSinkTestContainers("c1")...)); err == nil {
+		t.Fatal("expected an error for an undeliverable request")
+	}
+	paths, _ := filepath.Glob(filepath.Join(directory, "*"))
+	if len(paths) != 1 {
+		t.Fatalf("expected 1 dead letter file, got %d", len(paths))
+	}
+	deadLetter, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("failed to read dead letter file: %v", err)
+	}
+
+	if err := sink.Redeliver(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	paths, _ = filepath.Glob(filepath.Join(directory, "*"))
+	if len(paths) != 0 {
+		t.Fatalf("expected dead letter files to be removed, got %d", len(paths))
+	}
+
+	recorder.Lock()
+	defer recorder.Unlock()
+	if len(recorder.bodies) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(recorder.bodies))
+	}
+	if string(recorder.bodies[2]) != string(deadLetter) {
+		t.Fatal("expected the redelivered body to be the dead letter file's contents")
+	}
+}
+
+func TestWebhookSinkDeltas(t *testing.T) {
+	tests := []struct {
+		name           string
+		results        []*discovery.Result
+		expectedDeltas []string // delta type and key
+	}{
+		{
+			name: "added and removed",
+			results: []*discovery.Result{
+				newSinkTestResult("d", newSinkTestContainers("c1", "c2")...),
+				newSinkTestResult("d", newSinkTestContainers("c2", "c3")...),
+			},
+			expectedDeltas: []string{
+				"added docker_container:c1",
+				"added docker_container:c2",
+				"added docker_container:c3",
+				"removed docker_container:c1",
+			},
+		},
+		{
+			name: "stale result is only a baseline",
+			results: []*discovery.Result{
+				stale(newSinkTestResult("d", newSinkTestContainers("c1", "c2")...)),
+				newSinkTestResult("d", newSinkTestContainers("c2")...),
+			},
+			expectedDeltas: []string{
+				"removed docker_container:c1",
+			},
+		},
+		{
+			name: "errored result does not replace the baseline",
+			results: []*discovery.Result{
+				newSinkTestResult("d", newSinkTestContainers("c1", "c2")...),
+				errored(newSinkTestResult("d")),
+				newSinkTestResult("d", newSinkTestContainers("c1", "c2")...),
+			},
+			expectedDeltas: []string{
+				"added docker_container:c1",
+				"added docker_container:c2",
+			},
+		},
+		{
+			name: "errored result is merged into the baseline",
+			results: []*discovery.Result{
+				newSinkTestResult("d", newSinkTestContainers("c1")...),
+				errored(newSinkTestResult("d", newSinkTestContainers("c2")...)),
+				newSinkTestResult("d", newSinkTestContainers("c2")...),
+			},
+			expectedDeltas: []string{
+				"added docker_container:c1",
+				"added docker_container:c2",
+				"removed docker_container:c1",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, recorder := newWebhookTestServer(t)
+			sink := NewWebhookSink(server.URL, WithWebhookSinkPayload(WebhookPayloadDeltas))
+
+			ctx := context.Background()
+			for _, result := range test.results {
+				if err := sink.Send(ctx, result); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			_, bodies := recorder.received()
+			deltas := []string{}
+			for _, body := range bodies {
+				batch := []string{}
+				for _, delta := range body.Deltas {
+					batch = append(batch, delta.DeltaType+" "+delta.Key)
+				}
+				sort.Strings(batch)
+				deltas = append(deltas, batch...)
+			}
+			if len(deltas) != len(test.expectedDeltas) {
+				t.Fatalf("expected deltas %v, got %v", test.expectedDeltas, deltas)
+			}
+			for i := range deltas {
+				if deltas[i] != test.expectedDeltas[i] {
+					t.Fatalf("expected deltas %v, got %v", test.expectedDeltas, deltas)
+				}
+			}
+		})
+	}
+}
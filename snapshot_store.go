@@ -0,0 +1,14 @@
+package discovery
+
+import "context"
+
+// SnapshotStore represents an entity capable of persisting
+// the latest Result of each discoverer across restarts.
+type SnapshotStore interface {
+	// Save persists a result, replacing any previously
+	// saved result for the same discoverer id.
+	Save(context.Context, *Result) error
+
+	// Load returns the last saved result for every discoverer.
+	Load(context.Context) ([]*Result, error)
+}
@@ -0,0 +1,52 @@
+package stores
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+var sqlIdentifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isValidSqlIdentifier returns true if the given string is safe to
+// interpolate into a SQL statement as a table name.
+func isValidSqlIdentifier(identifier string) bool {
+	return sqlIdentifierRegexp.MatchString(identifier)
+}
+
+// resourceHash returns a digest of a resource's details, used
+// to tell whether a resource changed between discovery runs.
+// Volatile fields (e.g. reachability check results) are not
+// hashed, so they do not create a new version on every run.
+func resourceHash(resource discovery.Resource) (string, error) {
+	byt, err := json.Marshal(resource.WithoutVolatileFields())
+	if err != nil {
+		return "", fmt.Errorf("failed to json encode resource: %v", err)
+	}
+	sum := sha256.Sum256(byt)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// observedAt returns the time at which the resources in a result were observed.
+func observedAt(result *discovery.Result) time.Time {
+	if !result.Metadata.EndedAt.IsZero() {
+		return result.Metadata.EndedAt
+	}
+	return time.Now()
+}
+
+// previousObservation returns the time of a discoverer's previously recorded
+// result. A resource version last seen at (or after) that time was present in
+// the previous result, so it can be extended rather than replaced. When there
+// is no previous result, only versions recorded at the current time qualify.
+func previousObservation(recordedAt map[string]time.Time, discovererId string, now time.Time) time.Time {
+	if previous, ok := recordedAt[discovererId]; ok {
+		return previous
+	}
+	return now
+}
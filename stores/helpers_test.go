@@ -0,0 +1,251 @@
+package stores
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+func newStoreTestContainer(containerId string, labels map[string]string) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeDockerContainer,
+		DockerContainerDetails: &discovery.DockerContainerDetails{
+			ContainerId: containerId,
+			Labels:      labels,
+		},
+	}
+}
+
+func newStoreTestResult(discovererId string, resources ...discovery.Resource) *discovery.Result {
+	result := discovery.NewResult(discovererId)
+	result.AddResources(resources...)
+	result.Done()
+	return result
+}
+
+// containerIds returns the ids of the containers in a result.
+func containerIds(result *discovery.Result) []string {
+	ids := []string{}
+	for _, resource := range result.Resources {
+		ids = append(ids, resource.DockerContainerDetails.ContainerId)
+	}
+	return ids
+}
+
+// testSnapshotStoreRoundTrip checks that a snapshot store loads back
+// the last saved result of each discoverer, starting from an empty store.
+func testSnapshotStoreRoundTrip(t *testing.T, store discovery.SnapshotStore) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	loaded, err := store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("expected no snapshots in an empty store, got %d", len(loaded))
+	}
+
+	withWarning := newStoreTestResult("docker/local", newStoreTestContainer("c3", map[string]string{"app": "web"}))
+	withWarning.AddWarning("skipped a container")
+	for _, result := range []*discovery.Result{
+		newStoreTestResult("docker/local", newStoreTestContainer("c1", nil), newStoreTestContainer("c2", nil)),
+		newStoreTestResult("other"),
+		withWarning, // replaces the first result
+	} {
+		if err := store.Save(ctx, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	loaded, err = store.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	slices.SortFunc(loaded, func(a, b *discovery.Result) int {
+		return strings.Compare(a.Metadata.DiscovererId, b.Metadata.DiscovererId)
+	})
+	if len(loaded) != 2 {
+		t.Fatalf("expected 2 snapshots, got %d", len(loaded))
+	}
+
+	docker := loaded[0]
+	if docker.Metadata.DiscovererId != "docker/local" {
+		t.Fatalf("expected the snapshot of docker/local, got %s", docker.Metadata.DiscovererId)
+	}
+	if !docker.Metadata.EndedAt.Equal(withWarning.Metadata.EndedAt) || docker.Metadata.Stale {
+		t.Fatalf("expected the metadata of the last result, got %+v", docker.Metadata)
+	}
+	if ids := containerIds(docker); !slices.Equal(ids, []string{"c3"}) {
+		t.Fatalf("expected the resources of the last result, got %v", ids)
+	}
+	if docker.Resources[0].DockerContainerDetails.Labels["app"] != "web" {
+		t.Fatalf("expected resource details to be kept, got %+v", docker.Resources[0].DockerContainerDetails)
+	}
+	if !slices.Equal(docker.Warnings, []string{"skipped a container"}) {
+		t.Fatalf("expected warnings to be kept, got %v", docker.Warnings)
+	}
+	if other := loaded[1]; other.Metadata.DiscovererId != "other" || len(other.Resources) != 0 {
+		t.Fatalf("expected the empty snapshot of other, got %+v", other)
+	}
+}
+
+// historyTestBase is the time from which history test results are observed.
+var historyTestBase = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// historyTestHour returns the time a number of hours after historyTestBase.
+func historyTestHour(hours int) time.Time {
+	return historyTestBase.Add(time.Duration(hours) * time.Hour)
+}
+
+// newHistoryTestResult returns a result which ended a number of hours after historyTestBase.
+func newHistoryTestResult(discovererId string, hours int, resources ...discovery.Resource) *discovery.Result {
+	result := newStoreTestResult(discovererId, resources...)
+	result.Metadata.EndedAt = historyTestHour(hours)
+	return result
+}
+
+func newStoreTestSsmTarget(instanceId string, lastPing time.Time) discovery.Resource {
+	return discovery.Resource{
+		ResourceType: discovery.ResourceTypeAwsSsmTarget,
+		AwsSsmTargetDetails: &discovery.AwsSsmTargetDetails{
+			InstanceId:       instanceId,
+			PingStatus:       "Online",
+			LastPingDateTime: &lastPing,
+		},
+	}
+}
+
+// versionSpans returns the keys of versions along with the
+// hours (after historyTestBase) during which they were seen.
+func versionSpans(versions []discovery.ResourceVersion) []string {
+	spans := []string{}
+	for _, version := range versions {
+		spans = append(spans, fmt.Sprintf(
+			"%s %d-%d",
+			version.Key,
+			int(version.FirstSeen.Sub(historyTestBase).Hours()),
+			int(version.LastSeen.Sub(historyTestBase).Hours()),
+		))
+	}
+	return spans
+}
+
+// testHistoryStore checks that a history store records versions of resources
+// as results arrive, and answers point-in-time and per-resource queries.
+func testHistoryStore(t *testing.T, store discovery.HistoryStore) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	web := newStoreTestContainer("c1", map[string]string{"app": "web"})
+	api := newStoreTestContainer("c1", map[string]string{"app": "api"})
+	c2 := newStoreTestContainer("c2", nil)
+	c3 := newStoreTestContainer("c3", nil)
+	ssm := newStoreTestSsmTarget("mi-1", historyTestHour(0))
+	ssmPinged := newStoreTestSsmTarget("mi-1", historyTestHour(2)) // only volatile fields changed
+
+	stale := newHistoryTestResult("docker", 5, c3)
+	stale.Metadata.Stale = true
+
+	for _, result := range []*discovery.Result{
+		newHistoryTestResult("docker", 1, web, c2),
+		newHistoryTestResult("ssm", 1, ssm),
+		newHistoryTestResult("docker", 2, web, c2), // unchanged, extended
+		newHistoryTestResult("ssm", 2, ssmPinged),
+		newHistoryTestResult("docker", 3, api),     // c1 changed, c2 gone
+		newHistoryTestResult("docker", 4, api, c2), // c2 back, as a new version
+		stale, // ignored
+	} {
+		if err := store.Record(ctx, result); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		query         func() ([]discovery.ResourceVersion, error)
+		expectedSpans []string
+	}{
+		{
+			name:          "history of a changed resource",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.History(ctx, web.Key()) },
+			expectedSpans: []string{web.Key() + " 1-2", web.Key() + " 3-4"},
+		},
+		{
+			name:          "history of a resource which came back",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.History(ctx, c2.Key()) },
+			expectedSpans: []string{c2.Key() + " 1-2", c2.Key() + " 4-4"},
+		},
+		{
+			name:          "history ignores volatile fields",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.History(ctx, ssm.Key()) },
+			expectedSpans: []string{ssm.Key() + " 1-2"},
+		},
+		{
+			name:          "history ignores stale results",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.History(ctx, c3.Key()) },
+			expectedSpans: []string{},
+		},
+		{
+			name:          "list at a time when all resources existed",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.ListAt(ctx, historyTestHour(2)) },
+			expectedSpans: []string{ssm.Key() + " 1-2", web.Key() + " 1-2", c2.Key() + " 1-2"},
+		},
+		{
+			name:          "list at a time when a resource was gone",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.ListAt(ctx, historyTestHour(3)) },
+			expectedSpans: []string{web.Key() + " 3-4"},
+		},
+		{
+			name: "list between observations",
+			query: func() ([]discovery.ResourceVersion, error) {
+				return store.ListAt(ctx, historyTestHour(1).Add(time.Minute))
+			},
+			expectedSpans: []string{ssm.Key() + " 1-2", web.Key() + " 1-2", c2.Key() + " 1-2"},
+		},
+		{
+			name:          "list before anything was observed",
+			query:         func() ([]discovery.ResourceVersion, error) { return store.ListAt(ctx, historyTestHour(0)) },
+			expectedSpans: []string{},
+		},
+	}
+	for _, test := range tests {
+		versions, err := test.query()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if spans := versionSpans(versions); !slices.Equal(spans, test.expectedSpans) {
+			t.Fatalf("%s: expected versions %v, got %v", test.name, test.expectedSpans, spans)
+		}
+	}
+
+	versions, err := store.History(ctx, web.Key())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels := versions[1].Resource.DockerContainerDetails.Labels; labels["app"] != "api" || versions[1].DiscovererId != "docker" {
+		t.Fatalf("expected the details of the changed resource, got %+v", versions[1])
+	}
+
+	pruned, err := store.Prune(ctx, historyTestHour(3))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pruned != 3 {
+		t.Fatalf("expected 3 versions last seen before hour 3 to be pruned, got %d", pruned)
+	}
+	versions, err = store.History(ctx, c2.Key())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spans := versionSpans(versions); !slices.Equal(spans, []string{c2.Key() + " 4-4"}) {
+		t.Fatalf("expected only the version seen after pruning, got %v", spans)
+	}
+}
@@ -0,0 +1,280 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	defaultFileHistoryStoreFileMode = os.FileMode(0600)
+)
+
+// fileHistoryStoreState is the on-disk representation of a FileHistoryStore.
+type fileHistoryStoreState struct {
+	Versions   []*fileHistoryStoreVersion `json:"versions"`
+	RecordedAt map[string]time.Time       `json:"recorded_at"`
+}
+
+// fileHistoryStoreVersion is a resource version along with the hash of its details.
+type fileHistoryStoreVersion struct {
+	discovery.ResourceVersion // extends
+
+	Hash string `json:"hash"`
+}
+
+// FileHistoryStore represents a history store which keeps all resource versions
+// in memory and persists them to a single JSON file after every change. It is
+// meant for small to medium inventories and for running fully offline.
+type FileHistoryStore struct {
+	sync.Mutex // inherit lock behaviour
+
+	path     string
+	fileMode os.FileMode
+
+	loaded bool
+	state  *fileHistoryStoreState
+	latest map[string]*fileHistoryStoreVersion // by resource key
+}
+
+// ensure FileHistoryStore implements discovery.HistoryStore at compile-time.
+var _ discovery.HistoryStore = (*FileHistoryStore)(nil)
+
+// FileHistoryStoreOption represents a configuration option for a FileHistoryStore.
+type FileHistoryStoreOption func(*FileHistoryStore)
+
+// WithFileHistoryStoreFileMode is the FileHistoryStoreOption
+// to set a non default file mode for the history file.
+func WithFileHistoryStoreFileMode(mode os.FileMode) FileHistoryStoreOption {
+	return func(fhs *FileHistoryStore) { fhs.fileMode = mode }
+}
+
+// NewFileHistoryStore returns a new FileHistoryStore which persists
+// history in the file at the given path, initialized with the given options.
+func NewFileHistoryStore(path string, opts ...FileHistoryStoreOption) *FileHistoryStore {
+	fhs := &FileHistoryStore{
+		path:     path,
+		fileMode: defaultFileHistoryStoreFileMode,
+	}
+	for _, opt := range opts {
+		opt(fhs)
+	}
+	return fhs
+}
+
+// Record records all the resources in a result in the FileHistoryStore.
+func (fhs *FileHistoryStore) Record(_ context.Context, result *discovery.Result) error {
+	result.Lock()
+	defer result.Unlock()
+
+	if result.Metadata.Stale {
+		return nil
+	}
+
+	fhs.Lock()
+	defer fhs.Unlock()
+
+	if err := fhs.load(); err != nil {
+		return err
+	}
+
+	discovererId := result.Metadata.DiscovererId
+	seenAt := observedAt(result)
+	presentSince := previousObservation(fhs.state.RecordedAt, discovererId, seenAt)
+
+	// the new state is built aside and only replaces the in-memory
+	// state once it was saved, so that memory and disk never drift
+	latestByKey := make(map[string]*fileHistoryStoreVersion, len(fhs.latest))
+	for key, version := range fhs.latest {
+		latestByKey[key] = version
+	}
+	extended := map[*fileHistoryStoreVersion]struct{}{}
+	added := []*fileHistoryStoreVersion{}
+
+	for _, resource := range result.Resources {
+		hash, err := resourceHash(resource)
+		if err != nil {
+			return err
+		}
+		key := resource.Key()
+
+		// extend the latest version if it is unchanged and
+		// was present in the discoverer's previous result
+		latest, ok := latestByKey[key]
+		if ok && latest.Hash == hash && !latest.LastSeen.Before(presentSince) {
+			if seenAt.After(latest.LastSeen) {
+				extended[latest] = struct{}{}
+			}
+			continue
+		}
+
+		version := &fileHistoryStoreVersion{
+			ResourceVersion: discovery.ResourceVersion{
+				Key:          key,
+				DiscovererId: discovererId,
+				Resource:     resource,
+				FirstSeen:    seenAt,
+				LastSeen:     seenAt,
+			},
+			Hash: hash,
+		}
+		added = append(added, version)
+		latestByKey[key] = version
+	}
+
+	state := &fileHistoryStoreState{
+		Versions:   make([]*fileHistoryStoreVersion, 0, len(fhs.state.Versions)+len(added)),
+		RecordedAt: map[string]time.Time{discovererId: seenAt},
+	}
+	for _, version := range fhs.state.Versions {
+		if _, ok := extended[version]; ok {
+			extendedVersion := *version
+			extendedVersion.LastSeen = seenAt
+			version = &extendedVersion
+		}
+		state.Versions = append(state.Versions, version)
+	}
+	state.Versions = append(state.Versions, added...)
+	for id, recordedAt := range fhs.state.RecordedAt {
+		if id != discovererId {
+			state.RecordedAt[id] = recordedAt
+		}
+	}
+
+	return fhs.commit(state)
+}
+
+// ListAt returns the versions of all resources that existed at a given time.
+func (fhs *FileHistoryStore) ListAt(_ context.Context, at time.Time) ([]discovery.ResourceVersion, error) {
+	fhs.Lock()
+	defer fhs.Unlock()
+
+	if err := fhs.load(); err != nil {
+		return nil, err
+	}
+
+	versions := []discovery.ResourceVersion{}
+	for _, version := range fhs.state.Versions {
+		if version.FirstSeen.After(at) || version.LastSeen.Before(at) {
+			continue
+		}
+		versions = append(versions, version.ResourceVersion)
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].Key < versions[j].Key })
+	return versions, nil
+}
+
+// History returns all recorded versions of the resource with a given key, oldest first.
+func (fhs *FileHistoryStore) History(_ context.Context, key string) ([]discovery.ResourceVersion, error) {
+	fhs.Lock()
+	defer fhs.Unlock()
+
+	if err := fhs.load(); err != nil {
+		return nil, err
+	}
+
+	versions := []discovery.ResourceVersion{}
+	for _, version := range fhs.state.Versions {
+		if version.Key == key {
+			versions = append(versions, version.ResourceVersion)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].FirstSeen.Before(versions[j].FirstSeen) })
+	return versions, nil
+}
+
+// Prune deletes all versions last seen before a given time.
+func (fhs *FileHistoryStore) Prune(_ context.Context, before time.Time) (int, error) {
+	fhs.Lock()
+	defer fhs.Unlock()
+
+	if err := fhs.load(); err != nil {
+		return 0, err
+	}
+
+	kept := []*fileHistoryStoreVersion{}
+	for _, version := range fhs.state.Versions {
+		if version.LastSeen.Before(before) {
+			continue
+		}
+		kept = append(kept, version)
+	}
+	pruned := len(fhs.state.Versions) - len(kept)
+	if pruned == 0 {
+		return 0, nil
+	}
+	state := &fileHistoryStoreState{
+		Versions:   kept,
+		RecordedAt: fhs.state.RecordedAt,
+	}
+	if err := fhs.commit(state); err != nil {
+		return 0, err
+	}
+	return pruned, nil
+}
+
+// load reads the history file into memory the first time it is called.
+// ** Note that it must be called with the lock held **
+func (fhs *FileHistoryStore) load() error {
+	if fhs.loaded {
+		return nil
+	}
+
+	state := &fileHistoryStoreState{
+		Versions:   []*fileHistoryStoreVersion{},
+		RecordedAt: map[string]time.Time{},
+	}
+	byt, err := os.ReadFile(fhs.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to read history file: %v", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(byt, state); err != nil {
+			return fmt.Errorf("failed to json decode history file: %v", err)
+		}
+		if state.RecordedAt == nil {
+			state.RecordedAt = map[string]time.Time{}
+		}
+	}
+
+	fhs.state = state
+	fhs.indexLatest()
+	fhs.loaded = true
+	return nil
+}
+
+// commit writes the given history to the history file and, only
+// if that succeeds, makes it the in-memory history.
+// ** Note that it must be called with the lock held **
+func (fhs *FileHistoryStore) commit(state *fileHistoryStoreState) error {
+	byt, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to json encode history: %v", err)
+	}
+	if err := utils.WriteFileAtomically(fhs.path, byt, fhs.fileMode); err != nil {
+		return fmt.Errorf("failed to write history file: %v", err)
+	}
+	fhs.state = state
+	fhs.indexLatest()
+	return nil
+}
+
+// indexLatest rebuilds the index of the latest version of each resource.
+// ** Note that it must be called with the lock held **
+func (fhs *FileHistoryStore) indexLatest() {
+	fhs.latest = map[string]*fileHistoryStoreVersion{}
+	for _, version := range fhs.state.Versions {
+		latest, ok := fhs.latest[version.Key]
+		if !ok || !version.FirstSeen.Before(latest.FirstSeen) {
+			fhs.latest[version.Key] = version
+		}
+	}
+}
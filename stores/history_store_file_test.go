@@ -0,0 +1,64 @@
+package stores
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestFileHistoryStore(t *testing.T) {
+	testHistoryStore(t, NewFileHistoryStore(filepath.Join(t.TempDir(), "history", "history.json")))
+}
+
+func TestFileHistoryStoreFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.json")
+	c1 := newStoreTestContainer("c1", nil)
+
+	store := NewFileHistoryStore(path)
+	if err := store.Record(ctx, newHistoryTestResult("docker", 1, c1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("expected a history file: %v", err)
+	}
+	if info.Mode().Perm() != defaultFileHistoryStoreFileMode {
+		t.Fatalf("expected file mode %v, got %v", defaultFileHistoryStoreFileMode, info.Mode().Perm())
+	}
+
+	// a result which fails to be saved leaves the history untouched
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove history file: %v", err)
+	}
+	if err := os.Mkdir(path, 0700); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := store.Record(ctx, newHistoryTestResult("docker", 2, c1)); err == nil {
+		t.Fatal("expected an error when the history file cannot be written")
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("failed to remove directory: %v", err)
+	}
+	if err := store.Record(ctx, newHistoryTestResult("other", 2)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the history is loaded back from the file
+	versions, err := NewFileHistoryStore(path).History(ctx, c1.Key())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spans := versionSpans(versions); !slices.Equal(spans, []string{c1.Key() + " 1-1"}) {
+		t.Fatalf("expected the version recorded before the failed save, got %v", spans)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatalf("failed to write history file: %v", err)
+	}
+	if _, err := NewFileHistoryStore(path).ListAt(ctx, historyTestHour(1)); err == nil {
+		t.Fatal("expected an error for a corrupt history file")
+	}
+}
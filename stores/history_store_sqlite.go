@@ -0,0 +1,289 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+const (
+	defaultSqliteHistoryStoreTablePrefix = "discovery_history"
+)
+
+// SqliteHistoryStore represents a history store which keeps
+// resource versions in tables of a SQLite database.
+//
+// The store does not import a SQLite driver. Callers open the *sql.DB
+// with the driver of their choice (e.g. modernc.org/sqlite or
+// github.com/mattn/go-sqlite3) and hand it to the constructor.
+type SqliteHistoryStore struct {
+	db          *sql.DB
+	tablePrefix string
+}
+
+// ensure SqliteHistoryStore implements discovery.HistoryStore at compile-time.
+var _ discovery.HistoryStore = (*SqliteHistoryStore)(nil)
+
+// SqliteHistoryStoreOption represents a configuration option for a SqliteHistoryStore.
+type SqliteHistoryStoreOption func(*SqliteHistoryStore)
+
+// WithSqliteHistoryStoreTablePrefix is the SqliteHistoryStoreOption
+// to set a non default prefix for the names of the history tables.
+func WithSqliteHistoryStoreTablePrefix(tablePrefix string) SqliteHistoryStoreOption {
+	return func(shs *SqliteHistoryStore) { shs.tablePrefix = tablePrefix }
+}
+
+// NewSqliteHistoryStore returns a new SqliteHistoryStore, initialized with
+// the given options. The history tables are created if they do not exist.
+func NewSqliteHistoryStore(
+	ctx context.Context,
+	db *sql.DB,
+	opts ...SqliteHistoryStoreOption,
+) (*SqliteHistoryStore, error) {
+	shs := &SqliteHistoryStore{
+		db:          db,
+		tablePrefix: defaultSqliteHistoryStoreTablePrefix,
+	}
+	for _, opt := range opts {
+		opt(shs)
+	}
+	if !isValidSqlIdentifier(shs.tablePrefix) {
+		return nil, fmt.Errorf("invalid table prefix \"%s\"", shs.tablePrefix)
+	}
+	statements := []string{
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				id            INTEGER PRIMARY KEY AUTOINCREMENT,
+				resource_key  TEXT NOT NULL,
+				discoverer_id TEXT NOT NULL,
+				hash          TEXT NOT NULL,
+				resource      BLOB NOT NULL,
+				first_seen    INTEGER NOT NULL,
+				last_seen     INTEGER NOT NULL
+			)`,
+			shs.versionsTable(),
+		),
+		fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS %s_key ON %s (resource_key, first_seen)`,
+			shs.versionsTable(),
+			shs.versionsTable(),
+		),
+		fmt.Sprintf(
+			`CREATE INDEX IF NOT EXISTS %s_seen ON %s (first_seen, last_seen)`,
+			shs.versionsTable(),
+			shs.versionsTable(),
+		),
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				discoverer_id TEXT PRIMARY KEY,
+				recorded_at   INTEGER NOT NULL
+			)`,
+			shs.discoverersTable(),
+		),
+	}
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return nil, fmt.Errorf("failed to create history tables: %v", err)
+		}
+	}
+	return shs, nil
+}
+
+// Record records all the resources in a result in the SqliteHistoryStore.
+func (shs *SqliteHistoryStore) Record(ctx context.Context, result *discovery.Result) error {
+	result.Lock()
+	defer result.Unlock()
+
+	if result.Metadata.Stale {
+		return nil
+	}
+
+	tx, err := shs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback() // no-op after a successful commit
+
+	discovererId := result.Metadata.DiscovererId
+	seenAt := observedAt(result)
+
+	recordedAt := map[string]time.Time{}
+	var previous int64
+	err = tx.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT recorded_at FROM %s WHERE discoverer_id = ?`, shs.discoverersTable()),
+		discovererId,
+	).Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to query previous recording time: %v", err)
+	}
+	if err == nil {
+		recordedAt[discovererId] = time.Unix(0, previous)
+	}
+	presentSince := previousObservation(recordedAt, discovererId, seenAt)
+
+	for _, resource := range result.Resources {
+		hash, err := resourceHash(resource)
+		if err != nil {
+			return err
+		}
+		key := resource.Key()
+
+		// extend the latest version if it is unchanged and
+		// was present in the discoverer's previous result
+		var latestId, latestLastSeen int64
+		var latestHash string
+		err = tx.QueryRowContext(
+			ctx,
+			fmt.Sprintf(
+				`SELECT id, hash, last_seen FROM %s WHERE resource_key = ? ORDER BY first_seen DESC, id DESC LIMIT 1`,
+				shs.versionsTable(),
+			),
+			key,
+		).Scan(&latestId, &latestHash, &latestLastSeen)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("failed to query latest version of resource %s: %v", key, err)
+		}
+		if err == nil && latestHash == hash && latestLastSeen >= presentSince.UnixNano() {
+			_, err = tx.ExecContext(
+				ctx,
+				fmt.Sprintf(`UPDATE %s SET last_seen = MAX(last_seen, ?) WHERE id = ?`, shs.versionsTable()),
+				seenAt.UnixNano(),
+				latestId,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to update version of resource %s: %v", key, err)
+			}
+			continue
+		}
+
+		byt, err := json.Marshal(resource)
+		if err != nil {
+			return fmt.Errorf("failed to json encode resource: %v", err)
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			fmt.Sprintf(
+				`INSERT INTO %s (resource_key, discoverer_id, hash, resource, first_seen, last_seen) VALUES (?, ?, ?, ?, ?, ?)`,
+				shs.versionsTable(),
+			),
+			key,
+			discovererId,
+			hash,
+			byt,
+			seenAt.UnixNano(),
+			seenAt.UnixNano(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert version of resource %s: %v", key, err)
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		fmt.Sprintf(`
+			INSERT INTO %s (discoverer_id, recorded_at) VALUES (?, ?)
+			ON CONFLICT (discoverer_id) DO UPDATE SET recorded_at = excluded.recorded_at`,
+			shs.discoverersTable(),
+		),
+		discovererId,
+		seenAt.UnixNano(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert recording time: %v", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %v", err)
+	}
+	return nil
+}
+
+// ListAt returns the versions of all resources that existed at a given time.
+func (shs *SqliteHistoryStore) ListAt(ctx context.Context, at time.Time) ([]discovery.ResourceVersion, error) {
+	return shs.queryVersions(
+		ctx,
+		fmt.Sprintf(
+			`SELECT resource_key, discoverer_id, resource, first_seen, last_seen FROM %s
+			WHERE first_seen <= ? AND last_seen >= ? ORDER BY resource_key, first_seen`,
+			shs.versionsTable(),
+		),
+		at.UnixNano(),
+		at.UnixNano(),
+	)
+}
+
+// History returns all recorded versions of the resource with a given key, oldest first.
+func (shs *SqliteHistoryStore) History(ctx context.Context, key string) ([]discovery.ResourceVersion, error) {
+	return shs.queryVersions(
+		ctx,
+		fmt.Sprintf(
+			`SELECT resource_key, discoverer_id, resource, first_seen, last_seen FROM %s
+			WHERE resource_key = ? ORDER BY first_seen, id`,
+			shs.versionsTable(),
+		),
+		key,
+	)
+}
+
+// Prune deletes all versions last seen before a given time.
+func (shs *SqliteHistoryStore) Prune(ctx context.Context, before time.Time) (int, error) {
+	res, err := shs.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`DELETE FROM %s WHERE last_seen < ?`, shs.versionsTable()),
+		before.UnixNano(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete versions: %v", err)
+	}
+	pruned, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get number of deleted versions: %v", err)
+	}
+	return int(pruned), nil
+}
+
+func (shs *SqliteHistoryStore) queryVersions(
+	ctx context.Context,
+	query string,
+	args ...any,
+) ([]discovery.ResourceVersion, error) {
+	rows, err := shs.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions: %v", err)
+	}
+	defer rows.Close()
+
+	versions := []discovery.ResourceVersion{}
+	for rows.Next() {
+		var version discovery.ResourceVersion
+		var byt []byte
+		var firstSeen, lastSeen int64
+		if err := rows.Scan(&version.Key, &version.DiscovererId, &byt, &firstSeen, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to scan version row: %v", err)
+		}
+		if err := json.Unmarshal(byt, &version.Resource); err != nil {
+			return nil, fmt.Errorf("failed to json decode version of resource %s: %v", version.Key, err)
+		}
+		version.FirstSeen = time.Unix(0, firstSeen)
+		version.LastSeen = time.Unix(0, lastSeen)
+		versions = append(versions, version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate version rows: %v", err)
+	}
+	return versions, nil
+}
+
+func (shs *SqliteHistoryStore) versionsTable() string {
+	return shs.tablePrefix + "_versions"
+}
+
+func (shs *SqliteHistoryStore) discoverersTable() string {
+	return shs.tablePrefix + "_discoverers"
+}
@@ -0,0 +1,55 @@
+//go:build cgo
+
+package stores
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestSqliteHistoryStore(t *testing.T) {
+	store, err := NewSqliteHistoryStore(context.Background(), openSqliteTestDb(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testHistoryStore(t, store)
+}
+
+func TestSqliteHistoryStoreTablePrefix(t *testing.T) {
+	ctx := context.Background()
+	db := openSqliteTestDb(t)
+	c1 := newStoreTestContainer("c1", nil)
+
+	if _, err := NewSqliteHistoryStore(ctx, db, WithSqliteHistoryStoreTablePrefix("history; DROP TABLE x")); err == nil {
+		t.Fatal("expected an error for an invalid table prefix")
+	}
+
+	store, err := NewSqliteHistoryStore(ctx, db, WithSqliteHistoryStoreTablePrefix("audit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Record(ctx, newHistoryTestResult("docker", 1, c1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_versions`).Scan(&count); err != nil || count != 1 {
+		t.Fatalf("expected 1 row in the prefixed table, got %d (err: %v)", count, err)
+	}
+
+	// recording continues where a previous store left off
+	reopened, err := NewSqliteHistoryStore(ctx, db, WithSqliteHistoryStoreTablePrefix("audit"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := reopened.Record(ctx, newHistoryTestResult("docker", 2, c1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	versions, err := reopened.History(ctx, c1.Key())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spans := versionSpans(versions); !slices.Equal(spans, []string{c1.Key() + " 1-2"}) {
+		t.Fatalf("expected the version to be extended, got %v", spans)
+	}
+}
@@ -0,0 +1,110 @@
+package stores
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/borderzero/discovery"
+	"github.com/borderzero/discovery/utils"
+)
+
+const (
+	defaultFileSnapshotStoreFileMode = os.FileMode(0600)
+	fileSnapshotStoreFileExtension   = ".json"
+)
+
+// FileSnapshotStore represents a snapshot store which keeps
+// the last result of each discoverer as a JSON file on disk.
+type FileSnapshotStore struct {
+	sync.Mutex // inherit lock behaviour
+
+	directory string
+	fileMode  os.FileMode
+}
+
+// ensure FileSnapshotStore implements discovery.SnapshotStore at compile-time.
+var _ discovery.SnapshotStore = (*FileSnapshotStore)(nil)
+
+// FileSnapshotStoreOption represents a configuration option for a FileSnapshotStore.
+type FileSnapshotStoreOption func(*FileSnapshotStore)
+
+// WithFileSnapshotStoreFileMode is the FileSnapshotStoreOption
+// to set a non default file mode for snapshot files.
+func WithFileSnapshotStoreFileMode(mode os.FileMode) FileSnapshotStoreOption {
+	return func(fss *FileSnapshotStore) { fss.fileMode = mode }
+}
+
+// NewFileSnapshotStore returns a new FileSnapshotStore which stores
+// snapshots in the given directory, initialized with the given options.
+func NewFileSnapshotStore(directory string, opts ...FileSnapshotStoreOption) *FileSnapshotStore {
+	fss := &FileSnapshotStore{
+		directory: directory,
+		fileMode:  defaultFileSnapshotStoreFileMode,
+	}
+	for _, opt := range opts {
+		opt(fss)
+	}
+	return fss
+}
+
+// Save writes a result to the FileSnapshotStore. The file is
+// written atomically so that a crash never leaves a partial snapshot.
+func (fss *FileSnapshotStore) Save(_ context.Context, result *discovery.Result) error {
+	result.Lock()
+	byt, err := json.Marshal(result)
+	discovererId := result.Metadata.DiscovererId
+	result.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to json encode result: %v", err)
+	}
+
+	fss.Lock()
+	defer fss.Unlock()
+
+	if err := utils.WriteFileAtomically(fss.snapshotPath(discovererId), byt, fss.fileMode); err != nil {
+		return fmt.Errorf("failed to write snapshot file: %v", err)
+	}
+	return nil
+}
+
+// Load reads all results in the FileSnapshotStore. A missing
+// directory is treated as an empty store rather than an error.
+func (fss *FileSnapshotStore) Load(_ context.Context) ([]*discovery.Result, error) {
+	fss.Lock()
+	defer fss.Unlock()
+
+	entries, err := os.ReadDir(fss.directory)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []*discovery.Result{}, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshot directory: %v", err)
+	}
+
+	results := []*discovery.Result{}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != fileSnapshotStoreFileExtension {
+			continue
+		}
+		byt, err := os.ReadFile(filepath.Join(fss.directory, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot file %s: %v", entry.Name(), err)
+		}
+		var result discovery.Result
+		if err := json.Unmarshal(byt, &result); err != nil {
+			return nil, fmt.Errorf("failed to json decode snapshot file %s: %v", entry.Name(), err)
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+func (fss *FileSnapshotStore) snapshotPath(discovererId string) string {
+	return filepath.Join(fss.directory, url.PathEscape(discovererId)+fileSnapshotStoreFileExtension)
+}
@@ -0,0 +1,50 @@
+package stores
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSnapshotStoreRoundTrip(t *testing.T) {
+	// the directory is created on the first save
+	testSnapshotStoreRoundTrip(t, NewFileSnapshotStore(filepath.Join(t.TempDir(), "snapshots")))
+}
+
+func TestFileSnapshotStoreFiles(t *testing.T) {
+	directory := t.TempDir()
+	store := NewFileSnapshotStore(directory, WithFileSnapshotStoreFileMode(0640))
+
+	if err := store.Save(context.Background(), newStoreTestResult("docker/local", newStoreTestContainer("c1", nil))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// files which are not snapshots are ignored
+	if err := os.WriteFile(filepath.Join(directory, "notes.txt"), []byte("not a snapshot"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(directory, "docker%2Flocal.json"))
+	if err != nil {
+		t.Fatalf("expected a snapshot file named after the escaped discoverer id: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Fatalf("expected file mode 0640, got %v", info.Mode().Perm())
+	}
+
+	loaded, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Metadata.DiscovererId != "docker/local" {
+		t.Fatalf("expected the snapshot of docker/local only, got %v", loaded)
+	}
+
+	// corrupt snapshots are reported
+	if err := os.WriteFile(filepath.Join(directory, "corrupt.json"), []byte("{"), 0600); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if _, err := store.Load(context.Background()); err == nil {
+		t.Fatal("expected an error for a corrupt snapshot file")
+	}
+}
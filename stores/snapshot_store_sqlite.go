@@ -0,0 +1,126 @@
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/borderzero/discovery"
+)
+
+const (
+	defaultSqliteSnapshotStoreTableName = "discovery_snapshots"
+)
+
+// SqliteSnapshotStore represents a snapshot store which keeps the
+// last result of each discoverer in a table of a SQLite database.
+//
+// The store does not import a SQLite driver. Callers open the *sql.DB
+// with the driver of their choice (e.g. modernc.org/sqlite or
+// github.com/mattn/go-sqlite3) and hand it to the constructor.
+type SqliteSnapshotStore struct {
+	db        *sql.DB
+	tableName string
+}
+
+// ensure SqliteSnapshotStore implements discovery.SnapshotStore at compile-time.
+var _ discovery.SnapshotStore = (*SqliteSnapshotStore)(nil)
+
+// SqliteSnapshotStoreOption represents a configuration option for a SqliteSnapshotStore.
+type SqliteSnapshotStoreOption func(*SqliteSnapshotStore)
+
+// WithSqliteSnapshotStoreTableName is the SqliteSnapshotStoreOption
+// to set a non default name for the snapshots table.
+func WithSqliteSnapshotStoreTableName(tableName string) SqliteSnapshotStoreOption {
+	return func(sss *SqliteSnapshotStore) { sss.tableName = tableName }
+}
+
+// NewSqliteSnapshotStore returns a new SqliteSnapshotStore, initialized with
+// the given options. The snapshots table is created if it does not exist.
+func NewSqliteSnapshotStore(
+	ctx context.Context,
+	db *sql.DB,
+	opts ...SqliteSnapshotStoreOption,
+) (*SqliteSnapshotStore, error) {
+	sss := &SqliteSnapshotStore{
+		db:        db,
+		tableName: defaultSqliteSnapshotStoreTableName,
+	}
+	for _, opt := range opts {
+		opt(sss)
+	}
+	if !isValidSqlIdentifier(sss.tableName) {
+		return nil, fmt.Errorf("invalid table name \"%s\"", sss.tableName)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			discoverer_id TEXT PRIMARY KEY,
+			saved_at      INTEGER NOT NULL,
+			result        BLOB NOT NULL
+		)`,
+		sss.tableName,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshots table: %v", err)
+	}
+	return sss, nil
+}
+
+// Save writes a result to the SqliteSnapshotStore.
+func (sss *SqliteSnapshotStore) Save(ctx context.Context, result *discovery.Result) error {
+	result.Lock()
+	byt, err := json.Marshal(result)
+	discovererId := result.Metadata.DiscovererId
+	result.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to json encode result: %v", err)
+	}
+
+	_, err = sss.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`
+			INSERT INTO %s (discoverer_id, saved_at, result) VALUES (?, ?, ?)
+			ON CONFLICT (discoverer_id) DO UPDATE SET saved_at = excluded.saved_at, result = excluded.result`,
+			sss.tableName,
+		),
+		discovererId,
+		time.Now().UnixNano(),
+		byt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert snapshot: %v", err)
+	}
+	return nil
+}
+
+// Load reads all results in the SqliteSnapshotStore.
+func (sss *SqliteSnapshotStore) Load(ctx context.Context) ([]*discovery.Result, error) {
+	rows, err := sss.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT discoverer_id, result FROM %s ORDER BY discoverer_id`, sss.tableName),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %v", err)
+	}
+	defer rows.Close()
+
+	results := []*discovery.Result{}
+	for rows.Next() {
+		var discovererId string
+		var byt []byte
+		if err := rows.Scan(&discovererId, &byt); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot row: %v", err)
+		}
+		var result discovery.Result
+		if err := json.Unmarshal(byt, &result); err != nil {
+			return nil, fmt.Errorf("failed to json decode snapshot for discoverer %s: %v", discovererId, err)
+		}
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate snapshot rows: %v", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,69 @@
+//go:build cgo
+
+package stores
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openSqliteTestDb opens a SQLite database in a temporary directory.
+func openSqliteTestDb(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "discovery.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSqliteSnapshotStoreRoundTrip(t *testing.T) {
+	store, err := NewSqliteSnapshotStore(context.Background(), openSqliteTestDb(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testSnapshotStoreRoundTrip(t, store)
+}
+
+func TestSqliteSnapshotStoreTableName(t *testing.T) {
+	ctx := context.Background()
+	db := openSqliteTestDb(t)
+
+	if _, err := NewSqliteSnapshotStore(ctx, db, WithSqliteSnapshotStoreTableName("snapshots; DROP TABLE x")); err == nil {
+		t.Fatal("expected an error for an invalid table name")
+	}
+
+	store, err := NewSqliteSnapshotStore(ctx, db, WithSqliteSnapshotStoreTableName("custom_snapshots"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Save(ctx, newStoreTestResult("docker")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM custom_snapshots").Scan(&count); err != nil {
+		t.Fatalf("failed to query table: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 snapshot row, got %d", count)
+	}
+
+	// stores over an existing table load what was saved before
+	reopened, err := NewSqliteSnapshotStore(ctx, db, WithSqliteSnapshotStoreTableName("custom_snapshots"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	loaded, err := reopened.Load(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Metadata.DiscovererId != "docker" {
+		t.Fatalf("expected the snapshot of docker, got %v", loaded)
+	}
+}
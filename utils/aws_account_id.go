@@ -6,9 +6,18 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
+// AwsStsClient represents the subset of the AWS STS API needed to get the aws account id.
+type AwsStsClient interface {
+	GetCallerIdentity(context.Context, *sts.GetCallerIdentityInput, ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
+// ensure *sts.Client implements AwsStsClient at compile-time.
+var _ AwsStsClient = (*sts.Client)(nil)
+
 // AwsAccountIdFromConfig returns the aws account id given an aws config.
 // It makes a call to AWS Session Token Service's (STS) "GetCallerIdentity"
 // API endpoint -- which does not require any IAM permissions to call.
@@ -17,20 +26,49 @@ func AwsAccountIdFromConfig(
 	cfg aws.Config,
 	timeout time.Duration,
 ) (string, error) {
+	return AwsAccountIdFromStsClient(ctx, sts.NewFromConfig(cfg), timeout)
+}
+
+// AwsAccountIdFromStsClient returns the aws account id given an aws sts client.
+// It is otherwise identical to AwsAccountIdFromConfig.
+func AwsAccountIdFromStsClient(
+	ctx context.Context,
+	stsClient AwsStsClient,
+	timeout time.Duration,
+) (string, error) {
+	awsAccountId, _, err := AwsAccountIdAndPartitionFromStsClient(ctx, stsClient, timeout)
+	return awsAccountId, err
+}
+
+// AwsAccountIdAndPartitionFromStsClient returns the aws account id and the
+// aws partition (e.g. "aws" or "aws-cn") of the caller given an aws sts client.
+// The partition is taken from the caller's ARN, and defaults to "aws".
+func AwsAccountIdAndPartitionFromStsClient(
+	ctx context.Context,
+	stsClient AwsStsClient,
+	timeout time.Duration,
+) (string, string, error) {
 	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	stsClient := sts.NewFromConfig(cfg)
-
 	getCallerIdentityOutput, err := stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get AWS account ID via the AWS STS API: %w", err)
+		return "", "", fmt.Errorf("failed to get AWS account ID via the AWS STS API: %w", err)
 	}
 
 	awsAccountId := aws.ToString(getCallerIdentityOutput.Account)
 	if awsAccountId == "" {
-		return "", fmt.Errorf("the AWS STS API returned an empty AWS account ID")
+		return "", "", fmt.Errorf("the AWS STS API returned an empty AWS account ID")
 	}
 
-	return awsAccountId, nil
+	return awsAccountId, AwsPartitionFromArn(aws.ToString(getCallerIdentityOutput.Arn)), nil
+}
+
+// AwsPartitionFromArn returns the aws partition of an ARN,
+// or "aws" (the standard partition) if the ARN is not valid.
+func AwsPartitionFromArn(awsArn string) string {
+	if parsed, err := arn.Parse(awsArn); err == nil && parsed.Partition != "" {
+		return parsed.Partition
+	}
+	return "aws"
 }
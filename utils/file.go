@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomically writes data to a file via a temporary file in the same
+// directory, so that readers never see a partially written file and a crash
+// never leaves one behind. The parent directory is created if it does not
+// exist, with the permissions of the file plus search (execute) permission
+// wherever the file is readable (e.g. 0700 for a 0600 file).
+func WriteFileAtomically(path string, data []byte, mode os.FileMode) error {
+	directory := filepath.Dir(path)
+	if err := os.MkdirAll(directory, directoryMode(mode)); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(directory, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %v", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temporary file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temporary file: %v", err)
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return fmt.Errorf("failed to set file mode: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move temporary file into place: %v", err)
+	}
+	return nil
+}
+
+// directoryMode returns the mode of a directory holding files with a given mode.
+func directoryMode(fileMode os.FileMode) os.FileMode {
+	mode := fileMode.Perm()
+	return mode | (mode&0444)>>2
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteFileAtomically(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "file.json")
+
+	for _, data := range []string{"first", "second"} {
+		if err := WriteFileAtomically(path, []byte(data), 0640); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		byt, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read file: %v", err)
+		}
+		if string(byt) != data {
+			t.Fatalf("expected contents %q, got %q", data, byt)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0640 {
+		t.Fatalf("expected file mode 0640, got %v", info.Mode().Perm())
+	}
+	info, err = os.Stat(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to stat directory: %v", err)
+	}
+	if info.Mode().Perm() != 0750 {
+		t.Fatalf("expected directory mode 0750, got %v", info.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatalf("failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected no temporary files to be left behind, got %d entries", len(entries))
+	}
+}